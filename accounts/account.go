@@ -5,6 +5,11 @@ import (
 	"time"
 
 	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/pgtype"
+	"github.com/joyent/triton-service-groups/convert"
+	"github.com/joyent/triton-service-groups/envelope"
+	"github.com/joyent/triton-service-groups/keys"
+	"github.com/joyent/triton-service-groups/tracing"
 	"github.com/pkg/errors"
 )
 
@@ -22,6 +27,7 @@ type Account struct {
 	KeyID       string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	Archived    bool
 
 	store *Store
 }
@@ -53,7 +59,7 @@ VALUES ($1, $2, NOW(), NOW());
 	}
 	defer tx.Rollback() // nolint: errcheck
 
-	_, err = pool.ExecEx(ctx, query, nil,
+	_, err = tracing.ExecEx(ctx, pool, query, nil,
 		a.AccountName,
 		a.TritonUUID,
 	)
@@ -95,13 +101,14 @@ func (a *Account) Save(ctx context.Context) error {
 
 	if a.KeyID == "" {
 		query := `
-UPDATE tsg_accounts SET (account_name, triton_uuid, updated_at) = ($2, $3, $4)
+UPDATE tsg_accounts SET (account_name, triton_uuid, archived, updated_at) = ($2, $3, $4, $5)
 WHERE id = $1;
 `
-		_, err := pool.ExecEx(ctx, query, nil,
+		_, err := tracing.ExecEx(ctx, pool, query, nil,
 			a.ID,
 			a.AccountName,
 			a.TritonUUID,
+			a.Archived,
 			updatedAt,
 		)
 		if err != nil {
@@ -110,14 +117,15 @@ WHERE id = $1;
 	} else {
 
 		query := `
-UPDATE tsg_accounts SET (account_name, triton_uuid, key_id, updated_at) = ($2, $3, $4, $5)
+UPDATE tsg_accounts SET (account_name, triton_uuid, key_id, archived, updated_at) = ($2, $3, $4, $5, $6)
 WHERE id = $1;
 `
-		_, err := pool.ExecEx(ctx, query, nil,
+		_, err := tracing.ExecEx(ctx, pool, query, nil,
 			a.ID,
 			a.AccountName,
 			a.TritonUUID,
 			a.KeyID,
+			a.Archived,
 			updatedAt,
 		)
 		if err != nil {
@@ -134,6 +142,75 @@ WHERE id = $1;
 	return nil
 }
 
+// rotationTimeout is how long a claim taken by ClaimRotation is honoured, in
+// case the agent holding it stopped before releasing it.
+const rotationTimeout = 15 * time.Minute
+
+// ClaimRotation marks the account as rotating its management key, serialising
+// rotations without holding a transaction open while they call CloudAPI and
+// Nomad. False is returned while another rotation holds the claim. KeyID is
+// re-read once claimed, since it may have changed since it was loaded.
+func (a *Account) ClaimRotation(ctx context.Context) (bool, error) {
+	if a.ID == "" {
+		return false, ErrMissingID
+	}
+
+	query := `
+UPDATE tsg_accounts SET rotating_at = $2
+WHERE id = $1 AND (rotating_at IS NULL OR rotating_at < $3)
+RETURNING key_id;
+`
+	now := time.Now()
+
+	var keyID pgtype.UUID
+	err := tracing.QueryRowEx(ctx, a.store.pool, query, nil,
+		a.ID,
+		now,
+		now.Add(-rotationTimeout),
+	).Scan(&keyID)
+	switch err {
+	case nil:
+	case pgx.ErrNoRows:
+		return false, nil
+	default:
+		return false, errors.Wrap(err, "failed to claim account rotation")
+	}
+
+	a.KeyID = convert.BytesToUUID(keyID.Bytes)
+
+	return true, nil
+}
+
+// ReleaseRotation releases the claim taken by ClaimRotation.
+func (a *Account) ReleaseRotation(ctx context.Context) error {
+	query := `UPDATE tsg_accounts SET rotating_at = NULL WHERE id = $1;`
+
+	if _, err := tracing.ExecEx(ctx, a.store.pool, query, nil, a.ID); err != nil {
+		return errors.Wrap(err, "failed to release account rotation")
+	}
+
+	return nil
+}
+
+// SaveKeyID switches the account to the management key identified by keyID.
+// KeyID is left for the caller to update.
+func (a *Account) SaveKeyID(ctx context.Context, keyID string) error {
+	query := `
+UPDATE tsg_accounts SET (key_id, updated_at) = ($2, $3)
+WHERE id = $1;
+`
+	_, err := tracing.ExecEx(ctx, a.store.pool, query, nil,
+		a.ID,
+		keyID,
+		time.Now(),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to save account key_id")
+	}
+
+	return nil
+}
+
 // Exists returns a boolean and error. True if the row exists, false if it
 // doesn't, error if there was an error executing the query.
 func (a *Account) Exists(ctx context.Context) (bool, error) {
@@ -154,7 +231,7 @@ WHERE (id = $1 OR account_name = $2) AND archived = false;
 		accountID = a.ID
 	}
 
-	err := a.store.pool.QueryRowEx(ctx, query, nil,
+	err := tracing.QueryRowEx(ctx, a.store.pool, query, nil,
 		accountID,
 		a.AccountName,
 	).Scan(
@@ -173,8 +250,8 @@ WHERE (id = $1 OR account_name = $2) AND archived = false;
 // GetTritonCredential gets Triton credentials from an existing Account. If the
 // account is found, then we will get the KeyID and KeyMaterial for the TSG
 // Management key of that account. If we do not find any credentials, we return
-// an error.
-func (a *Account) GetTritonCredential(ctx context.Context) (*TritonCredential, error) {
+// an error. Encrypted key material is decrypted with keyring.
+func (a *Account) GetTritonCredential(ctx context.Context, keyring *envelope.Keyring) (*TritonCredential, error) {
 	if a.AccountName == "" && a.KeyID == "" {
 		return nil, ErrCredExists
 	}
@@ -186,7 +263,7 @@ func (a *Account) GetTritonCredential(ctx context.Context) (*TritonCredential, e
 
 	query := `SELECT fingerprint, material FROM tsg_keys WHERE id = $1 AND archived = false;`
 
-	err := a.store.pool.QueryRowEx(ctx, query, nil, a.KeyID).Scan(
+	err := tracing.QueryRowEx(ctx, a.store.pool, query, nil, a.KeyID).Scan(
 		&fingerprint,
 		&material,
 	)
@@ -194,6 +271,11 @@ func (a *Account) GetTritonCredential(ctx context.Context) (*TritonCredential, e
 		return nil, err
 	}
 
+	material, err = keys.OpenMaterial(keyring, material)
+	if err != nil {
+		return nil, err
+	}
+
 	return &TritonCredential{
 		AccountName: a.AccountName,
 		KeyID:       fingerprint,
@@ -5,6 +5,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/jackc/pgx"
 	"github.com/joyent/triton-service-groups/accounts"
 	"github.com/joyent/triton-service-groups/keys"
 	"github.com/joyent/triton-service-groups/testutils"
@@ -136,7 +137,7 @@ func TestSave(t *testing.T) {
 		assert.Empty(t, account.KeyID)
 		assert.Equal(t, account.CreatedAt, account.UpdatedAt)
 
-		keyStore := keys.NewStore(db.Conn)
+		keyStore := keys.NewStore(db.Conn, nil)
 		require.NotNil(t, keyStore)
 
 		key := keys.New(keyStore)
@@ -168,6 +169,14 @@ func TestSave(t *testing.T) {
 		assert.NotZero(t, acct.CreatedAt)
 		assert.NotZero(t, acct.UpdatedAt)
 		assert.NotEqual(t, acct.CreatedAt, acct.UpdatedAt)
+
+		acct.Archived = true
+
+		err = acct.Save(context.Background())
+		require.NoError(t, err)
+
+		_, err = store.FindByName(context.Background(), accountName)
+		assert.Equal(t, pgx.ErrNoRows, err)
 	}
 }
 
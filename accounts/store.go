@@ -6,6 +6,7 @@ import (
 	"github.com/jackc/pgx"
 	"github.com/jackc/pgx/pgtype"
 	"github.com/joyent/triton-service-groups/convert"
+	"github.com/joyent/triton-service-groups/tracing"
 )
 
 type Store struct {
@@ -35,7 +36,7 @@ SELECT id, account_name, triton_uuid, key_id, created_at, updated_at
 FROM tsg_accounts
 WHERE id = $1 AND archived = false;
 `
-	err := s.pool.QueryRowEx(ctx, query, nil, accountID).Scan(
+	err := tracing.QueryRowEx(ctx, s.pool, query, nil, accountID).Scan(
 		&id,
 		&name,
 		&uuid,
@@ -74,7 +75,7 @@ SELECT id, account_name, triton_uuid, key_id, created_at, updated_at
 FROM tsg_accounts
 WHERE account_name = $1 AND archived = false;
 `
-	err := s.pool.QueryRowEx(ctx, query, nil, accountName).Scan(
+	err := tracing.QueryRowEx(ctx, s.pool, query, nil, accountName).Scan(
 		&id,
 		&name,
 		&uuid,
@@ -0,0 +1,96 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package accounts_v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/accounts"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/rs/zerolog/log"
+)
+
+// Offboard removes the account named within the path from TSG, responding
+// with a report of what was removed. Passing dry_run=true reports what would be
+// removed without changing anything.
+func Offboard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	vars := mux.Vars(r)
+	accountName := vars["account"]
+
+	var dryRun bool
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			apierror.Write(w, r, apierror.BadRequest("dry_run must be a boolean"))
+			return
+		}
+		dryRun = parsed
+	}
+
+	pool, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		apierror.Write(w, r, handlers.ErrNoConnPool)
+		return
+	}
+
+	acct, err := accounts.NewStore(pool).FindByName(ctx, accountName)
+	switch err {
+	case nil:
+	case pgx.ErrNoRows:
+		apierror.Write(w, r, apierror.NotFound("account %q not found", accountName))
+		return
+	default:
+		apierror.Write(w, r, err)
+		return
+	}
+
+	report, err := OffboardAccount(ctx, acct, session.Config(), session.Actor(), dryRun)
+	if err != nil {
+		log.Error().
+			Str("actor", session.Actor()).
+			Str("account_name", accountName).
+			Err(err).
+			Msg("accounts: failed to offboard account")
+
+		if report == nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		// Respond with what was removed before the failure, so the operator
+		// knows where to resume.
+		apierror.Write(w, r, apierror.New(http.StatusInternalServerError,
+			apierror.CodeInternal, "failed to offboard account").
+			With("report", report))
+		return
+	}
+
+	bytes, err := json.Marshal(report)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+func writeJSONResponse(w http.ResponseWriter, bytes []byte, statusCode int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if n, err := w.Write(bytes); err != nil {
+		log.Printf("%v", err)
+	} else if n != len(bytes) {
+		log.Printf("short write: %d/%d", n, len(bytes))
+	}
+}
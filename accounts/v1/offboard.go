@@ -0,0 +1,197 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package accounts_v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/joyent/triton-service-groups/accounts"
+	"github.com/joyent/triton-service-groups/allowlist"
+	"github.com/joyent/triton-service-groups/groups"
+	"github.com/joyent/triton-service-groups/keys"
+	"github.com/joyent/triton-service-groups/keys/v1"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/handlers/auth"
+	"github.com/joyent/triton-service-groups/sessions"
+	"github.com/joyent/triton-service-groups/templates"
+	"github.com/joyent/triton-service-groups/tokens"
+	"github.com/joyent/triton-service-groups/users"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// offboardedNote is recorded against the allow-list entry denying an
+// offboarded account.
+const offboardedNote = "Offboarded"
+
+// Offboarding reports what was removed when offboarding an account, or what
+// would be removed for a dry run.
+type Offboarding struct {
+	AccountName  string    `json:"account_name"`
+	Groups       []string  `json:"groups"`
+	Instances    int       `json:"instances"`
+	Templates    []string  `json:"templates"`
+	Key          string    `json:"key"`
+	Fingerprint  string    `json:"fingerprint"`
+	Tokens       int       `json:"tokens"`
+	Failed       []string  `json:"failed,omitempty"`
+	DryRun       bool      `json:"dry_run"`
+	OffboardedAt time.Time `json:"offboarded_at"`
+}
+
+// OffboardAccount removes acct from TSG. Every group of the account is scaled
+// to zero, its orchestrator job deleted and the group archived. Only once every
+// group has been removed is the TSG management key removed from Triton and the
+// account, its keys and templates archived. The account is denied within the
+// allow-list, so it isn't signed up again the next time it authenticates, and
+// its API tokens and browser sessions are revoked. Should any group fail, the
+// key is kept so the offboarding can be retried. Nothing is changed when
+// dryRun is true.
+//
+// ctx must carry the handler context. actor is recorded against every
+// archived group and template.
+func OffboardAccount(ctx context.Context, acct *accounts.Account, cfg auth.Config, actor string, dryRun bool) (*Offboarding, error) {
+	ctx = handlers.WithAuthSession(ctx, &auth.Session{
+		AccountID:  acct.ID,
+		Datacenter: cfg.Datacenter,
+		TritonURL:  cfg.TritonURL,
+		Permission: users.PermissionOwner,
+	})
+
+	report := &Offboarding{
+		AccountName:  acct.AccountName,
+		Groups:       []string{},
+		Templates:    []string{},
+		DryRun:       dryRun,
+		OffboardedAt: time.Now().UTC(),
+	}
+
+	groups, err := groups_v1.FindGroups(ctx, acct.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find groups")
+	}
+
+	for _, group := range groups {
+		capacity := group.Capacity
+
+		if !dryRun {
+			if err := removeGroup(ctx, acct, group, actor); err != nil {
+				log.Error().
+					Str("account_name", acct.AccountName).
+					Str("group_name", group.GroupName).
+					Err(err).
+					Msg("accounts: failed to remove group")
+				report.Failed = append(report.Failed, group.GroupName)
+				continue
+			}
+		}
+
+		report.Groups = append(report.Groups, group.GroupName)
+		report.Instances += capacity
+	}
+
+	if len(report.Failed) > 0 {
+		return report, fmt.Errorf("failed to remove groups, management key kept: %s",
+			strings.Join(report.Failed, ", "))
+	}
+
+	templates, err := templates_v1.FindTemplates(ctx, acct.ID)
+	if err != nil {
+		return report, errors.Wrap(err, "failed to find templates")
+	}
+
+	for _, template := range templates {
+		if !dryRun {
+			if err := templates_v1.RemoveTemplate(ctx, template.ID, acct.ID, actor); err != nil {
+				return report, errors.Wrapf(err, "failed to archive template %q", template.TemplateName)
+			}
+		}
+
+		report.Templates = append(report.Templates, template.TemplateName)
+	}
+
+	pool, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		return report, handlers.ErrNoConnPool
+	}
+
+	if dryRun {
+		list, err := tokens.NewStore(pool).FindByAccount(ctx, acct.ID)
+		if err != nil {
+			return report, errors.Wrap(err, "failed to find tokens")
+		}
+		for _, token := range list {
+			if !token.IsRevoked() {
+				report.Tokens++
+			}
+		}
+
+		if acct.KeyID != "" {
+			keyring, _ := handlers.GetKeyring(ctx)
+			key, err := keys.NewStore(pool, keyring).FindByID(ctx, acct.KeyID)
+			if err != nil {
+				return report, errors.Wrap(err, "failed to find current key")
+			}
+
+			report.Key = key.Name
+			report.Fingerprint = key.Fingerprint
+		}
+
+		return report, nil
+	}
+
+	if acct.KeyID != "" {
+		key, err := keys_v1.RemoveKey(ctx, acct, cfg)
+		if err != nil {
+			return report, err
+		}
+
+		report.Key = key.Name
+		report.Fingerprint = key.Fingerprint
+	}
+
+	if _, err := allowlist.NewStore(pool).Set(ctx, acct.AccountName, false, offboardedNote, actor); err != nil {
+		return report, errors.Wrap(err, "failed to deny account")
+	}
+
+	revoked, err := tokens.NewStore(pool).RevokeByAccount(ctx, acct.ID)
+	if err != nil {
+		return report, errors.Wrap(err, "failed to revoke tokens")
+	}
+	report.Tokens = int(revoked)
+
+	if _, err := sessions.NewStore(pool).DeleteByAccount(ctx, acct.ID); err != nil {
+		return report, errors.Wrap(err, "failed to delete sessions")
+	}
+
+	acct.Archived = true
+	if err := acct.Save(ctx); err != nil {
+		return report, errors.Wrap(err, "failed to archive account")
+	}
+
+	log.Info().
+		Str("actor", actor).
+		Str("account_name", acct.AccountName).
+		Int("groups", len(report.Groups)).
+		Int("templates", len(report.Templates)).
+		Int("tokens", report.Tokens).
+		Msg("accounts: offboarded account")
+
+	return report, nil
+}
+
+// removeGroup scales a group to zero, deletes its orchestrator job and
+// archives it.
+func removeGroup(ctx context.Context, acct *accounts.Account, group *groups_v1.ServiceGroup, actor string) error {
+	if err := groups_v1.DeleteOrchestratorJob(ctx, group); err != nil {
+		return err
+	}
+
+	return groups_v1.RemoveGroup(ctx, group.ID, acct.ID, actor)
+}
@@ -3,15 +3,25 @@ package agent
 import (
 	"context"
 	"os"
+	"sync"
+	"time"
 
 	nomad "github.com/hashicorp/nomad/api"
 	"github.com/jackc/pgx"
 	"github.com/joyent/triton-service-groups/buildtime"
 	"github.com/joyent/triton-service-groups/config"
+	"github.com/joyent/triton-service-groups/envelope"
 	"github.com/joyent/triton-service-groups/server"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 )
 
+// tracingFlushTimeout bounds how long exporting the remaining spans can delay
+// shutting down.
+const tracingFlushTimeout = 5 * time.Second
+
 type Agent struct {
 	signalCh    chan os.Signal
 	shutdownCtx context.Context
@@ -19,6 +29,10 @@ type Agent struct {
 	config      *config.Config
 	pool        *pgx.ConnPool
 	nomad       *nomad.Client
+	keyring     *envelope.Keyring
+
+	mu     sync.Mutex
+	server *server.HTTPServer
 }
 
 func New(cfg *config.Config) *Agent {
@@ -34,18 +48,39 @@ func (a *Agent) Run(ctx context.Context) (err error) {
 
 	a.shutdownCtx, a.shutdown = context.WithCancel(ctx)
 
+	// Refuse to serve the API with a provider which skips authentication
+	// unless it's been explicitly allowed.
+	if err = server.NewAuthConfig(a.config.HTTPServer).Validate(); err != nil {
+		return errors.Wrap(err, "invalid auth configuration")
+	}
+
 	go a.handleSignals()
 
-	if err = a.ensureDBPool(); err != nil {
+	if err = a.Open(); err != nil {
 		return err
 	}
 
-	if err = a.ensureNomadClient(); err != nil {
-		return err
+	var shutdownTracing func(context.Context) error
+	if a.config.Tracing.Enable {
+		exporter, err := tracing.NewExporter(a.shutdownCtx, a.config.Tracing.Endpoint)
+		if err != nil {
+			return errors.Wrap(err, "failed to create span exporter")
+		}
+		shutdownTracing = tracing.Install(tracing.NewProvider(exporter, a.config.Tracing.ServiceName))
+		log.Info().Msgf("tracing: exporting spans to %q", a.config.Tracing.Endpoint)
 	}
 
-	srv := server.New(a.config.HTTPServer, a.pool, a.nomad)
-	srv.Start()
+	srv := server.New(a.config.HTTPServer, a.pool, a.nomad, a.keyring)
+	if err = srv.Start(); err != nil {
+		return errors.Wrap(err, "failed to start HTTP server")
+	}
+	a.setServer(srv)
+
+	var metricsSrv *server.MetricsServer
+	if a.config.Metrics.Enable {
+		metricsSrv = server.NewMetricsServer(a.config.Metrics, a.pool)
+		metricsSrv.Start()
+	}
 
 	for {
 		<-a.shutdownCtx.Done()
@@ -53,6 +88,24 @@ func (a *Agent) Run(ctx context.Context) (err error) {
 		if err != nil {
 			log.Warn().Err(err)
 		}
+
+		if metricsSrv != nil {
+			if err := metricsSrv.Stop(a.shutdownCtx); err != nil {
+				log.Warn().Err(err).Msg("metrics: failed to stop metrics server")
+			}
+		}
+
+		if shutdownTracing != nil {
+			flushCtx, cancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+			if err := shutdownTracing(flushCtx); err != nil {
+				log.Warn().Err(err).Msg("tracing: failed to export remaining spans")
+			}
+			cancel()
+		}
+
+		// The pool is only closed once the server has stopped, so requests
+		// served while draining can still reach the database.
+		a.Close()
 		return nil
 	}
 }
@@ -61,6 +114,65 @@ func (a *Agent) Stop() {
 	log.Info().Msgf("agent: shutting down %s agent", buildtime.PROGNAME)
 
 	a.stopSignalCh()
-	a.pool.Close()
 	a.shutdown()
 }
+
+func (a *Agent) setServer(srv *server.HTTPServer) {
+	a.mu.Lock()
+	a.server = srv
+	a.mu.Unlock()
+}
+
+// Reload reads the TLS certificates of the HTTP server from disk again.
+func (a *Agent) Reload() {
+	a.mu.Lock()
+	srv := a.server
+	a.mu.Unlock()
+
+	if srv == nil {
+		return
+	}
+
+	if err := srv.ReloadTLS(); err != nil {
+		log.Error().Err(err).Msg("agent: failed to reload TLS certificates, serving the previous ones")
+	}
+}
+
+// Open connects to the database and job scheduler and loads the encryption
+// keyring without serving the HTTP API. Commands which call into the API
+// handlers directly use it alongside Context.
+func (a *Agent) Open() error {
+	if err := a.ensureDBPool(); err != nil {
+		return err
+	}
+
+	if err := a.ensureNomadClient(); err != nil {
+		return err
+	}
+
+	return a.ensureKeyring()
+}
+
+// Context returns a copy of ctx carrying the connections opened by Open, as
+// expected by the API handlers.
+func (a *Agent) Context(ctx context.Context) context.Context {
+	return handlers.NewContext(ctx, a.pool, a.nomad, a.keyring)
+}
+
+// Pool returns the database connection pool opened by Open.
+func (a *Agent) Pool() *pgx.ConnPool {
+	return a.pool
+}
+
+// Keyring returns the encryption keyring loaded by Open, or nil when no
+// key-encryption key has been configured.
+func (a *Agent) Keyring() *envelope.Keyring {
+	return a.keyring
+}
+
+// Close closes the connections opened by Open.
+func (a *Agent) Close() {
+	if a.pool != nil {
+		a.pool.Close()
+	}
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"github.com/joyent/triton-service-groups/envelope"
+	"github.com/rs/zerolog/log"
+)
+
+func (a *Agent) ensureKeyring() error {
+	cfg := a.config.Encryption
+
+	var (
+		kek []byte
+		err error
+	)
+
+	switch {
+	case cfg.Key != "":
+		kek, err = envelope.ParseKey(cfg.Key)
+	case cfg.KeyFile != "":
+		kek, err = envelope.LoadKey(cfg.KeyFile)
+	default:
+		log.Warn().Msg("agent: no encryption key configured, secrets are disabled " +
+			"and management keys are stored unencrypted")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	keyring, err := envelope.NewKeyring(kek)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range cfg.PreviousKeyFiles {
+		previous, err := envelope.LoadKey(path)
+		if err != nil {
+			return err
+		}
+
+		if err := keyring.AddPreviousKey(previous); err != nil {
+			return err
+		}
+	}
+
+	a.keyring = keyring
+
+	log.Debug().
+		Str("key_id", keyring.ID()).
+		Msg("agent: loaded key-encryption key")
+
+	return nil
+}
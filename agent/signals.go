@@ -15,6 +15,7 @@ func (a *Agent) handleSignals() {
 	signal.Notify(a.signalCh,
 		syscall.SIGINT,
 		syscall.SIGTERM,
+		syscall.SIGHUP,
 	)
 	defer a.Stop()
 	for {
@@ -31,6 +32,12 @@ func (a *Agent) handleSignals() {
 					Msgf("agent: process received %s signal", sig)
 
 				return
+			case syscall.SIGHUP:
+				log.Info().
+					Str("signal", sig.String()).
+					Msg("agent: reloading TLS certificates")
+
+				a.Reload()
 			default:
 				panic(fmt.Sprintf("unsupported signal: %v", sig))
 			}
@@ -0,0 +1,172 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package allowlist
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrMissingName = errors.New("missing account name for save")
+	ErrMissingID   = errors.New("missing identifer for save")
+)
+
+// Entry represents the data associated with an tsg_allowlist row. An entry
+// explicitly allows or denies a Triton account access to TSG, whether or not
+// the account has authenticated with TSG before.
+type Entry struct {
+	ID          string
+	AccountName string
+	Allowed     bool
+	Note        string
+	CreatedBy   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+
+	store *Store
+}
+
+// New constructs a new Entry with the Store for backend persistence.
+func New(store *Store) *Entry {
+	return &Entry{
+		store: store,
+	}
+}
+
+// Insert inserts a new entry into the tsg_allowlist table.
+func (e *Entry) Insert(ctx context.Context) error {
+	if e.AccountName == "" {
+		return ErrMissingName
+	}
+
+	query := `
+INSERT INTO tsg_allowlist (account_name, allowed, note, created_by, created_at, updated_at)
+VALUES ($1, $2, $3, $4, NOW(), NOW());
+`
+	pool := e.store.pool
+
+	tx, err := pool.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback() // nolint: errcheck
+
+	_, err = tracing.ExecEx(ctx, pool, query, nil,
+		e.AccountName,
+		e.Allowed,
+		e.Note,
+		e.CreatedBy,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert allow-list entry")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	entry, err := e.store.FindByName(ctx, e.AccountName)
+	if err != nil {
+		return errors.Wrap(err, "failed to find allow-list entry after insert")
+	}
+
+	e.ID = entry.ID
+	e.CreatedAt = entry.CreatedAt
+	e.UpdatedAt = entry.UpdatedAt
+
+	return nil
+}
+
+// Save saves an allowlist.Entry object and it's field values.
+func (e *Entry) Save(ctx context.Context) error {
+	if e.ID == "" {
+		return ErrMissingID
+	}
+
+	query := `
+UPDATE tsg_allowlist SET (allowed, note, updated_at) = ($2, $3, $4)
+WHERE id = $1;
+`
+	updatedAt := time.Now()
+
+	pool := e.store.pool
+
+	tx, err := pool.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback() // nolint: errcheck
+
+	_, err = tracing.ExecEx(ctx, pool, query, nil,
+		e.ID,
+		e.Allowed,
+		e.Note,
+		updatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update allow-list entry")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	e.UpdatedAt = updatedAt
+
+	return nil
+}
+
+// Delete removes the entry from the tsg_allowlist table, after which the
+// account is treated as if it had never been listed.
+func (e *Entry) Delete(ctx context.Context) error {
+	if e.ID == "" {
+		return ErrMissingID
+	}
+
+	query := `DELETE FROM tsg_allowlist WHERE id = $1;`
+
+	if _, err := tracing.ExecEx(ctx, e.store.pool, query, nil, e.ID); err != nil {
+		return errors.Wrap(err, "failed to delete allow-list entry")
+	}
+
+	return nil
+}
+
+// Set allows or denies an account, creating its entry when it isn't listed
+// yet. The note of an existing entry is only replaced when note isn't empty.
+func (s *Store) Set(ctx context.Context, accountName string, allowed bool, note string, actor string) (*Entry, error) {
+	entry, err := s.FindByName(ctx, accountName)
+	switch err {
+	case nil:
+		entry.Allowed = allowed
+		if note != "" {
+			entry.Note = note
+		}
+
+		if err := entry.Save(ctx); err != nil {
+			return nil, err
+		}
+	case pgx.ErrNoRows:
+		entry = New(s)
+		entry.AccountName = accountName
+		entry.Allowed = allowed
+		entry.Note = note
+		entry.CreatedBy = actor
+
+		if err := entry.Insert(ctx); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Wrap(err, "failed to find allow-list entry")
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,58 @@
+package allowlist_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/allowlist"
+	"github.com/joyent/triton-service-groups/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetAndDelete(t *testing.T) {
+	if os.Getenv("TSG_TEST") == "" {
+		t.Skip("Acceptance tests skipped unless env 'TSG_TEST=1' set")
+		return
+	}
+
+	db, err := testutils.NewTestDB()
+	if err != nil {
+		t.Error(err)
+	}
+	db.Clear(t)
+	defer db.Clear(t)
+
+	ctx := context.Background()
+	store := allowlist.NewStore(db.Conn)
+
+	entry, err := store.Set(ctx, "baconuser", true, "Beta customer", "ops")
+	require.NoError(t, err)
+	assert.NotZero(t, entry.ID)
+	assert.True(t, entry.Allowed)
+
+	denied, err := store.Set(ctx, "baconuser", false, "", "ops")
+	require.NoError(t, err)
+	assert.Equal(t, entry.ID, denied.ID)
+
+	found, err := store.FindByName(ctx, "baconuser")
+	require.NoError(t, err)
+	assert.False(t, found.Allowed)
+	assert.Equal(t, "Beta customer", found.Note)
+	assert.Equal(t, "ops", found.CreatedBy)
+
+	_, err = store.Set(ctx, "eggsuser", true, "", "ops")
+	require.NoError(t, err)
+
+	all, err := store.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "baconuser", all[0].AccountName)
+
+	require.NoError(t, found.Delete(ctx))
+
+	_, err = store.FindByName(ctx, "baconuser")
+	assert.Equal(t, pgx.ErrNoRows, err)
+}
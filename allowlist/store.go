@@ -0,0 +1,102 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package allowlist
+
+import (
+	"context"
+
+	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/pgtype"
+	"github.com/joyent/triton-service-groups/convert"
+	"github.com/joyent/triton-service-groups/tracing"
+)
+
+type Store struct {
+	pool *pgx.ConnPool
+}
+
+// NewStore returns a new store object.
+func NewStore(pool *pgx.ConnPool) *Store {
+	return &Store{
+		pool: pool,
+	}
+}
+
+const selectEntries = `
+SELECT id, account_name, allowed, COALESCE(note, ''), COALESCE(created_by, ''),
+       created_at, updated_at
+FROM tsg_allowlist
+`
+
+// scanner is implemented by both *pgx.Row and *pgx.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (s *Store) scanEntry(row scanner) (*Entry, error) {
+	var (
+		id          pgtype.UUID
+		accountName string
+		allowed     bool
+		note        string
+		createdBy   string
+		createdAt   pgtype.Timestamptz
+		updatedAt   pgtype.Timestamptz
+	)
+
+	err := row.Scan(
+		&id,
+		&accountName,
+		&allowed,
+		&note,
+		&createdBy,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := New(s)
+	entry.ID = convert.BytesToUUID(id.Bytes)
+	entry.AccountName = accountName
+	entry.Allowed = allowed
+	entry.Note = note
+	entry.CreatedBy = createdBy
+	entry.CreatedAt = createdAt.Time
+	entry.UpdatedAt = updatedAt.Time
+
+	return entry, nil
+}
+
+// FindByName finds the entry of an account by the account's name.
+func (s *Store) FindByName(ctx context.Context, accountName string) (*Entry, error) {
+	query := selectEntries + `WHERE account_name = $1;`
+
+	return s.scanEntry(tracing.QueryRowEx(ctx, s.pool, query, nil, accountName))
+}
+
+// FindAll finds every entry, ordered by account name.
+func (s *Store) FindAll(ctx context.Context) ([]*Entry, error) {
+	query := selectEntries + `ORDER BY account_name;`
+
+	rows, err := tracing.QueryEx(ctx, s.pool, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*Entry
+	for rows.Next() {
+		entry, err := s.scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,209 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package allowlist_v1
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/allowlist"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/schema"
+	"github.com/rs/zerolog/log"
+)
+
+var errEntryNotFound = apierror.NotFound("account is not listed")
+
+// Entry represents an account which has explicitly been allowed or denied
+// access to TSG.
+type Entry struct {
+	AccountName string    `json:"account_name"`
+	Allowed     bool      `json:"allowed"`
+	Note        string    `json:"note"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func newEntry(entry *allowlist.Entry) *Entry {
+	return &Entry{
+		AccountName: entry.AccountName,
+		Allowed:     entry.Allowed,
+		Note:        entry.Note,
+		CreatedBy:   entry.CreatedBy,
+		CreatedAt:   entry.CreatedAt,
+		UpdatedAt:   entry.UpdatedAt,
+	}
+}
+
+func getStore(ctx context.Context) (*allowlist.Store, error) {
+	pool, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		return nil, handlers.ErrNoConnPool
+	}
+	return allowlist.NewStore(pool), nil
+}
+
+func Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	accountName := vars["account"]
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	entry, err := store.FindByName(ctx, accountName)
+	if err != nil {
+		writeFindError(w, r, err)
+		return
+	}
+
+	bytes, err := json.Marshal(newEntry(entry))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+// Update allows or denies the account named within the path, creating its
+// entry when the account isn't listed yet.
+func Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	vars := mux.Vars(r)
+	accountName := vars["account"]
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	var input *Entry
+	if err := schema.Decode(schema.AllowListEntry, body, &input); err != nil {
+		schema.WriteError(w, r, err)
+		return
+	}
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	entry, err := store.Set(ctx, accountName, input.Allowed, input.Note, session.Actor())
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	log.Info().
+		Str("actor", session.Actor()).
+		Str("account_name", entry.AccountName).
+		Bool("allowed", entry.Allowed).
+		Msg("allowlist: updated account access")
+
+	bytes, err := json.Marshal(newEntry(entry))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+func Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	vars := mux.Vars(r)
+	accountName := vars["account"]
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	entry, err := store.FindByName(ctx, accountName)
+	if err != nil {
+		writeFindError(w, r, err)
+		return
+	}
+
+	if err := entry.Delete(ctx); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	log.Info().
+		Str("actor", session.Actor()).
+		Str("account_name", entry.AccountName).
+		Msg("allowlist: removed account entry")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	rows, err := store.FindAll(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	list := make([]*Entry, 0, len(rows))
+	for _, entry := range rows {
+		list = append(list, newEntry(entry))
+	}
+
+	bytes, err := json.Marshal(list)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+// writeFindError responds with a 404 when an account isn't listed. Any other
+// error is logged and treated the same way to match the other resources.
+func writeFindError(w http.ResponseWriter, r *http.Request, err error) {
+	if err != pgx.ErrNoRows {
+		log.Debug().Err(err).Msg("allowlist: failed to find entry")
+	}
+	apierror.Write(w, r, errEntryNotFound)
+}
+
+func writeJSONResponse(w http.ResponseWriter, bytes []byte, statusCode int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if n, err := w.Write(bytes); err != nil {
+		log.Printf("%v", err)
+	} else if n != len(bytes) {
+		log.Printf("short write: %d/%d", n, len(bytes))
+	}
+}
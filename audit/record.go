@@ -0,0 +1,107 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/pgtype"
+	"github.com/joyent/triton-service-groups/convert"
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/pkg/errors"
+)
+
+const (
+	// OutcomeSuccess is recorded when a request was served with a 1xx, 2xx
+	// or 3xx response.
+	OutcomeSuccess = "success"
+
+	// OutcomeFailure is recorded when a request was rejected or failed with a
+	// 4xx or 5xx response.
+	OutcomeFailure = "failure"
+)
+
+var ErrNoAccountID = errors.New("missing account identifer for insert")
+
+// Record represents the data associated with an tsg_audit row. A record is
+// written for every mutating API call and is never updated.
+type Record struct {
+	ID          string
+	AccountID   string
+	Actor       string
+	UserName    string
+	TokenID     string
+	Fingerprint string
+	Route       string
+	Method      string
+	Path        string
+	ResourceID  string
+
+	// RequestBody is the sanitized JSON request body, empty when the request
+	// had no JSON body.
+	RequestBody string
+	Status      int
+	Outcome     string
+	CreatedAt   time.Time
+
+	store *Store
+}
+
+// New constructs a new Record with the Store for backend persistence.
+func New(store *Store) *Record {
+	return &Record{
+		store: store,
+	}
+}
+
+// OutcomeOf returns the outcome of a request served with status.
+func OutcomeOf(status int) string {
+	if status >= 400 {
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
+
+// Insert inserts the record into the tsg_audit table.
+func (r *Record) Insert(ctx context.Context) error {
+	if r.AccountID == "" {
+		return ErrNoAccountID
+	}
+
+	query := `
+INSERT INTO tsg_audit (account_id, actor, user_name, token_id, fingerprint, route, method, path, resource_id, request_body, status, outcome, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW())
+RETURNING id, created_at;
+`
+	var (
+		id        pgtype.UUID
+		createdAt pgtype.Timestamptz
+	)
+
+	err := tracing.QueryRowEx(ctx, r.store.pool, query, nil,
+		r.AccountID,
+		r.Actor,
+		r.UserName,
+		r.TokenID,
+		r.Fingerprint,
+		r.Route,
+		r.Method,
+		r.Path,
+		r.ResourceID,
+		r.RequestBody,
+		r.Status,
+		r.Outcome,
+	).Scan(&id, &createdAt)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert audit record")
+	}
+
+	r.ID = convert.BytesToUUID(id.Bytes)
+	r.CreatedAt = createdAt.Time
+
+	return nil
+}
@@ -0,0 +1,77 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Redacted replaces the value of every sensitive field.
+	Redacted = "[REDACTED]"
+
+	// MaxBodySize is the largest sanitized request body which is recorded.
+	MaxBodySize = 8 * 1024
+)
+
+// sensitiveFields are the request body fields whose values are never
+// recorded, such as the value of a secret.
+var sensitiveFields = map[string]bool{
+	"value":       true,
+	"secret":      true,
+	"token":       true,
+	"password":    true,
+	"material":    true,
+	"private_key": true,
+}
+
+// Sanitize returns the JSON request body with the value of every sensitive
+// field redacted, at any depth. Bodies which aren't JSON return an empty
+// string and bodies larger than MaxBodySize are summarized by their size.
+func Sanitize(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return ""
+	}
+
+	sanitized, err := json.Marshal(redact(value))
+	if err != nil {
+		return ""
+	}
+
+	if len(sanitized) > MaxBodySize {
+		return fmt.Sprintf(`{"truncated":true,"size":%d}`, len(body))
+	}
+
+	return string(sanitized)
+}
+
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if sensitiveFields[strings.ToLower(key)] {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = redact(field)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
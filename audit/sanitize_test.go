@@ -0,0 +1,41 @@
+package audit_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/audit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"empty", ``, ``},
+		{"not json", `capacity=3`, ``},
+		{"plain", `{"capacity":3}`, `{"capacity":3}`},
+		{"secret value", `{"name":"db","value":"hunter2"}`, `{"name":"db","value":"[REDACTED]"}`},
+		{"nested", `{"items":[{"Token":"tsg_abc","name":"ci"}]}`, `{"items":[{"Token":"[REDACTED]","name":"ci"}]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, audit.Sanitize([]byte(test.body)))
+		})
+	}
+}
+
+func TestSanitizeTruncates(t *testing.T) {
+	body := `{"userdata":"` + strings.Repeat("x", audit.MaxBodySize) + `"}`
+
+	assert.Equal(t, `{"truncated":true,"size":8207}`, audit.Sanitize([]byte(body)))
+}
+
+func TestOutcomeOf(t *testing.T) {
+	assert.Equal(t, audit.OutcomeSuccess, audit.OutcomeOf(201))
+	assert.Equal(t, audit.OutcomeSuccess, audit.OutcomeOf(204))
+	assert.Equal(t, audit.OutcomeFailure, audit.OutcomeOf(403))
+	assert.Equal(t, audit.OutcomeFailure, audit.OutcomeOf(500))
+}
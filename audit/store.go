@@ -0,0 +1,186 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/pgtype"
+	"github.com/joyent/triton-service-groups/convert"
+	"github.com/joyent/triton-service-groups/tracing"
+)
+
+type Store struct {
+	pool *pgx.ConnPool
+}
+
+// NewStore returns a new store object.
+func NewStore(pool *pgx.ConnPool) *Store {
+	return &Store{
+		pool: pool,
+	}
+}
+
+// Filter selects the records of an account. Zero values don't filter.
+type Filter struct {
+	AccountID  string
+	Since      time.Time
+	Until      time.Time
+	Route      string
+	ResourceID string
+
+	// Limit caps the number of records returned, zero returns every record.
+	Limit int
+
+	// Chronological orders records oldest first rather than newest first.
+	Chronological bool
+}
+
+const selectRecords = `
+SELECT id, account_id, actor, user_name, token_id, fingerprint, route, method,
+       path, resource_id, request_body, status, outcome, created_at
+FROM tsg_audit
+`
+
+// scanner is implemented by both *pgx.Row and *pgx.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (s *Store) scanRecord(row scanner) (*Record, error) {
+	var (
+		id          pgtype.UUID
+		accountID   pgtype.UUID
+		actor       string
+		userName    string
+		tokenID     string
+		fingerprint string
+		route       string
+		method      string
+		path        string
+		resourceID  string
+		requestBody string
+		status      int64
+		outcome     string
+		createdAt   pgtype.Timestamptz
+	)
+
+	err := row.Scan(
+		&id,
+		&accountID,
+		&actor,
+		&userName,
+		&tokenID,
+		&fingerprint,
+		&route,
+		&method,
+		&path,
+		&resourceID,
+		&requestBody,
+		&status,
+		&outcome,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	record := New(s)
+	record.ID = convert.BytesToUUID(id.Bytes)
+	record.AccountID = convert.BytesToUUID(accountID.Bytes)
+	record.Actor = actor
+	record.UserName = userName
+	record.TokenID = tokenID
+	record.Fingerprint = fingerprint
+	record.Route = route
+	record.Method = method
+	record.Path = path
+	record.ResourceID = resourceID
+	record.RequestBody = requestBody
+	record.Status = int(status)
+	record.Outcome = outcome
+	record.CreatedAt = createdAt.Time
+
+	return record, nil
+}
+
+// Each calls fn with every record selected by filter, without loading every
+// record into memory first. Iteration stops at the first error returned by
+// fn.
+func (s *Store) Each(ctx context.Context, filter Filter, fn func(*Record) error) error {
+	var (
+		where []string
+		args  []interface{}
+	)
+
+	add := func(clause string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+
+	add("account_id = $%d", filter.AccountID)
+	if !filter.Since.IsZero() {
+		add("created_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		add("created_at < $%d", filter.Until)
+	}
+	if filter.Route != "" {
+		add("route = $%d", filter.Route)
+	}
+	if filter.ResourceID != "" {
+		add("resource_id = $%d", filter.ResourceID)
+	}
+
+	query := selectRecords + `WHERE ` + strings.Join(where, " AND ")
+
+	if filter.Chronological {
+		query += ` ORDER BY created_at ASC, id ASC`
+	} else {
+		query += ` ORDER BY created_at DESC, id DESC`
+	}
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(` LIMIT %d`, filter.Limit)
+	}
+
+	rows, err := tracing.QueryEx(ctx, s.pool, query+`;`, nil, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		record, err := s.scanRecord(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// FindAll finds every record selected by filter.
+func (s *Store) FindAll(ctx context.Context, filter Filter) ([]*Record, error) {
+	var records []*Record
+
+	err := s.Each(ctx, filter, func(record *Record) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
@@ -0,0 +1,66 @@
+package audit_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joyent/triton-service-groups/audit"
+	"github.com/joyent/triton-service-groups/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsertAndFindAll(t *testing.T) {
+	if os.Getenv("TSG_TEST") == "" {
+		t.Skip("Acceptance tests skipped unless env 'TSG_TEST=1' set")
+		return
+	}
+
+	db, err := testutils.NewTestDB()
+	if err != nil {
+		t.Error(err)
+	}
+	db.Clear(t)
+	defer db.Clear(t)
+
+	ctx := context.Background()
+	store := audit.NewStore(db.Conn)
+
+	accountID := "6f873d02-172c-418f-8416-4da2b50d5c53"
+
+	for _, route := range []string{"CreateTemplate", "DeleteTemplate"} {
+		record := audit.New(store)
+		record.AccountID = accountID
+		record.Actor = "testaccount/demouser"
+		record.UserName = "demouser"
+		record.Route = route
+		record.Method = "POST"
+		record.Path = "/v1/tsg/templates"
+		record.ResourceID = "ad74301e-ad62-404a-be44-3b2f24d082ac"
+		record.Status = 204
+		record.Outcome = audit.OutcomeOf(204)
+		require.NoError(t, record.Insert(ctx))
+		assert.NotZero(t, record.ID)
+		assert.NotZero(t, record.CreatedAt)
+	}
+
+	records, err := store.FindAll(ctx, audit.Filter{AccountID: accountID})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "DeleteTemplate", records[0].Route, "newest first")
+
+	records, err = store.FindAll(ctx, audit.Filter{AccountID: accountID, Route: "DeleteTemplate"})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "testaccount/demouser", records[0].Actor)
+
+	records, err = store.FindAll(ctx, audit.Filter{AccountID: accountID, Since: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Len(t, records, 0)
+
+	records, err = store.FindAll(ctx, audit.Filter{AccountID: "d255305d-aa60-49bc-acc2-3713cf0beb1c"})
+	require.NoError(t, err)
+	assert.Len(t, records, 0)
+}
@@ -0,0 +1,193 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package audit_v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/joyent/triton-service-groups/audit"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultLimit is the number of records listed unless a limit is given.
+	DefaultLimit = 100
+
+	// MaxLimit is the largest number of records which can be listed at once.
+	// Exports aren't limited.
+	MaxLimit = 1000
+
+	// FormatJSONLines exports every record as a JSON object per line.
+	FormatJSONLines = "jsonl"
+)
+
+// Record is an audit record of a mutating API call.
+type Record struct {
+	ID          string          `json:"id"`
+	Actor       string          `json:"actor"`
+	UserName    string          `json:"user_name"`
+	TokenID     string          `json:"token_id"`
+	Fingerprint string          `json:"fingerprint"`
+	Route       string          `json:"route"`
+	Method      string          `json:"method"`
+	Path        string          `json:"path"`
+	ResourceID  string          `json:"resource_id"`
+	Request     json.RawMessage `json:"request"`
+	Status      int             `json:"status"`
+	Outcome     string          `json:"outcome"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func newRecord(record *audit.Record) *Record {
+	request := json.RawMessage("null")
+	if record.RequestBody != "" {
+		request = json.RawMessage(record.RequestBody)
+	}
+
+	return &Record{
+		ID:          record.ID,
+		Actor:       record.Actor,
+		UserName:    record.UserName,
+		TokenID:     record.TokenID,
+		Fingerprint: record.Fingerprint,
+		Route:       record.Route,
+		Method:      record.Method,
+		Path:        record.Path,
+		ResourceID:  record.ResourceID,
+		Request:     request,
+		Status:      record.Status,
+		Outcome:     record.Outcome,
+		CreatedAt:   record.CreatedAt,
+	}
+}
+
+// List lists the audit records of the account, newest first. Records can be
+// filtered by time range, route and resource. With ?format=jsonl every
+// matching record is exported oldest first as JSON lines.
+func List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	filter, format, err := parseFilter(r.URL.Query())
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("%s", err))
+		return
+	}
+	filter.AccountID = session.AccountID
+
+	pool, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		apierror.Write(w, r, handlers.ErrNoConnPool)
+		return
+	}
+	store := audit.NewStore(pool)
+
+	if format == FormatJSONLines {
+		exportJSONLines(w, r, store, filter)
+		return
+	}
+
+	rows, err := store.FindAll(ctx, filter)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	list := make([]*Record, 0, len(rows))
+	for _, record := range rows {
+		list = append(list, newRecord(record))
+	}
+
+	bytes, err := json.Marshal(list)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+// exportJSONLines streams every record selected by filter, oldest first. Once
+// streaming has started errors can only be logged.
+func exportJSONLines(w http.ResponseWriter, r *http.Request, store *audit.Store, filter audit.Filter) {
+	filter.Chronological = true
+	filter.Limit = 0
+
+	w.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+
+	enc := json.NewEncoder(w)
+	err := store.Each(r.Context(), filter, func(record *audit.Record) error {
+		return enc.Encode(newRecord(record))
+	})
+	if err != nil {
+		log.Error().
+			Str("module", "audit").
+			Err(err).
+			Msg("audit: failed to export records")
+	}
+}
+
+func parseFilter(query url.Values) (audit.Filter, string, error) {
+	filter := audit.Filter{
+		Route:      query.Get("route"),
+		ResourceID: query.Get("resource"),
+		Limit:      DefaultLimit,
+	}
+
+	for name, dest := range map[string]*time.Time{
+		"since": &filter.Since,
+		"until": &filter.Until,
+	} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, "", fmt.Errorf("invalid %s, expected an ISO8601 date: %q", name, value)
+		}
+		*dest = t
+	}
+
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return filter, "", fmt.Errorf("since must be before until")
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return filter, "", fmt.Errorf("invalid limit, expected 1 to %d: %q", MaxLimit, value)
+		}
+		filter.Limit = limit
+	}
+
+	format := query.Get("format")
+	switch format {
+	case "", "json", FormatJSONLines:
+	default:
+		return filter, "", fmt.Errorf("unsupported format: %q (supported formats: json %s)", format, FormatJSONLines)
+	}
+
+	return filter, format, nil
+}
+
+func writeJSONResponse(w http.ResponseWriter, bytes []byte, statusCode int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if n, err := w.Write(bytes); err != nil {
+		log.Printf("%v", err)
+	} else if n != len(bytes) {
+		log.Printf("short write: %d/%d", n, len(bytes))
+	}
+}
@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joyent/triton-service-groups/accounts"
+	"github.com/joyent/triton-service-groups/accounts/v1"
+	"github.com/joyent/triton-service-groups/agent"
+	"github.com/joyent/triton-service-groups/allowlist"
+	"github.com/joyent/triton-service-groups/buildtime"
+	"github.com/joyent/triton-service-groups/config"
+	"github.com/joyent/triton-service-groups/server"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CLI flags
+var (
+	accountsNote   string
+	offboardDryRun bool
+)
+
+var accountsCmd = &cobra.Command{
+	Use:   "accounts",
+	Short: buildtime.PROGNAME + ` account administration`,
+	Long: fmt.Sprintf(`
+%s - Triton Service Groups API
+
+Administers the Triton accounts which can access TSG. When triton.whitelist is
+enabled, accounts which are new to TSG must be allowed before they can
+authenticate. Denied accounts are always rejected, including their sub-users
+and API tokens. Accounts which no longer use TSG can be offboarded.
+
+`, buildtime.PROGNAME),
+}
+
+var accountsAllowCmd = &cobra.Command{
+	Use:   "allow ACCOUNT",
+	Short: `Allow a Triton account to access TSG`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setAccountAccess(args[0], true)
+	},
+}
+
+var accountsDenyCmd = &cobra.Command{
+	Use:   "deny ACCOUNT",
+	Short: `Deny a Triton account access to TSG`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setAccountAccess(args[0], false)
+	},
+}
+
+var accountsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: `List the accounts within the allow-list`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := openAgent()
+		if err != nil {
+			return err
+		}
+		defer a.Close()
+
+		ctx := a.Context(context.Background())
+
+		entries, err := allowlist.NewStore(a.Pool()).FindAll(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to list allow-list")
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ACCOUNT\tACCESS\tUPDATED\tNOTE")
+		for _, entry := range entries {
+			access := "allowed"
+			if !entry.Allowed {
+				access = "denied"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				entry.AccountName,
+				access,
+				entry.UpdatedAt.Format("2006-01-02 15:04:05"),
+				entry.Note,
+			)
+		}
+
+		return w.Flush()
+	},
+}
+
+var accountsOffboardCmd = &cobra.Command{
+	Use:   "offboard ACCOUNT",
+	Short: `Remove an account and its groups from TSG`,
+	Long: fmt.Sprintf(`
+%s - Triton Service Groups API
+
+Offboards a Triton account. Every group of the account is scaled to zero, its
+orchestrator job deleted and the group archived. Once every group has been
+removed, the TSG management key is removed from Triton and the account, its
+keys and templates are archived. Should any group fail to be removed, the
+management key is kept and the command can be run again.
+
+`, buildtime.PROGNAME),
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.NewDefault()
+		if err != nil {
+			return err
+		}
+
+		a := agent.New(cfg)
+		if err := a.Open(); err != nil {
+			return err
+		}
+		defer a.Close()
+
+		ctx := a.Context(context.Background())
+
+		acct, err := accounts.NewStore(a.Pool()).FindByName(ctx, args[0])
+		if err != nil {
+			return errors.Wrapf(err, "failed to find account %q", args[0])
+		}
+
+		authConfig := server.NewAuthConfig(cfg.HTTPServer)
+
+		report, err := accounts_v1.OffboardAccount(ctx, acct, authConfig, buildtime.PROGNAME, offboardDryRun)
+		if report != nil {
+			if report.DryRun {
+				fmt.Printf("Dry run, nothing has been removed\n")
+			} else {
+				fmt.Printf("Offboarded account %s\n", report.AccountName)
+			}
+			fmt.Printf("Groups: %s\n", strings.Join(report.Groups, ", "))
+			fmt.Printf("Instances: %d\n", report.Instances)
+			fmt.Printf("Templates: %s\n", strings.Join(report.Templates, ", "))
+			fmt.Printf("Key: %s %s\n", report.Key, report.Fingerprint)
+			fmt.Printf("Tokens: %d\n", report.Tokens)
+			if len(report.Failed) > 0 {
+				fmt.Printf("Failed: %s\n", strings.Join(report.Failed, ", "))
+			}
+		}
+
+		return err
+	},
+}
+
+func openAgent() (*agent.Agent, error) {
+	cfg, err := config.NewDefault()
+	if err != nil {
+		return nil, err
+	}
+
+	a := agent.New(cfg)
+	if err := a.Open(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func setAccountAccess(accountName string, allowed bool) error {
+	a, err := openAgent()
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	ctx := a.Context(context.Background())
+
+	entry, err := allowlist.NewStore(a.Pool()).Set(ctx, accountName, allowed, accountsNote, buildtime.PROGNAME)
+	if err != nil {
+		return err
+	}
+
+	if entry.Allowed {
+		fmt.Printf("Allowed account %s\n", entry.AccountName)
+	} else {
+		fmt.Printf("Denied account %s\n", entry.AccountName)
+	}
+
+	return nil
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{accountsAllowCmd, accountsDenyCmd} {
+		cmd.Flags().StringVar(&accountsNote,
+			"note", "", "Note recorded against the account")
+		accountsCmd.AddCommand(cmd)
+	}
+
+	accountsOffboardCmd.Flags().BoolVar(&offboardDryRun,
+		"dry-run", false, "Report what would be removed without removing it")
+
+	accountsCmd.AddCommand(accountsListCmd)
+	accountsCmd.AddCommand(accountsOffboardCmd)
+	RootCmd.AddCommand(accountsCmd)
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/joyent/triton-service-groups/agent"
+	"github.com/joyent/triton-service-groups/buildtime"
+	"github.com/joyent/triton-service-groups/config"
+	"github.com/joyent/triton-service-groups/envelope"
+	"github.com/joyent/triton-service-groups/keys"
+	"github.com/joyent/triton-service-groups/secrets"
+	"github.com/spf13/cobra"
+)
+
+// CLI flags
+var (
+	migrateDryRun bool
+)
+
+var encryptionCmd = &cobra.Command{
+	Use:   "encryption",
+	Short: buildtime.PROGNAME + ` encryption at rest administration`,
+	Long: fmt.Sprintf(`
+%s - Triton Service Groups API
+
+Administers the envelope encryption of sensitive values stored within the
+database, such as management key material and secrets.
+
+`, buildtime.PROGNAME),
+}
+
+var encryptionMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: `Encrypt and re-wrap stored values under the current key`,
+	Long: fmt.Sprintf(`
+%s - Triton Service Groups API
+
+Encrypts management key material which is still stored in plain text using the
+configured key-encryption key. Values encrypted under a key listed within
+encryption.previous-key-files are re-wrapped with the current key, after which
+the previous key can be removed from the configuration.
+
+To replace the key-encryption key, configure the new key through encryption.key
+or encryption.key-file, list the old key within encryption.previous-key-files
+and run this command before restarting the API server without the old key.
+
+`, buildtime.PROGNAME),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.NewDefault()
+		if err != nil {
+			return err
+		}
+
+		a := agent.New(cfg)
+		if err := a.Open(); err != nil {
+			return err
+		}
+		defer a.Close()
+
+		if a.Keyring() == nil {
+			return envelope.ErrNoKey
+		}
+
+		ctx := a.Context(context.Background())
+
+		migration, err := keys.NewStore(a.Pool(), a.Keyring()).MigrateMaterial(ctx, migrateDryRun)
+		if err != nil {
+			return err
+		}
+
+		rewrapped, err := secrets_v1.RewrapSecrets(ctx, migrateDryRun)
+		if err != nil {
+			return err
+		}
+
+		if migrateDryRun {
+			fmt.Printf("Dry run, nothing has been written\n")
+		}
+		fmt.Printf("Key ID: %s\n", a.Keyring().ID())
+		fmt.Printf("Keys encrypted: %d\n", migration.Encrypted)
+		fmt.Printf("Keys re-wrapped: %d\n", migration.Rewrapped)
+		fmt.Printf("Keys unchanged: %d\n", migration.Unchanged)
+		fmt.Printf("Secrets re-wrapped: %d\n", rewrapped)
+
+		return nil
+	},
+}
+
+func init() {
+	encryptionMigrateCmd.Flags().BoolVar(&migrateDryRun,
+		"dry-run", false, "Report what would change without writing")
+
+	encryptionCmd.AddCommand(encryptionMigrateCmd)
+	RootCmd.AddCommand(encryptionCmd)
+}
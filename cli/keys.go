@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/joyent/triton-service-groups/accounts"
+	"github.com/joyent/triton-service-groups/agent"
+	"github.com/joyent/triton-service-groups/buildtime"
+	"github.com/joyent/triton-service-groups/config"
+	"github.com/joyent/triton-service-groups/keys/v1"
+	"github.com/joyent/triton-service-groups/server"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/handlers/auth"
+	"github.com/joyent/triton-service-groups/users"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CLI flags
+var (
+	rotateAccount string
+	rotateKeyType string
+)
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: buildtime.PROGNAME + ` management key administration`,
+	Long: fmt.Sprintf(`
+%s - Triton Service Groups API
+
+Administers the TSG management keys used to scale instances on behalf of Triton
+accounts.
+
+`, buildtime.PROGNAME),
+}
+
+var keysRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: `Rotate the management key of an account`,
+	Long: fmt.Sprintf(`
+%s - Triton Service Groups API
+
+Generates a new management key for a Triton account and uploads it to Triton.
+The account is switched over to the new key and every orchestrator job of the
+account is re-rendered with it before the previous key is removed from Triton
+and archived.
+
+The key type defaults to the configured triton.key-type and can be one of
+"rsa" (4096 bits), "ecdsa" (P-256) or "ed25519".
+
+`, buildtime.PROGNAME),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if rotateAccount == "" {
+			return errors.New("an account name is required")
+		}
+
+		cfg, err := config.NewDefault()
+		if err != nil {
+			return err
+		}
+
+		authConfig := server.NewAuthConfig(cfg.HTTPServer)
+
+		keyType := authConfig.KeyType
+		if rotateKeyType != "" {
+			keyType, err = auth.ParseKeyType(rotateKeyType)
+			if err != nil {
+				return err
+			}
+		}
+
+		a := agent.New(cfg)
+		if err := a.Open(); err != nil {
+			return err
+		}
+		defer a.Close()
+
+		ctx := a.Context(context.Background())
+
+		acct, err := accounts.NewStore(a.Pool()).FindByName(ctx, rotateAccount)
+		if err != nil {
+			return errors.Wrapf(err, "failed to find account %q", rotateAccount)
+		}
+
+		ctx = handlers.WithAuthSession(ctx, &auth.Session{
+			AccountID:  acct.ID,
+			Datacenter: authConfig.Datacenter,
+			TritonURL:  authConfig.TritonURL,
+			Permission: users.PermissionOwner,
+		})
+
+		rotation, err := keys_v1.RotateKey(ctx, acct, keyType, authConfig)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Rotated management key of %s\n", acct.AccountName)
+		fmt.Printf("Name: %s\n", rotation.Name)
+		fmt.Printf("Type: %s\n", rotation.Type)
+		fmt.Printf("Fingerprint: %s\n", rotation.Fingerprint)
+		fmt.Printf("Previous: %s\n", rotation.PreviousFingerprint)
+		fmt.Printf("Groups: %d\n", rotation.Groups)
+
+		return nil
+	},
+}
+
+func init() {
+	keysRotateCmd.Flags().StringVar(&rotateAccount,
+		"account", "", "Name of the Triton account")
+	keysRotateCmd.Flags().StringVar(&rotateKeyType,
+		"type", "", `Type of the new key ("rsa", "ecdsa" or "ed25519")`)
+
+	keysCmd.AddCommand(keysRotateCmd)
+	RootCmd.AddCommand(keysCmd)
+}
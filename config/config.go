@@ -1,8 +1,11 @@
 package config
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net/url"
 	"strings"
+	"time"
 
 	nomad "github.com/hashicorp/nomad/api"
 	"github.com/jackc/pgx"
@@ -21,12 +24,31 @@ type Config struct {
 	Agent
 	HTTPServer
 	Nomad
+	Encryption
+	Metrics
+	Tracing
 }
 
 type Agent struct {
 	LogFormat LogFormat
 }
 
+// Metrics configures the listener serving Prometheus metrics, separately
+// from the API so it can be kept private.
+type Metrics struct {
+	Enable bool
+	Bind   string
+	Port   uint16
+}
+
+// Tracing configures the export of spans to an OpenTelemetry collector with
+// OTLP over HTTP.
+type Tracing struct {
+	Enable      bool
+	Endpoint    string
+	ServiceName string
+}
+
 type HTTPServer struct {
 	Bind            string
 	Port            uint16
@@ -35,7 +57,83 @@ type HTTPServer struct {
 	TritonURL       string
 	AuthURL         string
 	KeyNamePrefix   string
+	KeyType         string
 	EnableWhitelist bool
+	Operators       []string
+	KeyCacheTTL     time.Duration
+	MaxClockSkew    time.Duration
+	DrainDelay      time.Duration
+	AuthProvider    string
+	AuthInsecure    bool
+	StaticAccounts  []StaticAccount
+	RateLimit       RateLimit
+	TLS             TLS
+	CORS            CORS
+	Console         Console
+}
+
+// TLS configures the HTTP server to serve HTTPS with the certificate and key
+// within CertFile and KeyFile. When ClientCAFile is set, clients must present
+// a certificate signed by one of the CAs within it. The files are read again
+// whenever the agent receives SIGHUP.
+type TLS struct {
+	CertFile     string
+	KeyFile      string
+	MinVersion   uint16
+	ClientCAFile string
+}
+
+// Enabled returns true when a certificate has been configured.
+func (c TLS) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// CORS configures the origins, besides the API's own, from which browsers can
+// call the API. CORS is disabled when AllowedOrigins is empty. MaxAge is how
+// long browsers cache the response to a preflight request.
+type CORS struct {
+	AllowedOrigins []string
+	MaxAge         time.Duration
+}
+
+// Console configures the web console served under /console/. Browsers signed
+// in to the console are signed out once SessionTTL has passed.
+type Console struct {
+	Enable     bool
+	SessionTTL time.Duration
+}
+
+// RateLimit configures the budgets of requests each account can make within
+// every period, separately for reads and mutations. Backend is either
+// "memory", counting requests within each agent, or "database", sharing the
+// budgets across every agent. Concurrency limits the mutations of each
+// account in flight within a single agent.
+type RateLimit struct {
+	Enable      bool
+	Backend     string
+	Period      time.Duration
+	Reads       int
+	Mutations   int
+	Concurrency int
+}
+
+// StaticAccount maps a token accepted by the static auth provider to an
+// account seeded within the TSG database. Permission defaults to the account
+// owner's.
+type StaticAccount struct {
+	Token      string `mapstructure:"token"`
+	Account    string `mapstructure:"account"`
+	Permission string `mapstructure:"permission"`
+}
+
+// Encryption configures the key-encryption key used to encrypt sensitive
+// values at rest. The key is a base64 encoded 32 byte value, either set
+// directly or read from a file. PreviousKeyFiles lists retired keys which are
+// still used to decrypt values until they've been re-wrapped.
+type Encryption struct {
+	Key              string
+	KeyFile          string
+	PreviousKeyFiles []string
 }
 
 type PGXLogger struct {
@@ -76,6 +174,12 @@ func GetTSGCliVersion() string {
 	return viper.GetString(KeyTSGCliVersion)
 }
 
+// GetTSGCliAPIURL returns the URL scaling jobs use to reach the API, such as
+// when fetching the values of secrets.
+func GetTSGCliAPIURL() string {
+	return viper.GetString(KeyTSGCliAPIURL)
+}
+
 func NewDefault() (cfg *Config, err error) {
 	var pgxLogLevel int = pgx.LogLevelInfo
 	switch logLevel := strings.ToUpper(viper.GetString(KeyLogLevel)); logLevel {
@@ -104,6 +208,11 @@ func NewDefault() (cfg *Config, err error) {
 	}
 
 	viper.SetDefault(KeyTritonWhitelist, true)
+	viper.SetDefault(KeyRateLimitEnable, true)
+	viper.SetDefault(KeyRateLimitReads, 600)
+	viper.SetDefault(KeyRateLimitMutations, 60)
+	viper.SetDefault(KeyRateLimitConcurrency, 4)
+	viper.SetDefault(KeyConsoleEnable, true)
 
 	httpServerConfig := HTTPServer{}
 	{
@@ -135,11 +244,91 @@ func NewDefault() (cfg *Config, err error) {
 		}
 
 		httpServerConfig.EnableWhitelist = viper.GetBool(KeyTritonWhitelist)
+		httpServerConfig.Operators = viper.GetStringSlice(KeyTritonOperators)
+
+		httpServerConfig.KeyCacheTTL = 5 * time.Minute
+		if ttl := viper.GetDuration(KeyTritonKeyTTL); ttl != 0 {
+			httpServerConfig.KeyCacheTTL = ttl
+		}
+
+		httpServerConfig.MaxClockSkew = 5 * time.Minute
+		if skew := viper.GetDuration(KeyHTTPServerMaxClockSkew); skew != 0 {
+			httpServerConfig.MaxClockSkew = skew
+		}
+
+		httpServerConfig.DrainDelay = viper.GetDuration(KeyHTTPServerDrainDelay)
+		if httpServerConfig.DrainDelay < 0 {
+			return nil, fmt.Errorf("invalid drain delay: %v (must not be negative)", httpServerConfig.DrainDelay)
+		}
+
+		httpServerConfig.TLS, err = parseTLS()
+		if err != nil {
+			return nil, err
+		}
+
+		httpServerConfig.CORS, err = parseCORS()
+		if err != nil {
+			return nil, err
+		}
+
+		httpServerConfig.Console.Enable = viper.GetBool(KeyConsoleEnable)
+		httpServerConfig.Console.SessionTTL = 12 * time.Hour
+		if ttl := viper.GetDuration(KeyConsoleSessionTTL); ttl != 0 {
+			if ttl < 0 {
+				return nil, fmt.Errorf("invalid console session TTL: %v (must not be negative)", ttl)
+			}
+			httpServerConfig.Console.SessionTTL = ttl
+		}
 
 		httpServerConfig.KeyNamePrefix = "TSG_Management"
 		if prefix := viper.GetString(KeyTritonKeyPrefix); prefix != "" {
 			httpServerConfig.KeyNamePrefix = prefix
 		}
+
+		httpServerConfig.KeyType = "rsa"
+		if keyType := strings.ToLower(viper.GetString(KeyTritonKeyType)); keyType != "" {
+			switch keyType {
+			case "rsa", "ecdsa", "ed25519":
+				httpServerConfig.KeyType = keyType
+			default:
+				return nil, fmt.Errorf("unsupported key type: %q (supported types: rsa ecdsa ed25519)", keyType)
+			}
+		}
+
+		httpServerConfig.AuthProvider = "triton-signature"
+		if provider := strings.ToLower(viper.GetString(KeyAuthProvider)); provider != "" {
+			switch provider {
+			case "triton-signature", "static":
+				httpServerConfig.AuthProvider = provider
+			default:
+				return nil, fmt.Errorf("unsupported auth provider: %q (supported providers: triton-signature static)", provider)
+			}
+		}
+
+		httpServerConfig.AuthInsecure = viper.GetBool(KeyAuthInsecure)
+		if err := viper.UnmarshalKey(KeyAuthStatic, &httpServerConfig.StaticAccounts); err != nil {
+			return nil, errors.Wrap(err, "unable to parse the static auth accounts")
+		}
+
+		httpServerConfig.RateLimit.Enable = viper.GetBool(KeyRateLimitEnable)
+		httpServerConfig.RateLimit.Reads = viper.GetInt(KeyRateLimitReads)
+		httpServerConfig.RateLimit.Mutations = viper.GetInt(KeyRateLimitMutations)
+		httpServerConfig.RateLimit.Concurrency = viper.GetInt(KeyRateLimitConcurrency)
+
+		httpServerConfig.RateLimit.Period = time.Minute
+		if period := viper.GetDuration(KeyRateLimitPeriod); period != 0 {
+			httpServerConfig.RateLimit.Period = period
+		}
+
+		httpServerConfig.RateLimit.Backend = "memory"
+		if backend := strings.ToLower(viper.GetString(KeyRateLimitBackend)); backend != "" {
+			switch backend {
+			case "memory", "database":
+				httpServerConfig.RateLimit.Backend = backend
+			default:
+				return nil, fmt.Errorf("unsupported rate limit backend: %q (supported backends: memory database)", backend)
+			}
+		}
 	}
 
 	pgxLogger := &PGXLogger{}
@@ -163,6 +352,45 @@ func NewDefault() (cfg *Config, err error) {
 		}
 	}
 
+	viper.SetDefault(KeyMetricsEnable, true)
+
+	metricsConfig := Metrics{
+		Enable: viper.GetBool(KeyMetricsEnable),
+		Bind:   "127.0.0.1",
+		Port:   uint16(9292),
+	}
+	{
+		if bind := viper.GetString(KeyMetricsBind); bind != "" {
+			metricsConfig.Bind = bind
+		}
+
+		if port := cast.ToUint16(viper.GetInt(KeyMetricsPort)); port != 0 {
+			metricsConfig.Port = port
+		}
+	}
+
+	encryptionConfig := Encryption{
+		Key:     viper.GetString(KeyEncryptionKey),
+		KeyFile: viper.GetString(KeyEncryptionKeyFile),
+
+		PreviousKeyFiles: viper.GetStringSlice(KeyEncryptionPreviousKeyFiles),
+	}
+
+	tracingConfig := Tracing{
+		Enable:      viper.GetBool(KeyTracingEnable),
+		Endpoint:    "http://127.0.0.1:4318",
+		ServiceName: buildtime.PROGNAME,
+	}
+	{
+		if endpoint := viper.GetString(KeyTracingEndpoint); endpoint != "" {
+			tracingConfig.Endpoint = endpoint
+		}
+
+		if name := viper.GetString(KeyTracingServiceName); name != "" {
+			tracingConfig.ServiceName = name
+		}
+	}
+
 	return &Config{
 		DBPool: pgx.ConnPoolConfig{
 			MaxConnections: 5,
@@ -186,9 +414,82 @@ func NewDefault() (cfg *Config, err error) {
 		Agent:      agentConfig,
 		HTTPServer: httpServerConfig,
 		Nomad:      nomadConfig,
+		Encryption: encryptionConfig,
+		Metrics:    metricsConfig,
+		Tracing:    tracingConfig,
 	}, nil
 }
 
+// tlsVersions maps the supported values of http.tls.min-version to their TLS
+// versions.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func parseTLS() (TLS, error) {
+	tlsConfig := TLS{
+		CertFile:     viper.GetString(KeyTLSCertFile),
+		KeyFile:      viper.GetString(KeyTLSKeyFile),
+		MinVersion:   tls.VersionTLS12,
+		ClientCAFile: viper.GetString(KeyTLSClientCAFile),
+	}
+
+	if (tlsConfig.CertFile == "") != (tlsConfig.KeyFile == "") {
+		return TLS{}, fmt.Errorf("both %s and %s are required to serve TLS", KeyTLSCertFile, KeyTLSKeyFile)
+	}
+
+	if tlsConfig.ClientCAFile != "" && !tlsConfig.Enabled() {
+		return TLS{}, fmt.Errorf("%s requires %s and %s", KeyTLSClientCAFile, KeyTLSCertFile, KeyTLSKeyFile)
+	}
+
+	if version := viper.GetString(KeyTLSMinVersion); version != "" {
+		v, ok := tlsVersions[version]
+		if !ok {
+			return TLS{}, fmt.Errorf("unsupported TLS version: %q (supported versions: 1.0 1.1 1.2 1.3)", version)
+		}
+		tlsConfig.MinVersion = v
+	}
+
+	return tlsConfig, nil
+}
+
+// parseCORS parses the allowed origins, which must be "*" or a scheme and host
+// without any path, such as "https://tsg.example.com:8443".
+func parseCORS() (CORS, error) {
+	corsConfig := CORS{
+		MaxAge: 10 * time.Minute,
+	}
+
+	if maxAge := viper.GetDuration(KeyCORSMaxAge); maxAge != 0 {
+		if maxAge < 0 {
+			return CORS{}, fmt.Errorf("invalid CORS max age: %v (must not be negative)", maxAge)
+		}
+		corsConfig.MaxAge = maxAge
+	}
+
+	for _, origin := range viper.GetStringSlice(KeyCORSAllowedOrigins) {
+		origin = strings.TrimSuffix(strings.TrimSpace(origin), "/")
+		if origin == "" {
+			continue
+		}
+
+		if origin != "*" {
+			u, err := url.Parse(origin)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+				u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+				return CORS{}, fmt.Errorf("invalid CORS origin: %q (must be \"*\" or a scheme and host, such as \"https://tsg.example.com\")", origin)
+			}
+		}
+
+		corsConfig.AllowedOrigins = append(corsConfig.AllowedOrigins, origin)
+	}
+
+	return corsConfig, nil
+}
+
 // IsDebug returns true when the server is configured for debug level
 func IsDebug() bool {
 	switch logLevel := strings.ToUpper(viper.GetString(KeyLogLevel)); logLevel {
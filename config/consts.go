@@ -27,19 +27,60 @@ const (
 	KeyPProfBind   = "pprof.bind"
 	KeyPProfPort   = "pprof.port"
 
-	KeyHTTPServerBind = "http.bind"
-	KeyHTTPServerPort = "http.port"
+	KeyMetricsEnable = "metrics.enable"
+	KeyMetricsBind   = "metrics.bind"
+	KeyMetricsPort   = "metrics.port"
+
+	KeyTracingEnable      = "tracing.enable"
+	KeyTracingEndpoint    = "tracing.endpoint"
+	KeyTracingServiceName = "tracing.service-name"
+
+	KeyHTTPServerBind         = "http.bind"
+	KeyHTTPServerPort         = "http.port"
+	KeyHTTPServerMaxClockSkew = "http.max-clock-skew"
+	KeyHTTPServerDrainDelay   = "http.drain-delay"
+
+	KeyTLSCertFile     = "http.tls.cert-file"
+	KeyTLSKeyFile      = "http.tls.key-file"
+	KeyTLSMinVersion   = "http.tls.min-version"
+	KeyTLSClientCAFile = "http.tls.client-ca-file"
+
+	KeyCORSAllowedOrigins = "http.cors.allowed-origins"
+	KeyCORSMaxAge         = "http.cors.max-age"
+
+	KeyConsoleEnable     = "console.enable"
+	KeyConsoleSessionTTL = "console.session-ttl"
+
+	KeyAuthProvider = "auth.provider"
+	KeyAuthInsecure = "auth.insecure"
+	KeyAuthStatic   = "auth.static"
+
+	KeyRateLimitEnable      = "ratelimit.enable"
+	KeyRateLimitBackend     = "ratelimit.backend"
+	KeyRateLimitPeriod      = "ratelimit.period"
+	KeyRateLimitReads       = "ratelimit.reads"
+	KeyRateLimitMutations   = "ratelimit.mutations"
+	KeyRateLimitConcurrency = "ratelimit.concurrency"
 
 	KeyTritonDC        = "triton.dc"
 	KeyTritonURL       = "triton.url"
 	KeyTritonAuthURL   = "triton.auth-url"
 	KeyTritonKeyPrefix = "triton.key-prefix"
 	KeyTritonWhitelist = "triton.whitelist"
+	KeyTritonKeyTTL    = "triton.key-cache-ttl"
+	KeyTritonKeyType   = "triton.key-type"
+	KeyTritonOperators = "triton.operators"
 
 	KeyNomadURL  = "nomad.url"
 	KeyNomadPort = "nomad.port"
 
 	KeyTSGCliVersion = "tsgcli.version"
+	KeyTSGCliAPIURL  = "tsgcli.api-url"
+
+	KeyEncryptionKey     = "encryption.key"
+	KeyEncryptionKeyFile = "encryption.key-file"
+
+	KeyEncryptionPreviousKeyFiles = "encryption.previous-key-files"
 )
 
 const (
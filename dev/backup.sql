@@ -2,8 +2,16 @@ SET sql_safe_updates = false;
 
 DELETE FROM tsg_groups;
 DELETE FROM tsg_templates;
+DELETE FROM tsg_secrets;
 DELETE FROM tsg_keys;
+DELETE FROM tsg_tokens;
 DELETE FROM tsg_users;
+DELETE FROM tsg_allowlist;
+DELETE FROM tsg_rate_limits;
+DELETE FROM tsg_audit;
+DELETE FROM tsg_group_events;
+DELETE FROM tsg_operations;
+DELETE FROM tsg_sessions;
 DELETE FROM tsg_accounts;
 
 INSERT INTO tsg_keys (id, name, fingerprint, material, created_at, updated_at)
@@ -12,6 +20,12 @@ VALUES ('1d32f239-81e2-4e35-a258-a5649dc4e6f3', 'TSG_Management', '5a:ce:1e:1d:b
 INSERT INTO tsg_accounts (id, account_name, triton_uuid, key_id, created_at, updated_at)
 VALUES ('6f873d02-172c-418f-8416-4da2b50d5c53', 'joyent', '87307a00-ab96-4fec-8df7-1a256e49fbcc', '1d32f239-81e2-4e35-a258-a5649dc4e6f3', NOW(), NOW());
 
+INSERT INTO tsg_allowlist (id, account_name, allowed, note, created_by, created_at, updated_at)
+VALUES ('b3f1e0a4-5c6d-4e8f-9a0b-1c2d3e4f5a6b', 'joyent', true, 'Development account', 'operator', NOW(), NOW());
+
+INSERT INTO tsg_users (id, username, account_id, permission, created_at, updated_at)
+VALUES ('0ea3d0b0-5ae0-4d37-a7c4-6d3a6e2cfd2e', 'demouser', '6f873d02-172c-418f-8416-4da2b50d5c53', 'scale-only', NOW(), NOW());
+
 INSERT INTO tsg_templates (id, template_name, package, image_id, account_id, firewall_enabled, networks, metadata, userdata, tags, created_at, archived) VALUES
     ('ad74301e-ad62-404a-be44-3b2f24d082ac', 'test-template-1', 'test-package', '49b22aec-0c8a-11e6-8807-a3eb4db576ba', '6f873d02-172c-418f-8416-4da2b50d5c53', false, 'f7ed95d3-faaf-43ef-9346-15644403b963', NULL, 'bash script here', NULL, NOW(), false),
     ('f1ead2a9-92fc-4435-9eb8-9e520bc3e4f9', 'test-template-2', 'test-package', '49b22aec-0c8a-11e6-8807-a3eb4db576ba', '6f873d02-172c-418f-8416-4da2b50d5c53', false, 'f7ed95d3-faaf-43ef-9346-15644403b963', NULL, 'bash script here', NULL, NOW(), false),
@@ -2,6 +2,14 @@ SET sql_safe_updates = false;
 
 DELETE FROM tsg_groups;
 DELETE FROM tsg_templates;
+DELETE FROM tsg_secrets;
+DELETE FROM tsg_tokens;
 DELETE FROM tsg_users;
+DELETE FROM tsg_allowlist;
+DELETE FROM tsg_rate_limits;
+DELETE FROM tsg_audit;
+DELETE FROM tsg_group_events;
+DELETE FROM tsg_operations;
+DELETE FROM tsg_sessions;
 DELETE FROM tsg_accounts;
 DELETE FROM tsg_keys;
@@ -2,6 +2,14 @@ SET sql_safe_updates = false;
 
 DROP TABLE IF EXISTS tsg_groups;
 DROP TABLE IF EXISTS tsg_templates;
+DROP TABLE IF EXISTS tsg_secrets;
+DROP TABLE IF EXISTS tsg_tokens;
 DROP TABLE IF EXISTS tsg_users;
+DROP TABLE IF EXISTS tsg_allowlist;
+DROP TABLE IF EXISTS tsg_rate_limits;
+DROP TABLE IF EXISTS tsg_audit;
+DROP TABLE IF EXISTS tsg_group_events;
+DROP TABLE IF EXISTS tsg_operations;
+DROP TABLE IF EXISTS tsg_sessions;
 DROP TABLE IF EXISTS tsg_accounts;
 DROP TABLE IF EXISTS tsg_keys;
@@ -46,13 +46,90 @@ CREATE TABLE IF NOT EXISTS tsg_accounts (
     created_at TIMESTAMP WITH TIME ZONE NOT NULL,
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
     archived BOOL NULL DEFAULT false,
+    rotating_at TIMESTAMP WITH TIME ZONE NULL,
     CONSTRAINT "primary" PRIMARY KEY (id ASC),
     CONSTRAINT key_id_tsg_keys_id_fk FOREIGN KEY (key_id) REFERENCES tsg_keys (id),
     INDEX key_id_tsg_keys_id_fk_idx (key_id ASC),
     INDEX name_idx (account_name ASC),
     INDEX id_name_idx (id ASC, account_name ASC),
     INDEX archived_idx (archived ASC),
-    FAMILY "primary" (id, account_name, triton_uuid, key_id, created_at, updated_at, archived)
+    FAMILY "primary" (id, account_name, triton_uuid, key_id, created_at, updated_at, archived, rotating_at)
+);
+EOS
+
+    cat <<'EOS' | $SQL -d $env
+CREATE TABLE IF NOT EXISTS tsg_allowlist (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_name STRING NOT NULL,
+    allowed BOOL NOT NULL DEFAULT true,
+    note STRING NULL,
+    created_by STRING NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    UNIQUE INDEX account_name_idx (account_name ASC)
+);
+EOS
+
+    cat <<'EOS' | $SQL -d $env
+CREATE TABLE IF NOT EXISTS tsg_rate_limits (
+    bucket STRING NOT NULL,
+    window_start TIMESTAMPTZ NOT NULL,
+    hits INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (bucket, window_start),
+    INDEX expires_at_idx (expires_at ASC)
+);
+EOS
+
+    cat <<'EOS' | $SQL -d $env
+CREATE TABLE IF NOT EXISTS tsg_audit (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id UUID NOT NULL,
+    actor STRING NOT NULL,
+    user_name STRING NOT NULL DEFAULT '',
+    token_id STRING NOT NULL DEFAULT '',
+    fingerprint STRING NOT NULL DEFAULT '',
+    route STRING NOT NULL,
+    method STRING NOT NULL,
+    path STRING NOT NULL,
+    resource_id STRING NOT NULL DEFAULT '',
+    request_body STRING NOT NULL DEFAULT '',
+    status INT NOT NULL,
+    outcome STRING NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    INDEX account_id_created_at_idx (account_id ASC, created_at DESC)
+);
+EOS
+
+    cat <<'EOS' | $SQL -d $env
+CREATE TABLE IF NOT EXISTS tsg_group_events (
+    id INT8 PRIMARY KEY DEFAULT unique_rowid(),
+    account_id UUID NOT NULL,
+    group_id UUID NOT NULL,
+    type STRING NOT NULL,
+    actor STRING NOT NULL DEFAULT '',
+    data STRING NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    INDEX account_id_id_idx (account_id ASC, id ASC),
+    INDEX created_at_idx (created_at ASC)
+);
+EOS
+
+    cat <<'EOS' | $SQL -d $env
+CREATE TABLE IF NOT EXISTS tsg_operations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id UUID NOT NULL,
+    group_id UUID NULL,
+    type STRING NOT NULL,
+    state STRING NOT NULL,
+    progress STRING NOT NULL DEFAULT '',
+    error STRING NOT NULL DEFAULT '',
+    created_by STRING NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NULL,
+    INDEX account_id_created_at_idx (account_id ASC, created_at DESC),
+    INDEX state_updated_at_idx (state ASC, updated_at ASC)
 );
 EOS
 
@@ -61,9 +138,47 @@ CREATE TABLE IF NOT EXISTS tsg_users (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     username STRING NOT NULL,
     account_id UUID NOT NULL REFERENCES tsg_accounts (id),
+    permission STRING NOT NULL DEFAULT 'read-only',
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    archived BOOL DEFAULT false,
+    INDEX username_account_id_idx (username ASC, account_id ASC)
+);
+EOS
+
+    cat <<'EOS' | $SQL -d $env
+CREATE TABLE IF NOT EXISTS tsg_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    "name" STRING NOT NULL,
+    token_hash STRING NOT NULL,
+    prefix STRING NOT NULL,
+    account_id UUID NOT NULL REFERENCES tsg_accounts (id),
+    permission STRING NOT NULL DEFAULT 'read-only',
+    group_ids STRING NOT NULL DEFAULT '[]',
+    created_by STRING NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NULL,
     created_at TIMESTAMPTZ NOT NULL,
     updated_at TIMESTAMPTZ NOT NULL,
-    archived BOOL DEFAULT false
+    UNIQUE INDEX token_hash_idx (token_hash ASC),
+    INDEX account_id_idx (account_id ASC)
+);
+EOS
+
+    cat <<'EOS' | $SQL -d $env
+CREATE TABLE IF NOT EXISTS tsg_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_hash STRING NOT NULL,
+    account_id UUID NOT NULL REFERENCES tsg_accounts (id),
+    user_id UUID NULL,
+    token_id UUID NULL,
+    permission STRING NOT NULL,
+    csrf_token STRING NOT NULL,
+    created_by STRING NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    UNIQUE INDEX session_hash_idx (session_hash ASC),
+    INDEX expires_at_idx (expires_at ASC)
 );
 EOS
 
@@ -79,6 +194,8 @@ CREATE TABLE IF NOT EXISTS tsg_templates (
     userdata STRING NULL,
     metadata STRING NULL,
     tags STRING NULL,
+    created_by STRING NULL,
+    updated_by STRING NULL,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL,
     archived BOOL NULL DEFAULT false,
     CONSTRAINT "primary" PRIMARY KEY (id ASC),
@@ -86,7 +203,7 @@ CREATE TABLE IF NOT EXISTS tsg_templates (
     INDEX account_id_tsg_accounts_id_fk_idx (account_id ASC),
     INDEX name_idx (template_name ASC),
     INDEX archived_idx (archived ASC),
-    FAMILY "primary" (id, template_name, account_id, package, image_id, firewall_enabled, networks, userdata, metadata, tags, created_at, archived)
+    FAMILY "primary" (id, template_name, account_id, package, image_id, firewall_enabled, networks, userdata, metadata, tags, created_by, updated_by, created_at, archived)
 );
 EOS
 
@@ -98,6 +215,8 @@ CREATE TABLE IF NOT EXISTS tsg_groups (
     account_id UUID NOT NULL,
     capacity INT NOT NULL,
     health_check_interval INT NULL DEFAULT 300:::INT,
+    created_by STRING NULL,
+    updated_by STRING NULL,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL,
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
     archived BOOL NULL DEFAULT false,
@@ -109,7 +228,29 @@ CREATE TABLE IF NOT EXISTS tsg_groups (
     INDEX name_idx ("name" ASC),
     INDEX name_templates_id_idx ("name" ASC, template_id ASC),
     INDEX archived_idx (archived ASC),
-    FAMILY "primary" (id, "name", template_id, account_id, capacity, health_check_interval, created_at, updated_at, archived)
+    FAMILY "primary" (id, "name", template_id, account_id, capacity, health_check_interval, created_by, updated_by, created_at, updated_at, archived)
+);
+EOS
+
+    cat <<'EOS' | $SQL -d $env
+CREATE TABLE IF NOT EXISTS tsg_secrets (
+    id UUID NOT NULL DEFAULT gen_random_uuid(),
+    "name" STRING NOT NULL,
+    ciphertext STRING NOT NULL,
+    account_id UUID NOT NULL,
+    created_by STRING NULL,
+    updated_by STRING NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    archived BOOL NULL DEFAULT false,
+    live_name STRING NULL,
+    CONSTRAINT "primary" PRIMARY KEY (id ASC),
+    CONSTRAINT account_id_tsg_accounts_id_fk FOREIGN KEY (account_id) REFERENCES tsg_accounts (id),
+    INDEX account_id_tsg_accounts_id_fk_idx (account_id ASC),
+    INDEX name_idx ("name" ASC),
+    INDEX archived_idx (archived ASC),
+    UNIQUE INDEX account_id_live_name_idx (account_id ASC, live_name ASC),
+    FAMILY "primary" (id, "name", ciphertext, account_id, created_by, updated_by, created_at, updated_at, archived, live_name)
 );
 EOS
 
@@ -0,0 +1,284 @@
+// Package envelope implements envelope encryption for values stored within the
+// TSG database. Every value is encrypted with its own randomly generated data
+// key, which is then wrapped by a key-encryption key (KEK) loaded from the
+// agent's configuration. Only the wrapped data key is stored alongside the
+// ciphertext.
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// KeySize is the required size, in bytes, of a key-encryption key.
+	KeySize = 32
+
+	prefix = "tsg1"
+)
+
+var (
+	ErrKeySize   = errors.New("envelope: key-encryption key must be 32 bytes")
+	ErrNoKey     = errors.New("envelope: no key-encryption key configured")
+	ErrFormat    = errors.New("envelope: value is not an encrypted envelope")
+	ErrUnknownID = errors.New("envelope: value was encrypted with an unknown key")
+)
+
+// Keyring holds the key-encryption key used to wrap and unwrap data keys.
+// Previous key-encryption keys can be added to a Keyring so values wrapped
+// before a key was replaced can still be decrypted, and re-wrapped.
+type Keyring struct {
+	id  string
+	kek []byte
+
+	previous map[string][]byte
+}
+
+// NewKeyring constructs a Keyring from a raw key-encryption key.
+func NewKeyring(kek []byte) (*Keyring, error) {
+	if len(kek) != KeySize {
+		return nil, ErrKeySize
+	}
+
+	return &Keyring{
+		id:       keyID(kek),
+		kek:      kek,
+		previous: make(map[string][]byte),
+	}, nil
+}
+
+func keyID(kek []byte) string {
+	sum := sha256.Sum256(kek)
+	return hex.EncodeToString(sum[:4])
+}
+
+// AddPreviousKey adds a retired key-encryption key to the Keyring. Previous
+// keys are only used to unwrap data keys, never to wrap new ones.
+func (k *Keyring) AddPreviousKey(kek []byte) error {
+	if len(kek) != KeySize {
+		return ErrKeySize
+	}
+
+	if id := keyID(kek); id != k.id {
+		k.previous[id] = kek
+	}
+
+	return nil
+}
+
+// ParseKey decodes a base64 encoded key-encryption key.
+func ParseKey(encoded string) ([]byte, error) {
+	kek, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, errors.Wrap(err, "envelope: failed to decode key-encryption key")
+	}
+
+	if len(kek) != KeySize {
+		return nil, ErrKeySize
+	}
+
+	return kek, nil
+}
+
+// LoadKey reads a base64 encoded key-encryption key from a file.
+func LoadKey(path string) ([]byte, error) {
+	encoded, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "envelope: failed to read key file %q", path)
+	}
+
+	return ParseKey(string(encoded))
+}
+
+// ID returns a short, non-secret identifier of the key-encryption key. The ID
+// is stored with every envelope so the key used to encrypt it can be found.
+func (k *Keyring) ID() string {
+	return k.id
+}
+
+// Encrypt encrypts plaintext under a new data key and returns the envelope as
+// a string safe for storing within a STRING column.
+func (k *Keyring) Encrypt(plaintext []byte) (string, error) {
+	if k == nil {
+		return "", ErrNoKey
+	}
+
+	dek := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return "", errors.Wrap(err, "envelope: failed to generate data key")
+	}
+
+	wrapped, err := seal(k.kek, dek)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := seal(dek, plaintext)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join([]string{
+		prefix,
+		k.id,
+		base64.StdEncoding.EncodeToString(wrapped),
+		base64.StdEncoding.EncodeToString(ciphertext),
+	}, ":"), nil
+}
+
+// Decrypt opens an envelope created by Encrypt, returning the plaintext.
+func (k *Keyring) Decrypt(envelope string) ([]byte, error) {
+	if k == nil {
+		return nil, ErrNoKey
+	}
+
+	parts, err := split(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	dek, err := k.unwrap(parts)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return nil, ErrFormat
+	}
+
+	plaintext, err := open(dek, ciphertext)
+	if err != nil {
+		return nil, errors.Wrap(err, "envelope: failed to decrypt value")
+	}
+
+	return plaintext, nil
+}
+
+// Rewrap re-wraps the data key of an envelope with the current key-encryption
+// key, leaving the encrypted value itself untouched. Envelopes already wrapped
+// by the current key are returned unchanged.
+func (k *Keyring) Rewrap(envelope string) (string, error) {
+	if k == nil {
+		return "", ErrNoKey
+	}
+
+	parts, err := split(envelope)
+	if err != nil {
+		return "", err
+	}
+
+	if parts[1] == k.id {
+		return envelope, nil
+	}
+
+	dek, err := k.unwrap(parts)
+	if err != nil {
+		return "", err
+	}
+
+	wrapped, err := seal(k.kek, dek)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join([]string{
+		prefix,
+		k.id,
+		base64.StdEncoding.EncodeToString(wrapped),
+		parts[3],
+	}, ":"), nil
+}
+
+// IsCurrent returns true if envelope was wrapped by the current key-encryption
+// key.
+func (k *Keyring) IsCurrent(envelope string) bool {
+	parts, err := split(envelope)
+	return err == nil && k != nil && parts[1] == k.id
+}
+
+// unwrap returns the data key of a split envelope, using whichever of the
+// current or previous key-encryption keys wrapped it.
+func (k *Keyring) unwrap(parts []string) ([]byte, error) {
+	kek := k.kek
+	if parts[1] != k.id {
+		var ok bool
+		if kek, ok = k.previous[parts[1]]; !ok {
+			return nil, ErrUnknownID
+		}
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrFormat
+	}
+
+	dek, err := open(kek, wrapped)
+	if err != nil {
+		return nil, errors.Wrap(err, "envelope: failed to unwrap data key")
+	}
+
+	return dek, nil
+}
+
+func split(envelope string) ([]string, error) {
+	parts := strings.Split(envelope, ":")
+	if len(parts) != 4 || parts[0] != prefix {
+		return nil, ErrFormat
+	}
+	return parts, nil
+}
+
+// IsEncrypted returns true if value looks like an envelope created by Encrypt.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix+":")
+}
+
+// seal encrypts plaintext with AES-256-GCM, prefixing the output with the
+// randomly generated nonce.
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, "envelope: failed to generate nonce")
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, ErrFormat
+	}
+
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,126 @@
+package envelope_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/envelope"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newKeyring(t *testing.T, fill byte) *envelope.Keyring {
+	keyring, err := envelope.NewKeyring(bytes.Repeat([]byte{fill}, envelope.KeySize))
+	require.NoError(t, err)
+	return keyring
+}
+
+func TestNewKeyring(t *testing.T) {
+	_, err := envelope.NewKeyring([]byte("too short"))
+	assert.Equal(t, envelope.ErrKeySize, err)
+
+	keyring := newKeyring(t, 1)
+	assert.Len(t, keyring.ID(), 8)
+	assert.NotEqual(t, keyring.ID(), newKeyring(t, 2).ID())
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	keyring := newKeyring(t, 1)
+
+	sealed, err := keyring.Encrypt([]byte("database123"))
+	require.NoError(t, err)
+
+	assert.True(t, envelope.IsEncrypted(sealed))
+	assert.NotContains(t, sealed, "database123")
+
+	again, err := keyring.Encrypt([]byte("database123"))
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again)
+
+	plaintext, err := keyring.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "database123", string(plaintext))
+}
+
+func TestDecryptErrors(t *testing.T) {
+	keyring := newKeyring(t, 1)
+
+	sealed, err := keyring.Encrypt([]byte("database123"))
+	require.NoError(t, err)
+
+	_, err = newKeyring(t, 2).Decrypt(sealed)
+	assert.Equal(t, envelope.ErrUnknownID, err)
+
+	_, err = keyring.Decrypt("database123")
+	assert.Equal(t, envelope.ErrFormat, err)
+	assert.False(t, envelope.IsEncrypted("database123"))
+
+	tampered := sealed[:len(sealed)-4] + "AAA="
+	_, err = keyring.Decrypt(tampered)
+	assert.Error(t, err)
+
+	var nilKeyring *envelope.Keyring
+	_, err = nilKeyring.Encrypt([]byte("database123"))
+	assert.Equal(t, envelope.ErrNoKey, err)
+}
+
+func TestLoadKey(t *testing.T) {
+	kek := bytes.Repeat([]byte{7}, envelope.KeySize)
+
+	f, err := ioutil.TempFile("", "tsg-kek")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(base64.StdEncoding.EncodeToString(kek) + "\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	loaded, err := envelope.LoadKey(f.Name())
+	require.NoError(t, err)
+	assert.Equal(t, kek, loaded)
+
+	_, err = envelope.ParseKey(base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.Equal(t, envelope.ErrKeySize, err)
+
+	_, err = envelope.ParseKey("not base64!")
+	assert.Error(t, err)
+}
+
+func TestRewrap(t *testing.T) {
+	old := newKeyring(t, 1)
+
+	sealed, err := old.Encrypt([]byte("database123"))
+	require.NoError(t, err)
+
+	current := newKeyring(t, 2)
+	assert.False(t, current.IsCurrent(sealed))
+
+	_, err = current.Rewrap(sealed)
+	assert.Equal(t, envelope.ErrUnknownID, err)
+
+	require.NoError(t, current.AddPreviousKey(bytes.Repeat([]byte{1}, envelope.KeySize)))
+	assert.Equal(t, envelope.ErrKeySize, current.AddPreviousKey([]byte("short")))
+
+	plaintext, err := current.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "database123", string(plaintext))
+
+	rewrapped, err := current.Rewrap(sealed)
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, rewrapped)
+	assert.True(t, current.IsCurrent(rewrapped))
+
+	again, err := current.Rewrap(rewrapped)
+	require.NoError(t, err)
+	assert.Equal(t, rewrapped, again)
+
+	plaintext, err = newKeyring(t, 2).Decrypt(rewrapped)
+	require.NoError(t, err)
+	assert.Equal(t, "database123", string(plaintext))
+
+	_, err = old.Decrypt(rewrapped)
+	assert.Equal(t, envelope.ErrUnknownID, err)
+}
@@ -0,0 +1,254 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package events
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultPollInterval is how often the broker polls for new events.
+	DefaultPollInterval = time.Second
+
+	// Retention is how long events are kept for watchers to resume from.
+	Retention = 24 * time.Hour
+
+	// overlap is how far back every poll looks before the newest event
+	// already seen, so events committed by another agent after a newer event
+	// was polled are still delivered.
+	overlap = 5 * time.Second
+
+	pruneInterval = time.Hour
+	pollTimeout   = 10 * time.Second
+
+	// subscriptionBuffer is the number of events a subscriber can fall
+	// behind by before it's dropped.
+	subscriptionBuffer = 64
+)
+
+// Source finds recently recorded events, as implemented by *Store.
+type Source interface {
+	FindSince(ctx context.Context, since time.Time) ([]*Event, error)
+	DeleteBefore(ctx context.Context, before time.Time) error
+}
+
+// Broker polls for the events recorded by every agent and delivers them to
+// the subscribers of each account. Polling the database, rather than
+// delivering events in process, means a watcher connected to one agent sees
+// the changes made through every other agent.
+type Broker struct {
+	source   Source
+	interval time.Duration
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+
+	// newest is the creation time of the newest event polled, and seen the
+	// events polled within the overlap before it.
+	newest time.Time
+	seen   map[int64]time.Time
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// NewBroker constructs a broker polling source every interval.
+func NewBroker(source Source, interval time.Duration) *Broker {
+	return &Broker{
+		source:   source,
+		interval: interval,
+		subs:     make(map[*Subscription]struct{}),
+		seen:     make(map[int64]time.Time),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Start polls for events in the background until Stop is called.
+func (b *Broker) Start() {
+	b.newest = time.Now()
+
+	go func() {
+		defer close(b.stopped)
+
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+
+		lastPrune := time.Now()
+		for {
+			select {
+			case <-b.done:
+				return
+			case <-ticker.C:
+				b.Poll()
+
+				if time.Since(lastPrune) > pruneInterval {
+					b.prune()
+					lastPrune = time.Now()
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops polling and closes every subscription, ending every stream.
+func (b *Broker) Stop() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	for sub := range b.subs {
+		close(sub.events)
+		delete(b.subs, sub)
+	}
+	b.mu.Unlock()
+
+	close(b.done)
+	<-b.stopped
+}
+
+// Poll delivers the events recorded since the previous poll to subscribers.
+func (b *Broker) Poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), pollTimeout)
+	defer cancel()
+
+	events, err := b.source.FindSince(ctx, b.newest.Add(-overlap))
+	if err != nil {
+		log.Warn().Err(err).Msg("events: failed to poll for group events")
+		return
+	}
+
+	for _, event := range events {
+		if _, ok := b.seen[event.ID]; ok {
+			continue
+		}
+		b.seen[event.ID] = event.CreatedAt
+		if event.CreatedAt.After(b.newest) {
+			b.newest = event.CreatedAt
+		}
+
+		b.publish(event)
+	}
+
+	for id, createdAt := range b.seen {
+		if createdAt.Before(b.newest.Add(-2 * overlap)) {
+			delete(b.seen, id)
+		}
+	}
+}
+
+func (b *Broker) prune() {
+	ctx, cancel := context.WithTimeout(context.Background(), pollTimeout)
+	defer cancel()
+
+	if err := b.source.DeleteBefore(ctx, time.Now().Add(-Retention)); err != nil {
+		log.Warn().Err(err).Msg("events: failed to delete expired group events")
+	}
+}
+
+// publish delivers event to every matching subscriber. Subscribers which have
+// fallen too far behind are dropped rather than holding up the others, and
+// are expected to resume from the last event they received.
+func (b *Broker) publish(event *Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		if !sub.matches(event) {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			log.Debug().
+				Str("account_id", sub.accountID).
+				Msg("events: dropping subscriber which fell behind")
+			close(sub.events)
+			delete(b.subs, sub)
+		}
+	}
+}
+
+// Subscribe subscribes to the events of an account. An empty groupID
+// subscribes to the events of every group.
+func (b *Broker) Subscribe(accountID, groupID string) *Subscription {
+	sub := &Subscription{
+		accountID: accountID,
+		groupID:   groupID,
+		events:    make(chan *Event, subscriptionBuffer),
+		broker:    b,
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		close(sub.events)
+	} else {
+		b.subs[sub] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// Subscription receives the events of an account.
+type Subscription struct {
+	accountID string
+	groupID   string
+	events    chan *Event
+	broker    *Broker
+}
+
+// Events returns the channel events are delivered on. It's closed when the
+// broker stops or the subscriber falls too far behind.
+func (s *Subscription) Events() <-chan *Event {
+	return s.events
+}
+
+// Close unsubscribes.
+func (s *Subscription) Close() {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[s]; ok {
+		close(s.events)
+		delete(b.subs, s)
+	}
+}
+
+func (s *Subscription) matches(event *Event) bool {
+	return event.AccountID == s.accountID && (s.groupID == "" || event.GroupID == s.groupID)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying broker.
+func NewContext(ctx context.Context, broker *Broker) context.Context {
+	return context.WithValue(ctx, contextKey{}, broker)
+}
+
+// FromContext returns the broker carried by ctx, if any.
+func FromContext(ctx context.Context) (*Broker, bool) {
+	broker, ok := ctx.Value(contextKey{}).(*Broker)
+	return broker, ok && broker != nil
+}
+
+// Handler wraps an HTTP handler, attaching broker to the context of every
+// request.
+func Handler(broker *Broker, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		handler.ServeHTTP(w, req.WithContext(NewContext(req.Context(), broker)))
+	})
+}
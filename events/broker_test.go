@@ -0,0 +1,149 @@
+package events_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joyent/triton-service-groups/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// source is an in-memory events.Source.
+type source struct {
+	mu     sync.Mutex
+	events []*events.Event
+}
+
+func (s *source) add(event *events.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+}
+
+func (s *source) FindSince(ctx context.Context, since time.Time) ([]*events.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found []*events.Event
+	for _, event := range s.events {
+		if !event.CreatedAt.Before(since) {
+			found = append(found, event)
+		}
+	}
+	return found, nil
+}
+
+func (s *source) DeleteBefore(ctx context.Context, before time.Time) error {
+	return nil
+}
+
+func newEvent(id int64, accountID, groupID string) *events.Event {
+	event := events.New(nil)
+	event.ID = id
+	event.AccountID = accountID
+	event.GroupID = groupID
+	event.Type = events.TypeScaled
+	event.CreatedAt = time.Now()
+	return event
+}
+
+func receive(t *testing.T, sub *events.Subscription) *events.Event {
+	t.Helper()
+	select {
+	case event, ok := <-sub.Events():
+		require.True(t, ok, "subscription closed")
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+		return nil
+	}
+}
+
+func assertNoEvent(t *testing.T, sub *events.Subscription) {
+	t.Helper()
+	select {
+	case event := <-sub.Events():
+		t.Fatalf("unexpected event %v", event)
+	default:
+	}
+}
+
+func TestBrokerDeliversToMatchingSubscribers(t *testing.T) {
+	src := &source{}
+	broker := events.NewBroker(src, time.Hour)
+	broker.Start()
+	defer broker.Stop()
+
+	all := broker.Subscribe("account-a", "")
+	group := broker.Subscribe("account-a", "group-1")
+	other := broker.Subscribe("account-b", "")
+
+	src.add(newEvent(1, "account-a", "group-1"))
+	src.add(newEvent(2, "account-a", "group-2"))
+	broker.Poll()
+
+	assert.EqualValues(t, 1, receive(t, all).ID)
+	assert.EqualValues(t, 2, receive(t, all).ID)
+	assert.EqualValues(t, 1, receive(t, group).ID)
+	assertNoEvent(t, group)
+	assertNoEvent(t, other)
+}
+
+func TestBrokerDeliversEachEventOnce(t *testing.T) {
+	src := &source{}
+	broker := events.NewBroker(src, time.Hour)
+	broker.Start()
+	defer broker.Stop()
+
+	sub := broker.Subscribe("account-a", "")
+
+	src.add(newEvent(1, "account-a", "group-1"))
+	broker.Poll()
+	assert.EqualValues(t, 1, receive(t, sub).ID)
+
+	// An event committed late by another agent, with an older timestamp,
+	// is still delivered while the one already seen isn't repeated.
+	late := newEvent(2, "account-a", "group-1")
+	late.CreatedAt = time.Now().Add(-2 * time.Second)
+	src.add(late)
+	broker.Poll()
+	assert.EqualValues(t, 2, receive(t, sub).ID)
+	assertNoEvent(t, sub)
+}
+
+func TestBrokerDropsSlowSubscribers(t *testing.T) {
+	src := &source{}
+	broker := events.NewBroker(src, time.Hour)
+	broker.Start()
+	defer broker.Stop()
+
+	sub := broker.Subscribe("account-a", "")
+	for i := int64(1); i <= 100; i++ {
+		src.add(newEvent(i, "account-a", "group-1"))
+	}
+	broker.Poll()
+
+	var received int
+	for range sub.Events() {
+		received++
+	}
+	assert.True(t, received < 100, "expected the subscription to be closed early")
+}
+
+func TestBrokerStopClosesSubscriptions(t *testing.T) {
+	broker := events.NewBroker(&source{}, time.Hour)
+	broker.Start()
+
+	sub := broker.Subscribe("account-a", "")
+	broker.Stop()
+
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+
+	sub.Close()
+	_, ok = <-broker.Subscribe("account-a", "").Events()
+	assert.False(t, ok, "subscribing after stopping")
+}
@@ -0,0 +1,130 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/pgtype"
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/pkg/errors"
+)
+
+// The types of events recorded for a group.
+const (
+	TypeCreated       = "created"
+	TypeUpdated       = "updated"
+	TypeScaled        = "scaled"
+	TypeDeleted       = "deleted"
+	TypeStatusChanged = "status_changed"
+)
+
+// The statuses reported by status_changed events, describing whether the
+// orchestrator job of a group was deployed.
+const (
+	StatusDeployed = "deployed"
+	StatusFailed   = "failed"
+)
+
+var ErrNoAccountID = errors.New("missing account identifer for insert")
+
+// Event represents the data associated with a tsg_group_events row. An event is
+// written whenever a group changes and is never updated.
+type Event struct {
+	// ID identifies the event. Identifiers increase over time, so clients
+	// resume watching after the last identifier they received.
+	ID        int64
+	AccountID string
+	GroupID   string
+	Type      string
+	Actor     string
+
+	// Data is a JSON document describing the change. It's the group after
+	// the change, or its status for status_changed events.
+	Data      json.RawMessage
+	CreatedAt time.Time
+
+	store *Store
+}
+
+// New constructs a new Event with the Store for backend persistence.
+func New(store *Store) *Event {
+	return &Event{
+		store: store,
+	}
+}
+
+// MarshalJSON encodes the event as sent to clients. The identifier is encoded
+// as a string since it exceeds the integers JavaScript can represent.
+func (e *Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ID        string          `json:"id"`
+		Type      string          `json:"type"`
+		GroupID   string          `json:"group_id"`
+		Actor     string          `json:"actor,omitempty"`
+		Data      json.RawMessage `json:"data,omitempty"`
+		CreatedAt time.Time       `json:"created_at"`
+	}{
+		ID:        e.IDString(),
+		Type:      e.Type,
+		GroupID:   e.GroupID,
+		Actor:     e.Actor,
+		Data:      e.Data,
+		CreatedAt: e.CreatedAt,
+	})
+}
+
+// IDString returns the identifier of the event, as used within the
+// Last-Event-ID header.
+func (e *Event) IDString() string {
+	return strconv.FormatInt(e.ID, 10)
+}
+
+// ParseID parses an identifier returned by IDString.
+func ParseID(s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id < 0 {
+		return 0, errors.Errorf("invalid event identifier %q", s)
+	}
+	return id, nil
+}
+
+// Insert inserts the event into the tsg_group_events table.
+func (e *Event) Insert(ctx context.Context) error {
+	if e.AccountID == "" {
+		return ErrNoAccountID
+	}
+
+	data := "{}"
+	if len(e.Data) > 0 {
+		data = string(e.Data)
+	}
+
+	query := `
+INSERT INTO tsg_group_events (account_id, group_id, type, actor, data, created_at)
+VALUES ($1, $2, $3, $4, $5, NOW())
+RETURNING id, created_at;
+`
+	var createdAt pgtype.Timestamptz
+
+	err := tracing.QueryRowEx(ctx, e.store.pool, query, nil,
+		e.AccountID,
+		e.GroupID,
+		e.Type,
+		e.Actor,
+		data,
+	).Scan(&e.ID, &createdAt)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert group event")
+	}
+
+	e.CreatedAt = createdAt.Time
+
+	return nil
+}
@@ -0,0 +1,123 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package events records the changes made to groups and streams them to
+// watchers. Events are kept within the database, so every agent delivers the
+// events recorded by every other agent.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/pgtype"
+	"github.com/joyent/triton-service-groups/convert"
+	"github.com/joyent/triton-service-groups/tracing"
+)
+
+type Store struct {
+	pool *pgx.ConnPool
+}
+
+// NewStore returns a new store object.
+func NewStore(pool *pgx.ConnPool) *Store {
+	return &Store{
+		pool: pool,
+	}
+}
+
+const selectEvents = `
+SELECT id, account_id, group_id, type, actor, data, created_at
+FROM tsg_group_events
+`
+
+// scanner is implemented by both *pgx.Row and *pgx.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (s *Store) scanEvent(row scanner) (*Event, error) {
+	var (
+		id        int64
+		accountID pgtype.UUID
+		groupID   pgtype.UUID
+		eventType string
+		actor     string
+		data      string
+		createdAt pgtype.Timestamptz
+	)
+
+	err := row.Scan(
+		&id,
+		&accountID,
+		&groupID,
+		&eventType,
+		&actor,
+		&data,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	event := New(s)
+	event.ID = id
+	event.AccountID = convert.BytesToUUID(accountID.Bytes)
+	event.GroupID = convert.BytesToUUID(groupID.Bytes)
+	event.Type = eventType
+	event.Actor = actor
+	event.Data = json.RawMessage(data)
+	event.CreatedAt = createdAt.Time
+
+	return event, nil
+}
+
+func (s *Store) findAll(ctx context.Context, query string, args ...interface{}) ([]*Event, error) {
+	rows, err := tracing.QueryEx(ctx, s.pool, query, nil, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*Event
+	for rows.Next() {
+		event, err := s.scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// FindAfter finds the events of an account recorded after the event
+// identified by afterID, oldest first. An empty groupID finds the events of
+// every group.
+func (s *Store) FindAfter(ctx context.Context, accountID, groupID string, afterID int64) ([]*Event, error) {
+	if groupID == "" {
+		query := selectEvents + `WHERE account_id = $1 AND id > $2 ORDER BY id ASC;`
+		return s.findAll(ctx, query, accountID, afterID)
+	}
+
+	query := selectEvents + `WHERE account_id = $1 AND group_id = $2 AND id > $3 ORDER BY id ASC;`
+	return s.findAll(ctx, query, accountID, groupID, afterID)
+}
+
+// FindSince finds the events of every account recorded at or after since,
+// oldest first.
+func (s *Store) FindSince(ctx context.Context, since time.Time) ([]*Event, error) {
+	query := selectEvents + `WHERE created_at >= $1 ORDER BY id ASC;`
+	return s.findAll(ctx, query, since)
+}
+
+// DeleteBefore deletes the events recorded before a point in time.
+func (s *Store) DeleteBefore(ctx context.Context, before time.Time) error {
+	query := `DELETE FROM tsg_group_events WHERE created_at < $1;`
+	_, err := tracing.ExecEx(ctx, s.pool, query, nil, before)
+	return err
+}
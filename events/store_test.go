@@ -0,0 +1,75 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joyent/triton-service-groups/events"
+	"github.com/joyent/triton-service-groups/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsertAndFind(t *testing.T) {
+	if os.Getenv("TSG_TEST") == "" {
+		t.Skip("Acceptance tests skipped unless env 'TSG_TEST=1' set")
+		return
+	}
+
+	db, err := testutils.NewTestDB()
+	if err != nil {
+		t.Error(err)
+	}
+	db.Clear(t)
+	defer db.Clear(t)
+
+	ctx := context.Background()
+	store := events.NewStore(db.Conn)
+
+	accountID := "6f873d02-172c-418f-8416-4da2b50d5c53"
+	groupIDs := []string{
+		"ad74301e-ad62-404a-be44-3b2f24d082ac",
+		"722d25ed-f32a-4944-9861-8990e204850e",
+	}
+
+	var inserted []*events.Event
+	for _, groupID := range groupIDs {
+		event := events.New(store)
+		event.AccountID = accountID
+		event.GroupID = groupID
+		event.Type = events.TypeCreated
+		event.Actor = "testaccount"
+		event.Data = json.RawMessage(`{"capacity":1}`)
+		require.NoError(t, event.Insert(ctx))
+		assert.NotZero(t, event.ID)
+		assert.NotZero(t, event.CreatedAt)
+		inserted = append(inserted, event)
+	}
+
+	found, err := store.FindAfter(ctx, accountID, "", 0)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, inserted[0].ID, found[0].ID, "oldest first")
+	assert.JSONEq(t, `{"capacity":1}`, string(found[0].Data))
+
+	found, err = store.FindAfter(ctx, accountID, "", inserted[0].ID)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, groupIDs[1], found[0].GroupID)
+
+	found, err = store.FindAfter(ctx, accountID, groupIDs[0], 0)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+
+	found, err = store.FindSince(ctx, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+
+	require.NoError(t, store.DeleteBefore(ctx, time.Now().Add(time.Minute)))
+	found, err = store.FindAfter(ctx, accountID, "", 0)
+	require.NoError(t, err)
+	assert.Empty(t, found)
+}
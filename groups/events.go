@@ -0,0 +1,66 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package groups_v1
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/joyent/triton-service-groups/events"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/requestlog"
+)
+
+// recordEvent records a change to group for watchers. The change has already
+// been made, so failing to record it is logged rather than failing the
+// request.
+func recordEvent(ctx context.Context, eventType string, group *ServiceGroup, data interface{}) {
+	session := handlers.GetAuthSession(ctx)
+	logger := requestlog.FromContext(ctx)
+
+	db, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		logger.Error().Err(handlers.ErrNoConnPool).Msg("groups: failed to record event")
+		return
+	}
+
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		logger.Error().Err(err).Msg("groups: failed to encode event")
+		return
+	}
+
+	event := events.New(events.NewStore(db))
+	event.AccountID = session.AccountID
+	event.GroupID = group.ID
+	event.Type = eventType
+	event.Actor = session.Actor()
+	event.Data = bytes
+
+	if err := event.Insert(ctx); err != nil {
+		logger.Error().Err(err).
+			Str("group_id", group.ID).
+			Str("type", eventType).
+			Msg("groups: failed to record event")
+	}
+}
+
+// recordStatus records whether the orchestrator job of group was deployed,
+// given the error returned when submitting it.
+func recordStatus(ctx context.Context, group *ServiceGroup, err error) {
+	status := events.StatusDeployed
+	if err != nil {
+		status = events.StatusFailed
+	}
+
+	recordEvent(ctx, events.TypeStatusChanged, group, struct {
+		Status   string `json:"status"`
+		Capacity int    `json:"capacity"`
+	}{
+		Status:   status,
+		Capacity: group.Capacity,
+	})
+}
@@ -6,6 +6,7 @@
 package groups_v1
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -13,17 +14,24 @@ import (
 	"path"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/joyent/triton-go"
 	"github.com/joyent/triton-go/authentication"
 	"github.com/joyent/triton-go/compute"
 	"github.com/joyent/triton-service-groups/accounts"
+	"github.com/joyent/triton-service-groups/events"
+	"github.com/joyent/triton-service-groups/operations"
+	"github.com/joyent/triton-service-groups/server/apierror"
 	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/handlers/auth"
+	"github.com/joyent/triton-service-groups/server/schema"
+	"github.com/joyent/triton-service-groups/tracing"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 )
 
+var errGroupNotFound = apierror.NotFound("group not found")
+
 type ServiceGroup struct {
 	ID         string    `json:"id"`
 	GroupName  string    `json:"group_name"`
@@ -31,6 +39,8 @@ type ServiceGroup struct {
 	Capacity   int       `json:"capacity"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
+	CreatedBy  string    `json:"created_by"`
+	UpdatedBy  string    `json:"updated_by"`
 }
 
 func Get(w http.ResponseWriter, r *http.Request) {
@@ -44,13 +54,13 @@ func Get(w http.ResponseWriter, r *http.Request) {
 
 	group, ok := FindGroupByID(ctx, uuid, session.AccountID)
 	if !ok {
-		http.NotFound(w, r)
+		apierror.Write(w, r, errGroupNotFound)
 		return
 	}
 
 	bytes, err := json.Marshal(group)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -61,55 +71,78 @@ func Create(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	session := handlers.GetAuthSession(ctx)
 
+	wait, err := parseWait(r)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
 	group, err := decodeGroupResponseBodyAndValidate(body)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		schema.WriteError(w, r, err)
 		return
 	}
 
 	groupExists, err := CheckGroupExistsByName(ctx, group.GroupName, session.AccountID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 	if groupExists {
-		http.Error(w, fmt.Sprintf("Cannot create group %q, "+
+		apierror.Write(w, r, apierror.Conflict("Cannot create group %q, "+
 			"group name conflicts with existing group.",
-			group.GroupName), http.StatusConflict)
+			group.GroupName))
 		return
 	}
 
+	group.CreatedBy = session.Actor()
+	group.UpdatedBy = session.Actor()
+
 	err = SaveGroup(ctx, session.AccountID, group)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
+		return
+	}
+
+	com, ok := FindGroupByName(ctx, group.GroupName, session.AccountID)
+	if !ok {
+		apierror.Write(w, r, errors.Errorf("failed to find group %q after save", group.GroupName))
 		return
 	}
 
-	err = SubmitOrchestratorJob(ctx, group)
+	recordEvent(ctx, events.TypeCreated, com, com)
+	op := startOperation(ctx, operations.TypeCreateGroup, com)
+
+	err = SubmitOrchestratorJob(ctx, com)
+	recordStatus(ctx, com, err)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		failOperation(ctx, op, err)
+		apierror.Write(w, r, err)
 		return
 	}
 
-	com, ok := FindGroupByName(ctx, group.GroupName, session.AccountID)
-	if !ok {
-		http.NotFound(w, r)
+	w.Header().Set("Location", path.Join(r.URL.Path, com.ID))
+
+	if wait > 0 {
+		writeConverged(w, r, com, op, wait, http.StatusCreated)
 		return
 	}
 
+	trackOperation(ctx, op, com)
+	setOperationHeaders(w, op, http.StatusCreated)
+
 	bytes, err := json.Marshal(com)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
-	w.Header().Set("Location", path.Join(r.URL.Path, com.ID))
 	writeJSONResponse(w, bytes, http.StatusCreated)
 }
 
@@ -120,50 +153,72 @@ func Update(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	identifier := vars["identifier"]
 
+	wait, err := parseWait(r)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
 	group, err := decodeGroupResponseBodyAndValidate(body)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		schema.WriteError(w, r, err)
 		return
 	}
 
 	com, ok := FindGroupByID(ctx, identifier, session.AccountID)
 	if !ok {
-		http.NotFound(w, r)
+		apierror.Write(w, r, errGroupNotFound)
 		return
 	}
 
 	if group.GroupName != com.GroupName {
-		http.Error(w, fmt.Sprintf("The group name %q does not match "+
-			"the name on the record.", group.GroupName),
-			http.StatusBadRequest)
+		apierror.Write(w, r, apierror.BadRequest("The group name %q does not match "+
+			"the name on the record.", group.GroupName))
 		return
 	}
 
+	group.ID = com.ID
+	group.UpdatedBy = session.Actor()
+
 	err = UpdateGroup(ctx, identifier, session.AccountID, group)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
+	com.Capacity = group.Capacity
+	com.TemplateID = group.TemplateID
+	com.UpdatedAt = group.UpdatedAt
+	com.UpdatedBy = group.UpdatedBy
+
+	recordEvent(ctx, events.TypeUpdated, com, com)
+	op := startOperation(ctx, operations.TypeUpdateGroup, com)
+
 	err = UpdateOrchestratorJob(ctx, group)
+	recordStatus(ctx, com, err)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		failOperation(ctx, op, err)
+		apierror.Write(w, r, err)
 		return
 	}
 
-	com.Capacity = group.Capacity
-	com.TemplateID = group.TemplateID
-	com.UpdatedAt = group.UpdatedAt
+	if wait > 0 {
+		writeConverged(w, r, com, op, wait, http.StatusOK)
+		return
+	}
+
+	trackOperation(ctx, op, com)
+	setOperationHeaders(w, op, http.StatusOK)
 
 	bytes, err := json.Marshal(com)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -181,22 +236,31 @@ func Delete(w http.ResponseWriter, r *http.Request) {
 
 	group, ok := FindGroupByID(ctx, uuid, session.AccountID)
 	if !ok {
-		http.NotFound(w, r)
+		apierror.Write(w, r, errGroupNotFound)
 		return
 	}
 
-	err := RemoveGroup(ctx, group.ID, session.AccountID)
+	err := RemoveGroup(ctx, group.ID, session.AccountID, session.Actor())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
+	recordEvent(ctx, events.TypeDeleted, group, group)
+	op := startOperation(ctx, operations.TypeDeleteGroup, group)
+
 	if err := DeleteOrchestratorJob(ctx, group); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		failOperation(ctx, op, err)
+		apierror.Write(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	// The operation tracks the instances of the group until every one has
+	// been removed.
+	removed := *group
+	removed.Capacity = 0
+	trackOperation(ctx, op, &removed)
+	writeOperation(w, r, op)
 }
 
 func List(w http.ResponseWriter, r *http.Request) {
@@ -205,7 +269,7 @@ func List(w http.ResponseWriter, r *http.Request) {
 
 	rows, err := FindGroups(ctx, session.AccountID)
 	if err != nil {
-		http.NotFound(w, r)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -216,7 +280,7 @@ func List(w http.ResponseWriter, r *http.Request) {
 
 	bytes, err := json.Marshal(rows)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -236,21 +300,29 @@ func Increment(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	uuid := vars["identifier"]
 
+	wait, err := parseWait(r)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
 	// Get the Current Group Config
 	group, ok := FindGroupByID(ctx, uuid, session.AccountID)
 	if !ok {
-		http.NotFound(w, r)
+		apierror.Write(w, r, errGroupNotFound)
 		return
 	}
 
-	input, err := buildActionableInput(r)
+	input, err := buildActionableInput(r, schema.Increment)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		schema.WriteError(w, r, err)
 		return
 	}
 
 	if group.Capacity >= input.MaxInstance {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.CodeCapacityLimit,
+			fmt.Sprintf("group capacity %d is already at or above the maximum of %d",
+				group.Capacity, input.MaxInstance)))
 		return
 	}
 
@@ -261,21 +333,35 @@ func Increment(w http.ResponseWriter, r *http.Request) {
 		group.Capacity = group.Capacity + input.InstanceCount
 	}
 
+	group.UpdatedBy = session.Actor()
+
 	//Update the Database and the orchestration job
 	err = UpdateGroup(ctx, uuid, session.AccountID, group)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
+	recordEvent(ctx, events.TypeScaled, group, group)
+	op := startOperation(ctx, operations.TypeScaleGroup, group)
+
 	err = UpdateOrchestratorJob(ctx, group)
+	recordStatus(ctx, group, err)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		failOperation(ctx, op, err)
+		apierror.Write(w, r, err)
 		return
 	}
 
-	//Return a 202 to suggest accepted
-	w.WriteHeader(http.StatusAccepted)
+	if wait > 0 {
+		writeConverged(w, r, group, op, wait, http.StatusOK)
+		return
+	}
+
+	//Return a 202 to suggest accepted, along with the operation tracking
+	//the new capacity
+	trackOperation(ctx, op, group)
+	writeOperation(w, r, op)
 }
 
 func Decrement(w http.ResponseWriter, r *http.Request) {
@@ -285,21 +371,29 @@ func Decrement(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	uuid := vars["identifier"]
 
+	wait, err := parseWait(r)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
 	// Get the Current Group Config
 	group, ok := FindGroupByID(ctx, uuid, session.AccountID)
 	if !ok {
-		http.NotFound(w, r)
+		apierror.Write(w, r, errGroupNotFound)
 		return
 	}
 
-	input, err := buildActionableInput(r)
+	input, err := buildActionableInput(r, schema.Decrement)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		schema.WriteError(w, r, err)
 		return
 	}
 
 	if group.Capacity <= input.MinInstance {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.CodeCapacityLimit,
+			fmt.Sprintf("group capacity %d is already at or below the minimum of %d",
+				group.Capacity, input.MinInstance)))
 		return
 	}
 
@@ -310,20 +404,35 @@ func Decrement(w http.ResponseWriter, r *http.Request) {
 		group.Capacity = group.Capacity - input.InstanceCount
 	}
 
+	group.UpdatedBy = session.Actor()
+
 	//Update the Database and the orchestration job
 	err = UpdateGroup(ctx, uuid, session.AccountID, group)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
+		return
+	}
+
+	recordEvent(ctx, events.TypeScaled, group, group)
+	op := startOperation(ctx, operations.TypeScaleGroup, group)
+
+	err = UpdateOrchestratorJob(ctx, group)
+	recordStatus(ctx, group, err)
+	if err != nil {
+		failOperation(ctx, op, err)
+		apierror.Write(w, r, err)
 		return
 	}
 
-	if err := UpdateOrchestratorJob(ctx, group); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if wait > 0 {
+		writeConverged(w, r, group, op, wait, http.StatusOK)
 		return
 	}
 
-	//Return a 202 to suggest accepted
-	w.WriteHeader(http.StatusAccepted)
+	//Return a 202 to suggest accepted, along with the operation tracking
+	//the new capacity
+	trackOperation(ctx, op, group)
+	writeOperation(w, r, op)
 }
 
 func ListInstances(w http.ResponseWriter, r *http.Request) {
@@ -335,39 +444,57 @@ func ListInstances(w http.ResponseWriter, r *http.Request) {
 
 	group, ok := FindGroupByID(ctx, uuid, session.AccountID)
 	if !ok {
-		http.NotFound(w, r)
+		apierror.Write(w, r, errGroupNotFound)
 		return
 	}
 
+	instances, err := listInstances(ctx, group, &compute.ListInstancesInput{})
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	if instances != nil && len(instances) == 0 {
+		writeJSONResponse(w, []byte("[]"), http.StatusOK)
+		return
+	}
+
+	bytes, err := json.Marshal(instances)
+	if err != nil {
+		returnError := errors.Wrapf(err, "error marshalling TSG instance list")
+		apierror.Write(w, r, returnError)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+// listInstances lists the instances of group matching input with CloudAPI,
+// using the credentials of the account.
+func listInstances(ctx context.Context, group *ServiceGroup, input *compute.ListInstancesInput) ([]*compute.Instance, error) {
+	session := handlers.GetAuthSession(ctx)
+
 	db, ok := handlers.GetDBPool(ctx)
 	if !ok {
-		http.Error(w, handlers.ErrNoConnPool.Error(), http.StatusInternalServerError)
-		return
+		return nil, handlers.ErrNoConnPool
 	}
 	store := accounts.NewStore(db)
 	account, err := store.FindByID(ctx, session.AccountID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
-	credential, err := account.GetTritonCredential(ctx)
+	keyring, _ := handlers.GetKeyring(ctx)
+	credential, err := account.GetTritonCredential(ctx, keyring)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
-	input := authentication.PrivateKeySignerInput{
-		KeyID:              credential.KeyID,
-		PrivateKeyMaterial: []byte(credential.KeyMaterial),
-		AccountName:        credential.AccountName,
-	}
-	signer, err := authentication.NewPrivateKeySigner(input)
+	keypair, err := auth.DecodeKeyPair(credential.KeyMaterial)
 	if err != nil {
-		returnError := errors.Wrapf(err, "error Creating SSH Private Key Signer")
-		http.Error(w, returnError.Error(), http.StatusInternalServerError)
-		return
+		return nil, errors.Wrapf(err, "error decoding SSH Private Key")
 	}
+	signer := auth.NewKeySigner(keypair, credential.AccountName)
 
 	config := &triton.ClientConfig{
 		TritonURL:   session.TritonURL,
@@ -377,36 +504,21 @@ func ListInstances(w http.ResponseWriter, r *http.Request) {
 
 	c, err := compute.NewClient(config)
 	if err != nil {
-		returnError := errors.Wrapf(err, "error constructing ComputeClient")
-		http.Error(w, returnError.Error(), http.StatusInternalServerError)
-		return
+		return nil, errors.Wrapf(err, "error constructing ComputeClient")
 	}
 
-	params := &compute.ListInstancesInput{}
-	t := make(map[string]interface{}, 0)
-	t["tsg.name"] = group.GroupName
-	params.Tags = t
-
-	instances, err := c.Instances().List(ctx, params)
-	if err != nil {
-		returnError := errors.Wrapf(err, "error listing instances in TSG")
-		http.Error(w, returnError.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	if instances != nil && len(instances) == 0 {
-		writeJSONResponse(w, []byte("[]"), http.StatusOK)
-		return
+	input.Tags = map[string]interface{}{
+		"tsg.name": group.GroupName,
 	}
 
-	bytes, err := json.Marshal(instances)
+	_, span := tracing.StartClient(ctx, "cloudapi", "ListMachines")
+	instances, err := c.Instances().List(ctx, input)
+	tracing.Finish(span, err)
 	if err != nil {
-		returnError := errors.Wrapf(err, "error marshalling TSG instance list")
-		http.Error(w, returnError.Error(), http.StatusInternalServerError)
-		return
+		return nil, errors.Wrapf(err, "error listing instances in TSG")
 	}
 
-	writeJSONResponse(w, bytes, http.StatusOK)
+	return instances, nil
 }
 
 func writeJSONResponse(w http.ResponseWriter, bytes []byte, statusCode int) {
@@ -419,15 +531,14 @@ func writeJSONResponse(w http.ResponseWriter, bytes []byte, statusCode int) {
 	}
 }
 
-func buildActionableInput(r *http.Request) (*ActionableInput, error) {
+func buildActionableInput(r *http.Request, s *schema.Schema) (*ActionableInput, error) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		return nil, err
 	}
 
 	var input *ActionableInput
-	err = json.Unmarshal(body, &input)
-	if err != nil {
+	if err := schema.Decode(s, body, &input); err != nil {
 		return nil, err
 	}
 
@@ -449,35 +560,9 @@ func (i *ActionableInput) Validate() error {
 
 func decodeGroupResponseBodyAndValidate(body []byte) (*ServiceGroup, error) {
 	var group *ServiceGroup
-	err := json.Unmarshal(body, &group)
-	if err != nil {
-		return nil, errors.New("error in unmarshal request body")
-	}
-
-	if group.GroupName == "" {
-		return nil, errors.New("group name cannot be empty")
-	}
-
-	if len(group.GroupName) > 182 {
-		return nil, errors.New("group name cannot be more than 182 characters")
-	}
-
-	if !isValidUUID(group.TemplateID) {
-		return nil, errors.New("template ID must be a valid UUID")
-	}
-
-	if group.Capacity < 0 {
-		return nil, errors.New("group capacity cannot be a negative number")
-	}
-
-	if group.Capacity > 100 {
-		return nil, errors.New("group capacity cannot be more than 100 compute instances")
+	if err := schema.Decode(schema.Group, body, &group); err != nil {
+		return nil, err
 	}
 
 	return group, nil
 }
-
-func isValidUUID(u string) bool {
-	_, err := uuid.Parse(u)
-	return err == nil
-}
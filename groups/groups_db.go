@@ -13,6 +13,7 @@ import (
 	"github.com/jackc/pgx/pgtype"
 	"github.com/joyent/triton-service-groups/convert"
 	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/tracing"
 )
 
 func CheckGroupExistsByName(ctx context.Context, groupName, accountID string) (bool, error) {
@@ -31,7 +32,7 @@ SELECT EXISTS
           AND account_id = $2)
      AND archived IS FALSE);`
 
-	err := db.QueryRowEx(ctx, sql, nil, groupName, accountID).Scan(&exists)
+	err := tracing.QueryRowEx(ctx, db, sql, nil, groupName, accountID).Scan(&exists)
 	if err != nil {
 		return false, err
 	}
@@ -48,12 +49,12 @@ func FindGroups(ctx context.Context, accountID string) ([]*ServiceGroup, error)
 	var groups []*ServiceGroup
 
 	sqlStatement := `
-SELECT id, name, template_id, capacity, created_at, updated_at
+SELECT id, name, template_id, capacity, created_at, updated_at, COALESCE(created_by, ''), COALESCE(updated_by, '')
 FROM tsg_groups
 WHERE account_id = $1
 AND archived = false;`
 
-	rows, err := db.QueryEx(ctx, sqlStatement, nil, accountID)
+	rows, err := tracing.QueryEx(ctx, db, sqlStatement, nil, accountID)
 	if err != nil {
 		return nil, err
 	}
@@ -74,6 +75,8 @@ AND archived = false;`
 			&group.Capacity,
 			&createdAt,
 			&updatedAt,
+			&group.CreatedBy,
+			&group.UpdatedBy,
 		)
 		if err != nil {
 			return nil, err
@@ -90,6 +93,59 @@ AND archived = false;`
 	return groups, nil
 }
 
+// GroupCapacity is the desired capacity of a group, as exposed by the metrics
+// endpoint.
+type GroupCapacity struct {
+	AccountName string
+	GroupID     string
+	GroupName   string
+	Capacity    int
+}
+
+// FindCapacities returns the desired capacity of every group of every
+// account.
+func FindCapacities(ctx context.Context) ([]*GroupCapacity, error) {
+	db, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		return nil, handlers.ErrNoConnPool
+	}
+
+	sqlStatement := `
+SELECT a.account_name, g.id, g.name, g.capacity
+FROM tsg_groups AS g
+JOIN tsg_accounts AS a ON a.id = g.account_id
+WHERE g.archived = false;`
+
+	rows, err := tracing.QueryEx(ctx, db, sqlStatement, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var capacities []*GroupCapacity
+	for rows.Next() {
+		var (
+			capacity GroupCapacity
+			groupID  pgtype.UUID
+		)
+
+		err := rows.Scan(
+			&capacity.AccountName,
+			&groupID,
+			&capacity.GroupName,
+			&capacity.Capacity,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		capacity.GroupID = convert.BytesToUUID(groupID.Bytes)
+		capacities = append(capacities, &capacity)
+	}
+
+	return capacities, rows.Err()
+}
+
 func FindGroupByID(ctx context.Context, key string, accountID string) (*ServiceGroup, bool) {
 	db, ok := handlers.GetDBPool(ctx)
 	if !ok {
@@ -104,19 +160,21 @@ func FindGroupByID(ctx context.Context, key string, accountID string) (*ServiceG
 	)
 
 	sqlStatement := `
-SELECT id, name, template_id, capacity, created_at, updated_at
+SELECT id, name, template_id, capacity, created_at, updated_at, COALESCE(created_by, ''), COALESCE(updated_by, '')
 FROM tsg_groups
 WHERE account_id = $2 and id = $1
 AND archived = false
 `
 
-	err := db.QueryRowEx(ctx, sqlStatement, nil, key, accountID).Scan(
+	err := tracing.QueryRowEx(ctx, db, sqlStatement, nil, key, accountID).Scan(
 		&groupID,
 		&group.GroupName,
 		&group.TemplateID,
 		&group.Capacity,
 		&createdAt,
 		&updatedAt,
+		&group.CreatedBy,
+		&group.UpdatedBy,
 	)
 	switch err {
 	case nil:
@@ -148,18 +206,20 @@ func FindGroupByName(ctx context.Context, name string, accountID string) (*Servi
 	)
 
 	sqlStatement := `
-SELECT id, name, template_id, capacity, created_at, updated_at
+SELECT id, name, template_id, capacity, created_at, updated_at, COALESCE(created_by, ''), COALESCE(updated_by, '')
 FROM tsg_groups
 WHERE account_id = $2 and name = $1
 AND archived = false;
 `
-	err := db.QueryRowEx(ctx, sqlStatement, nil, name, accountID).Scan(
+	err := tracing.QueryRowEx(ctx, db, sqlStatement, nil, name, accountID).Scan(
 		&groupID,
 		&group.GroupName,
 		&group.TemplateID,
 		&group.Capacity,
 		&createdAt,
 		&updatedAt,
+		&group.CreatedBy,
+		&group.UpdatedBy,
 	)
 	switch err {
 	case nil:
@@ -184,14 +244,16 @@ func SaveGroup(ctx context.Context, accountID string, group *ServiceGroup) error
 	}
 
 	sqlStatement := `
-INSERT INTO tsg_groups (name, template_id, capacity, account_id, created_at, updated_at)
-VALUES ($1, $2, $3, $4, NOW(), NOW())
+INSERT INTO tsg_groups (name, template_id, capacity, account_id, created_by, updated_by, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
 `
-	_, err := db.ExecEx(ctx, sqlStatement, nil,
+	_, err := tracing.ExecEx(ctx, db, sqlStatement, nil,
 		group.GroupName,
 		group.TemplateID,
 		group.Capacity,
 		accountID,
+		group.CreatedBy,
+		group.UpdatedBy,
 	)
 	if err != nil {
 		return err
@@ -208,14 +270,15 @@ func UpdateGroup(ctx context.Context, uuid string, accountID string, group *Serv
 
 	sqlStatement := `
 UPDATE tsg_groups
-SET template_id = $3, capacity = $4, updated_at = NOW()
+SET template_id = $3, capacity = $4, updated_by = $5, updated_at = NOW()
 WHERE id = $1 and account_id = $2
 `
-	_, err := db.ExecEx(ctx, sqlStatement, nil,
+	_, err := tracing.ExecEx(ctx, db, sqlStatement, nil,
 		uuid,
 		accountID,
 		group.TemplateID,
 		group.Capacity,
+		group.UpdatedBy,
 	)
 	if err != nil {
 		return err
@@ -224,7 +287,7 @@ WHERE id = $1 and account_id = $2
 	return nil
 }
 
-func RemoveGroup(ctx context.Context, identifier string, accountID string) error {
+func RemoveGroup(ctx context.Context, identifier string, accountID string, actor string) error {
 	db, ok := handlers.GetDBPool(ctx)
 	if !ok {
 		return handlers.ErrNoConnPool
@@ -232,10 +295,10 @@ func RemoveGroup(ctx context.Context, identifier string, accountID string) error
 
 	sqlStatement := `
 UPDATE tsg_groups
-SET archived = true, updated_at = NOW()
+SET archived = true, updated_by = $3, updated_at = NOW()
 WHERE id = $1 and account_id = $2
 `
-	_, err := db.ExecEx(ctx, sqlStatement, nil, identifier, accountID)
+	_, err := tracing.ExecEx(ctx, db, sqlStatement, nil, identifier, accountID, actor)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,208 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package groups_v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/joyent/triton-go/compute"
+	"github.com/joyent/triton-service-groups/events"
+	"github.com/joyent/triton-service-groups/operations"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/requestlog"
+	"github.com/pkg/errors"
+)
+
+// trackTimeout is how long an operation waits for its group to converge
+// before failing.
+const trackTimeout = 30 * time.Minute
+
+// operationLocationHeader carries the path of the operation started by a
+// request whose Location refers to something else, such as a created group.
+const operationLocationHeader = "Operation-Location"
+
+var errOrchestratorFailed = errors.New("failed to update the orchestrator job")
+
+// startOperation records an operation of group. Operations are only recorded
+// when the agent runs them, and a failure to record one is logged rather than
+// failing the request, so the operation returned may be nil.
+func startOperation(ctx context.Context, opType string, group *ServiceGroup) *operations.Operation {
+	if _, ok := operations.FromContext(ctx); !ok {
+		return nil
+	}
+
+	session := handlers.GetAuthSession(ctx)
+	db, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		return nil
+	}
+
+	op := operations.New(operations.NewStore(db))
+	op.AccountID = session.AccountID
+	op.GroupID = group.ID
+	op.Type = opType
+	op.CreatedBy = session.Actor()
+	op.Progress = "updating the orchestrator job"
+
+	if err := op.Insert(ctx); err != nil {
+		requestlog.FromContext(ctx).Error().Err(err).
+			Str("group_id", group.ID).
+			Msg("groups: failed to record operation")
+		return nil
+	}
+
+	return op
+}
+
+// failOperation records that op failed with err. Errors which aren't API
+// errors may reveal internal details, so are recorded as the step which
+// failed.
+func failOperation(ctx context.Context, op *operations.Operation, err error) {
+	if op == nil {
+		return
+	}
+
+	if _, ok := errors.Cause(err).(*apierror.Error); !ok {
+		err = errOrchestratorFailed
+	}
+
+	finishOperation(ctx, op, err)
+}
+
+// finishOperation records that op succeeded, or failed with err, unless it
+// has been cancelled.
+func finishOperation(ctx context.Context, op *operations.Operation, err error) {
+	if op == nil {
+		return
+	}
+
+	if err := op.Finish(ctx, err); err != nil && err != operations.ErrNotRunning {
+		requestlog.FromContext(ctx).Error().Err(err).
+			Str("operation_id", op.ID).
+			Msg("groups: failed to record operation outcome")
+	}
+}
+
+// trackOperation waits in the background for group to converge, recording
+// the progress of op until it has.
+func trackOperation(ctx context.Context, op *operations.Operation, group *ServiceGroup) {
+	runner, ok := operations.FromContext(ctx)
+	if op == nil || !ok {
+		return
+	}
+
+	runner.Run(ctx, op, trackTimeout, func(ctx context.Context) error {
+		convergence := waitForCapacity(ctx, group.Capacity, trackTimeout, convergencePollInterval,
+			countRunning(group, op))
+		if convergence.Converged {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return context.DeadlineExceeded
+	})
+}
+
+// HaltScaling stops scaling the group with groupID where it is, setting its
+// capacity to the number of instances running. It's called when a scaling
+// operation of the group is cancelled, so instances still being created are
+// removed and those still being removed are kept.
+func HaltScaling(ctx context.Context, groupID string) (*ServiceGroup, error) {
+	session := handlers.GetAuthSession(ctx)
+
+	group, ok := FindGroupByID(ctx, groupID, session.AccountID)
+	if !ok {
+		return nil, errGroupNotFound
+	}
+
+	instances, err := listInstances(ctx, group, &compute.ListInstancesInput{
+		State: instanceStateRunning,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(instances) == group.Capacity {
+		return group, nil
+	}
+
+	group.Capacity = len(instances)
+	group.UpdatedBy = session.Actor()
+
+	if err := UpdateGroup(ctx, group.ID, session.AccountID, group); err != nil {
+		return nil, err
+	}
+
+	recordEvent(ctx, events.TypeScaled, group, group)
+
+	err = UpdateOrchestratorJob(ctx, group)
+	recordStatus(ctx, group, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// countRunning returns a function counting the running instances of group,
+// recording the count as the progress of op.
+func countRunning(group *ServiceGroup, op *operations.Operation) func(context.Context) (int, error) {
+	return func(ctx context.Context) (int, error) {
+		instances, err := listInstances(ctx, group, &compute.ListInstancesInput{
+			State: instanceStateRunning,
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		if op != nil {
+			progress := fmt.Sprintf("%d of %d instances running", len(instances), group.Capacity)
+			if err := op.SetProgress(ctx, progress); err != nil && err != operations.ErrNotRunning {
+				requestlog.FromContext(ctx).Warn().Err(err).
+					Str("operation_id", op.ID).
+					Msg("groups: failed to record operation progress")
+			}
+		}
+
+		return len(instances), nil
+	}
+}
+
+// setOperationHeaders refers the response to op. Accepted responses refer to
+// it with their Location, unless it's already set.
+func setOperationHeaders(w http.ResponseWriter, op *operations.Operation, status int) {
+	if op == nil {
+		return
+	}
+
+	w.Header().Set(operationLocationHeader, op.Path())
+	if status == http.StatusAccepted && w.Header().Get("Location") == "" {
+		w.Header().Set("Location", op.Path())
+	}
+}
+
+// writeOperation accepts the request, writing op as the response.
+func writeOperation(w http.ResponseWriter, r *http.Request, op *operations.Operation) {
+	setOperationHeaders(w, op, http.StatusAccepted)
+	if op == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	bytes, err := json.Marshal(op)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusAccepted)
+}
@@ -0,0 +1,36 @@
+package groups_v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/operations"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetOperationHeaders(t *testing.T) {
+	op := &operations.Operation{ID: "b4a0a1e4-4b0b-4f3d-9d5a-0d6e8f7d5c3a"}
+	path := "/v1/tsg/operations/b4a0a1e4-4b0b-4f3d-9d5a-0d6e8f7d5c3a"
+
+	w := httptest.NewRecorder()
+	setOperationHeaders(w, op, http.StatusAccepted)
+	assert.Equal(t, path, w.Header().Get("Location"))
+	assert.Equal(t, path, w.Header().Get(operationLocationHeader))
+
+	w = httptest.NewRecorder()
+	w.Header().Set("Location", "/v1/tsg/groups/722d25ed-f32a-4944-9861-8990e204850e")
+	setOperationHeaders(w, op, http.StatusAccepted)
+	assert.Equal(t, "/v1/tsg/groups/722d25ed-f32a-4944-9861-8990e204850e", w.Header().Get("Location"),
+		"the Location of a created group is kept")
+	assert.Equal(t, path, w.Header().Get(operationLocationHeader))
+
+	w = httptest.NewRecorder()
+	setOperationHeaders(w, op, http.StatusOK)
+	assert.Empty(t, w.Header().Get("Location"))
+	assert.Equal(t, path, w.Header().Get(operationLocationHeader))
+
+	w = httptest.NewRecorder()
+	setOperationHeaders(w, nil, http.StatusAccepted)
+	assert.Empty(t, w.Header())
+}
@@ -14,11 +14,27 @@ import (
 	"github.com/hashicorp/nomad/jobspec"
 	"github.com/joyent/triton-service-groups/accounts"
 	"github.com/joyent/triton-service-groups/config"
+	"github.com/joyent/triton-service-groups/metrics"
 	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/requestlog"
 	"github.com/joyent/triton-service-groups/templates"
-	"github.com/rs/zerolog/log"
+	"github.com/joyent/triton-service-groups/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// errNoTSGURL is returned when a template references secrets but scaling jobs
+// haven't been told where to fetch them from.
+var errNoTSGURL = errors.New("tsgcli.api-url must be configured for templates referencing secrets")
+
+type keyIDKey struct{}
+
+// WithKeyID returns a copy of ctx which renders orchestrator jobs with the
+// management key identified by keyID rather than the account's current key,
+// such as while rotating keys.
+func WithKeyID(ctx context.Context, keyID string) context.Context {
+	return context.WithValue(ctx, keyIDKey{}, keyID)
+}
+
 type OrchestratorJob struct {
 	Datacenter        string
 	JobName           string
@@ -26,17 +42,21 @@ type OrchestratorJob struct {
 	PackageID         string
 	ImageID           string
 	ServiceGroupName  string
+	ServiceGroupID    string
 	TemplateID        string
+	TemplateVersion   string
 	UserData          string
 	FirewallEnabled   bool
 	Networks          []string
 	Tags              map[string]string
 	MetaData          map[string]string
+	Secrets           []string
 	TritonAccount     string
 	TritonURL         string
 	TritonKeyID       string
 	TritonKeyMaterial string
 	TSGCliVersion     string
+	TSGURL            string
 }
 
 func SubmitOrchestratorJob(ctx context.Context, group *ServiceGroup) error {
@@ -131,7 +151,10 @@ func deregisterJob(ctx context.Context, jobID string) (bool, error) {
 		return false, handlers.ErrNoNomadClient
 	}
 
-	_, _, err := client.Jobs().Deregister(jobID, true, nil)
+	err := callNomad(ctx, "deregister", jobID, func() error {
+		_, _, err := client.Jobs().Deregister(jobID, true, nil)
+		return err
+	})
 	if err != nil {
 		return false, fmt.Errorf("Unable to deregister job with Nomad: %v", err)
 	}
@@ -142,21 +165,30 @@ func deregisterJob(ctx context.Context, jobID string) (bool, error) {
 func registerJob(ctx context.Context, job *nomad.Job) (bool, error) {
 	client, ok := handlers.GetNomadClient(ctx)
 	if !ok {
-		log.Error().Err(handlers.ErrNoNomadClient)
+		requestlog.FromContext(ctx).Error().Err(handlers.ErrNoNomadClient)
 		return false, handlers.ErrNoNomadClient
 	}
 
-	_, _, err := client.Jobs().Validate(job, nil)
+	err := callNomad(ctx, "validate", *job.ID, func() error {
+		_, _, err := client.Jobs().Validate(job, nil)
+		return err
+	})
 	if err != nil {
 		return false, fmt.Errorf("Failed to validate Nomad Job: %v", err)
 	}
 
-	_, _, err = client.Jobs().Register(job, nil)
+	err = callNomad(ctx, "register", *job.ID, func() error {
+		_, _, err := client.Jobs().Register(job, nil)
+		return err
+	})
 	if err != nil {
 		return false, fmt.Errorf("Unable to register job with Nomad: %v", err)
 	}
 
-	_, _, err = client.Jobs().PeriodicForce(*job.ID, nil)
+	err = callNomad(ctx, "periodic_force", *job.ID, func() error {
+		_, _, err := client.Jobs().PeriodicForce(*job.ID, nil)
+		return err
+	})
 	if err != nil {
 		return false, fmt.Errorf("Unable to trigger a periodic instance of job: %v", err)
 	}
@@ -164,6 +196,17 @@ func registerJob(ctx context.Context, job *nomad.Job) (bool, error) {
 	return true, nil
 }
 
+// callNomad calls operation of the Nomad API on the job identified by jobID,
+// recording its outcome as a metric and a span.
+func callNomad(ctx context.Context, operation string, jobID string, call func() error) error {
+	_, span := tracing.StartClient(ctx, "nomad", operation, attribute.String("nomad.job_id", jobID))
+	err := call()
+	tracing.Finish(span, err)
+
+	metrics.OrchestratorOperations.WithLabelValues(operation, metrics.Outcome(err)).Inc()
+	return err
+}
+
 func prepareJob(ctx context.Context, t *templates_v1.InstanceTemplate, group *ServiceGroup) (*nomad.Job, error) {
 	session := handlers.GetAuthSession(ctx)
 
@@ -171,6 +214,10 @@ func prepareJob(ctx context.Context, t *templates_v1.InstanceTemplate, group *Se
 	details := createJobDetails(t, group)
 	details.Datacenter = session.Datacenter
 	details.TSGCliVersion = config.GetTSGCliVersion()
+	details.TSGURL = config.GetTSGCliAPIURL()
+	if len(details.Secrets) > 0 && details.TSGURL == "" {
+		return nil, errNoTSGURL
+	}
 	if err := details.getTritonAccountDetails(ctx); err != nil {
 		return nil, err
 	}
@@ -199,7 +246,7 @@ func (j *OrchestratorJob) getTritonAccountDetails(ctx context.Context) error {
 
 	db, ok := handlers.GetDBPool(ctx)
 	if !ok {
-		log.Error().Err(handlers.ErrNoConnPool)
+		requestlog.FromContext(ctx).Error().Err(handlers.ErrNoConnPool)
 		return handlers.ErrNoConnPool
 	}
 
@@ -207,17 +254,22 @@ func (j *OrchestratorJob) getTritonAccountDetails(ctx context.Context) error {
 
 	account, err := store.FindByID(ctx, session.AccountID)
 	if err != nil {
-		log.Error().Err(err)
+		requestlog.FromContext(ctx).Error().Err(err)
 		return err
 	}
 
-	credential, err := account.GetTritonCredential(ctx)
+	if keyID, ok := ctx.Value(keyIDKey{}).(string); ok {
+		account.KeyID = keyID
+	}
+
+	keyring, _ := handlers.GetKeyring(ctx)
+	credential, err := account.GetTritonCredential(ctx, keyring)
 	if err != nil {
-		log.Error().Err(err)
+		requestlog.FromContext(ctx).Error().Err(err)
 		return err
 	}
 
-	log.Debug().
+	requestlog.FromContext(ctx).Debug().
 		Str("account_id", account.ID).
 		Str("account_name", account.AccountName).
 		Str("fingerprint", credential.KeyID).
@@ -239,8 +291,11 @@ func createJobDetails(template *templates_v1.InstanceTemplate, group *ServiceGro
 		PackageID:        template.Package,
 		ImageID:          template.ImageID,
 		ServiceGroupName: group.GroupName,
+		ServiceGroupID:   group.ID,
 		FirewallEnabled:  template.FirewallEnabled,
 		TemplateID:       template.ID,
+		TemplateVersion:  template.ShortID(),
+		Secrets:          template.SecretNames(),
 	}
 
 	if template.UserData != "" {
@@ -300,7 +355,10 @@ job "{{ .JobName }}" {
 	  "--pkg-id", "{{ .PackageID }}",
 	  "--img-id", "{{ .ImageID }}",
 	  "--tsg-name", "{{ .ServiceGroupName }}",
+	  "--tsg-id", "{{ .ServiceGroupID }}",
 	  "--template-id", "{{ .TemplateID }}",
+	  "--template-version", "{{ .TemplateVersion }}",
+	  "--datacenter", "{{ .Datacenter }}",
 	  {{if .UserData -}}
 	  "--userdata", "{{ .UserData | base64_encode }}",
 	  {{- end }}
@@ -313,6 +371,12 @@ job "{{ .JobName }}" {
 	  {{ range $key, $value := .MetaData }}
 	  "--metadata", "{{ printf "%s=%s" $key $value | base64_encode }}",
 	  {{- end }}
+	  {{ if .Secrets -}}
+	  "--tsg-url", "{{ .TSGURL }}",
+	  {{- end }}
+	  {{ range .Secrets }}
+	  "--secret-name", "{{ . }}",
+	  {{- end }}
 	  "-A", "{{ .TritonAccount }}",
 	  "-K", "{{ .TritonKeyID }}",
 	  "-U", "{{ .TritonURL }}",
@@ -1,7 +1,13 @@
 package groups_v1
 
 import (
+	"bytes"
+	"fmt"
+	"strings"
 	"testing"
+	"text/template"
+
+	"github.com/hashicorp/nomad/jobspec"
 )
 
 func TestBase64Encode(t *testing.T) {
@@ -60,3 +66,44 @@ func TestEscapeNewlines(t *testing.T) {
 		}
 	}
 }
+
+func TestJobTemplateSecrets(t *testing.T) {
+	details := OrchestratorJob{
+		Datacenter:    "us-east-1",
+		JobName:       "web_6f873d02",
+		DesiredCount:  2,
+		TemplateID:    "d255305d-aa60-49bc-acc2-3713cf0beb1c",
+		Secrets:       []string{"db_password", "api_key"},
+		TSGURL:        "https://tsg.example.com",
+		TritonAccount: "demo",
+		TSGCliVersion: "0.1.0",
+	}
+
+	tpl := &bytes.Buffer{}
+	jobT := template.Must(template.New("job").Funcs(template.FuncMap{
+		"base64_encode":   base64Encode,
+		"escape_newlines": escapeNewlines,
+	}).Parse(jobTemplate))
+	if err := jobT.Execute(tpl, details); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := jobspec.Parse(tpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := fmt.Sprint(job.TaskGroups[0].Tasks[0].Config["args"])
+	for _, expected := range []string{
+		"--tsg-url https://tsg.example.com",
+		"--secret-name db_password",
+		"--secret-name api_key",
+	} {
+		if !strings.Contains(args, expected) {
+			t.Errorf("expected %q within the job arguments, got %q", expected, args)
+		}
+	}
+	if strings.Contains(args, "--secret ") {
+		t.Errorf("secret values were passed to the job: %q", args)
+	}
+}
@@ -0,0 +1,69 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package groups_v1
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/joyent/triton-service-groups/secrets"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/templates"
+)
+
+// errNotScalingJob is written when anything other than a group's scaling job
+// asks for the values of its secrets.
+var errNotScalingJob = apierror.New(http.StatusForbidden, apierror.CodeForbidden,
+	"secret values are only handed to scaling jobs")
+
+// ResolveSecrets returns the values of the secrets referenced by the template
+// of a group. Scaling jobs are only given the names of secrets and fetch their
+// values when creating instances, signing the request with the key TSG manages
+// for the account. Values are never returned to anybody else.
+func ResolveSecrets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	if !session.IsManagementKey() {
+		apierror.Write(w, r, errNotScalingJob)
+		return
+	}
+
+	vars := mux.Vars(r)
+	uuid := vars["identifier"]
+
+	group, ok := FindGroupByID(ctx, uuid, session.AccountID)
+	if !ok {
+		apierror.Write(w, r, errGroupNotFound)
+		return
+	}
+
+	t, ok := templates_v1.FindTemplateByID(ctx, group.TemplateID, session.AccountID)
+	if !ok {
+		apierror.Write(w, r, apierror.NotFound("template not found"))
+		return
+	}
+
+	values, err := secrets_v1.ResolveSecrets(ctx, session.AccountID, t.SecretNames())
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if values == nil {
+		values = map[string]string{}
+	}
+
+	bytes, err := json.Marshal(values)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
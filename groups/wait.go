@@ -0,0 +1,136 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package groups_v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/joyent/triton-service-groups/operations"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/requestlog"
+)
+
+const (
+	// MaxWait is the longest a request can wait for a group to converge.
+	MaxWait = 10 * time.Minute
+
+	// convergencePollInterval is how often the instances of a group are
+	// counted while waiting for it to converge.
+	convergencePollInterval = 5 * time.Second
+
+	instanceStateRunning = "running"
+)
+
+// Convergence describes whether the running instances of a group matched
+// its capacity before the requested wait expired.
+type Convergence struct {
+	Converged bool    `json:"converged"`
+	Capacity  int     `json:"capacity"`
+	Running   int     `json:"running"`
+	Waited    float64 `json:"waited_seconds"`
+}
+
+// convergedGroup is the response of a call which waited for the group to
+// converge, the group along with its convergence.
+type convergedGroup struct {
+	*ServiceGroup
+	Convergence *Convergence `json:"convergence"`
+}
+
+// parseWait parses the ?wait=<duration> query parameter of r, returning zero
+// when the caller doesn't want to wait.
+func parseWait(r *http.Request) (time.Duration, error) {
+	value := r.URL.Query().Get("wait")
+	if value == "" {
+		return 0, nil
+	}
+
+	wait, err := time.ParseDuration(value)
+	if err != nil || wait < 0 {
+		return 0, apierror.BadRequest("wait must be a positive duration, such as 5m: %q", value)
+	}
+	if wait > MaxWait {
+		return 0, apierror.BadRequest("wait must be at most %v", MaxWait)
+	}
+
+	return wait, nil
+}
+
+// waitForGroup waits up to timeout for the number of running instances of
+// group to match its capacity, counting them with CloudAPI and recording the
+// count as the progress of op.
+func waitForGroup(ctx context.Context, group *ServiceGroup, op *operations.Operation, timeout time.Duration) *Convergence {
+	return waitForCapacity(ctx, group.Capacity, timeout, convergencePollInterval, countRunning(group, op))
+}
+
+// waitForCapacity calls count every interval until it returns capacity, ctx
+// is done or timeout expires. Failures to count are logged and retried.
+func waitForCapacity(ctx context.Context, capacity int, timeout, interval time.Duration,
+	count func(context.Context) (int, error)) *Convergence {
+	start := time.Now()
+	convergence := &Convergence{Capacity: capacity}
+	defer func() {
+		convergence.Waited = time.Since(start).Seconds()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		running, err := count(ctx)
+		switch {
+		case err == nil:
+			convergence.Running = running
+			convergence.Converged = running == capacity
+		case ctx.Err() == nil:
+			requestlog.FromContext(ctx).Warn().Err(err).
+				Msg("groups: failed to count instances while waiting")
+		}
+		if convergence.Converged {
+			return convergence
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return convergence
+		}
+	}
+}
+
+// writeConverged waits up to wait for group to converge, then writes it along
+// with its convergence. status is written once the group has converged, or
+// 202 Accepted if the wait expired first, in which case op keeps tracking the
+// group in the background. The mutation has been made by then, so its
+// concurrency slot is released rather than held for the whole wait.
+func writeConverged(w http.ResponseWriter, r *http.Request, group *ServiceGroup, op *operations.Operation, wait time.Duration, status int) {
+	ctx := r.Context()
+	handlers.ReleaseConcurrency(ctx)
+
+	convergence := waitForGroup(ctx, group, op, wait)
+	if convergence.Converged {
+		finishOperation(ctx, op, nil)
+	} else {
+		status = http.StatusAccepted
+		trackOperation(ctx, op, group)
+	}
+	setOperationHeaders(w, op, status)
+
+	bytes, err := json.Marshal(convergedGroup{group, convergence})
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, status)
+}
@@ -0,0 +1,70 @@
+package groups_v1
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWait(t *testing.T) {
+	tests := []struct {
+		query string
+		wait  time.Duration
+		fails bool
+	}{
+		{"", 0, false},
+		{"?wait=90s", 90 * time.Second, false},
+		{"?wait=10m", MaxWait, false},
+		{"?wait=11m", 0, true},
+		{"?wait=-1s", 0, true},
+		{"?wait=soon", 0, true},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("PUT", "/v1/tsg/groups/x/increment"+test.query, nil)
+		wait, err := parseWait(r)
+		if test.fails {
+			assert.Error(t, err, test.query)
+			continue
+		}
+		require.NoError(t, err, test.query)
+		assert.Equal(t, test.wait, wait, test.query)
+	}
+}
+
+func TestWaitForCapacityConverges(t *testing.T) {
+	var calls int
+	count := func(context.Context) (int, error) {
+		calls++
+		switch calls {
+		case 1:
+			return 1, nil
+		case 2:
+			return 0, errors.New("cloudapi unavailable")
+		default:
+			return 3, nil
+		}
+	}
+
+	convergence := waitForCapacity(context.Background(), 3, time.Second, time.Millisecond, count)
+	assert.True(t, convergence.Converged)
+	assert.Equal(t, 3, convergence.Running)
+	assert.Equal(t, 3, convergence.Capacity)
+	assert.Equal(t, 3, calls)
+}
+
+func TestWaitForCapacityTimesOut(t *testing.T) {
+	count := func(context.Context) (int, error) {
+		return 1, nil
+	}
+
+	convergence := waitForCapacity(context.Background(), 3, 20*time.Millisecond, time.Millisecond, count)
+	assert.False(t, convergence.Converged)
+	assert.Equal(t, 1, convergence.Running)
+	assert.True(t, convergence.Waited >= 0.02)
+}
@@ -0,0 +1,139 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package groups_v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/joyent/triton-service-groups/events"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/pkg/errors"
+)
+
+// heartbeatInterval is how often a comment is sent to idle watchers, so
+// proxies don't close the connection.
+const heartbeatInterval = 15 * time.Second
+
+var errWatchUnavailable = apierror.New(http.StatusServiceUnavailable,
+	apierror.CodeUnavailable, "watching groups is unavailable")
+
+// Watch streams the changes made to the groups of the account as
+// Server-Sent Events, or to a single group when routed with an identifier.
+// Clients resume after the last event they received by sending its identifier
+// within the Last-Event-ID header, or the last_event_id query parameter.
+func Watch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	groupID := mux.Vars(r)["identifier"]
+	if groupID != "" {
+		if _, ok := FindGroupByID(ctx, groupID, session.AccountID); !ok {
+			apierror.Write(w, r, errGroupNotFound)
+			return
+		}
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	var afterID int64
+	if lastID != "" {
+		id, err := events.ParseID(lastID)
+		if err != nil {
+			apierror.Write(w, r, apierror.BadRequest("%v", err))
+			return
+		}
+		afterID = id
+	}
+
+	broker, ok := events.FromContext(ctx)
+	if !ok {
+		apierror.Write(w, r, errWatchUnavailable)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		apierror.Write(w, r, errors.New("response writer doesn't support streaming"))
+		return
+	}
+	db, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		apierror.Write(w, r, handlers.ErrNoConnPool)
+		return
+	}
+
+	// Subscribe before replaying, so no event is missed between the two.
+	sub := broker.Subscribe(session.AccountID, groupID)
+	defer sub.Close()
+
+	var replay []*events.Event
+	if lastID != "" {
+		found, err := events.NewStore(db).FindAfter(ctx, session.AccountID, groupID, afterID)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		replay = found
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	replayed := make(map[int64]bool, len(replay))
+	for _, event := range replay {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+		replayed[event.ID] = true
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if replayed[event.ID] {
+				continue
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes event as a message of an event stream.
+func writeEvent(w io.Writer, event *events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.IDString(), event.Type, data)
+	return err
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/tracing"
 	"github.com/pkg/errors"
 )
 
@@ -41,6 +42,11 @@ func (k *Key) Insert(ctx context.Context) error {
 		return ErrNoAccountID
 	}
 
+	material, err := SealMaterial(k.store.keyring, k.Material)
+	if err != nil {
+		return err
+	}
+
 	query := `
 INSERT INTO tsg_keys (name, fingerprint, material, account_id, archived, created_at, updated_at)
 VALUES ($1, $2, $3, $4, $5, NOW(), NOW());
@@ -54,10 +60,10 @@ VALUES ($1, $2, $3, $4, $5, NOW(), NOW());
 	}
 	defer tx.Rollback() // nolint: errcheck
 
-	_, err = pool.ExecEx(ctx, query, nil,
+	_, err = tracing.ExecEx(ctx, pool, query, nil,
 		k.Name,
 		k.Fingerprint,
-		k.Material,
+		material,
 		k.AccountID,
 		k.Archived,
 	)
@@ -87,6 +93,11 @@ func (k *Key) Save(ctx context.Context) error {
 		return ErrMissingID
 	}
 
+	material, err := SealMaterial(k.store.keyring, k.Material)
+	if err != nil {
+		return err
+	}
+
 	query := `
 UPDATE tsg_keys SET (name, fingerprint, material, archived, updated_at) = ($2, $3, $4, $5, $6)
 WHERE id = $1;
@@ -101,11 +112,11 @@ WHERE id = $1;
 	}
 	defer tx.Rollback() // nolint: errcheck
 
-	_, err = pool.ExecEx(ctx, query, nil,
+	_, err = tracing.ExecEx(ctx, pool, query, nil,
 		k.ID,
 		k.Name,
 		k.Fingerprint,
-		k.Material,
+		material,
 		k.Archived,
 		updatedAt,
 	)
@@ -141,7 +152,7 @@ WHERE (id = $1 OR name = $2) AND archived = false;
 		keyID = k.ID
 	}
 
-	err := k.store.pool.QueryRowEx(ctx, query, nil,
+	err := tracing.QueryRowEx(ctx, k.store.pool, query, nil,
 		keyID,
 		k.Name,
 	).Scan(&count)
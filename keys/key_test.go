@@ -23,7 +23,7 @@ func TestNew(t *testing.T) {
 	}
 	db.Clear(t)
 
-	store := keys.NewStore(db.Conn)
+	store := keys.NewStore(db.Conn, nil)
 	require.NotNil(t, store)
 
 	key := keys.New(store)
@@ -43,7 +43,7 @@ func TestInsert(t *testing.T) {
 	db.Clear(t)
 	defer db.Clear(t)
 
-	store := keys.NewStore(db.Conn)
+	store := keys.NewStore(db.Conn, nil)
 	require.NotNil(t, store)
 
 	key := keys.New(store)
@@ -83,7 +83,7 @@ func TestSave(t *testing.T) {
 	db.Clear(t)
 	defer db.Clear(t)
 
-	store := keys.NewStore(db.Conn)
+	store := keys.NewStore(db.Conn, nil)
 	require.NotNil(t, store)
 
 	key := keys.New(store)
@@ -129,7 +129,7 @@ func TestExists(t *testing.T) {
 	db.Clear(t)
 	defer db.Clear(t)
 
-	store := keys.NewStore(db.Conn)
+	store := keys.NewStore(db.Conn, nil)
 	require.NotNil(t, store)
 
 	accountID := "d255305d-aa60-49bc-acc2-3713cf0beb1c"
@@ -0,0 +1,117 @@
+package keys
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/pgtype"
+	"github.com/joyent/triton-service-groups/convert"
+	"github.com/joyent/triton-service-groups/envelope"
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/pkg/errors"
+)
+
+// SealMaterial encrypts private key material before it's stored. Material is
+// stored as is when no keyring has been configured.
+func SealMaterial(keyring *envelope.Keyring, material string) (string, error) {
+	if keyring == nil || material == "" {
+		return material, nil
+	}
+
+	sealed, err := keyring.Encrypt([]byte(material))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encrypt key material")
+	}
+
+	return sealed, nil
+}
+
+// OpenMaterial decrypts stored private key material. Material stored in plain
+// text, before encryption was configured, is returned as is.
+func OpenMaterial(keyring *envelope.Keyring, stored string) (string, error) {
+	if !envelope.IsEncrypted(stored) {
+		return stored, nil
+	}
+
+	material, err := keyring.Decrypt(stored)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decrypt key material")
+	}
+
+	return string(material), nil
+}
+
+// Migration counts the rows changed by MigrateMaterial.
+type Migration struct {
+	Encrypted int
+	Rewrapped int
+	Unchanged int
+}
+
+// MigrateMaterial encrypts the material of every key, including archived keys,
+// which is still stored in plain text and re-wraps material encrypted under a
+// previous key-encryption key with the current one. Nothing is written when
+// dryRun is true.
+func (s *Store) MigrateMaterial(ctx context.Context, dryRun bool) (*Migration, error) {
+	if s.keyring == nil {
+		return nil, envelope.ErrNoKey
+	}
+
+	query := `
+SELECT id, material
+FROM tsg_keys
+WHERE material IS NOT NULL AND material != '';
+`
+	rows, err := tracing.QueryEx(ctx, s.pool, query, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list keys")
+	}
+
+	stored := make(map[string]string)
+	for rows.Next() {
+		var (
+			id       pgtype.UUID
+			material string
+		)
+		if err := rows.Scan(&id, &material); err != nil {
+			rows.Close()
+			return nil, errors.Wrap(err, "failed to scan key")
+		}
+		stored[convert.BytesToUUID(id.Bytes)] = material
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to list keys")
+	}
+
+	migration := &Migration{}
+
+	for id, material := range stored {
+		var migrated string
+
+		switch {
+		case !envelope.IsEncrypted(material):
+			migrated, err = SealMaterial(s.keyring, material)
+			migration.Encrypted++
+		case !s.keyring.IsCurrent(material):
+			migrated, err = s.keyring.Rewrap(material)
+			migration.Rewrapped++
+		default:
+			migration.Unchanged++
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to migrate key %s", id)
+		}
+
+		if dryRun {
+			continue
+		}
+
+		update := `UPDATE tsg_keys SET material = $2 WHERE id = $1;`
+		if _, err := tracing.ExecEx(ctx, s.pool, update, nil, id, migrated); err != nil {
+			return nil, errors.Wrapf(err, "failed to update key %s", id)
+		}
+	}
+
+	return migration, nil
+}
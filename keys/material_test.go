@@ -0,0 +1,92 @@
+package keys_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/envelope"
+	"github.com/joyent/triton-service-groups/keys"
+	"github.com/joyent/triton-service-groups/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newKeyring(t *testing.T, fill byte) *envelope.Keyring {
+	keyring, err := envelope.NewKeyring(bytes.Repeat([]byte{fill}, envelope.KeySize))
+	require.NoError(t, err)
+	return keyring
+}
+
+func TestSealOpenMaterial(t *testing.T) {
+	keyring := newKeyring(t, 1)
+
+	sealed, err := keys.SealMaterial(keyring, "this is key material")
+	require.NoError(t, err)
+	assert.True(t, envelope.IsEncrypted(sealed))
+	assert.NotContains(t, sealed, "key material")
+
+	material, err := keys.OpenMaterial(keyring, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "this is key material", material)
+
+	material, err = keys.OpenMaterial(keyring, "this is key material")
+	require.NoError(t, err)
+	assert.Equal(t, "this is key material", material)
+
+	plain, err := keys.SealMaterial(nil, "this is key material")
+	require.NoError(t, err)
+	assert.Equal(t, "this is key material", plain)
+
+	_, err = keys.OpenMaterial(nil, sealed)
+	assert.Error(t, err)
+}
+
+func TestMigrateMaterial(t *testing.T) {
+	if os.Getenv("TSG_TEST") == "" {
+		t.Skip("Acceptance tests skipped unless env 'TSG_TEST=1' set")
+		return
+	}
+
+	db, err := testutils.NewTestDB()
+	if err != nil {
+		t.Error(err)
+	}
+	db.Clear(t)
+	defer db.Clear(t)
+
+	accountID := "d255305d-aa60-49bc-acc2-3713cf0beb1c"
+
+	plain := keys.New(keys.NewStore(db.Conn, nil))
+	plain.Name = "TSG_Management"
+	plain.Fingerprint = "12:23:34:45:56:67:78:89:90:0A:AB:BC:CD:DE:AD:01"
+	plain.Material = "this is key material"
+	plain.AccountID = accountID
+	require.NoError(t, plain.Insert(context.Background()))
+
+	old := newKeyring(t, 1)
+	sealed := keys.New(keys.NewStore(db.Conn, old))
+	sealed.Name = "TSG_Management_rotated"
+	sealed.Fingerprint = "12:23:34:45:56:67:78:89:90:0A:AB:BC:CD:DE:AD:02"
+	sealed.Material = "this is other key material"
+	sealed.AccountID = accountID
+	require.NoError(t, sealed.Insert(context.Background()))
+
+	keyring := newKeyring(t, 2)
+	require.NoError(t, keyring.AddPreviousKey(bytes.Repeat([]byte{1}, envelope.KeySize)))
+	store := keys.NewStore(db.Conn, keyring)
+
+	migration, err := store.MigrateMaterial(context.Background(), false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, migration.Encrypted)
+	assert.Equal(t, 1, migration.Rewrapped)
+
+	migration, err = store.MigrateMaterial(context.Background(), false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, migration.Unchanged)
+
+	key, err := keys.NewStore(db.Conn, newKeyring(t, 2)).FindByID(context.Background(), sealed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "this is other key material", key.Material)
+}
@@ -6,16 +6,22 @@ import (
 	"github.com/jackc/pgx"
 	"github.com/jackc/pgx/pgtype"
 	"github.com/joyent/triton-service-groups/convert"
+	"github.com/joyent/triton-service-groups/envelope"
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/pkg/errors"
 )
 
 type Store struct {
-	pool *pgx.ConnPool
+	pool    *pgx.ConnPool
+	keyring *envelope.Keyring
 }
 
-// NewStore returns a new store object.
-func NewStore(pool *pgx.ConnPool) *Store {
+// NewStore returns a new store object. Key material is encrypted with keyring
+// before being stored, or stored in plain text when keyring is nil.
+func NewStore(pool *pgx.ConnPool, keyring *envelope.Keyring) *Store {
 	return &Store{
-		pool: pool,
+		pool:    pool,
+		keyring: keyring,
 	}
 }
 
@@ -35,7 +41,7 @@ SELECT id, name, fingerprint, material, created_at, updated_at
 FROM tsg_keys
 WHERE id = $1 AND archived = false;
 `
-	err := s.pool.QueryRowEx(ctx, query, nil, keyID).Scan(
+	err := tracing.QueryRowEx(ctx, s.pool, query, nil, keyID).Scan(
 		&id,
 		&name,
 		&fingerprint,
@@ -47,6 +53,11 @@ WHERE id = $1 AND archived = false;
 		return nil, err
 	}
 
+	material, err = OpenMaterial(s.keyring, material)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open key %q", name)
+	}
+
 	key := New(s)
 	key.ID = convert.BytesToUUID(id.Bytes)
 	key.Name = name
@@ -74,7 +85,7 @@ SELECT id, name, fingerprint, material, created_at, updated_at
 FROM tsg_keys
 WHERE name = $1 AND account_id = $2 AND archived = false;
 `
-	err := s.pool.QueryRowEx(ctx, query, nil, keyName, accountID).Scan(
+	err := tracing.QueryRowEx(ctx, s.pool, query, nil, keyName, accountID).Scan(
 		&id,
 		&name,
 		&fingerprint,
@@ -86,6 +97,11 @@ WHERE name = $1 AND account_id = $2 AND archived = false;
 		return nil, err
 	}
 
+	material, err = OpenMaterial(s.keyring, material)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open key %q", name)
+	}
+
 	key := New(s)
 	key.ID = convert.BytesToUUID(id.Bytes)
 	key.AccountID = accountID
@@ -97,3 +113,18 @@ WHERE name = $1 AND account_id = $2 AND archived = false;
 
 	return key, nil
 }
+
+// ArchiveByAccount archives every key of an account, returning the number of
+// keys archived.
+func (s *Store) ArchiveByAccount(ctx context.Context, accountID string) (int64, error) {
+	query := `
+UPDATE tsg_keys SET (archived, updated_at) = (true, NOW())
+WHERE account_id = $1 AND archived = false;
+`
+	tag, err := tracing.ExecEx(ctx, s.pool, query, nil, accountID)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to archive account keys")
+	}
+
+	return tag.RowsAffected(), nil
+}
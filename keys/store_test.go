@@ -5,6 +5,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/jackc/pgx"
 	"github.com/joyent/triton-service-groups/keys"
 	"github.com/joyent/triton-service-groups/testutils"
 	"github.com/stretchr/testify/assert"
@@ -24,7 +25,7 @@ func TestFindByID(t *testing.T) {
 	db.Clear(t)
 	defer db.Clear(t)
 
-	store := keys.NewStore(db.Conn)
+	store := keys.NewStore(db.Conn, nil)
 	require.NotNil(t, store)
 
 	key := keys.New(store)
@@ -66,7 +67,7 @@ func TestFindByName(t *testing.T) {
 	db.Clear(t)
 	defer db.Clear(t)
 
-	store := keys.NewStore(db.Conn)
+	store := keys.NewStore(db.Conn, nil)
 	require.NotNil(t, store)
 
 	key := keys.New(store)
@@ -95,3 +96,35 @@ func TestFindByName(t *testing.T) {
 	assert.Equal(t, key.CreatedAt, found.CreatedAt)
 	assert.Equal(t, key.UpdatedAt, found.UpdatedAt)
 }
+
+func TestArchiveByAccount(t *testing.T) {
+	if os.Getenv("TSG_TEST") == "" {
+		t.Skip("Acceptance tests skipped unless env 'TSG_TEST=1' set")
+		return
+	}
+
+	db, err := testutils.NewTestDB()
+	if err != nil {
+		t.Error(err)
+	}
+	db.Clear(t)
+	defer db.Clear(t)
+
+	store := keys.NewStore(db.Conn, nil)
+
+	accountID := "d255305d-aa60-49bc-acc2-3713cf0beb1c"
+
+	key := keys.New(store)
+	key.Name = "TSG_Management"
+	key.Fingerprint = "12:23:34:45:56:67:78:89:90:0A:AB:BC:CD:DE:AD:01"
+	key.Material = "this is key material"
+	key.AccountID = accountID
+	require.NoError(t, key.Insert(context.Background()))
+
+	archived, err := store.ArchiveByAccount(context.Background(), accountID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), archived)
+
+	_, err = store.FindByID(context.Background(), key.ID)
+	assert.Equal(t, pgx.ErrNoRows, err)
+}
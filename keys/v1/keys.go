@@ -0,0 +1,103 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package keys_v1
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/joyent/triton-service-groups/accounts"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/handlers/auth"
+	"github.com/joyent/triton-service-groups/server/schema"
+	"github.com/rs/zerolog/log"
+)
+
+type rotateInput struct {
+	Type string `json:"type"`
+}
+
+// Rotate replaces the TSG management key of the session's account. The type of
+// the new key can be chosen within the request body, otherwise the configured
+// key type is used.
+func Rotate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+	cfg := session.Config()
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	input, err := decodeRotateBodyAndValidate(body)
+	if err != nil {
+		schema.WriteError(w, r, err)
+		return
+	}
+
+	keyType := cfg.KeyType
+	if input.Type != "" {
+		keyType = auth.KeyType(input.Type)
+	}
+
+	pool, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		apierror.Write(w, r, handlers.ErrNoConnPool)
+		return
+	}
+
+	acct, err := accounts.NewStore(pool).FindByID(ctx, session.AccountID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	rotation, err := RotateKey(ctx, acct, keyType, cfg)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	log.Info().
+		Str("actor", session.Actor()).
+		Str("fingerprint", rotation.Fingerprint).
+		Msg("keys: rotated management key")
+
+	bytes, err := json.Marshal(rotation)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+func decodeRotateBodyAndValidate(body []byte) (*rotateInput, error) {
+	input := &rotateInput{}
+	if len(body) == 0 {
+		return input, nil
+	}
+
+	if err := schema.Decode(schema.KeyRotation, body, input); err != nil {
+		return nil, err
+	}
+
+	return input, nil
+}
+
+func writeJSONResponse(w http.ResponseWriter, bytes []byte, statusCode int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if n, err := w.Write(bytes); err != nil {
+		log.Printf("%v", err)
+	} else if n != len(bytes) {
+		log.Printf("short write: %d/%d", n, len(bytes))
+	}
+}
@@ -0,0 +1,70 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package keys_v1
+
+import (
+	"context"
+
+	"github.com/joyent/triton-service-groups/accounts"
+	"github.com/joyent/triton-service-groups/keys"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/handlers/auth"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// RemoveKey removes the TSG management key of acct from Triton and archives
+// every key of the account within the database. The key removes itself, so no
+// request signed by the account owner is needed. Returns the removed key.
+func RemoveKey(ctx context.Context, acct *accounts.Account, cfg auth.Config) (*keys.Key, error) {
+	pool, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		return nil, handlers.ErrNoConnPool
+	}
+
+	if acct.KeyID == "" {
+		return nil, auth.ErrNoAccountKey
+	}
+
+	keyring, _ := handlers.GetKeyring(ctx)
+	store := keys.NewStore(pool, keyring)
+
+	key, err := store.FindByID(ctx, acct.KeyID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find current key")
+	}
+
+	keypair, err := auth.DecodeKeyPair(key.Material)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode current key")
+	}
+
+	client, err := newKeysClient(acct, keypair, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := deleteTritonKey(ctx, client, key.Name); err != nil {
+		return nil, err
+	}
+
+	key.Archived = true
+	if err := key.Save(ctx); err != nil {
+		return nil, errors.Wrap(err, "failed to archive key")
+	}
+
+	if _, err := store.ArchiveByAccount(ctx, acct.ID); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("account_name", acct.AccountName).
+		Str("key_name", key.Name).
+		Str("fingerprint", key.Fingerprint).
+		Msg("keys: removed management key")
+
+	return key, nil
+}
@@ -0,0 +1,274 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package keys_v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	triton "github.com/joyent/triton-go"
+	"github.com/joyent/triton-go/account"
+	"github.com/joyent/triton-go/authentication"
+	terrors "github.com/joyent/triton-go/errors"
+	"github.com/joyent/triton-service-groups/accounts"
+	"github.com/joyent/triton-service-groups/groups"
+	"github.com/joyent/triton-service-groups/keys"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/handlers/auth"
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// releaseTimeout bounds how long releasing a rotation claim can take.
+const releaseTimeout = 10 * time.Second
+
+// ErrRotationInProgress is returned while another rotation of the account's
+// management key is in progress.
+var ErrRotationInProgress = apierror.Conflict("a management key rotation is already in progress")
+
+// Rotation describes the outcome of rotating an account's TSG management key.
+type Rotation struct {
+	Name                string    `json:"name"`
+	Type                string    `json:"type"`
+	Fingerprint         string    `json:"fingerprint"`
+	PreviousFingerprint string    `json:"previous_fingerprint"`
+	Groups              int       `json:"groups"`
+	RotatedAt           time.Time `json:"rotated_at"`
+}
+
+// RotateKey replaces the TSG management key of acct with a newly generated key
+// of keyType. The new key is uploaded to Triton and every orchestrator job of
+// the account is re-rendered with it before the account switches to it. Only
+// then is the previous key removed from Triton and archived. Should anything
+// fail before the switch, the account keeps its previous key and the new key is
+// removed again.
+//
+// Rotations of an account are serialised by claiming it for the duration of
+// the rotation, which is rejected with ErrRotationInProgress while another
+// rotation holds the claim. CloudAPI is called with the management key itself, so rotation
+// doesn't need a request signed by the account owner. ctx must carry the
+// handler context and a session for acct, which is used to re-render the jobs.
+func RotateKey(ctx context.Context, acct *accounts.Account, keyType auth.KeyType, cfg auth.Config) (*Rotation, error) {
+	pool, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		return nil, handlers.ErrNoConnPool
+	}
+
+	claimed, err := acct.ClaimRotation(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrRotationInProgress
+	}
+	defer releaseRotation(ctx, acct)
+
+	if acct.KeyID == "" {
+		return nil, auth.ErrNoAccountKey
+	}
+
+	keyring, _ := handlers.GetKeyring(ctx)
+	store := keys.NewStore(pool, keyring)
+
+	oldKey, err := store.FindByID(ctx, acct.KeyID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find current key")
+	}
+
+	oldPair, err := auth.DecodeKeyPair(oldKey.Material)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode current key")
+	}
+
+	newPair, err := auth.GenerateKeyPair(keyType)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate new keypair")
+	}
+
+	rotatedAt := time.Now().UTC()
+	keyName := fmt.Sprintf("%s_%s_%d", cfg.KeyNamePrefix, cfg.Datacenter, rotatedAt.Unix())
+
+	oldClient, err := newKeysClient(acct, oldPair, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	_, span := tracing.StartClient(ctx, "cloudapi", "CreateKey")
+	_, err = oldClient.Create(ctx, &account.CreateKeyInput{
+		Name: keyName,
+		Key:  newPair.PublicKeyBase64(),
+	})
+	tracing.Finish(span, err)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create new account key")
+	}
+
+	newKey := keys.New(store)
+	newKey.Name = keyName
+	newKey.Fingerprint = newPair.FingerprintMD5
+	newKey.Material = newPair.PrivateKeyPEM()
+	newKey.AccountID = acct.ID
+
+	if err := newKey.Insert(ctx); err != nil {
+		deleteTritonKey(ctx, oldClient, keyName) // nolint: errcheck
+		return nil, errors.Wrap(err, "failed to store new key")
+	}
+
+	rendered, err := rerenderJobs(groups_v1.WithKeyID(ctx, newKey.ID), acct)
+	if err != nil {
+		discardKey(ctx, acct, oldClient, newKey)
+		return nil, errors.Wrap(err, "failed to re-render orchestrator jobs, previous key kept")
+	}
+
+	if err := acct.SaveKeyID(ctx, newKey.ID); err != nil {
+		discardKey(ctx, acct, oldClient, newKey)
+		return nil, errors.Wrap(err, "failed to store account key_id, previous key kept")
+	}
+	acct.KeyID = newKey.ID
+
+	log.Info().
+		Str("account_name", acct.AccountName).
+		Str("fingerprint", newKey.Fingerprint).
+		Str("key_type", string(newPair.Type())).
+		Msg("keys: switched account to new management key")
+
+	newClient, err := newKeysClient(acct, newPair, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := deleteTritonKey(ctx, newClient, oldKey.Name); err != nil {
+		return nil, err
+	}
+
+	oldKey.Archived = true
+	if err := oldKey.Save(ctx); err != nil {
+		return nil, errors.Wrap(err, "failed to archive previous key")
+	}
+
+	log.Info().
+		Str("account_name", acct.AccountName).
+		Str("fingerprint", oldKey.Fingerprint).
+		Msg("keys: archived previous management key")
+
+	return &Rotation{
+		Name:                keyName,
+		Type:                string(newPair.Type()),
+		Fingerprint:         newKey.Fingerprint,
+		PreviousFingerprint: oldKey.Fingerprint,
+		Groups:              rendered,
+		RotatedAt:           rotatedAt,
+	}, nil
+}
+
+// releaseRotation releases the rotation claim of acct. The claim is released
+// even if ctx has been cancelled, so the account can be rotated again without
+// waiting for the claim to expire.
+func releaseRotation(ctx context.Context, acct *accounts.Account) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancel()
+
+	if err := acct.ReleaseRotation(ctx); err != nil {
+		log.Error().
+			Str("account_name", acct.AccountName).
+			Err(err).
+			Msg("keys: failed to release rotation claim")
+	}
+}
+
+// discardKey backs out a rotation which failed before acct switched to newKey.
+// Jobs are re-rendered with the account's current key before newKey is removed
+// from Triton and archived. Should any job fail to re-render, newKey is kept so
+// it can still reach Triton. Rotating again will replace both keys.
+func discardKey(ctx context.Context, acct *accounts.Account, client *account.KeysClient, newKey *keys.Key) {
+	if _, err := rerenderJobs(ctx, acct); err != nil {
+		log.Error().
+			Str("account_name", acct.AccountName).
+			Str("fingerprint", newKey.Fingerprint).
+			Err(err).
+			Msg("keys: failed to restore orchestrator jobs, new key kept")
+		return
+	}
+
+	if err := deleteTritonKey(ctx, client, newKey.Name); err != nil {
+		return
+	}
+
+	newKey.Archived = true
+	if err := newKey.Save(ctx); err != nil {
+		log.Error().
+			Str("account_name", acct.AccountName).
+			Str("fingerprint", newKey.Fingerprint).
+			Err(err).
+			Msg("keys: failed to archive discarded key")
+	}
+}
+
+// newKeysClient constructs a CloudAPI keys client signing requests with the
+// management key of acct.
+func newKeysClient(acct *accounts.Account, keypair *auth.KeyPair, cfg auth.Config) (*account.KeysClient, error) {
+	config := &triton.ClientConfig{
+		TritonURL:   cfg.AuthURL,
+		AccountName: acct.AccountName,
+		Signers:     []authentication.Signer{auth.NewKeySigner(keypair, acct.AccountName)},
+	}
+
+	a, err := account.NewClient(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create account key client")
+	}
+
+	return a.Keys(), nil
+}
+
+// deleteTritonKey removes a key from Triton. Keys which have already been
+// removed are ignored.
+func deleteTritonKey(ctx context.Context, client *account.KeysClient, keyName string) error {
+	_, span := tracing.StartClient(ctx, "cloudapi", "DeleteKey")
+	err := client.Delete(ctx, &account.DeleteKeyInput{
+		KeyName: keyName,
+	})
+	tracing.Finish(span, err)
+	if err != nil && !terrors.IsSpecificStatusCode(err, http.StatusNotFound) {
+		err = errors.Wrap(err, "failed to delete account key")
+		log.Error().Str("key_name", keyName).Err(err).Msg("keys: failed to delete account key")
+		return err
+	}
+
+	return nil
+}
+
+// rerenderJobs re-renders the orchestrator job of every group of acct,
+// returning the number of jobs updated.
+func rerenderJobs(ctx context.Context, acct *accounts.Account) (int, error) {
+	list, err := groups_v1.FindGroups(ctx, acct.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	var failed []string
+	for _, group := range list {
+		if err := groups_v1.UpdateOrchestratorJob(ctx, group); err != nil {
+			log.Error().
+				Str("account_name", acct.AccountName).
+				Str("group_name", group.GroupName).
+				Err(err).
+				Msg("keys: failed to re-render orchestrator job")
+			failed = append(failed, group.GroupName)
+		}
+	}
+
+	if len(failed) > 0 {
+		return len(list) - len(failed), fmt.Errorf("failed to update groups: %s", strings.Join(failed, ", "))
+	}
+
+	return len(list), nil
+}
@@ -0,0 +1,96 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package metrics defines the Prometheus metrics of TSG and serves them from
+// a registry separate from the default one of client_golang.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+// scrapeTimeout bounds how long collecting a GaugeFunc can take.
+const scrapeTimeout = 5 * time.Second
+
+// NewRegistry constructs a registry of the API metrics and collectors.
+func NewRegistry(collectors ...prometheus.Collector) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		HTTPRequests,
+		HTTPRequestDuration,
+		AuthRequests,
+		OrchestratorOperations,
+	)
+	registry.MustRegister(collectors...)
+	return registry
+}
+
+// errorLogger logs the metrics which fail to be collected.
+type errorLogger struct{}
+
+func (errorLogger) Println(v ...interface{}) {
+	log.Warn().Msg(fmt.Sprint(v...))
+}
+
+// Handler serves every metric of registry. A metric which fails to be
+// collected is logged and skipped so the remaining metrics are still
+// exposed.
+func Handler(registry *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		ErrorLog:      errorLogger{},
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// Sample is a single value of a gauge, with one value for each label of the
+// gauge.
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+// GaugeFunc is a family of gauges whose samples are collected whenever the
+// metrics are scraped, such as the desired capacity of every group.
+type GaugeFunc struct {
+	desc    *prometheus.Desc
+	collect func(ctx context.Context) ([]Sample, error)
+}
+
+// NewGaugeFunc constructs a gauge family named name, partitioned by the
+// labels named within labels, whose samples are returned by collect.
+func NewGaugeFunc(name, help string, collect func(ctx context.Context) ([]Sample, error), labels ...string) *GaugeFunc {
+	return &GaugeFunc{
+		desc:    prometheus.NewDesc(name, help, labels, nil),
+		collect: collect,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (g *GaugeFunc) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.desc
+}
+
+// Collect implements prometheus.Collector.
+func (g *GaugeFunc) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+
+	samples, err := g.collect(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(g.desc, err)
+		return
+	}
+
+	for _, sample := range samples {
+		ch <- prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, sample.Value, sample.LabelValues...)
+	}
+}
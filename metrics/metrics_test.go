@@ -0,0 +1,48 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, collectors ...prometheus.Collector) string {
+	rec := httptest.NewRecorder()
+	handler := metrics.Handler(metrics.NewRegistry(collectors...))
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+func TestGaugeFunc(t *testing.T) {
+	gauge := metrics.NewGaugeFunc("test_capacity", "Test gauge.",
+		func(ctx context.Context) ([]metrics.Sample, error) {
+			return []metrics.Sample{
+				{LabelValues: []string{`say "hi"\` + "\n"}, Value: 2},
+			}, nil
+		}, "name")
+
+	assert.Contains(t, scrape(t, gauge), `# HELP test_capacity Test gauge.
+# TYPE test_capacity gauge
+test_capacity{name="say \"hi\"\\\n"} 2
+`)
+}
+
+func TestHandlerSkipsFailedMetrics(t *testing.T) {
+	failing := metrics.NewGaugeFunc("test_failing", "Failing gauge.",
+		func(ctx context.Context) ([]metrics.Sample, error) {
+			return nil, errors.New("database unavailable")
+		})
+	metrics.AuthRequests.WithLabelValues("bearer", metrics.OutcomeSuccess).Inc()
+
+	body := scrape(t, failing)
+	assert.NotContains(t, body, "test_failing")
+	assert.Contains(t, body, `tsg_auth_requests_total{outcome="success",scheme="bearer"}`)
+}
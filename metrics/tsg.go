@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/jackc/pgx"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Outcomes recorded by the counters below.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+var (
+	// HTTPRequests counts API requests by route name, method and status code.
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tsg_http_requests_total",
+		Help: "API requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	// HTTPRequestDuration records the latency of API requests by route name,
+	// method and status code.
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tsg_http_request_duration_seconds",
+		Help:    "Latency of API requests by route, method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// AuthRequests counts authentication attempts by credential scheme and
+	// outcome.
+	AuthRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tsg_auth_requests_total",
+		Help: "Authentication attempts by credential scheme and outcome.",
+	}, []string{"scheme", "outcome"})
+
+	// OrchestratorOperations counts the calls made to the Nomad jobs API by
+	// operation and outcome.
+	OrchestratorOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tsg_orchestrator_operations_total",
+		Help: "Nomad job operations by operation and outcome.",
+	}, []string{"operation", "outcome"})
+)
+
+// Outcome returns the outcome label of an operation which returned err.
+func Outcome(err error) string {
+	if err != nil {
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
+
+// NewPoolStats constructs a gauge of the connections of pool, by state.
+func NewPoolStats(pool *pgx.ConnPool) *GaugeFunc {
+	return NewGaugeFunc("tsg_db_pool_connections",
+		"Database connections of the pool by state.",
+		func(ctx context.Context) ([]Sample, error) {
+			stat := pool.Stat()
+			return []Sample{
+				{LabelValues: []string{"max"}, Value: float64(stat.MaxConnections)},
+				{LabelValues: []string{"open"}, Value: float64(stat.CurrentConnections)},
+				{LabelValues: []string{"idle"}, Value: float64(stat.AvailableConnections)},
+				{LabelValues: []string{"in_use"}, Value: float64(stat.CurrentConnections - stat.AvailableConnections)},
+			}, nil
+		}, "state")
+}
@@ -0,0 +1,190 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package operations
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/pgtype"
+	"github.com/joyent/triton-service-groups/convert"
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/pkg/errors"
+)
+
+// The types of operation.
+const (
+	TypeCreateGroup = "create_group"
+	TypeUpdateGroup = "update_group"
+	TypeScaleGroup  = "scale_group"
+	TypeDeleteGroup = "delete_group"
+)
+
+// The states of an operation. Operations start running and end in one of the
+// other states, after which they never change.
+const (
+	StateRunning   = "running"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+var (
+	ErrNoAccountID = errors.New("missing account identifer for insert")
+
+	// ErrNotRunning is returned when changing an operation which has already
+	// finished, or been cancelled.
+	ErrNotRunning = errors.New("operation is no longer running")
+)
+
+// Operation represents the data associated with a tsg_operations row. An
+// operation tracks a long-running action, such as scaling a group, from the
+// request which started it until it succeeds, fails or is cancelled.
+type Operation struct {
+	ID        string `json:"id"`
+	AccountID string `json:"-"`
+	GroupID   string `json:"group_id,omitempty"`
+	Type      string `json:"type"`
+	State     string `json:"state"`
+
+	// Progress describes the current step of a running operation, such as
+	// the number of instances running.
+	Progress string `json:"progress,omitempty"`
+
+	// Error describes why a failed operation failed.
+	Error      string     `json:"error,omitempty"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	store *Store
+}
+
+// New constructs a new Operation with the Store for backend persistence.
+func New(store *Store) *Operation {
+	return &Operation{
+		store: store,
+	}
+}
+
+// Path returns the path of the operation within the API.
+func (o *Operation) Path() string {
+	return "/v1/tsg/operations/" + o.ID
+}
+
+// Finished returns true once the operation is no longer running.
+func (o *Operation) Finished() bool {
+	return o.State != StateRunning
+}
+
+// Insert inserts the operation into the tsg_operations table, running.
+func (o *Operation) Insert(ctx context.Context) error {
+	if o.AccountID == "" {
+		return ErrNoAccountID
+	}
+
+	var groupID interface{}
+	if o.GroupID != "" {
+		groupID = o.GroupID
+	}
+
+	query := `
+INSERT INTO tsg_operations (account_id, group_id, type, state, progress, created_by, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+RETURNING id, created_at;
+`
+	var (
+		id        pgtype.UUID
+		createdAt pgtype.Timestamptz
+	)
+
+	err := tracing.QueryRowEx(ctx, o.store.pool, query, nil,
+		o.AccountID,
+		groupID,
+		o.Type,
+		StateRunning,
+		o.Progress,
+		o.CreatedBy,
+	).Scan(&id, &createdAt)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert operation")
+	}
+
+	o.ID = convert.BytesToUUID(id.Bytes)
+	o.State = StateRunning
+	o.CreatedAt = createdAt.Time
+	o.UpdatedAt = createdAt.Time
+
+	return nil
+}
+
+// SetProgress records the current step of a running operation.
+func (o *Operation) SetProgress(ctx context.Context, progress string) error {
+	query := `
+UPDATE tsg_operations SET progress = $2, updated_at = NOW()
+WHERE id = $1 AND state = $3
+RETURNING updated_at;
+`
+	if err := o.update(ctx, query, progress, StateRunning); err != nil {
+		return err
+	}
+
+	o.Progress = progress
+	return nil
+}
+
+// Finish records that the operation succeeded, or failed with err.
+func (o *Operation) Finish(ctx context.Context, err error) error {
+	state, message := StateSucceeded, ""
+	if err != nil {
+		state, message = StateFailed, err.Error()
+	}
+
+	return o.finish(ctx, state, message)
+}
+
+// Cancel records that the operation was cancelled. The agent running it stops
+// once it notices.
+func (o *Operation) Cancel(ctx context.Context) error {
+	return o.finish(ctx, StateCancelled, "")
+}
+
+func (o *Operation) finish(ctx context.Context, state, message string) error {
+	query := `
+UPDATE tsg_operations SET state = $2, error = $3, updated_at = NOW(), finished_at = NOW()
+WHERE id = $1 AND state = $4
+RETURNING updated_at;
+`
+	if err := o.update(ctx, query, state, message, StateRunning); err != nil {
+		return err
+	}
+
+	o.State = state
+	o.Error = message
+	finishedAt := o.UpdatedAt
+	o.FinishedAt = &finishedAt
+	return nil
+}
+
+// update runs a query updating the operation while it's running, which
+// returns the time it was updated.
+func (o *Operation) update(ctx context.Context, query string, args ...interface{}) error {
+	var updatedAt pgtype.Timestamptz
+
+	args = append([]interface{}{o.ID}, args...)
+	err := tracing.QueryRowEx(ctx, o.store.pool, query, nil, args...).Scan(&updatedAt)
+	switch {
+	case err == pgx.ErrNoRows:
+		return ErrNotRunning
+	case err != nil:
+		return errors.Wrap(err, "failed to update operation")
+	}
+
+	o.UpdatedAt = updatedAt.Time
+	return nil
+}
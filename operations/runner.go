@@ -0,0 +1,238 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package operations
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// HeartbeatInterval is how often a running operation is marked as alive,
+	// and checked for cancellation.
+	HeartbeatInterval = 5 * time.Second
+
+	// abandonedAfter is how long a running operation can go without a
+	// heartbeat before it's failed as abandoned.
+	abandonedAfter = 2 * time.Minute
+
+	reapInterval  = time.Minute
+	updateTimeout = 10 * time.Second
+)
+
+var (
+	// ErrInterrupted fails the operations still running when an agent shuts
+	// down.
+	ErrInterrupted = errors.New("interrupted by the agent shutting down")
+
+	// ErrAbandoned fails the operations of an agent which stopped without
+	// finishing them.
+	ErrAbandoned = errors.New("abandoned by the agent running it")
+)
+
+// Runner runs operations in the background of the agent, detached from the
+// requests which started them.
+type Runner struct {
+	store *Store
+
+	mu       sync.Mutex
+	cancels  map[string]context.CancelFunc
+	stopping bool
+	wg       sync.WaitGroup
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// NewRunner constructs a runner failing the abandoned operations within store.
+func NewRunner(store *Store) *Runner {
+	return &Runner{
+		store:   store,
+		cancels: make(map[string]context.CancelFunc),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Start periodically fails abandoned operations until Stop is called.
+func (r *Runner) Start() {
+	go func() {
+		defer close(r.stopped)
+
+		ticker := time.NewTicker(reapInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.done:
+				return
+			case <-ticker.C:
+				r.reap()
+			}
+		}
+	}()
+}
+
+func (r *Runner) reap() {
+	ctx, cancel := context.WithTimeout(context.Background(), updateTimeout)
+	defer cancel()
+
+	n, err := r.store.FailAbandoned(ctx, time.Now().Add(-abandonedAfter))
+	if err != nil {
+		log.Warn().Err(err).Msg("operations: failed to fail abandoned operations")
+		return
+	}
+	if n > 0 {
+		log.Info().Int64("operations", n).Msg("operations: failed abandoned operations")
+	}
+}
+
+// Stop interrupts every running operation, waiting for them to be recorded
+// as failed.
+func (r *Runner) Stop() {
+	r.mu.Lock()
+	if r.stopping {
+		r.mu.Unlock()
+		return
+	}
+	r.stopping = true
+	for _, cancel := range r.cancels {
+		cancel()
+	}
+	r.mu.Unlock()
+
+	r.wg.Wait()
+
+	close(r.done)
+	<-r.stopped
+}
+
+// Run runs fn in the background until it returns, timeout expires or the
+// operation is cancelled, then records the outcome of op. ctx carries the
+// values fn requires, such as the session, but cancelling it doesn't stop fn.
+func (r *Runner) Run(ctx context.Context, op *Operation, timeout time.Duration, fn func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+
+	r.mu.Lock()
+	if r.stopping {
+		r.mu.Unlock()
+		cancel()
+		r.finish(ctx, op, ErrInterrupted)
+		return
+	}
+	r.cancels[op.ID] = cancel
+	r.wg.Add(1)
+	r.mu.Unlock()
+
+	go func() {
+		defer r.wg.Done()
+		defer r.remove(op.ID)
+
+		go r.heartbeat(ctx, op.ID, cancel)
+
+		err := fn(ctx)
+		switch {
+		case err == nil:
+		case r.isStopping():
+			err = ErrInterrupted
+		case err == context.DeadlineExceeded, ctx.Err() == context.DeadlineExceeded:
+			err = fmt.Errorf("timed out after %v", timeout)
+		}
+
+		r.finish(ctx, op, err)
+	}()
+}
+
+// Cancel stops running the operation identified by id, if it's run by this
+// agent. It must already have been cancelled with (*Operation).Cancel, which
+// other agents notice with their next heartbeat.
+func (r *Runner) Cancel(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cancel, ok := r.cancels[id]; ok {
+		cancel()
+	}
+}
+
+func (r *Runner) isStopping() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stopping
+}
+
+func (r *Runner) remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cancel, ok := r.cancels[id]; ok {
+		cancel()
+		delete(r.cancels, id)
+	}
+}
+
+// heartbeat marks the operation identified by id as alive until ctx is done,
+// cancelling it once it's no longer running.
+func (r *Runner) heartbeat(ctx context.Context, id string, cancel context.CancelFunc) {
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := r.store.heartbeat(ctx, id)
+			switch {
+			case err == ErrNotRunning:
+				cancel()
+				return
+			case err != nil && ctx.Err() == nil:
+				log.Warn().Err(err).Str("operation_id", id).
+					Msg("operations: failed to record heartbeat")
+			}
+		}
+	}
+}
+
+// finish records the outcome of op. Operations cancelled while running have
+// already finished, so aren't changed.
+func (r *Runner) finish(ctx context.Context, op *Operation, err error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), updateTimeout)
+	defer cancel()
+
+	if err := op.Finish(ctx, err); err != nil && err != ErrNotRunning {
+		log.Error().Err(err).Str("operation_id", op.ID).
+			Msg("operations: failed to record outcome")
+	}
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying runner.
+func NewContext(ctx context.Context, runner *Runner) context.Context {
+	return context.WithValue(ctx, contextKey{}, runner)
+}
+
+// FromContext returns the runner carried by ctx, if any.
+func FromContext(ctx context.Context) (*Runner, bool) {
+	runner, ok := ctx.Value(contextKey{}).(*Runner)
+	return runner, ok && runner != nil
+}
+
+// Handler wraps an HTTP handler, attaching runner to the context of every
+// request.
+func Handler(runner *Runner, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		handler.ServeHTTP(w, req.WithContext(NewContext(req.Context(), runner)))
+	})
+}
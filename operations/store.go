@@ -0,0 +1,163 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package operations records long-running actions, such as scaling a group,
+// so callers can follow their progress and cancel them. Operations are kept
+// within the database, so any agent can report on or cancel an operation run
+// by another.
+package operations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/pgtype"
+	"github.com/joyent/triton-service-groups/convert"
+	"github.com/joyent/triton-service-groups/tracing"
+)
+
+type Store struct {
+	pool *pgx.ConnPool
+}
+
+// NewStore returns a new store object.
+func NewStore(pool *pgx.ConnPool) *Store {
+	return &Store{
+		pool: pool,
+	}
+}
+
+const selectOperations = `
+SELECT id, account_id, group_id, type, state, progress, error, created_by,
+       created_at, updated_at, finished_at
+FROM tsg_operations
+`
+
+// scanner is implemented by both *pgx.Row and *pgx.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (s *Store) scanOperation(row scanner) (*Operation, error) {
+	var (
+		id         pgtype.UUID
+		accountID  pgtype.UUID
+		groupID    pgtype.UUID
+		opType     string
+		state      string
+		progress   string
+		message    string
+		createdBy  string
+		createdAt  pgtype.Timestamptz
+		updatedAt  pgtype.Timestamptz
+		finishedAt pgtype.Timestamptz
+	)
+
+	err := row.Scan(
+		&id,
+		&accountID,
+		&groupID,
+		&opType,
+		&state,
+		&progress,
+		&message,
+		&createdBy,
+		&createdAt,
+		&updatedAt,
+		&finishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	op := New(s)
+	op.ID = convert.BytesToUUID(id.Bytes)
+	op.AccountID = convert.BytesToUUID(accountID.Bytes)
+	if groupID.Status == pgtype.Present {
+		op.GroupID = convert.BytesToUUID(groupID.Bytes)
+	}
+	op.Type = opType
+	op.State = state
+	op.Progress = progress
+	op.Error = message
+	op.CreatedBy = createdBy
+	op.CreatedAt = createdAt.Time
+	op.UpdatedAt = updatedAt.Time
+	if finishedAt.Status == pgtype.Present {
+		op.FinishedAt = &finishedAt.Time
+	}
+
+	return op, nil
+}
+
+// FindByID finds an operation of an account by its identifier.
+func (s *Store) FindByID(ctx context.Context, id, accountID string) (*Operation, error) {
+	query := selectOperations + `WHERE id = $1 AND account_id = $2;`
+
+	row := tracing.QueryRowEx(ctx, s.pool, query, nil, id, accountID)
+	return s.scanOperation(row)
+}
+
+// FindAll finds the most recent operations of an account, newest first. An
+// empty groupID finds the operations of every group.
+func (s *Store) FindAll(ctx context.Context, accountID, groupID string, limit int) ([]*Operation, error) {
+	args := []interface{}{accountID}
+	query := selectOperations + `WHERE account_id = $1`
+	if groupID != "" {
+		args = append(args, groupID)
+		query += ` AND group_id = $2`
+	}
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT %d;`, limit)
+
+	rows, err := tracing.QueryEx(ctx, s.pool, query, nil, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ops []*Operation
+	for rows.Next() {
+		op, err := s.scanOperation(rows)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+
+	return ops, rows.Err()
+}
+
+// heartbeat records that the operation identified by id is still being run by
+// this agent, returning ErrNotRunning once it has been cancelled.
+func (s *Store) heartbeat(ctx context.Context, id string) error {
+	query := `UPDATE tsg_operations SET updated_at = NOW() WHERE id = $1 AND state = $2;`
+
+	tag, err := tracing.ExecEx(ctx, s.pool, query, nil, id, StateRunning)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotRunning
+	}
+	return nil
+}
+
+// FailAbandoned fails the running operations which haven't been updated since
+// before, such as those of an agent which crashed.
+func (s *Store) FailAbandoned(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+UPDATE tsg_operations
+SET state = $1, error = $2, updated_at = NOW(), finished_at = NOW()
+WHERE state = $3 AND updated_at < $4;
+`
+	tag, err := tracing.ExecEx(ctx, s.pool, query, nil,
+		StateFailed, ErrAbandoned.Error(), StateRunning, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
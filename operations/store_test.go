@@ -0,0 +1,148 @@
+package operations_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joyent/triton-service-groups/operations"
+	"github.com/joyent/triton-service-groups/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	accountID = "6f873d02-172c-418f-8416-4da2b50d5c53"
+	groupID   = "722d25ed-f32a-4944-9861-8990e204850e"
+)
+
+func newTestStore(t *testing.T) *operations.Store {
+	if os.Getenv("TSG_TEST") == "" {
+		t.Skip("Acceptance tests skipped unless env 'TSG_TEST=1' set")
+	}
+
+	db, err := testutils.NewTestDB()
+	if err != nil {
+		t.Error(err)
+	}
+	db.Clear(t)
+	t.Cleanup(func() { db.Clear(t) })
+
+	return operations.NewStore(db.Conn)
+}
+
+func insert(t *testing.T, store *operations.Store) *operations.Operation {
+	op := operations.New(store)
+	op.AccountID = accountID
+	op.GroupID = groupID
+	op.Type = operations.TypeScaleGroup
+	op.CreatedBy = "testaccount"
+	require.NoError(t, op.Insert(context.Background()))
+	return op
+}
+
+func TestOperationLifecycle(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	op := insert(t, store)
+	assert.NotEmpty(t, op.ID)
+	assert.Equal(t, operations.StateRunning, op.State)
+
+	require.NoError(t, op.SetProgress(ctx, "1 of 2 instances running"))
+	require.NoError(t, op.Finish(ctx, errors.New("timed out")))
+	assert.Equal(t, operations.ErrNotRunning, op.Cancel(ctx), "finished operations can't be cancelled")
+
+	found, err := store.FindByID(ctx, op.ID, accountID)
+	require.NoError(t, err)
+	assert.Equal(t, operations.StateFailed, found.State)
+	assert.Equal(t, "1 of 2 instances running", found.Progress)
+	assert.Equal(t, "timed out", found.Error)
+	assert.Equal(t, groupID, found.GroupID)
+	require.NotNil(t, found.FinishedAt)
+
+	_, err = store.FindByID(ctx, op.ID, "00000000-0000-0000-0000-000000000000")
+	assert.Error(t, err, "operations of other accounts aren't found")
+}
+
+func TestFindAll(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	first := insert(t, store)
+	second := insert(t, store)
+
+	ops, err := store.FindAll(ctx, accountID, "", 10)
+	require.NoError(t, err)
+	require.Len(t, ops, 2)
+	assert.Equal(t, second.ID, ops[0].ID, "newest first")
+	assert.Equal(t, first.ID, ops[1].ID)
+
+	ops, err = store.FindAll(ctx, accountID, "ad74301e-ad62-404a-be44-3b2f24d082ac", 10)
+	require.NoError(t, err)
+	assert.Empty(t, ops)
+}
+
+func TestRunnerRecordsOutcome(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	runner := operations.NewRunner(store)
+	runner.Start()
+
+	succeeded := insert(t, store)
+	runner.Run(ctx, succeeded, time.Minute, func(context.Context) error {
+		return nil
+	})
+
+	cancelled := insert(t, store)
+	started := make(chan struct{})
+	runner.Run(ctx, cancelled, time.Minute, func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+	require.NoError(t, cancelled.Cancel(ctx))
+	runner.Cancel(cancelled.ID)
+
+	interrupted := insert(t, store)
+	runner.Run(ctx, interrupted, time.Minute, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	runner.Stop()
+
+	for op, state := range map[*operations.Operation]string{
+		succeeded:   operations.StateSucceeded,
+		cancelled:   operations.StateCancelled,
+		interrupted: operations.StateFailed,
+	} {
+		found, err := store.FindByID(ctx, op.ID, accountID)
+		require.NoError(t, err)
+		assert.Equal(t, state, found.State)
+	}
+}
+
+func TestFailAbandoned(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	op := insert(t, store)
+
+	n, err := store.FailAbandoned(ctx, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Zero(t, n, "recently updated operations aren't abandoned")
+
+	n, err = store.FailAbandoned(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+
+	found, err := store.FindByID(ctx, op.ID, accountID)
+	require.NoError(t, err)
+	assert.Equal(t, operations.StateFailed, found.State)
+	assert.Equal(t, operations.ErrAbandoned.Error(), found.Error)
+}
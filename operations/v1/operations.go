@@ -0,0 +1,237 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package operations_v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/groups"
+	"github.com/joyent/triton-service-groups/operations"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultLimit is the number of operations listed unless a limit is
+	// given.
+	DefaultLimit = 100
+
+	// MaxLimit is the largest number of operations which can be listed at
+	// once.
+	MaxLimit = 1000
+)
+
+var errOperationNotFound = apierror.NotFound("operation not found")
+
+func getStore(ctx context.Context) (*operations.Store, error) {
+	pool, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		return nil, handlers.ErrNoConnPool
+	}
+	return operations.NewStore(pool), nil
+}
+
+// GroupOf returns the group of the operation identified by the request, or the
+// group given by ?group= when listing operations, so API tokens limited to
+// specific groups can follow the operations of those groups.
+func GroupOf(r *http.Request) (string, error) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	identifier, ok := mux.Vars(r)["identifier"]
+	if !ok {
+		return r.URL.Query().Get("group"), nil
+	}
+
+	store, err := getStore(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	op, err := store.FindByID(ctx, identifier, session.AccountID)
+	if err != nil {
+		if err != pgx.ErrNoRows {
+			log.Debug().Err(err).Msg("operations: failed to find operation")
+		}
+		return "", errOperationNotFound
+	}
+
+	return op.GroupID, nil
+}
+
+// List lists the most recent operations of the account, newest first,
+// optionally only those of the group given by ?group=.
+func List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	query := r.URL.Query()
+	limit := DefaultLimit
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > MaxLimit {
+			apierror.Write(w, r, apierror.BadRequest("invalid limit, expected 1 to %d: %q", MaxLimit, value))
+			return
+		}
+		limit = parsed
+	}
+
+	groupID := query.Get("group")
+	if groupID != "" {
+		if _, err := uuid.Parse(groupID); err != nil {
+			apierror.Write(w, r, apierror.BadRequest("group must be a valid UUID: %q", groupID))
+			return
+		}
+	}
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	ops, err := store.FindAll(ctx, session.AccountID, groupID, limit)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if ops == nil {
+		ops = []*operations.Operation{}
+	}
+
+	bytes, err := json.Marshal(ops)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+func Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	vars := mux.Vars(r)
+	identifier := vars["identifier"]
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	op, err := store.FindByID(ctx, identifier, session.AccountID)
+	if err != nil {
+		writeFindError(w, r, err)
+		return
+	}
+
+	bytes, err := json.Marshal(op)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+// Cancel cancels a running scaling operation, stopping the group where it is.
+// Only the latest operation of a group can be cancelled, since halting an
+// earlier one would undo the change of those started since.
+func Cancel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	vars := mux.Vars(r)
+	identifier := vars["identifier"]
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	op, err := store.FindByID(ctx, identifier, session.AccountID)
+	if err != nil {
+		writeFindError(w, r, err)
+		return
+	}
+
+	if op.Type != operations.TypeScaleGroup {
+		apierror.Write(w, r, apierror.Conflict("only scaling operations can be cancelled, operation %s is %s",
+			op.ID, op.Type))
+		return
+	}
+
+	latest, err := store.FindAll(ctx, session.AccountID, op.GroupID, 1)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if len(latest) == 0 || latest[0].ID != op.ID {
+		apierror.Write(w, r, apierror.Conflict("operation %s has been superseded by a later operation of group %s",
+			op.ID, op.GroupID))
+		return
+	}
+
+	switch err := op.Cancel(ctx); err {
+	case nil:
+	case operations.ErrNotRunning:
+		apierror.Write(w, r, apierror.Conflict("operation %s is no longer running", op.ID))
+		return
+	default:
+		apierror.Write(w, r, err)
+		return
+	}
+
+	// Stop the operation straight away if it's run by this agent, any other
+	// agent stops it with its next heartbeat.
+	if runner, ok := operations.FromContext(ctx); ok {
+		runner.Cancel(op.ID)
+	}
+
+	if _, err := groups_v1.HaltScaling(ctx, op.GroupID); err != nil {
+		log.Error().Err(err).
+			Str("operation_id", op.ID).
+			Str("group_id", op.GroupID).
+			Msg("operations: failed to halt scaling of cancelled operation")
+		apierror.Write(w, r, err)
+		return
+	}
+
+	bytes, err := json.Marshal(op)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+func writeFindError(w http.ResponseWriter, r *http.Request, err error) {
+	if err != pgx.ErrNoRows {
+		log.Debug().Err(err).Msg("operations: failed to find operation")
+	}
+	apierror.Write(w, r, errOperationNotFound)
+}
+
+func writeJSONResponse(w http.ResponseWriter, bytes []byte, statusCode int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if n, err := w.Write(bytes); err != nil {
+		log.Printf("%v", err)
+	} else if n != len(bytes) {
+		log.Printf("short write: %d/%d", n, len(bytes))
+	}
+}
@@ -0,0 +1,60 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ratelimit
+
+import "sync"
+
+// Concurrency limits the number of requests in flight for each key within a
+// single agent.
+type Concurrency struct {
+	max int
+
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+// NewConcurrency returns a limit of max requests in flight for each key. A max
+// of zero doesn't limit any requests.
+func NewConcurrency(max int) *Concurrency {
+	return &Concurrency{
+		max:      max,
+		inFlight: make(map[string]int),
+	}
+}
+
+// Acquire returns true if another request for key can be served, in which
+// case Release must be called once it has been.
+func (c *Concurrency) Acquire(key string) bool {
+	if c.max <= 0 {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.inFlight[key] >= c.max {
+		return false
+	}
+	c.inFlight[key]++
+
+	return true
+}
+
+// Release marks a request for key acquired by Acquire as served.
+func (c *Concurrency) Release(key string) {
+	if c.max <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.inFlight[key] <= 1 {
+		delete(c.inFlight, key)
+		return
+	}
+	c.inFlight[key]--
+}
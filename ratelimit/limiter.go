@@ -0,0 +1,62 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit is a budget of requests allowed within each period. Requests are
+// counted within fixed windows of Period, aligned to the Unix epoch so every
+// agent sharing a backend counts within the same windows.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// IsZero returns true when the limit doesn't restrict any requests.
+func (l Limit) IsZero() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// Result reports whether a request was allowed by a limit.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+
+	// RetryAfter is how long until the current window ends and the budget is
+	// replenished.
+	RetryAfter time.Duration
+}
+
+// Limiter counts requests against a budget. Limiters sharing a backend, such
+// as the database, share their budgets across every agent.
+type Limiter interface {
+	// Allow counts a request for key made at now against limit.
+	Allow(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// window returns the start and end of the window containing now.
+func window(now time.Time, period time.Duration) (time.Time, time.Time) {
+	start := now.Truncate(period)
+	return start, start.Add(period)
+}
+
+func newResult(hits int, limit Limit, end, now time.Time) Result {
+	remaining := limit.Requests - hits
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return Result{
+		Allowed:    hits <= limit.Requests,
+		Limit:      limit.Requests,
+		Remaining:  remaining,
+		RetryAfter: end.Sub(now),
+	}
+}
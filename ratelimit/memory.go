@@ -0,0 +1,69 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryWindow struct {
+	end  time.Time
+	hits int
+}
+
+// MemoryLimiter counts requests within the memory of a single agent. Budgets
+// aren't shared when running multiple agents, use a Store instead.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	windows   map[string]*memoryWindow
+	lastSweep time.Time
+}
+
+// NewMemoryLimiter returns a new in-memory limiter.
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		windows: make(map[string]*memoryWindow),
+	}
+}
+
+// Allow counts a request for key made at now against limit.
+func (m *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	if limit.IsZero() {
+		return Result{Allowed: true}, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now, limit.Period)
+
+	_, end := window(now, limit.Period)
+
+	w, ok := m.windows[key]
+	if !ok || !w.end.Equal(end) {
+		w = &memoryWindow{end: end}
+		m.windows[key] = w
+	}
+	w.hits++
+
+	return newResult(w.hits, limit, end, now), nil
+}
+
+// sweep forgets windows which have ended, at most once per period.
+func (m *MemoryLimiter) sweep(now time.Time, period time.Duration) {
+	if now.Sub(m.lastSweep) < period {
+		return
+	}
+
+	for key, w := range m.windows {
+		if !now.Before(w.end) {
+			delete(m.windows, key)
+		}
+	}
+	m.lastSweep = now
+}
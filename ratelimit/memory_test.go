@@ -0,0 +1,72 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/joyent/triton-service-groups/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	ctx := context.Background()
+	limiter := ratelimit.NewMemoryLimiter()
+	limit := ratelimit.Limit{Requests: 2, Period: time.Minute}
+
+	now := time.Date(2018, 10, 19, 15, 20, 15, 0, time.UTC)
+
+	result, err := limiter.Allow(ctx, "account/mutations", limit, now)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Limit)
+	assert.Equal(t, 1, result.Remaining)
+
+	result, err = limiter.Allow(ctx, "account/mutations", limit, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	result, err = limiter.Allow(ctx, "account/mutations", limit, now.Add(5*time.Second))
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, 40*time.Second, result.RetryAfter)
+
+	result, err = limiter.Allow(ctx, "account/reads", limit, now.Add(5*time.Second))
+	require.NoError(t, err)
+	assert.True(t, result.Allowed, "budgets are kept per key")
+
+	result, err = limiter.Allow(ctx, "account/mutations", limit, now.Add(45*time.Second))
+	require.NoError(t, err)
+	assert.True(t, result.Allowed, "budget is replenished in the next window")
+	assert.Equal(t, 1, result.Remaining)
+}
+
+func TestMemoryLimiterZero(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter()
+
+	for i := 0; i < 10; i++ {
+		result, err := limiter.Allow(context.Background(), "account/reads", ratelimit.Limit{}, time.Now())
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	concurrency := ratelimit.NewConcurrency(2)
+
+	assert.True(t, concurrency.Acquire("account"))
+	assert.True(t, concurrency.Acquire("account"))
+	assert.False(t, concurrency.Acquire("account"))
+	assert.True(t, concurrency.Acquire("other"))
+
+	concurrency.Release("account")
+	assert.True(t, concurrency.Acquire("account"))
+
+	unlimited := ratelimit.NewConcurrency(0)
+	for i := 0; i < 10; i++ {
+		assert.True(t, unlimited.Acquire("account"))
+	}
+}
@@ -0,0 +1,87 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/pkg/errors"
+)
+
+// purgeInterval is how often a Store deletes windows which have ended.
+const purgeInterval = time.Minute
+
+// Store counts requests within the tsg_rate_limits table, sharing budgets
+// across every agent connected to the same database.
+type Store struct {
+	pool *pgx.ConnPool
+
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+// NewStore returns a new store object.
+func NewStore(pool *pgx.ConnPool) *Store {
+	return &Store{
+		pool: pool,
+	}
+}
+
+const upsertHit = `
+INSERT INTO tsg_rate_limits (bucket, window_start, hits, expires_at)
+VALUES ($1, $2, 1, $3)
+ON CONFLICT (bucket, window_start)
+DO UPDATE SET hits = tsg_rate_limits.hits + 1
+RETURNING hits;
+`
+
+// Allow counts a request for key made at now against limit.
+func (s *Store) Allow(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	if limit.IsZero() {
+		return Result{Allowed: true}, nil
+	}
+
+	if err := s.purge(ctx, now); err != nil {
+		return Result{}, err
+	}
+
+	start, end := window(now, limit.Period)
+
+	var hits int64
+	err := tracing.QueryRowEx(ctx, s.pool, upsertHit, nil,
+		key,
+		start,
+		end,
+	).Scan(&hits)
+	if err != nil {
+		return Result{}, errors.Wrap(err, "failed to count request")
+	}
+
+	return newResult(int(hits), limit, end, now), nil
+}
+
+// purge deletes windows which have ended, at most once per purgeInterval.
+func (s *Store) purge(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	if now.Sub(s.lastPurge) < purgeInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastPurge = now
+	s.mu.Unlock()
+
+	query := `DELETE FROM tsg_rate_limits WHERE expires_at < $1;`
+
+	if _, err := tracing.ExecEx(ctx, s.pool, query, nil, now); err != nil {
+		return errors.Wrap(err, "failed to purge expired rate limits")
+	}
+
+	return nil
+}
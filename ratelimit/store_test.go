@@ -0,0 +1,53 @@
+package ratelimit_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joyent/triton-service-groups/ratelimit"
+	"github.com/joyent/triton-service-groups/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreAllow(t *testing.T) {
+	if os.Getenv("TSG_TEST") == "" {
+		t.Skip("Acceptance tests skipped unless env 'TSG_TEST=1' set")
+		return
+	}
+
+	db, err := testutils.NewTestDB()
+	if err != nil {
+		t.Error(err)
+	}
+	db.Clear(t)
+	defer db.Clear(t)
+
+	ctx := context.Background()
+	limit := ratelimit.Limit{Requests: 2, Period: time.Minute}
+	now := time.Now()
+
+	// Two stores share their budgets the same way two agents would.
+	first := ratelimit.NewStore(db.Conn)
+	second := ratelimit.NewStore(db.Conn)
+
+	result, err := first.Allow(ctx, "account/mutations", limit, now)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = second.Allow(ctx, "account/mutations", limit, now)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	result, err = first.Allow(ctx, "account/mutations", limit, now)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.True(t, result.RetryAfter > 0)
+
+	result, err = first.Allow(ctx, "account/mutations", limit, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
@@ -0,0 +1,94 @@
+#!/usr/bin/env bash
+#
+# Vendors the Go modules dep can't solve: those only published with a major
+# version suffix, such as github.com/cespare/xxhash/v2, and the modules
+# depending on them. They're pinned within scripts/vendor-modules.txt, ignored
+# by dep and fetched through the Go module proxy. Run this after dep ensure,
+# which removes them from vendor/.
+#
+# Dependencies shared with the projects managed by dep are left to dep. The
+# script fails if Gopkg.lock pins any of them below the version required.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+root="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
+project="github.com/joyent/triton-service-groups"
+go_version="1.22"
+
+work="$(mktemp -d)"
+trap 'rm -rf "${work}"' EXIT
+
+pinned="$(sed -e 's/#.*//' -e '/^[[:space:]]*$/d' "${root}/scripts/vendor-modules.txt")"
+modules="$(awk '{print $1}' <<< "${pinned}")"
+
+# locked_version prints the version Gopkg.lock pins module at, or "branch"
+# when it tracks a branch. Nothing is printed for modules dep doesn't manage.
+locked_version() {
+    awk -v name="$1" '
+        $1 == "[[projects]]" { if (found) exit; project = ""; version = "" }
+        $1 == "name" { project = $3; gsub(/"/, "", project); if (project == name) found = 1 }
+        $1 == "version" { version = $3; gsub(/"/, "", version) }
+        END { if (found) print (version == "" ? "branch" : version) }
+    ' "${root}/Gopkg.lock"
+}
+
+# Every package of the pinned modules imported by the project.
+packages="$(cd "${root}" && GO111MODULE=off go list -e \
+    -f '{{join .Imports "\n"}}{{"\n"}}{{join .TestImports "\n"}}{{"\n"}}{{join .XTestImports "\n"}}' ./... |
+    sed "s#^${project}/vendor/##" | sort -u |
+    while read -r pkg; do
+        for module in ${modules}; do
+            case "${pkg}" in
+            "${module}" | "${module}"/*) echo "${pkg}"; break ;;
+            esac
+        done
+    done)"
+
+if [ -z "${packages}" ]; then
+    echo "==> None of the pinned modules are imported"
+    exit 0
+fi
+
+{
+    printf 'module %s/vendor-modules\n\ngo %s\n\nrequire (\n' "${project}" "${go_version}"
+    sed 's/^/\t/' <<< "${pinned}"
+    printf ')\n'
+} > "${work}/go.mod"
+
+{
+    printf 'package main\n\nimport (\n'
+    for pkg in ${packages}; do
+        printf '\t_ "%s"\n' "${pkg}"
+    done
+    printf ')\n\nfunc main() {}\n'
+} > "${work}/main.go"
+
+(
+    cd "${work}"
+    GO111MODULE=on GOFLAGS=-mod=mod go mod tidy
+    GO111MODULE=on go mod vendor
+)
+
+# Modules are listed parents first, so copying a module never removes one
+# nested within it.
+grep '^# ' "${work}/vendor/modules.txt" | while read -r _ module version _; do
+    locked="$(locked_version "${module}")"
+    if [ "${locked}" = "branch" ]; then
+        echo "${module} tracks a branch within Gopkg.lock, ${version} is required" >&2
+        continue
+    elif [ -n "${locked}" ]; then
+        lowest="$(printf '%s\n%s\n' "${locked#v}" "${version#v}" | sort -V | head -n 1)"
+        if [ "${lowest}" != "${version#v}" ]; then
+            echo "${module} is locked at ${locked} within Gopkg.lock, ${version} is required" >&2
+            exit 1
+        fi
+        continue
+    fi
+
+    echo "==> Vendoring ${module} ${version}"
+    rm -rf "${root}/vendor/${module}"
+    mkdir -p "${root}/vendor/${module}"
+    cp -R "${work}/vendor/${module}/." "${root}/vendor/${module}/"
+done
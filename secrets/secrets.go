@@ -0,0 +1,325 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package secrets_v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/schema"
+	"github.com/rs/zerolog/log"
+)
+
+const maxValueSize = 64 * 1024
+
+var (
+	errSecretNotFound = apierror.NotFound("secret not found")
+
+	// errNoKeyring is written when secrets can't be stored because no
+	// key-encryption key has been configured.
+	errNoKeyring = apierror.New(http.StatusServiceUnavailable, apierror.CodeUnavailable,
+		"secrets are unavailable until an encryption key is configured")
+)
+
+// Secret represents a named secret belonging to an account. The value of a
+// secret is write-only and never returned through the API.
+type Secret struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	CreatedBy string    `json:"created_by"`
+	UpdatedBy string    `json:"updated_by"`
+}
+
+// SecretTemplate is a template which references a secret, listed when the
+// secret can't be deleted.
+type SecretTemplate struct {
+	ID           string `json:"id"`
+	TemplateName string `json:"template_name"`
+}
+
+// SecretInput is the request body used to create or update a secret.
+type SecretInput struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	vars := mux.Vars(r)
+	uuid := vars["identifier"]
+
+	secret, ok := FindSecretByID(ctx, uuid, session.AccountID)
+	if !ok {
+		apierror.Write(w, r, errSecretNotFound)
+		return
+	}
+
+	bytes, err := json.Marshal(secret)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+func Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	keyring, ok := handlers.GetKeyring(ctx)
+	if !ok {
+		apierror.Write(w, r, errNoKeyring)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	input, err := decodeSecretBodyAndValidate(body)
+	if err != nil {
+		schema.WriteError(w, r, err)
+		return
+	}
+
+	ciphertext, err := keyring.Encrypt([]byte(input.Value))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	err = SaveSecret(ctx, session.AccountID, input.Name, ciphertext, session.Actor())
+	if err == errSecretExists {
+		apierror.Write(w, r, apierror.Conflict("Cannot create secret %q, "+
+			"conflicts with another secret.", input.Name))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	com, ok := FindSecretByName(ctx, input.Name, session.AccountID)
+	if !ok {
+		apierror.Write(w, r, fmt.Errorf("failed to find secret %q after save", input.Name))
+		return
+	}
+
+	bytes, err := json.Marshal(com)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", path.Join(r.URL.Path, com.ID))
+	writeJSONResponse(w, bytes, http.StatusCreated)
+}
+
+func Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	keyring, ok := handlers.GetKeyring(ctx)
+	if !ok {
+		apierror.Write(w, r, errNoKeyring)
+		return
+	}
+
+	vars := mux.Vars(r)
+	identifier := vars["identifier"]
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	input, err := decodeSecretBodyAndValidate(body)
+	if err != nil {
+		schema.WriteError(w, r, err)
+		return
+	}
+
+	com, ok := FindSecretByID(ctx, identifier, session.AccountID)
+	if !ok {
+		apierror.Write(w, r, errSecretNotFound)
+		return
+	}
+
+	if input.Name != com.Name {
+		apierror.Write(w, r, apierror.BadRequest("The secret name %q does not match "+
+			"the name on the record.", input.Name))
+		return
+	}
+
+	ciphertext, err := keyring.Encrypt([]byte(input.Value))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	err = UpdateSecret(ctx, com.ID, session.AccountID, ciphertext, session.Actor())
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	com.UpdatedAt = time.Now().UTC()
+	com.UpdatedBy = session.Actor()
+
+	bytes, err := json.Marshal(com)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+func Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	vars := mux.Vars(r)
+	uuid := vars["identifier"]
+
+	secret, ok := FindSecretByID(ctx, uuid, session.AccountID)
+	if !ok {
+		apierror.Write(w, r, errSecretNotFound)
+		return
+	}
+
+	templates, err := RemoveSecret(ctx, secret, session.AccountID, session.Actor())
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if len(templates) > 0 {
+		conflict := apierror.New(http.StatusConflict, apierror.CodeSecretInUse,
+			fmt.Sprintf("Cannot delete secret %q while in use, "+
+				"must be removed from all templates first.", secret.Name))
+
+		apierror.Write(w, r, conflict.With("templates", templates))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	rows, err := FindSecrets(ctx, session.AccountID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	if len(rows) == 0 {
+		writeJSONResponse(w, []byte("[]"), http.StatusOK)
+		return
+	}
+
+	bytes, err := json.Marshal(rows)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+// FindMissingSecrets returns the names which don't match any of the account's
+// secrets.
+func FindMissingSecrets(ctx context.Context, accountID string, names []string) ([]string, error) {
+	found, err := FindSecretCiphertexts(ctx, accountID, names)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, name := range names {
+		if _, ok := found[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+
+	return missing, nil
+}
+
+// ResolveSecrets decrypts the values of every named secret. This should only
+// be called when a scaling job asks for the secrets of its group.
+func ResolveSecrets(ctx context.Context, accountID string, names []string) (map[string]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	keyring, ok := handlers.GetKeyring(ctx)
+	if !ok {
+		return nil, handlers.ErrNoKeyring
+	}
+
+	ciphertexts, err := FindSecretCiphertexts(ctx, accountID, names)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		ciphertext, ok := ciphertexts[name]
+		if !ok {
+			return nil, fmt.Errorf("secret %q does not exist", name)
+		}
+
+		value, err := keyring.Decrypt(ciphertext)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = string(value)
+	}
+
+	return values, nil
+}
+
+func writeJSONResponse(w http.ResponseWriter, bytes []byte, statusCode int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if n, err := w.Write(bytes); err != nil {
+		log.Printf("%v", err)
+	} else if n != len(bytes) {
+		log.Printf("short write: %d/%d", n, len(bytes))
+	}
+}
+
+func decodeSecretBodyAndValidate(body []byte) (*SecretInput, error) {
+	var input *SecretInput
+	if err := schema.Decode(schema.Secret, body, &input); err != nil {
+		return nil, err
+	}
+
+	if len(input.Value) > maxValueSize {
+		return nil, schema.NewValidationError(schema.Pointer("value"),
+			"cannot be more than 64KiB")
+	}
+
+	return input, nil
+}
@@ -0,0 +1,367 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package secrets_v1
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/pgtype"
+	"github.com/joyent/triton-service-groups/convert"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/templates/render"
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/pkg/errors"
+)
+
+// uniqueViolation is the SQLSTATE returned when an insert conflicts with a
+// unique index, such as the live_name of a secret. live_name holds the name of
+// a secret until it's archived, so only the names of live secrets conflict.
+const uniqueViolation = "23505"
+
+// errSecretExists is returned by SaveSecret when the account already has a
+// secret with the same name.
+var errSecretExists = errors.New("secret already exists")
+
+func FindSecrets(ctx context.Context, accountID string) ([]*Secret, error) {
+	db, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		return nil, handlers.ErrNoConnPool
+	}
+
+	var secrets []*Secret
+
+	sqlStatement := `
+SELECT id, name, created_at, updated_at, COALESCE(created_by, ''), COALESCE(updated_by, '')
+FROM tsg_secrets
+WHERE account_id = $1
+AND archived = false
+ORDER BY name;`
+
+	rows, err := tracing.QueryEx(ctx, db, sqlStatement, nil, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			secret    Secret
+			secretID  pgtype.UUID
+			createdAt pgtype.Timestamp
+			updatedAt pgtype.Timestamp
+		)
+
+		err := rows.Scan(
+			&secretID,
+			&secret.Name,
+			&createdAt,
+			&updatedAt,
+			&secret.CreatedBy,
+			&secret.UpdatedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		secret.ID = convert.BytesToUUID(secretID.Bytes)
+		secret.CreatedAt = createdAt.Time
+		secret.UpdatedAt = updatedAt.Time
+
+		secrets = append(secrets, &secret)
+	}
+
+	return secrets, nil
+}
+
+func FindSecretByID(ctx context.Context, key string, accountID string) (*Secret, bool) {
+	return findSecret(ctx, "id", key, accountID)
+}
+
+func FindSecretByName(ctx context.Context, name string, accountID string) (*Secret, bool) {
+	return findSecret(ctx, "name", name, accountID)
+}
+
+func findSecret(ctx context.Context, column, key, accountID string) (*Secret, bool) {
+	db, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	var (
+		secret    Secret
+		secretID  pgtype.UUID
+		createdAt pgtype.Timestamp
+		updatedAt pgtype.Timestamp
+	)
+
+	sqlStatement := `
+SELECT id, name, created_at, updated_at, COALESCE(created_by, ''), COALESCE(updated_by, '')
+FROM tsg_secrets
+WHERE ` + column + ` = $1 and account_id = $2
+AND archived = false;`
+
+	err := tracing.QueryRowEx(ctx, db, sqlStatement, nil, key, accountID).Scan(
+		&secretID,
+		&secret.Name,
+		&createdAt,
+		&updatedAt,
+		&secret.CreatedBy,
+		&secret.UpdatedBy,
+	)
+	switch err {
+	case nil:
+		secret.ID = convert.BytesToUUID(secretID.Bytes)
+		secret.CreatedAt = createdAt.Time
+		secret.UpdatedAt = updatedAt.Time
+
+		return &secret, true
+	case pgx.ErrNoRows:
+		return nil, false
+	default:
+		return nil, false
+	}
+}
+
+// FindSecretCiphertexts returns the encrypted values of every named secret
+// belonging to the account, keyed by secret name. Names which don't exist are
+// omitted from the result.
+func FindSecretCiphertexts(ctx context.Context, accountID string, names []string) (map[string]string, error) {
+	db, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		return nil, handlers.ErrNoConnPool
+	}
+
+	values := make(map[string]string, len(names))
+	if len(names) == 0 {
+		return values, nil
+	}
+
+	sqlStatement := `
+SELECT name, ciphertext
+FROM tsg_secrets
+WHERE account_id = $1
+AND name = ANY($2)
+AND archived = false;`
+
+	rows, err := tracing.QueryEx(ctx, db, sqlStatement, nil, accountID, names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, ciphertext string
+
+		if err := rows.Scan(&name, &ciphertext); err != nil {
+			return nil, err
+		}
+
+		values[name] = ciphertext
+	}
+
+	return values, rows.Err()
+}
+
+func SaveSecret(ctx context.Context, accountID, name, ciphertext, actor string) error {
+	db, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		return handlers.ErrNoConnPool
+	}
+
+	sqlStatement := `
+INSERT INTO tsg_secrets (name, live_name, ciphertext, account_id, created_by, updated_by, created_at, updated_at)
+VALUES ($1, $1, $2, $3, $4, $4, NOW(), NOW())
+`
+	_, err := tracing.ExecEx(ctx, db, sqlStatement, nil,
+		name,
+		ciphertext,
+		accountID,
+		actor,
+	)
+	if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == uniqueViolation {
+		return errSecretExists
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func UpdateSecret(ctx context.Context, identifier, accountID, ciphertext, actor string) error {
+	db, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		return handlers.ErrNoConnPool
+	}
+
+	sqlStatement := `
+UPDATE tsg_secrets
+SET ciphertext = $3, updated_by = $4, updated_at = NOW()
+WHERE id = $1 and account_id = $2
+`
+	_, err := tracing.ExecEx(ctx, db, sqlStatement, nil, identifier, accountID, ciphertext, actor)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// findSecretTemplates returns the templates of the account which reference the
+// named secret.
+func findSecretTemplates(ctx context.Context, q tracing.Querier, name, accountID string) ([]*SecretTemplate, error) {
+	sqlStatement := `
+SELECT id, template_name, userdata, COALESCE(metadata, '')
+FROM tsg_templates
+WHERE account_id = $1
+AND archived = false
+ORDER BY template_name;`
+
+	rows, err := tracing.QueryEx(ctx, q, sqlStatement, nil, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*SecretTemplate
+	for rows.Next() {
+		var (
+			template     SecretTemplate
+			templateID   pgtype.UUID
+			userData     string
+			metaDataJson string
+		)
+
+		if err := rows.Scan(&templateID, &template.TemplateName, &userData, &metaDataJson); err != nil {
+			return nil, err
+		}
+
+		values := []string{userData}
+		if metaDataJson != "" {
+			metaData := map[string]string{}
+			if err := json.Unmarshal([]byte(metaDataJson), &metaData); err != nil {
+				return nil, errors.Wrap(err, "failed to decode template metadata")
+			}
+			for _, value := range metaData {
+				values = append(values, value)
+			}
+		}
+
+		for _, referenced := range render.SecretNames(values...) {
+			if referenced == name {
+				template.ID = convert.BytesToUUID(templateID.Bytes)
+				templates = append(templates, &template)
+				break
+			}
+		}
+	}
+
+	return templates, rows.Err()
+}
+
+// RemoveSecret archives a secret, discarding its encrypted value. Should any
+// template of the account reference the secret, it's kept and the templates
+// are returned instead. Both happen within one transaction, so a template
+// created meanwhile can't be left referencing an archived secret.
+func RemoveSecret(ctx context.Context, secret *Secret, accountID, actor string) ([]*SecretTemplate, error) {
+	db, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		return nil, handlers.ErrNoConnPool
+	}
+
+	tx, err := db.BeginEx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback() // nolint: errcheck
+
+	templates, err := findSecretTemplates(ctx, tx, secret.Name, accountID)
+	if err != nil || len(templates) > 0 {
+		return templates, err
+	}
+
+	sqlStatement := `
+UPDATE tsg_secrets
+SET archived = true, live_name = NULL, ciphertext = '', updated_by = $3, updated_at = NOW()
+WHERE id = $1 and account_id = $2
+`
+	_, err = tracing.ExecEx(ctx, tx, sqlStatement, nil, secret.ID, accountID, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return nil, nil
+}
+
+// RewrapSecrets re-wraps the value of every secret encrypted under a previous
+// key-encryption key with the current one, returning the number of secrets
+// re-wrapped. Nothing is written when dryRun is true.
+func RewrapSecrets(ctx context.Context, dryRun bool) (int, error) {
+	db, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		return 0, handlers.ErrNoConnPool
+	}
+
+	keyring, ok := handlers.GetKeyring(ctx)
+	if !ok {
+		return 0, handlers.ErrNoKeyring
+	}
+
+	sqlStatement := `
+SELECT id, ciphertext
+FROM tsg_secrets
+WHERE archived = false;`
+
+	rows, err := tracing.QueryEx(ctx, db, sqlStatement, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	stale := make(map[string]string)
+	for rows.Next() {
+		var (
+			id         pgtype.UUID
+			ciphertext string
+		)
+
+		if err := rows.Scan(&id, &ciphertext); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		if !keyring.IsCurrent(ciphertext) {
+			stale[convert.BytesToUUID(id.Bytes)] = ciphertext
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for id, ciphertext := range stale {
+		rewrapped, err := keyring.Rewrap(ciphertext)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to re-wrap secret %s", id)
+		}
+
+		if dryRun {
+			continue
+		}
+
+		_, err = tracing.ExecEx(ctx, db, `UPDATE tsg_secrets SET ciphertext = $2 WHERE id = $1;`, nil, id, rewrapped)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(stale), nil
+}
@@ -0,0 +1,39 @@
+package secrets_v1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeSecretBodyAndValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  bool
+	}{
+		{"valid", `{"name": "db_password", "value": "database123"}`, false},
+		{"bad json", `{"name": `, true},
+		{"null body", `null`, true},
+		{"empty name", `{"value": "database123"}`, true},
+		{"leading digit", `{"name": "1password", "value": "database123"}`, true},
+		{"dashed name", `{"name": "db-password", "value": "database123"}`, true},
+		{"empty value", `{"name": "db_password", "value": ""}`, true},
+		{"large value", `{"name": "db_password", "value": "` + strings.Repeat("a", maxValueSize+1) + `"}`, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input, err := decodeSecretBodyAndValidate([]byte(test.body))
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "db_password", input.Name)
+			assert.Equal(t, "database123", input.Value)
+		})
+	}
+}
@@ -0,0 +1,161 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package apierror
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/joyent/triton-service-groups/server/requestid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Codes identifying the kind of an error. Codes are part of the API and never
+// change once published, unlike messages.
+const (
+	CodeBadRequest           = "bad_request"
+	CodeInvalidBody          = "invalid_body"
+	CodeAuthenticationFailed = "authentication_failed"
+	CodeInvalidAuthorization = "invalid_authorization"
+	CodeAccountRejected      = "account_rejected"
+	CodeKeyFailed            = "key_failed"
+	CodeTokenInvalid         = "token_invalid"
+	CodeSessionInvalid       = "session_invalid"
+	CodeCSRFFailed           = "csrf_failed"
+	CodeUserForbidden        = "user_forbidden"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeConflict             = "conflict"
+	CodeCapacityLimit        = "capacity_limit"
+	CodeTemplateInUse        = "template_in_use"
+	CodeSecretInUse          = "secret_in_use"
+	CodeRateLimited          = "rate_limited"
+	CodeTooManyConcurrent    = "too_many_concurrent_requests"
+	CodeUnavailable          = "service_unavailable"
+	CodeInternal             = "internal_error"
+)
+
+var (
+	// ErrNotFound is written when a resource can't be found.
+	ErrNotFound = New(http.StatusNotFound, CodeNotFound, "resource not found")
+
+	// ErrInternal is written in place of every error which isn't an *Error,
+	// so internal details such as database errors are never returned.
+	ErrInternal = New(http.StatusInternalServerError, CodeInternal, "internal server error")
+)
+
+// Error is an error returned to API clients. It's written as a JSON object
+// with a machine-readable code and a message, along with the identifier of the
+// request and, for invalid request bodies, the failure of each field.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Details []*Detail
+
+	// Extra holds additional fields written alongside the error, such as the
+	// groups preventing a template from being deleted.
+	Extra map[string]interface{}
+}
+
+// Detail describes a single invalid field of a request body. Pointer is a JSON
+// Pointer to the field, empty for the body itself.
+type Detail struct {
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+// New returns a new error written with the HTTP status code status.
+func New(status int, code, message string) *Error {
+	return &Error{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+// BadRequest returns a new 400 error with a formatted message.
+func BadRequest(format string, args ...interface{}) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, fmt.Sprintf(format, args...))
+}
+
+// NotFound returns a new 404 error with a formatted message.
+func NotFound(format string, args ...interface{}) *Error {
+	return New(http.StatusNotFound, CodeNotFound, fmt.Sprintf(format, args...))
+}
+
+// Conflict returns a new 409 error with a formatted message.
+func Conflict(format string, args ...interface{}) *Error {
+	return New(http.StatusConflict, CodeConflict, fmt.Sprintf(format, args...))
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// WithDetails returns a copy of the error describing the invalid fields of a
+// request body.
+func (e *Error) WithDetails(details ...*Detail) *Error {
+	c := *e
+	c.Details = details
+	return &c
+}
+
+// With returns a copy of the error written with the additional field key.
+func (e *Error) With(key string, value interface{}) *Error {
+	c := *e
+	c.Extra = make(map[string]interface{}, len(e.Extra)+1)
+	for k, v := range e.Extra {
+		c.Extra[k] = v
+	}
+	c.Extra[key] = value
+	return &c
+}
+
+// Write writes err as the response to r. Errors which aren't an *Error, once
+// unwrapped, are logged and written as ErrInternal.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	requestID := requestid.FromContext(r.Context())
+
+	apiErr, ok := errors.Cause(err).(*Error)
+	if !ok {
+		log.Error().
+			Str("request_id", requestID).
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Err(err).
+			Msg("http: internal error")
+		apiErr = ErrInternal
+	}
+
+	body := make(map[string]interface{}, len(apiErr.Extra)+4)
+	for key, value := range apiErr.Extra {
+		body[key] = value
+	}
+	body["code"] = apiErr.Code
+	body["message"] = apiErr.Message
+	body["request_id"] = requestID
+	if len(apiErr.Details) > 0 {
+		body["details"] = apiErr.Details
+	}
+
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		log.Printf("%v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(apiErr.Status)
+	if n, err := w.Write(bytes); err != nil {
+		log.Printf("%v", err)
+	} else if n != len(bytes) {
+		log.Printf("short write: %d/%d", n, len(bytes))
+	}
+}
@@ -0,0 +1,75 @@
+package apierror_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/requestid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func write(t *testing.T, err error) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/tsg/groups", nil)
+	req = req.WithContext(requestid.NewContext(req.Context(), "abc123"))
+
+	rec := httptest.NewRecorder()
+	apierror.Write(rec, req, err)
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return rec, body
+}
+
+func TestWrite(t *testing.T) {
+	rec, body := write(t, apierror.NotFound("group %q not found", "web"))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "not_found", body["code"])
+	assert.Equal(t, `group "web" not found`, body["message"])
+	assert.Equal(t, "abc123", body["request_id"])
+	assert.NotContains(t, body, "details")
+}
+
+func TestWriteWrapped(t *testing.T) {
+	rec, body := write(t, errors.Wrap(apierror.Conflict("name taken"), "failed to save"))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "conflict", body["code"])
+	assert.Equal(t, "name taken", body["message"])
+}
+
+func TestWriteInternal(t *testing.T) {
+	rec, body := write(t, errors.New("pq: connection refused"))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "internal_error", body["code"])
+	assert.Equal(t, "internal server error", body["message"])
+	assert.NotContains(t, rec.Body.String(), "connection refused")
+}
+
+func TestWriteDetails(t *testing.T) {
+	err := apierror.New(http.StatusUnprocessableEntity, apierror.CodeInvalidBody, "invalid body").
+		WithDetails(&apierror.Detail{Pointer: "/capacity", Message: "must be at least 0"}).
+		With("groups", []string{"web"})
+
+	rec, body := write(t, err)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, []interface{}{"web"}, body["groups"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"pointer": "/capacity", "message": "must be at least 0"},
+	}, body["details"])
+}
+
+func TestWithCopies(t *testing.T) {
+	base := apierror.Conflict("in use")
+	_ = base.With("groups", []string{"web"})
+
+	assert.Empty(t, base.Extra)
+}
@@ -0,0 +1,285 @@
+// The web console of Triton Service Groups. Browsers can't sign requests with
+// a Triton key, so the console exchanges an API token for a browser session
+// cookie and sends the session's CSRF token along with every change.
+"use strict";
+
+(function () {
+  const scalePermissions = ["scale-only", "full", "owner", "operator"];
+  const eventTypes = ["created", "updated", "scaled", "deleted", "status_changed"];
+
+  let session = null;
+  let watcher = null;
+  let reloadTimer = null;
+  let templateNames = {};
+  const expanded = new Set();
+
+  const $ = (id) => document.getElementById(id);
+
+  // api calls the API with the browser session cookie, returning the decoded
+  // response body. Errors are thrown with the message of the API error.
+  async function api(method, path, body, headers) {
+    const init = {
+      method: method,
+      credentials: "same-origin",
+      headers: Object.assign({ "Accept": "application/json" }, headers),
+    };
+    if (body !== undefined) {
+      init.headers["Content-Type"] = "application/json";
+      init.body = JSON.stringify(body);
+    }
+    if (session && method !== "GET") {
+      init.headers["X-CSRF-Token"] = session.csrf_token;
+    }
+
+    const res = await fetch(path, init);
+    const text = await res.text();
+    const data = text ? JSON.parse(text) : null;
+    if (!res.ok) {
+      const err = new Error((data && data.message) || res.statusText);
+      err.status = res.status;
+      throw err;
+    }
+    return data;
+  }
+
+  function showError(err) {
+    const el = $("error");
+    if (!err) {
+      el.hidden = true;
+      return;
+    }
+    if (err.status === 401 && session) {
+      showSignIn();
+    }
+    el.textContent = err.message;
+    el.hidden = false;
+  }
+
+  function cell(row, text, className) {
+    const td = row.insertCell();
+    td.textContent = text === undefined || text === null ? "" : String(text);
+    if (className) {
+      td.className = className;
+    }
+    return td;
+  }
+
+  function button(label, onClick, disabled) {
+    const b = document.createElement("button");
+    b.type = "button";
+    b.textContent = label;
+    b.disabled = !!disabled;
+    b.addEventListener("click", onClick);
+    return b;
+  }
+
+  function formatDate(value) {
+    return value ? new Date(value).toLocaleString() : "";
+  }
+
+  function showSignIn() {
+    session = null;
+    stopWatching();
+    $("account").hidden = true;
+    $("dashboard").hidden = true;
+    $("sign-in").hidden = false;
+    $("token").focus();
+  }
+
+  function showDashboard(s) {
+    session = s;
+    $("actor").textContent = s.actor + " (" + s.permission + ")";
+    $("account").hidden = false;
+    $("sign-in").hidden = true;
+    $("dashboard").hidden = false;
+    load();
+    watch();
+  }
+
+  async function signIn(event) {
+    event.preventDefault();
+    const input = $("token");
+    const token = input.value.trim();
+    input.value = "";
+
+    try {
+      const s = await api("POST", "/v1/tsg/sessions", undefined, {
+        "Authorization": "Bearer " + token,
+      });
+      showError(null);
+      showDashboard(s);
+    } catch (err) {
+      showError(err);
+    }
+  }
+
+  async function signOut() {
+    try {
+      await api("DELETE", "/v1/tsg/sessions/current");
+    } catch (err) {
+      // The session is gone either way.
+    }
+    showError(null);
+    showSignIn();
+  }
+
+  async function load() {
+    try {
+      const [templates, groups] = await Promise.all([
+        api("GET", "/v1/tsg/templates"),
+        api("GET", "/v1/tsg/groups"),
+      ]);
+      renderTemplates(templates || []);
+      renderGroups(groups || []);
+      showError(null);
+    } catch (err) {
+      showError(err);
+    }
+  }
+
+  function renderTemplates(templates) {
+    templateNames = {};
+    const body = $("templates");
+    body.textContent = "";
+
+    for (const t of templates) {
+      templateNames[t.id] = t.template_name;
+
+      const row = body.insertRow();
+      cell(row, t.template_name);
+      cell(row, t.package);
+      cell(row, t.image_id);
+      cell(row, formatDate(t.created_at));
+    }
+    $("no-templates").hidden = templates.length > 0;
+  }
+
+  function renderGroups(groups) {
+    const body = $("groups");
+    body.textContent = "";
+    const canScale = scalePermissions.includes(session.permission);
+
+    for (const g of groups) {
+      const row = body.insertRow();
+      cell(row, g.group_name);
+      cell(row, templateNames[g.template_id] || g.template_id);
+      cell(row, g.capacity, "capacity");
+
+      const open = expanded.has(g.id);
+      cell(row).appendChild(button(open ? "Hide" : "Show", () => toggleInstances(g.id)));
+
+      const scale = cell(row);
+      scale.appendChild(button("−", () => scaleGroup(g, "decrement"), !canScale || g.capacity <= 0));
+      scale.appendChild(button("+", () => scaleGroup(g, "increment"), !canScale));
+
+      if (open) {
+        const detail = body.insertRow();
+        detail.className = "instances";
+        const td = detail.insertCell();
+        td.colSpan = 5;
+        td.textContent = "Loading instances…";
+        loadInstances(g.id, td);
+      }
+    }
+    $("no-groups").hidden = groups.length > 0;
+  }
+
+  function toggleInstances(groupID) {
+    if (expanded.has(groupID)) {
+      expanded.delete(groupID);
+    } else {
+      expanded.add(groupID);
+    }
+    load();
+  }
+
+  async function loadInstances(groupID, td) {
+    let instances;
+    try {
+      instances = (await api("GET", "/v1/tsg/groups/" + groupID + "/instances")) || [];
+    } catch (err) {
+      td.textContent = err.message;
+      return;
+    }
+
+    td.textContent = "";
+    if (instances.length === 0) {
+      td.textContent = "The group doesn't have any instances.";
+      return;
+    }
+
+    const table = document.createElement("table");
+    const head = table.createTHead().insertRow();
+    for (const label of ["Instance", "State", "IP", "Created"]) {
+      const th = document.createElement("th");
+      th.textContent = label;
+      head.appendChild(th);
+    }
+
+    const body = table.createTBody();
+    for (const i of instances) {
+      const row = body.insertRow();
+      cell(row, i.name);
+      cell(row, i.state, "state-" + i.state);
+      cell(row, i.primaryIp);
+      cell(row, formatDate(i.created));
+    }
+    td.appendChild(table);
+  }
+
+  // scaleGroup scales a group by a single instance. The limits sent along
+  // match the new capacity, so concurrent changes are never exceeded.
+  async function scaleGroup(group, action) {
+    const body = { instance_count: 1 };
+    if (action === "increment") {
+      body.max_instance = group.capacity + 1;
+    } else {
+      body.min_instance = group.capacity - 1;
+    }
+
+    try {
+      await api("PUT", "/v1/tsg/groups/" + group.id + "/" + action, body);
+      showError(null);
+    } catch (err) {
+      showError(err);
+    }
+    load();
+  }
+
+  // watch reloads the groups whenever any of them change, for as long as the
+  // browser is signed in.
+  function watch() {
+    stopWatching();
+    if (!window.EventSource) {
+      return;
+    }
+
+    watcher = new EventSource("/v1/tsg/groups/watch");
+    for (const type of eventTypes) {
+      watcher.addEventListener(type, scheduleReload);
+    }
+  }
+
+  function stopWatching() {
+    if (watcher) {
+      watcher.close();
+      watcher = null;
+    }
+  }
+
+  function scheduleReload() {
+    clearTimeout(reloadTimer);
+    reloadTimer = setTimeout(load, 500);
+  }
+
+  document.addEventListener("DOMContentLoaded", async () => {
+    $("sign-in-form").addEventListener("submit", signIn);
+    $("sign-out").addEventListener("click", signOut);
+
+    try {
+      showDashboard(await api("GET", "/v1/tsg/sessions/current"));
+    } catch (err) {
+      showSignIn();
+    }
+  });
+})();
@@ -0,0 +1,57 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package console serves the web console, a page listing the templates and
+// groups of an account which can scale its groups. The console's assets are
+// embedded within the agent and call the API with a browser session.
+package console
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// Path is the path the console is served under.
+const Path = "/console/"
+
+// contentSecurityPolicy only lets the console load its own assets and call
+// the API it's served by.
+const contentSecurityPolicy = "default-src 'self'; frame-ancestors 'none'; base-uri 'none'; form-action 'none'"
+
+//go:embed assets
+var assets embed.FS
+
+// Handler serves the console's assets without authentication, passing every
+// other request on to handler.
+func Handler(handler http.Handler) http.Handler {
+	root, err := fs.Sub(assets, "assets")
+	if err != nil {
+		panic(err)
+	}
+	files := http.StripPrefix(Path, http.FileServer(http.FS(root)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		switch {
+		case req.URL.Path == strings.TrimSuffix(Path, "/"):
+			http.Redirect(w, req, Path, http.StatusMovedPermanently)
+		case strings.HasPrefix(req.URL.Path, Path):
+			header := w.Header()
+			header.Set("Content-Security-Policy", contentSecurityPolicy)
+			header.Set("X-Content-Type-Options", "nosniff")
+			header.Set("Referrer-Policy", "no-referrer")
+			header.Set("Cache-Control", "no-cache")
+			files.ServeHTTP(w, req)
+		default:
+			handler.ServeHTTP(w, req)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package console_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/server/console"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	var calls int
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	handler := console.Handler(next)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/console/")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, rec.Header().Get("Content-Security-Policy"), "default-src 'self'")
+	assert.Contains(t, rec.Body.String(), "console.js")
+
+	for _, asset := range []string{"/console/console.js", "/console/console.css"} {
+		rec = serve(http.MethodGet, asset)
+		assert.Equal(t, http.StatusOK, rec.Code, asset)
+	}
+
+	rec = serve(http.MethodGet, "/console")
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, console.Path, rec.Header().Get("Location"))
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/console/missing.js").Code)
+	assert.Equal(t, 0, calls, "assets are served without authentication")
+
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/v1/tsg/groups").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodPost, "/console/").Code)
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,163 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/joyent/triton-service-groups/audit"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/requestlog"
+)
+
+// maxAuditCapture is the most of a request or response body read to record an
+// audit record.
+const maxAuditCapture = 64 * 1024
+
+// auditInsertTimeout bounds how long recording an audit record can take.
+const auditInsertTimeout = 10 * time.Second
+
+// resourceVars are the path variables identifying the resource of a route, in
+// order of preference.
+var resourceVars = []string{"identifier", "account", "name"}
+
+// auditBody streams a request body to the handler after the start of it has
+// been captured, so larger bodies are never held in memory by Audit.
+type auditBody struct {
+	io.Reader
+	io.Closer
+}
+
+// auditWriter captures the status code of a response, as well as the start of
+// its body so the identifier of a created resource can be recorded.
+type auditWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *auditWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if room := maxAuditCapture - w.body.Len(); room > 0 {
+		if len(b) < room {
+			room = len(b)
+		}
+		w.body.Write(b[:room])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Audit wraps an HTTP handler, recording an audit record of every request
+// served by the route named route. Requests are served even when the record
+// can't be written.
+func Audit(route string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body []byte
+		if req.Body != nil {
+			captured, err := ioutil.ReadAll(io.LimitReader(req.Body, maxAuditCapture+1))
+			if err != nil {
+				apierror.Write(w, req, err)
+				return
+			}
+			req.Body = &auditBody{
+				Reader: io.MultiReader(bytes.NewReader(captured), req.Body),
+				Closer: req.Body,
+			}
+
+			if len(captured) <= maxAuditCapture {
+				body = captured
+			}
+		}
+
+		aw := &auditWriter{ResponseWriter: w}
+		handler.ServeHTTP(aw, req)
+
+		if aw.status == 0 {
+			aw.status = http.StatusOK
+		}
+
+		writeAuditRecord(req, route, body, aw)
+	})
+}
+
+func writeAuditRecord(req *http.Request, route string, body []byte, aw *auditWriter) {
+	ctx := req.Context()
+	session := GetAuthSession(ctx)
+
+	pool, ok := GetDBPool(ctx)
+	if !ok || session.AccountID == "" {
+		return
+	}
+
+	record := audit.New(audit.NewStore(pool))
+	record.AccountID = session.AccountID
+	record.Actor = session.Actor()
+	record.TokenID = session.TokenID
+	record.Fingerprint = session.Fingerprint
+	record.Route = route
+	record.Method = req.Method
+	record.Path = req.URL.Path
+	record.ResourceID = resourceID(req, aw)
+	record.RequestBody = audit.Sanitize(body)
+	record.Status = aw.status
+	record.Outcome = audit.OutcomeOf(aw.status)
+
+	if session.ParsedRequest != nil {
+		record.UserName = session.UserName
+	}
+
+	// The request is recorded even if the client has gone away, since its
+	// mutation has already been made.
+	insertCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditInsertTimeout)
+	defer cancel()
+
+	if err := record.Insert(insertCtx); err != nil {
+		requestlog.FromContext(ctx).Error().
+			Str("module", "audit").
+			Str("actor", record.Actor).
+			Str("route", route).
+			Err(err).
+			Msg("audit: failed to record request")
+	}
+}
+
+// resourceID returns the identifier of the resource a request acted on, taken
+// from the path or, for created resources, the response body.
+func resourceID(req *http.Request, aw *auditWriter) string {
+	vars := mux.Vars(req)
+	for _, name := range resourceVars {
+		if id := vars[name]; id != "" {
+			return id
+		}
+	}
+
+	if aw.status >= 300 {
+		return ""
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(aw.body.Bytes(), &created); err != nil {
+		return ""
+	}
+
+	return created.ID
+}
@@ -0,0 +1,34 @@
+package handlers_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditPassesBodyThrough(t *testing.T) {
+	for _, size := range []int{0, 1024, 256 * 1024} {
+		sent := bytes.Repeat([]byte("a"), size)
+
+		var received []byte
+		handler := handlers.Audit("CreateThing", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var err error
+			received, err = ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/tsg/things", bytes.NewReader(sent))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, sent, received, "body of %d bytes", size)
+	}
+}
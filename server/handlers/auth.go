@@ -3,10 +3,11 @@ package handlers
 import (
 	"context"
 	"net/http"
+	"strings"
 
 	"github.com/jackc/pgx"
-	"github.com/joyent/triton-service-groups/accounts"
-	"github.com/joyent/triton-service-groups/keys"
+	"github.com/joyent/triton-service-groups/metrics"
+	"github.com/joyent/triton-service-groups/server/apierror"
 	"github.com/joyent/triton-service-groups/server/handlers/auth"
 	"github.com/rs/zerolog/log"
 )
@@ -14,18 +15,33 @@ import (
 // authHandler encapsulates the authentication HTTP handler itself. We pipe all
 // active HTTP requests through this object's ServeHTTP method.
 type authHandler struct {
-	pool    *pgx.ConnPool
-	handler http.Handler
-	config  auth.Config
+	handler  http.Handler
+	provider authProvider
 }
 
 // AuthHandler constructs and returns the HTTP handler object responsible for
-// authenticating a request. This accepts a chain of HTTP handlers.
+// authenticating a request. This accepts a chain of HTTP handlers. Requests
+// are authenticated by the provider chosen within config.
 func AuthHandler(pool *pgx.ConnPool, config auth.Config, handler http.Handler) authHandler {
+	var provider authProvider
+	switch config.Provider {
+	case auth.ProviderStatic:
+		log.Warn().Msg("auth: authenticating requests with insecure static tokens")
+		provider = staticProvider{
+			pool:   pool,
+			config: config,
+		}
+	default:
+		provider = signatureProvider{
+			pool:   pool,
+			config: config,
+			keys:   auth.NewKeyCache(config.KeyCacheTTL),
+		}
+	}
+
 	return authHandler{
-		pool:    pool,
-		handler: handler,
-		config:  config,
+		handler:  handler,
+		provider: provider,
 	}
 }
 
@@ -39,51 +55,68 @@ func GetAuthSession(ctx context.Context) *auth.Session {
 	return &auth.Session{}
 }
 
+// WithAuthSession returns a copy of ctx carrying session, for use when calling
+// into handler code outside of an HTTP request.
+func WithAuthSession(ctx context.Context, session *auth.Session) context.Context {
+	return context.WithValue(ctx, authKey, session)
+}
+
 // ServeHTTP serves HTTP requests through the authentication process scoped to
 // whatever pre-defined data we need accessible through the authHandler
 // struct. This method finalizes by calling ServeHTTP on the handler that this
 // authHandler was constructed for, passing along the active request down it's
 // chain of middleware.
 func (a authHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
+	scheme := authScheme(req.Header.Get("Authorization"))
+	if auth.HasCookie(req) {
+		scheme = "cookie"
+	}
 
-	session, err := auth.NewSession(req, a.config)
-	if err != nil {
-		log.Debug().
-			Str("module", "auth").
-			Err(err)
-		http.Error(w, ErrFailedSession.Error(), http.StatusInternalServerError)
+	sw := &statusWriter{ResponseWriter: w}
+	session, ok := a.provider.Authenticate(sw, req)
+	if !ok {
+		metrics.AuthRequests.WithLabelValues(scheme, authOutcome(sw.status)).Inc()
 		return
 	}
 
-	if !session.IsDevMode() {
-		accountStore := accounts.NewStore(a.pool)
+	if !session.IsAuthenticated() {
+		metrics.AuthRequests.WithLabelValues(scheme, authOutcome(http.StatusUnauthorized)).Inc()
+		apierror.Write(w, req, ErrFailedAuth)
+		return
+	}
 
-		acct, err := session.EnsureAccount(ctx, accountStore)
-		if err != nil {
-			log.Debug().
-				Str("module", "auth").
-				Err(err)
-			http.Error(w, ErrFailedAccount.Error(), http.StatusUnauthorized)
-			return
-		}
+	metrics.AuthRequests.WithLabelValues(scheme, metrics.OutcomeSuccess).Inc()
 
-		keyStore := keys.NewStore(a.pool)
+	ctx := WithAuthSession(req.Context(), session)
+	a.handler.ServeHTTP(w, req.WithContext(ctx))
+}
 
-		if err := session.EnsureKeys(ctx, acct, keyStore); err != nil {
-			log.Debug().
-				Str("module", "auth").
-				Err(err)
-			http.Error(w, ErrFailedKey.Error(), http.StatusUnauthorized)
-			return
-		}
+// authScheme returns the lower case scheme of an Authorization header, as
+// recorded by the authentication metrics. Requests authenticated by a browser
+// session cookie are recorded as "cookie" instead.
+func authScheme(authHeader string) string {
+	if authHeader == "" {
+		return "none"
 	}
 
-	if !session.IsAuthenticated() {
-		http.Error(w, ErrFailedAuth.Error(), http.StatusUnauthorized)
-		return
+	scheme := strings.ToLower(strings.SplitN(authHeader, " ", 2)[0])
+	switch scheme {
+	case "signature", "bearer":
+		return scheme
+	default:
+		return "other"
 	}
+}
 
-	ctx = context.WithValue(ctx, authKey, session)
-	a.handler.ServeHTTP(w, req.WithContext(ctx))
+// authOutcome returns the outcome recorded by the authentication metrics for
+// a request rejected with status.
+func authOutcome(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	default:
+		return "error"
+	}
 }
@@ -8,8 +8,10 @@ import (
 	"github.com/joyent/triton-go/account"
 	"github.com/joyent/triton-go/authentication"
 	"github.com/joyent/triton-service-groups/accounts"
+	"github.com/joyent/triton-service-groups/allowlist"
+	"github.com/joyent/triton-service-groups/server/requestlog"
+	"github.com/joyent/triton-service-groups/tracing"
 	"github.com/pkg/errors"
-	"github.com/rs/zerolog/log"
 )
 
 type AccountCheck struct {
@@ -20,10 +22,11 @@ type AccountCheck struct {
 
 	config          *triton.ClientConfig
 	store           *accounts.Store
+	allowList       *allowlist.Store
 	enableWhitelist bool
 }
 
-func NewAccountCheck(req *ParsedRequest, store *accounts.Store, cfg Config) *AccountCheck {
+func NewAccountCheck(req *ParsedRequest, store *accounts.Store, allowList *allowlist.Store, cfg Config) *AccountCheck {
 	signer := &authentication.TestSigner{}
 	config := &triton.ClientConfig{
 		TritonURL:   cfg.AuthURL,
@@ -35,6 +38,7 @@ func NewAccountCheck(req *ParsedRequest, store *accounts.Store, cfg Config) *Acc
 		ParsedRequest:   req,
 		config:          config,
 		store:           store,
+		allowList:       allowList,
 		enableWhitelist: cfg.EnableWhitelist,
 	}
 }
@@ -52,9 +56,11 @@ func (ac *AccountCheck) OnTriton(ctx context.Context) error {
 
 	a.SetHeader(ac.ParsedRequest.Header())
 
+	_, span := tracing.StartClient(ctx, "cloudapi", "GetAccount")
 	acct, err := a.Get(ctx, &account.GetInput{})
+	tracing.Finish(span, err)
 	if err != nil {
-		log.Debug().
+		requestlog.FromContext(ctx).Debug().
 			Str("account_name", ac.ParsedRequest.AccountName).
 			Msg("failed to get account")
 		return errors.Wrap(err, "failed to get account")
@@ -76,7 +82,7 @@ func (ac *AccountCheck) createAccount(ctx context.Context) error {
 
 	ac.Account = newAccount
 
-	log.Debug().
+	requestlog.FromContext(ctx).Debug().
 		Str("account_id", ac.Account.ID).
 		Str("account_name", ac.Account.AccountName).
 		Str("triton_uuid", ac.Account.TritonUUID).
@@ -99,14 +105,14 @@ func (ac *AccountCheck) SaveAccount(ctx context.Context) error {
 		return err
 	}
 
-	if !exists && ac.enableWhitelist {
-		log.Debug().
+	if err := checkAllowList(ctx, ac.allowList, ac.TritonAccount.Login, exists, ac.enableWhitelist); err != nil {
+		requestlog.FromContext(ctx).Debug().
 			Str("account_name", ac.TritonAccount.Login).
 			Str("triton_uuid", ac.TritonAccount.ID).
 			Str("module", "whitelist").
-			Msg("auth: access denied to new service users")
+			Msg("auth: access denied to service user")
 
-		return ErrWhitelist
+		return err
 	}
 
 	if !exists {
@@ -127,7 +133,7 @@ func (ac *AccountCheck) SaveAccount(ctx context.Context) error {
 
 	ac.Account = curAccount
 
-	log.Debug().
+	requestlog.FromContext(ctx).Debug().
 		Str("account_id", ac.Account.ID).
 		Str("account_name", ac.Account.AccountName).
 		Str("triton_uuid", ac.Account.TritonUUID).
@@ -149,3 +155,26 @@ func (ac *AccountCheck) HasAccount() bool {
 func (ac *AccountCheck) IsAuthentic() bool {
 	return ac.HasTritonAccount() && ac.HasAccount()
 }
+
+// checkAllowList decides whether an account can access TSG. Accounts denied
+// within the allow-list are always rejected. When the whitelist is enabled,
+// accounts which are new to TSG must have been allowed beforehand.
+func checkAllowList(ctx context.Context, store *allowlist.Store, accountName string, known bool, enableWhitelist bool) error {
+	entry, err := store.FindByName(ctx, accountName)
+	switch err {
+	case nil:
+		if !entry.Allowed {
+			return ErrDenied
+		}
+		return nil
+	case pgx.ErrNoRows:
+	default:
+		return errors.Wrap(err, "failed to check allow-list")
+	}
+
+	if !known && enableWhitelist {
+		return ErrWhitelist
+	}
+
+	return nil
+}
@@ -1,6 +1,23 @@
 package auth
 
+import (
+	"strings"
+	"time"
+)
+
 type Config struct {
+	// Provider authenticating incoming requests, either
+	// ProviderTritonSignature (the default) or ProviderStatic.
+	Provider string
+
+	// Tokens accepted by the static provider and the seeded accounts they're
+	// mapped to.
+	StaticAccounts []StaticAccount
+
+	// Insecure must be set for the server to start with the static provider,
+	// so it can't be left enabled by accident.
+	Insecure bool
+
 	// Name of the datacenter in which this TSG service is operating. This is
 	// used to create unique key names per-DC. The value is also available in
 	// the HTTP request Session object.
@@ -21,9 +38,41 @@ type Config struct {
 	// runtime.
 	KeyNamePrefix string
 
+	// Type of key generated for new TSG management keys, either "rsa"
+	// (4096 bits), "ecdsa" (P-256) or "ed25519". Defaults to DefaultKeyType.
+	KeyType KeyType
+
 	// Enable or disable whitelisting behavior. This feature only accepts
 	// requests from user accounts that have previously been authenticated. If
-	// this is set to true than a Triton account must be manually added to the
-	// tsg_accounts table, auto account creation will be disabled.
+	// this is set to true than a Triton account must be allowed through the
+	// allow-list before it can authenticate, auto account creation will be
+	// disabled. Accounts denied within the allow-list are always rejected.
 	EnableWhitelist bool
+
+	// Names of the Triton accounts operating the TSG service. Their account
+	// owners can manage the allow-list.
+	Operators []string
+
+	// Duration public keys fetched from CloudAPI are cached for before being
+	// fetched again. Request signatures are verified locally against the
+	// cached keys. Defaults to DefaultKeyCacheTTL.
+	KeyCacheTTL time.Duration
+
+	// Maximum difference allowed between the Date header of a request and the
+	// server's clock. Defaults to DefaultClockSkew.
+	MaxClockSkew time.Duration
+
+	// Duration browser sessions last for before the console must sign in
+	// again. Defaults to DefaultSessionTTL.
+	SessionTTL time.Duration
+}
+
+// IsOperator returns true if accountName is one of the configured operators.
+func (c Config) IsOperator(accountName string) bool {
+	for _, name := range c.Operators {
+		if strings.EqualFold(name, accountName) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,77 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-service-groups/server/handlers/auth"
+	"github.com/joyent/triton-service-groups/users"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigIsOperator(t *testing.T) {
+	cfg := auth.Config{Operators: []string{"ops", "Admin"}}
+
+	assert.True(t, cfg.IsOperator("ops"))
+	assert.True(t, cfg.IsOperator("admin"))
+	assert.False(t, cfg.IsOperator("testaccount"))
+	assert.False(t, auth.Config{}.IsOperator("ops"))
+}
+
+func TestConfigValidate(t *testing.T) {
+	static := []auth.StaticAccount{
+		{Token: "dev-token", AccountName: "joyent"},
+	}
+
+	tests := []struct {
+		name  string
+		cfg   auth.Config
+		valid bool
+	}{
+		{"default provider", auth.Config{}, true},
+		{"triton-signature", auth.Config{Provider: auth.ProviderTritonSignature}, true},
+		{"unknown provider", auth.Config{Provider: "none"}, false},
+		{"static without insecure", auth.Config{Provider: auth.ProviderStatic, StaticAccounts: static}, false},
+		{"static without accounts", auth.Config{Provider: auth.ProviderStatic, Insecure: true}, false},
+		{"static", auth.Config{Provider: auth.ProviderStatic, StaticAccounts: static, Insecure: true}, true},
+		{"static missing token", auth.Config{
+			Provider:       auth.ProviderStatic,
+			StaticAccounts: []auth.StaticAccount{{AccountName: "joyent"}},
+			Insecure:       true,
+		}, false},
+		{"static bad permission", auth.Config{
+			Provider:       auth.ProviderStatic,
+			StaticAccounts: []auth.StaticAccount{{Token: "dev-token", AccountName: "joyent", Permission: "root"}},
+			Insecure:       true,
+		}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.cfg.Validate()
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestConfigFindStaticAccount(t *testing.T) {
+	cfg := auth.Config{
+		StaticAccounts: []auth.StaticAccount{
+			{Token: "owner-token", AccountName: "joyent"},
+			{Token: "scale-token", AccountName: "joyent", Permission: users.PermissionScaleOnly},
+		},
+	}
+
+	static, ok := cfg.FindStaticAccount("scale-token")
+	assert.True(t, ok)
+	assert.Equal(t, "joyent", static.AccountName)
+	assert.Equal(t, users.PermissionScaleOnly, static.Permission)
+
+	_, ok = cfg.FindStaticAccount("Scale-Token")
+	assert.False(t, ok)
+
+	_, ok = cfg.FindStaticAccount("")
+	assert.False(t, ok)
+}
@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/accounts"
+	"github.com/joyent/triton-service-groups/allowlist"
+	"github.com/joyent/triton-service-groups/keys"
+	"github.com/joyent/triton-service-groups/server/requestlog"
+	"github.com/joyent/triton-service-groups/sessions"
+	"github.com/joyent/triton-service-groups/tokens"
+	"github.com/joyent/triton-service-groups/users"
+	"github.com/pkg/errors"
+)
+
+const (
+	// CookieName is the name of the cookie holding the secret of a browser
+	// session.
+	CookieName = "tsg_session"
+
+	// CSRFHeader carries the CSRF token of a browser session. Requests
+	// authenticated by cookie must send it to change any resource.
+	CSRFHeader = "X-CSRF-Token"
+
+	// DefaultSessionTTL is how long a browser session lasts when no TTL has
+	// been configured.
+	DefaultSessionTTL = 12 * time.Hour
+)
+
+// NewCookie returns the cookie handing the secret of a browser session to the
+// browser. Scripts can't read the cookie and browsers only send it along with
+// requests made by the console itself. It's marked Secure whenever req was
+// served over TLS.
+func NewCookie(req *http.Request, secret string, expiresAt time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     CookieName,
+		Value:    secret,
+		Path:     "/",
+		Expires:  expiresAt,
+		MaxAge:   int(time.Until(expiresAt).Seconds()),
+		HttpOnly: true,
+		Secure:   req.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
+
+// ExpiredCookie returns the cookie removing a browser session from the
+// browser.
+func ExpiredCookie(req *http.Request) *http.Cookie {
+	return &http.Cookie{
+		Name:     CookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   req.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
+
+// HasCookie returns true when req presents a browser session cookie instead of
+// an Authorization header.
+func HasCookie(req *http.Request) bool {
+	if req.Header.Get("Authorization") != "" {
+		return false
+	}
+
+	cookie, err := req.Cookie(CookieName)
+	return err == nil && cookie.Value != ""
+}
+
+// IsCookie returns true when the request presented the cookie of a browser
+// session.
+func (s *Session) IsCookie() bool {
+	return s.cookie != "" || s.SessionID != ""
+}
+
+// EnsureCookie authenticates a request presenting the cookie of a browser
+// session. The session acts as the account owner, sub-user or API token which
+// created it. They're checked again on every request, so denying the account,
+// removing the sub-user or revoking the token also ends the session.
+func (s *Session) EnsureCookie(ctx context.Context, sessionStore *sessions.Store, acctStore *accounts.Store, userStore *users.Store, tokenStore *tokens.Store, keyStore *keys.Store, allowList *allowlist.Store) error {
+	browser, err := sessionStore.FindBySecret(ctx, s.cookie)
+	switch err {
+	case nil:
+	case pgx.ErrNoRows:
+		return ErrBadCookie
+	default:
+		err = errors.Wrap(err, "failed to check database for session")
+		requestlog.FromContext(ctx).Error().Err(err)
+		return err
+	}
+
+	if !browser.IsActive(time.Now()) {
+		requestlog.FromContext(ctx).Debug().
+			Str("session_id", browser.ID).
+			Time("expires_at", browser.ExpiresAt).
+			Err(ErrExpiredCookie)
+		return ErrExpiredCookie
+	}
+
+	acct, err := acctStore.FindByID(ctx, browser.AccountID)
+	if err != nil {
+		err = errors.Wrap(err, "failed to find session account")
+		requestlog.FromContext(ctx).Error().Err(err)
+		return err
+	}
+
+	if err := checkAllowList(ctx, allowList, acct.AccountName, true, s.config.EnableWhitelist); err != nil {
+		return err
+	}
+
+	if acct.KeyID == "" {
+		return ErrNoAccountKey
+	}
+
+	key, err := keyStore.FindByID(ctx, acct.KeyID)
+	switch err {
+	case nil:
+	case pgx.ErrNoRows:
+		return ErrNoAccountKey
+	default:
+		err = errors.Wrap(err, "failed to check database for key")
+		requestlog.FromContext(ctx).Error().Err(err)
+		return err
+	}
+
+	s.ParsedRequest = &ParsedRequest{
+		AccountName: acct.AccountName,
+	}
+	s.Permission = browser.Permission
+
+	switch {
+	case browser.TokenID != "":
+		token, err := tokenStore.FindByID(ctx, browser.TokenID, acct.ID)
+		switch err {
+		case nil:
+		case pgx.ErrNoRows:
+			return ErrInactiveToken
+		default:
+			err = errors.Wrap(err, "failed to check database for token")
+			requestlog.FromContext(ctx).Error().Err(err)
+			return err
+		}
+
+		if !token.IsActive(time.Now()) {
+			return ErrInactiveToken
+		}
+
+		s.TokenID = token.ID
+		s.GroupIDs = token.GroupIDs
+		s.Permission = token.Permission
+		s.tokenName = token.Name
+	case browser.UserID != "":
+		user, err := userStore.FindByID(ctx, browser.UserID, acct.ID)
+		switch err {
+		case nil:
+		case pgx.ErrNoRows:
+			return ErrUnknownUser
+		default:
+			err = errors.Wrap(err, "failed to check database for user")
+			requestlog.FromContext(ctx).Error().Err(err)
+			return err
+		}
+
+		s.ParsedRequest.UserName = user.UserName
+		s.UserID = user.ID
+		s.Permission = user.Permission
+	case browser.Permission == users.PermissionOperator && !s.config.IsOperator(acct.AccountName):
+		s.Permission = users.PermissionOwner
+	}
+
+	s.AccountID = acct.ID
+	s.Fingerprint = key.Fingerprint
+	s.SessionID = browser.ID
+	s.browser = browser
+
+	requestlog.FromContext(ctx).Debug().
+		Str("account_name", acct.AccountName).
+		Str("session_id", browser.ID).
+		Str("permission", string(s.Permission)).
+		Msg("auth: browser session has been authenticated")
+
+	return nil
+}
+
+// VerifyCSRF checks the CSRF token sent along with a request authenticated by
+// the cookie of a browser session. Only requests changing a resource must send
+// the token, within the CSRFHeader.
+func (s *Session) VerifyCSRF(req *http.Request) error {
+	if s.browser == nil {
+		return nil
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+
+	if !s.browser.CheckCSRF(req.Header.Get(CSRFHeader)) {
+		return ErrBadCSRF
+	}
+	return nil
+}
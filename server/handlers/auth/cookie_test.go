@@ -0,0 +1,60 @@
+package auth_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/joyent/triton-service-groups/server/handlers/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSessionCookie(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/tsg/groups", nil)
+	req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: "secret"})
+
+	assert.True(t, auth.HasCookie(req))
+
+	session, err := auth.NewSession(req, auth.Config{})
+	require.NoError(t, err)
+	assert.True(t, session.IsCookie())
+	assert.False(t, session.IsToken())
+	assert.False(t, session.IsAuthenticated())
+
+	req.Header.Set("Authorization", "Bearer tsg_token")
+	assert.False(t, auth.HasCookie(req), "the Authorization header takes precedence")
+
+	session, err = auth.NewSession(req, auth.Config{})
+	require.NoError(t, err)
+	assert.False(t, session.IsCookie())
+	assert.True(t, session.IsToken())
+}
+
+func TestNewCookie(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/tsg/sessions", nil)
+	cookie := auth.NewCookie(req, "secret", expiresAt)
+	assert.Equal(t, auth.CookieName, cookie.Name)
+	assert.Equal(t, "secret", cookie.Value)
+	assert.True(t, cookie.HttpOnly)
+	assert.False(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+	assert.InDelta(t, time.Hour.Seconds(), cookie.MaxAge, 5)
+
+	req.TLS = &tls.ConnectionState{}
+	assert.True(t, auth.NewCookie(req, "secret", expiresAt).Secure)
+	assert.True(t, auth.ExpiredCookie(req).MaxAge < 0)
+}
+
+func TestVerifyCSRFWithoutCookie(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/v1/tsg/groups", nil)
+	req.Header.Set("Authorization", "Bearer tsg_token")
+
+	session, err := auth.NewSession(req, auth.Config{})
+	require.NoError(t, err)
+	assert.NoError(t, session.VerifyCSRF(req), "only browser sessions send a CSRF token")
+}
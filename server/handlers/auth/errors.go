@@ -11,6 +11,20 @@ var (
 	ErrNameLen       = errors.New("parsed name is too short")
 	ErrNameFormat    = errors.New("parsed name is not formatted properly")
 	ErrKeyConflict   = errors.New("auth: found conflicting key state")
+	ErrUnknownUser   = errors.New("auth: sub-user has not been granted access")
+	ErrNoAccountKey  = errors.New("auth: account owner has not authenticated")
+	ErrBadSignature  = errors.New("auth: request signature is invalid")
+	ErrBadAlgorithm  = errors.New("auth: unsupported signature algorithm")
+	ErrBadDate       = errors.New("auth: request date header is invalid")
+	ErrUnsignedDate  = errors.New("auth: request signature doesn't cover the date header")
+	ErrClockSkew     = errors.New("auth: request date is too far from server time")
+	ErrSignerKey     = errors.New("auth: signing key does not match fingerprint")
+	ErrBadToken      = errors.New("auth: API token is invalid")
+	ErrInactiveToken = errors.New("auth: API token has expired or been revoked")
+	ErrBadCookie     = errors.New("auth: session cookie is invalid")
+	ErrExpiredCookie = errors.New("auth: browser session has expired")
+	ErrBadCSRF       = errors.New("auth: CSRF token is missing or invalid")
 
 	ErrWhitelist = errors.New("service only accessible by whitelist")
+	ErrDenied    = errors.New("account has been denied access")
 )
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"path"
+	"sync"
+	"time"
+
+	"github.com/joyent/triton-go/account"
+)
+
+// DefaultKeyCacheTTL is how long keys fetched from CloudAPI are cached when no
+// TTL has been configured.
+const DefaultKeyCacheTTL = 5 * time.Minute
+
+// KeyCache caches the public keys fetched from CloudAPI, keyed by account,
+// sub-user and key name or fingerprint. Entries expire after the TTL so keys
+// removed from Triton eventually stop being accepted.
+type KeyCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu   sync.Mutex
+	keys map[string]cachedKey
+}
+
+type cachedKey struct {
+	key     *account.Key
+	expires time.Time
+}
+
+// NewKeyCache constructs an empty KeyCache. A TTL of zero uses
+// DefaultKeyCacheTTL.
+func NewKeyCache(ttl time.Duration) *KeyCache {
+	if ttl <= 0 {
+		ttl = DefaultKeyCacheTTL
+	}
+
+	return &KeyCache{
+		ttl:  ttl,
+		now:  time.Now,
+		keys: make(map[string]cachedKey),
+	}
+}
+
+func cacheKey(accountName, userName, keyID string) string {
+	return path.Join(accountName, userName, keyID)
+}
+
+// Get returns a cached key which hasn't expired.
+func (c *KeyCache) Get(accountName, userName, keyID string) (*account.Key, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := cacheKey(accountName, userName, keyID)
+
+	entry, ok := c.keys[id]
+	if !ok {
+		return nil, false
+	}
+	if c.now().After(entry.expires) {
+		delete(c.keys, id)
+		return nil, false
+	}
+
+	return entry.key, true
+}
+
+// Add caches key until the TTL expires.
+func (c *KeyCache) Add(accountName, userName, keyID string, key *account.Key) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.keys[cacheKey(accountName, userName, keyID)] = cachedKey{
+		key:     key,
+		expires: c.now().Add(c.ttl),
+	}
+}
+
+// Remove evicts a key from the cache.
+func (c *KeyCache) Remove(accountName, userName, keyID string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.keys, cacheKey(accountName, userName, keyID))
+}
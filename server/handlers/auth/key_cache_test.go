@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joyent/triton-go/account"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyCache(t *testing.T) {
+	now := time.Date(2018, 4, 14, 21, 45, 59, 0, time.UTC)
+
+	cache := NewKeyCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	key := &account.Key{Name: "laptop", Fingerprint: "aa:bb"}
+
+	_, ok := cache.Get("testaccount", "", "aa:bb")
+	assert.False(t, ok)
+
+	cache.Add("testaccount", "", "aa:bb", key)
+
+	found, ok := cache.Get("testaccount", "", "aa:bb")
+	assert.True(t, ok)
+	assert.Equal(t, key, found)
+
+	_, ok = cache.Get("testaccount", "demouser", "aa:bb")
+	assert.False(t, ok, "sub-user keys are cached separately")
+
+	_, ok = cache.Get("testaccount", "", "cc:dd")
+	assert.False(t, ok, "a changed fingerprint misses the cache")
+
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.Get("testaccount", "", "aa:bb")
+	assert.False(t, ok, "expired keys are refetched")
+
+	cache.Add("testaccount", "", "aa:bb", key)
+	cache.Remove("testaccount", "", "aa:bb")
+	_, ok = cache.Get("testaccount", "", "aa:bb")
+	assert.False(t, ok)
+
+	var nilCache *KeyCache
+	nilCache.Add("testaccount", "", "aa:bb", key)
+	_, ok = nilCache.Get("testaccount", "", "aa:bb")
+	assert.False(t, ok)
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"net/http"
+	"path"
 	"strings"
 
 	"github.com/jackc/pgx"
@@ -12,7 +13,9 @@ import (
 	terrors "github.com/joyent/triton-go/errors"
 	"github.com/joyent/triton-service-groups/accounts"
 	"github.com/joyent/triton-service-groups/keys"
+	"github.com/joyent/triton-service-groups/tracing"
 	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
 )
 
 type KeyCheck struct {
@@ -23,11 +26,12 @@ type KeyCheck struct {
 
 	config  *triton.ClientConfig
 	store   *keys.Store
+	cache   *KeyCache
 	account *accounts.Account
 	keyName string
 }
 
-func NewKeyCheck(req *ParsedRequest, acct *accounts.Account, store *keys.Store, cfg Config) *KeyCheck {
+func NewKeyCheck(req *ParsedRequest, acct *accounts.Account, store *keys.Store, cache *KeyCache, cfg Config) *KeyCheck {
 	signer := &authentication.TestSigner{}
 	config := &triton.ClientConfig{
 		TritonURL:   cfg.AuthURL,
@@ -42,6 +46,7 @@ func NewKeyCheck(req *ParsedRequest, acct *accounts.Account, store *keys.Store,
 		account:       acct,
 		config:        config,
 		store:         store,
+		cache:         cache,
 		keyName:       keyName,
 	}
 }
@@ -51,8 +56,26 @@ func (k *KeyCheck) newClient() (*account.AccountClient, error) {
 	return account.NewClient(k.config)
 }
 
-// CheckTriton checks Triton account keys for our TSG key
+// tritonKeyName returns the name of the account's key within Triton. Rotated
+// keys are named uniquely, so the name of the key stored in the database takes
+// precedence over the configured prefix.
+func (k *KeyCheck) tritonKeyName() string {
+	if k.Key != nil && k.Key.Name != "" {
+		return k.Key.Name
+	}
+	return k.keyName
+}
+
+// OnTriton checks Triton account keys for our TSG key. Keys found are cached so
+// CloudAPI is only consulted once per cache TTL.
 func (k *KeyCheck) OnTriton(ctx context.Context) error {
+	keyName := k.tritonKeyName()
+
+	if key, ok := k.cache.Get(k.ParsedRequest.AccountName, "", keyName); ok {
+		k.TritonKey = key
+		return nil
+	}
+
 	a, err := k.newClient()
 	if err != nil {
 		return errors.Wrap(err, "failed to create account key client")
@@ -61,9 +84,11 @@ func (k *KeyCheck) OnTriton(ctx context.Context) error {
 	a.SetHeader(k.ParsedRequest.Header())
 
 	input := &account.GetKeyInput{
-		KeyName: k.keyName,
+		KeyName: keyName,
 	}
+	_, span := tracing.StartClient(ctx, "cloudapi", "GetKey")
 	key, err := a.Keys().Get(ctx, input)
+	tracing.Finish(span, err)
 	if err != nil {
 		if terrors.IsSpecificStatusCode(err, http.StatusNotFound) {
 			return nil
@@ -72,6 +97,7 @@ func (k *KeyCheck) OnTriton(ctx context.Context) error {
 	}
 
 	k.TritonKey = key
+	k.cache.Add(k.ParsedRequest.AccountName, "", keyName, key)
 
 	return nil
 }
@@ -104,16 +130,21 @@ func (k *KeyCheck) AddTritonKey(ctx context.Context, keypair *KeyPair) error {
 
 	a.SetHeader(k.ParsedRequest.Header())
 
+	keyName := k.tritonKeyName()
+
 	createInput := &account.CreateKeyInput{
-		Name: k.keyName,
+		Name: keyName,
 		Key:  keypair.PublicKeyBase64(),
 	}
+	_, span := tracing.StartClient(ctx, "cloudapi", "CreateKey")
 	key, err := a.Keys().Create(ctx, createInput)
+	tracing.Finish(span, err)
 	if err != nil {
 		return errors.Wrap(err, "failed to create new account key")
 	}
 
 	k.TritonKey = key
+	k.cache.Add(k.ParsedRequest.AccountName, "", keyName, key)
 
 	return nil
 }
@@ -147,3 +178,53 @@ func (k *KeyCheck) HasTritonKey() bool {
 func (k *KeyCheck) HasKey() bool {
 	return k.Key != nil
 }
+
+// FetchSignerKey returns the public key which signed the request, fetching it
+// from CloudAPI when it isn't cached. Keys are cached by fingerprint so a
+// request signed by a different key is always checked with CloudAPI.
+func FetchSignerKey(ctx context.Context, req *ParsedRequest, cache *KeyCache, cfg Config) (ssh.PublicKey, error) {
+	key, ok := cache.Get(req.AccountName, req.UserName, req.Fingerprint)
+	if !ok {
+		// Sub-user keys live beneath the user within CloudAPI, which the
+		// account keys client can reach when given the user's path.
+		accountPath := req.AccountName
+		if req.UserName != "" {
+			accountPath = path.Join(req.AccountName, "users", req.UserName)
+		}
+
+		a, err := account.NewClient(&triton.ClientConfig{
+			TritonURL:   cfg.AuthURL,
+			AccountName: accountPath,
+			Signers:     []authentication.Signer{&authentication.TestSigner{}},
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create account key client")
+		}
+
+		a.SetHeader(req.Header())
+
+		_, span := tracing.StartClient(ctx, "cloudapi", "GetKey")
+		key, err = a.Keys().Get(ctx, &account.GetKeyInput{
+			KeyName: req.Fingerprint,
+		})
+		tracing.Finish(span, err)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get signing key")
+		}
+	}
+
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key.Key))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse signing key")
+	}
+
+	if !matchesFingerprint(pub, req.Fingerprint) {
+		return nil, ErrSignerKey
+	}
+
+	if !ok {
+		cache.Add(req.AccountName, req.UserName, req.Fingerprint, key)
+	}
+
+	return pub, nil
+}
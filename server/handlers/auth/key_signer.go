@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"path"
+
+	"github.com/joyent/triton-go/authentication"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ed25519"
+)
+
+const signatureFormat = `Signature keyId="%s",algorithm="%s",headers="date",signature="%s"`
+
+// KeySigner signs CloudAPI requests with a TSG management key. Unlike the
+// triton-go PrivateKeySigner it supports RSA, ECDSA and Ed25519 keys.
+type KeySigner struct {
+	keypair     *KeyPair
+	accountName string
+}
+
+var _ authentication.Signer = &KeySigner{}
+
+// NewKeySigner constructs a KeySigner which signs requests on behalf of
+// accountName.
+func NewKeySigner(keypair *KeyPair, accountName string) *KeySigner {
+	return &KeySigner{
+		keypair:     keypair,
+		accountName: accountName,
+	}
+}
+
+// DefaultAlgorithm returns the HTTP Signature algorithm used for the key.
+func (s *KeySigner) DefaultAlgorithm() string {
+	switch s.keypair.Type() {
+	case KeyTypeECDSA:
+		return "ecdsa-sha256"
+	case KeyTypeEd25519:
+		return "ed25519-sha512"
+	default:
+		return "rsa-sha256"
+	}
+}
+
+// KeyFingerprint returns the MD5 fingerprint of the key.
+func (s *KeySigner) KeyFingerprint() string {
+	return s.keypair.FingerprintMD5
+}
+
+// Sign returns the Authorization header for a request with the given Date
+// header.
+func (s *KeySigner) Sign(dateHeader string, isManta bool) (string, error) {
+	signed, algorithm, err := s.SignRaw(fmt.Sprintf("date: %s", dateHeader))
+	if err != nil {
+		return "", errors.Wrap(err, "unable to sign date header")
+	}
+
+	keyID := path.Join("/", s.accountName, "keys", s.keypair.FingerprintMD5)
+
+	return fmt.Sprintf(signatureFormat, keyID, algorithm, signed), nil
+}
+
+// SignRaw signs toSign, returning the base64 encoded signature and algorithm.
+func (s *KeySigner) SignRaw(toSign string) (string, string, error) {
+	var (
+		signed []byte
+		err    error
+	)
+
+	switch key := s.keypair.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		digest := crypto.SHA256.New()
+		digest.Write([]byte(toSign))
+		signed, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest.Sum(nil))
+	case *ecdsa.PrivateKey:
+		digest := crypto.SHA256.New()
+		digest.Write([]byte(toSign))
+		signed, err = key.Sign(rand.Reader, digest.Sum(nil), crypto.SHA256)
+	case ed25519.PrivateKey:
+		signed = ed25519.Sign(key, []byte(toSign))
+	default:
+		return "", "", fmt.Errorf("unsupported private key: %T", key)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(signed), s.DefaultAlgorithm(), nil
+}
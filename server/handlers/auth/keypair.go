@@ -3,14 +3,21 @@ package auth
 import (
 	"bufio"
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	stded25519 "crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
 	"fmt"
+	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/sean-/seed"
+	"golang.org/x/crypto/ed25519"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -18,41 +25,121 @@ func init() {
 	seed.MustInit()
 }
 
+// KeyType names the kind of key generated for the TSG management key.
+type KeyType string
+
+const (
+	KeyTypeRSA     KeyType = "rsa"
+	KeyTypeECDSA   KeyType = "ecdsa"
+	KeyTypeEd25519 KeyType = "ed25519"
+
+	// DefaultKeyType is used when no key type has been configured.
+	DefaultKeyType = KeyTypeRSA
+
+	// rsaKeyBits is the size of generated RSA keys.
+	rsaKeyBits = 4096
+)
+
+// ParseKeyType parses the name of a key type, returning DefaultKeyType for an
+// empty string.
+func ParseKeyType(s string) (KeyType, error) {
+	switch keyType := KeyType(strings.ToLower(s)); keyType {
+	case "":
+		return DefaultKeyType, nil
+	case KeyTypeRSA, KeyTypeECDSA, KeyTypeEd25519:
+		return keyType, nil
+	default:
+		return "", fmt.Errorf("unsupported key type: %q (supported types: %s, %s, %s)",
+			s, KeyTypeRSA, KeyTypeECDSA, KeyTypeEd25519)
+	}
+}
+
 type KeyPair struct {
 	PublicKey      ssh.PublicKey
-	PrivateKey     *rsa.PrivateKey
+	PrivateKey     crypto.Signer
 	FingerprintMD5 string
 
 	publicKeyBase64 string
 	privateKeyPEM   string
 }
 
-func NewKeyPair(bits int) (*KeyPair, error) {
-	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+// GenerateKeyPair generates a new KeyPair of the given type. RSA keys are 4096
+// bits and ECDSA keys use the P-256 curve.
+func GenerateKeyPair(keyType KeyType) (*KeyPair, error) {
+	var (
+		privateKey crypto.Signer
+		err        error
+	)
+
+	switch keyType {
+	case KeyTypeRSA, "":
+		privateKey, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case KeyTypeECDSA:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeEd25519:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported key type: %q", keyType)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	sshPublicKey, err := ssh.NewPublicKey(&privateKey.PublicKey)
+	return newKeyPair(privateKey)
+}
+
+// NewKeyPair generates a new RSA KeyPair with the given number of bits.
+func NewKeyPair(bits int) (*KeyPair, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
 	if err != nil {
 		return nil, err
 	}
-	fingerprintMD5 := ssh.FingerprintLegacyMD5(sshPublicKey)
 
-	return &KeyPair{
-		PrivateKey:     privateKey,
-		PublicKey:      sshPublicKey,
-		FingerprintMD5: fingerprintMD5,
-	}, nil
+	return newKeyPair(privateKey)
 }
 
+// DecodeKeyPair decodes the PEM encoded private key material stored within the
+// tsg_keys table.
 func DecodeKeyPair(material string) (*KeyPair, error) {
-	privateKey, err := x509.ParsePKCS1PrivateKey([]byte(material))
-	if err != nil {
-		return nil, err
+	block, _ := pem.Decode([]byte(material))
+	if block == nil {
+		return nil, errors.New("failed to decode key material")
 	}
 
-	sshPublicKey, err := ssh.NewPublicKey(&privateKey.PublicKey)
+	var privateKey crypto.Signer
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		privateKey = key
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		privateKey = key
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		edKey, ok := key.(stded25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("unsupported PKCS#8 key type: %T", key)
+		}
+		privateKey = ed25519.PrivateKey(edKey)
+	default:
+		return nil, fmt.Errorf("unsupported key material: %q", block.Type)
+	}
+
+	return newKeyPair(privateKey)
+}
+
+func newKeyPair(privateKey crypto.Signer) (*KeyPair, error) {
+	sshPublicKey, err := ssh.NewPublicKey(privateKey.Public())
 	if err != nil {
 		return nil, err
 	}
@@ -65,14 +152,46 @@ func DecodeKeyPair(material string) (*KeyPair, error) {
 	}, nil
 }
 
+// Type returns the type of the KeyPair.
+func (kp *KeyPair) Type() KeyType {
+	switch kp.PrivateKey.(type) {
+	case *ecdsa.PrivateKey:
+		return KeyTypeECDSA
+	case ed25519.PrivateKey:
+		return KeyTypeEd25519
+	default:
+		return KeyTypeRSA
+	}
+}
+
 func (kp *KeyPair) genPrivateKeyPEM() error {
+	privatePEMBlock := &pem.Block{}
+
+	switch key := kp.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		privatePEMBlock.Type = "RSA PRIVATE KEY"
+		privatePEMBlock.Bytes = x509.MarshalPKCS1PrivateKey(key)
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return err
+		}
+		privatePEMBlock.Type = "EC PRIVATE KEY"
+		privatePEMBlock.Bytes = der
+	case ed25519.PrivateKey:
+		der, err := x509.MarshalPKCS8PrivateKey(stded25519.PrivateKey(key))
+		if err != nil {
+			return err
+		}
+		privatePEMBlock.Type = "PRIVATE KEY"
+		privatePEMBlock.Bytes = der
+	default:
+		return fmt.Errorf("unsupported private key: %T", key)
+	}
+
 	privateKeyBuff := &bytes.Buffer{}
 	privatePEM := bufio.NewWriter(privateKeyBuff)
 
-	privatePEMBlock := &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(kp.PrivateKey),
-	}
 	if err := pem.Encode(privatePEM, privatePEMBlock); err != nil {
 		return err
 	}
@@ -98,7 +217,7 @@ func (kp *KeyPair) PrivateKeyPEM() string {
 
 func (kp *KeyPair) genPublicKeyBase64() {
 	publicKeyBase := base64.StdEncoding.EncodeToString(kp.PublicKey.Marshal())
-	kp.publicKeyBase64 = "ssh-rsa " + string(publicKeyBase)
+	kp.publicKeyBase64 = kp.PublicKey.Type() + " " + publicKeyBase
 }
 
 func (kp *KeyPair) PublicKeyBase64() string {
@@ -0,0 +1,69 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/server/handlers/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseKeyType(t *testing.T) {
+	keyType, err := auth.ParseKeyType("")
+	require.NoError(t, err)
+	assert.Equal(t, auth.DefaultKeyType, keyType)
+
+	keyType, err = auth.ParseKeyType("Ed25519")
+	require.NoError(t, err)
+	assert.Equal(t, auth.KeyTypeEd25519, keyType)
+
+	_, err = auth.ParseKeyType("dsa")
+	assert.Error(t, err)
+}
+
+func TestGenerateKeyPair(t *testing.T) {
+	for _, keyType := range []auth.KeyType{
+		auth.KeyTypeRSA,
+		auth.KeyTypeECDSA,
+		auth.KeyTypeEd25519,
+	} {
+		t.Run(string(keyType), func(t *testing.T) {
+			keypair, err := auth.GenerateKeyPair(keyType)
+			require.NoError(t, err)
+			assert.Equal(t, keyType, keypair.Type())
+
+			pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(keypair.PublicKeyBase64()))
+			require.NoError(t, err)
+			assert.Equal(t, keypair.FingerprintMD5, ssh.FingerprintLegacyMD5(pub))
+
+			material := keypair.PrivateKeyPEM()
+			require.NotEmpty(t, material)
+
+			decoded, err := auth.DecodeKeyPair(material)
+			require.NoError(t, err)
+			assert.Equal(t, keyType, decoded.Type())
+			assert.Equal(t, keypair.FingerprintMD5, decoded.FingerprintMD5)
+
+			signer := auth.NewKeySigner(decoded, "testaccount")
+			header, err := signer.Sign(testDate, false)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/tsg/groups", nil)
+			req.Header.Set("Date", testDate)
+			req.Header.Set("Authorization", header)
+
+			sig, err := auth.ParseSignature(header)
+			require.NoError(t, err)
+			assert.Equal(t, "/testaccount/keys/"+keypair.FingerprintMD5, sig.KeyID)
+			assert.NoError(t, sig.Verify(req, pub))
+		})
+	}
+}
+
+func TestDecodeKeyPairInvalid(t *testing.T) {
+	_, err := auth.DecodeKeyPair("not a key")
+	assert.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"github.com/joyent/triton-service-groups/users"
+)
+
+const (
+	// ProviderTritonSignature authenticates requests signed with a Triton key,
+	// or presenting an API token. It's the default provider.
+	ProviderTritonSignature = "triton-signature"
+
+	// ProviderStatic authenticates requests presenting one of the configured
+	// static tokens as the seeded account the token is mapped to. It never
+	// consults CloudAPI and is only meant for development and testing.
+	ProviderStatic = "static"
+)
+
+// StaticAccount maps a static token to an account seeded within the TSG
+// database.
+type StaticAccount struct {
+	Token       string
+	AccountName string
+
+	// Permission granted to requests presenting the token. Defaults to
+	// users.PermissionOwner.
+	Permission users.Permission
+}
+
+// Validate returns an error when the configured provider is unknown, or when
+// the static provider is configured without explicitly allowing insecure
+// authentication.
+func (c Config) Validate() error {
+	switch c.Provider {
+	case "", ProviderTritonSignature:
+		return nil
+	case ProviderStatic:
+	default:
+		return fmt.Errorf("unsupported auth provider: %q (supported providers: %s %s)",
+			c.Provider, ProviderTritonSignature, ProviderStatic)
+	}
+
+	if !c.Insecure {
+		return fmt.Errorf("refusing to use the %q auth provider unless auth.insecure is set", ProviderStatic)
+	}
+
+	if len(c.StaticAccounts) == 0 {
+		return fmt.Errorf("the %q auth provider requires at least one auth.static account", ProviderStatic)
+	}
+
+	for _, static := range c.StaticAccounts {
+		if static.Token == "" || static.AccountName == "" {
+			return fmt.Errorf("auth.static accounts require both a token and an account")
+		}
+		if static.Permission != "" && !static.Permission.Allows(users.PermissionReadOnly) {
+			return fmt.Errorf("unsupported permission for static account %q: %q", static.AccountName, static.Permission)
+		}
+	}
+
+	return nil
+}
+
+// IsStatic returns true when requests are authenticated by the static
+// provider.
+func (c Config) IsStatic() bool {
+	return c.Provider == ProviderStatic
+}
+
+// FindStaticAccount returns the static account mapped to token.
+func (c Config) FindStaticAccount(token string) (StaticAccount, bool) {
+	for _, static := range c.StaticAccounts {
+		if subtle.ConstantTimeCompare([]byte(static.Token), []byte(token)) == 1 {
+			return static, true
+		}
+	}
+	return StaticAccount{}, false
+}
+
+// BearerToken returns the token presented within authHeader, the
+// Authorization header of a request, if any.
+func BearerToken(authHeader string) (string, bool) {
+	if !strings.HasPrefix(authHeader, bearerPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(authHeader, bearerPrefix)), true
+}
@@ -3,12 +3,19 @@ package auth
 import (
 	"context"
 	"net/http"
-	"os"
+	"path"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx"
 	"github.com/joyent/triton-service-groups/accounts"
+	"github.com/joyent/triton-service-groups/allowlist"
 	"github.com/joyent/triton-service-groups/keys"
+	"github.com/joyent/triton-service-groups/server/requestlog"
+	"github.com/joyent/triton-service-groups/sessions"
+	"github.com/joyent/triton-service-groups/tokens"
+	"github.com/joyent/triton-service-groups/users"
 	"github.com/pkg/errors"
-	"github.com/rs/zerolog/log"
 )
 
 // authSession a private struct which is only accessible by pulling out of the
@@ -21,25 +28,52 @@ type Session struct {
 	Datacenter  string
 	TritonURL   string
 
-	devMode bool
-	config  Config
+	// UserID is the identifier of the tsg_users row of an authenticated
+	// sub-user, empty when the request was signed by the account owner.
+	UserID     string
+	Permission users.Permission
+
+	// TokenID is the identifier of the API token the request was
+	// authenticated with, empty when the request was signed with a Triton
+	// key. GroupIDs limits a token to the listed groups.
+	TokenID  string
+	GroupIDs []string
+
+	// SessionID is the identifier of the browser session the request was
+	// authenticated with by cookie, empty otherwise.
+	SessionID string
+
+	token     string
+	tokenName string
+	cookie    string
+	browser   *sessions.Session
+	config    Config
 }
 
 // NewSession constructs and returns a new Session by parsing the HTTP request,
 // validating and pulling out authentication headers.
 func NewSession(req *http.Request, cfg Config) (*Session, error) {
-	if devMode := os.Getenv("TSG_DEV_MODE"); devMode != "" {
-		log.Debug().
-			Str("account_id", testAccountID).
-			Str("fingerprint", testFingerprint).
-			Msg("auth: skipping authentication and using seeded defaults")
+	if token, ok := BearerToken(req.Header.Get("Authorization")); ok {
+		if token == "" {
+			return &Session{}, ErrBadToken
+		}
+
+		return &Session{
+			Datacenter: cfg.Datacenter,
+			TritonURL:  cfg.TritonURL,
+			token:      token,
+			config:     cfg,
+		}, nil
+	}
+
+	if HasCookie(req) {
+		cookie, _ := req.Cookie(CookieName)
 
 		return &Session{
-			AccountID:   testAccountID,
-			Fingerprint: testFingerprint,
-			Datacenter:  cfg.Datacenter,
-			TritonURL:   cfg.TritonURL,
-			devMode:     true,
+			Datacenter: cfg.Datacenter,
+			TritonURL:  cfg.TritonURL,
+			cookie:     cookie.Value,
+			config:     cfg,
 		}, nil
 	}
 
@@ -56,8 +90,64 @@ func NewSession(req *http.Request, cfg Config) (*Session, error) {
 	}, nil
 }
 
-func (s *Session) IsDevMode() bool {
-	return s.devMode
+// Config returns the authentication configuration the session was created
+// with.
+func (s *Session) Config() Config {
+	return s.config
+}
+
+// IsToken returns true when the request presented an API token rather than
+// an HTTP signature.
+func (s *Session) IsToken() bool {
+	return s.token != "" || s.TokenID != ""
+}
+
+// AllowsGroup returns true if the session can act on the group. Only API
+// tokens are ever limited to specific groups.
+func (s *Session) AllowsGroup(groupID string) bool {
+	if len(s.GroupIDs) == 0 {
+		return true
+	}
+
+	for _, id := range s.GroupIDs {
+		if strings.EqualFold(id, groupID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsSubUser returns true when the request was signed with the key of a Triton
+// sub-user rather than the account owner.
+func (s *Session) IsSubUser() bool {
+	return s.ParsedRequest != nil && s.UserName != ""
+}
+
+// IsManagementKey returns true when the request was signed with the key TSG
+// manages for the account, which is only ever handed to the account's scaling
+// jobs.
+func (s *Session) IsManagementKey() bool {
+	if s.ParsedRequest == nil || s.IsToken() || s.IsCookie() || s.IsSubUser() {
+		return false
+	}
+	return s.Fingerprint != "" && strings.EqualFold(s.ParsedRequest.Fingerprint, s.Fingerprint)
+}
+
+// Actor names who is responsible for the request, either the account name,
+// "account/user" for sub-users or "account/tokens/name" for API tokens. It's
+// recorded against every change.
+func (s *Session) Actor() string {
+	if s.ParsedRequest == nil {
+		return ""
+	}
+	if s.IsToken() {
+		return path.Join(s.AccountName, "tokens", s.tokenName)
+	}
+	if s.IsSubUser() {
+		return path.Join(s.AccountName, s.UserName)
+	}
+	return s.AccountName
 }
 
 // IsAuthenticated represents whatever it means for an authSession to be deemed
@@ -66,33 +156,83 @@ func (s *Session) IsAuthenticated() bool {
 	return s.AccountID != "" && s.Fingerprint != ""
 }
 
-// EnsureAccount ensures that a Triton account is authentic and an account has
-// been created for it within the TSG database. Returns the TSG account that was
-// either created or found.
-func (s *Session) EnsureAccount(ctx context.Context, store *accounts.Store) (*accounts.Account, error) {
-	check := NewAccountCheck(s.ParsedRequest, store, s.config)
+// VerifySignature verifies the request signature and Date header locally
+// against the public key of the signer. CloudAPI is only consulted when the
+// signing key isn't cached.
+func (s *Session) VerifySignature(ctx context.Context, req *http.Request, cache *KeyCache) error {
+	skew := s.config.MaxClockSkew
+	if skew <= 0 {
+		skew = DefaultClockSkew
+	}
+
+	if err := CheckDate(req, time.Now(), skew); err != nil {
+		return err
+	}
+
+	sig, err := ParseSignature(req.Header.Get("Authorization"))
+	if err != nil {
+		return err
+	}
+
+	pub, err := FetchSignerKey(ctx, s.ParsedRequest, cache, s.config)
+	if err != nil {
+		return err
+	}
+
+	return sig.Verify(req, pub)
+}
+
+// EnsureAccount ensures that an account has been created for the Triton
+// account within the TSG database. CloudAPI is only consulted for accounts which
+// haven't been seen before. Accounts are checked against the allow-list on every
+// request, so denying an account takes effect immediately. Returns the TSG
+// account that was either created or found.
+func (s *Session) EnsureAccount(ctx context.Context, store *accounts.Store, allowList *allowlist.Store) (*accounts.Account, error) {
+	acct, err := store.FindByName(ctx, s.AccountName)
+	switch err {
+	case nil:
+		if err := checkAllowList(ctx, allowList, acct.AccountName, true, s.config.EnableWhitelist); err != nil {
+			return nil, err
+		}
+
+		s.AccountID = acct.ID
+
+		requestlog.FromContext(ctx).Debug().
+			Str("account_id", s.AccountID).
+			Str("account_name", acct.AccountName).
+			Msg("auth: session account found in database")
+
+		return acct, nil
+	case pgx.ErrNoRows:
+	default:
+		err = errors.Wrap(err, "failed to check database for account")
+		requestlog.FromContext(ctx).Error().Err(err)
+		return nil, err
+	}
+
+	check := NewAccountCheck(s.ParsedRequest, store, allowList, s.config)
 
 	if err := check.OnTriton(ctx); err != nil {
 		err = errors.Wrap(err, "failed to check triton for account")
-		log.Error().Err(err)
+		requestlog.FromContext(ctx).Error().Err(err)
 		return nil, err
 	}
 
 	if !check.HasTritonAccount() {
 		err := errors.New("could not authenticate account with triton")
-		log.Error().Err(err)
+		requestlog.FromContext(ctx).Error().Err(err)
 		return nil, err
 	}
 
 	if err := check.SaveAccount(ctx); err != nil {
 		err := errors.Wrap(err, "failed to save account in database")
-		log.Error().Err(err)
+		requestlog.FromContext(ctx).Error().Err(err)
 		return nil, err
 	}
 
 	s.AccountID = check.Account.ID
 
-	log.Debug().
+	requestlog.FromContext(ctx).Debug().
 		Str("account_id", s.AccountID).
 		Str("account_name", check.Account.AccountName).
 		Msg("auth: session account has been authenticated")
@@ -102,25 +242,25 @@ func (s *Session) EnsureAccount(ctx context.Context, store *accounts.Store) (*ac
 
 // EnsureKey checks Triton for an active TSG account key. If one cannot be found
 // than a new key is created and stored it into the TSG database.
-func (s *Session) EnsureKeys(ctx context.Context, acct *accounts.Account, store *keys.Store) error {
-	check := NewKeyCheck(s.ParsedRequest, acct, store, s.config)
+func (s *Session) EnsureKeys(ctx context.Context, acct *accounts.Account, store *keys.Store, cache *KeyCache) error {
+	check := NewKeyCheck(s.ParsedRequest, acct, store, cache, s.config)
 
-	if err := check.OnTriton(ctx); err != nil {
-		err = errors.Wrap(err, "failed to check triton for key")
-		log.Error().Err(err)
+	if err := check.InDatabase(ctx); err != nil {
+		err = errors.Wrap(err, "failed to check database for key")
+		requestlog.FromContext(ctx).Error().Err(err)
 		return err
 	}
 
-	if err := check.InDatabase(ctx); err != nil {
-		err = errors.Wrap(err, "failed to check database for key")
-		log.Error().Err(err)
+	if err := check.OnTriton(ctx); err != nil {
+		err = errors.Wrap(err, "failed to check triton for key")
+		requestlog.FromContext(ctx).Error().Err(err)
 		return err
 	}
 
 	if check.HasKey() {
 		if check.HasTritonKey() {
 			if check.Key.Fingerprint == check.TritonKey.Fingerprint {
-				log.Debug().
+				requestlog.FromContext(ctx).Debug().
 					Str("account_name", acct.AccountName).
 					Str("fingerprint", check.Key.Fingerprint).
 					Msg("auth: found existing keys with matching fingerprint")
@@ -130,7 +270,7 @@ func (s *Session) EnsureKeys(ctx context.Context, acct *accounts.Account, store
 				return nil
 			}
 
-			log.Error().
+			requestlog.FromContext(ctx).Error().
 				Str("account_name", acct.AccountName).
 				Err(ErrKeyConflict)
 			return ErrKeyConflict
@@ -138,51 +278,51 @@ func (s *Session) EnsureKeys(ctx context.Context, acct *accounts.Account, store
 			keypair, err := DecodeKeyPair(check.Key.Material)
 			if err != nil {
 				err = errors.Wrap(err, "failed to generate new keypair")
-				log.Error().Err(err)
+				requestlog.FromContext(ctx).Error().Err(err)
 				return err
 			}
 
 			err = check.AddTritonKey(ctx, keypair)
 			if err != nil {
 				err = errors.Wrap(err, "failed to add new key")
-				log.Error().Err(err)
+				requestlog.FromContext(ctx).Error().Err(err)
 				return err
 			}
 		}
 	} else {
 		if check.HasTritonKey() {
 			err := errors.New("auth: found key in triton but not in tsg")
-			log.Error().
+			requestlog.FromContext(ctx).Error().
 				Str("account_name", acct.AccountName).
 				Str("fingerprint", check.TritonKey.Fingerprint).
 				Err(err)
 			return err
 		}
 
-		keypair, err := NewKeyPair(1024)
+		keypair, err := GenerateKeyPair(s.config.KeyType)
 		if err != nil {
 			err = errors.Wrap(err, "failed to generate new keypair")
-			log.Error().Err(err)
+			requestlog.FromContext(ctx).Error().Err(err)
 			return err
 		}
 
 		if !check.HasTritonKey() {
 			if err := check.AddTritonKey(ctx, keypair); err != nil {
 				err = errors.Wrap(err, "failed to add new key")
-				log.Error().Err(err)
+				requestlog.FromContext(ctx).Error().Err(err)
 				return err
 			}
 		}
 
 		if err := check.InsertKey(ctx, keypair); err != nil {
 			err = errors.Wrap(err, "failed to save new key")
-			log.Error().Err(err)
+			requestlog.FromContext(ctx).Error().Err(err)
 			return err
 		}
 	}
 
 	if check.Key.Fingerprint == check.TritonKey.Fingerprint {
-		log.Debug().
+		requestlog.FromContext(ctx).Debug().
 			Str("account_name", acct.AccountName).
 			Str("fingerprint", check.Key.Fingerprint).
 			Msg("auth: detected matching fingerprints for service keys")
@@ -194,3 +334,176 @@ func (s *Session) EnsureKeys(ctx context.Context, acct *accounts.Account, store
 
 	return nil
 }
+
+// EnsureUser authorizes a sub-user against the permissions granted to them by
+// the account owner. Sub-users never create the account's management key, the
+// account owner must have authenticated at least once beforehand.
+func (s *Session) EnsureUser(ctx context.Context, acct *accounts.Account, userStore *users.Store, keyStore *keys.Store) error {
+	user, err := userStore.FindByName(ctx, s.UserName, acct.ID)
+	switch err {
+	case nil:
+	case pgx.ErrNoRows:
+		requestlog.FromContext(ctx).Debug().
+			Str("account_name", acct.AccountName).
+			Str("user_name", s.UserName).
+			Err(ErrUnknownUser)
+		return ErrUnknownUser
+	default:
+		err = errors.Wrap(err, "failed to check database for user")
+		requestlog.FromContext(ctx).Error().Err(err)
+		return err
+	}
+
+	if acct.KeyID == "" {
+		return ErrNoAccountKey
+	}
+
+	key, err := keyStore.FindByID(ctx, acct.KeyID)
+	switch err {
+	case nil:
+	case pgx.ErrNoRows:
+		return ErrNoAccountKey
+	default:
+		err = errors.Wrap(err, "failed to check database for key")
+		requestlog.FromContext(ctx).Error().Err(err)
+		return err
+	}
+
+	s.Fingerprint = key.Fingerprint
+	s.UserID = user.ID
+	s.Permission = user.Permission
+
+	requestlog.FromContext(ctx).Debug().
+		Str("account_name", acct.AccountName).
+		Str("user_name", user.UserName).
+		Str("permission", string(user.Permission)).
+		Msg("auth: session sub-user has been authorized")
+
+	return nil
+}
+
+// EnsureToken authenticates a request presenting an API token. The token must
+// neither have expired nor been revoked. Like sub-users, tokens share the
+// account's management key, so the account owner must have authenticated at
+// least once beforehand, and are rejected once the account has been denied.
+func (s *Session) EnsureToken(ctx context.Context, tokenStore *tokens.Store, acctStore *accounts.Store, keyStore *keys.Store, allowList *allowlist.Store) error {
+	token, err := tokenStore.FindBySecret(ctx, s.token)
+	switch err {
+	case nil:
+	case pgx.ErrNoRows:
+		return ErrBadToken
+	default:
+		err = errors.Wrap(err, "failed to check database for token")
+		requestlog.FromContext(ctx).Error().Err(err)
+		return err
+	}
+
+	if !token.IsActive(time.Now()) {
+		requestlog.FromContext(ctx).Debug().
+			Str("token_id", token.ID).
+			Bool("revoked", token.IsRevoked()).
+			Time("expires_at", token.ExpiresAt).
+			Err(ErrInactiveToken)
+		return ErrInactiveToken
+	}
+
+	acct, err := acctStore.FindByID(ctx, token.AccountID)
+	if err != nil {
+		err = errors.Wrap(err, "failed to find token account")
+		requestlog.FromContext(ctx).Error().Err(err)
+		return err
+	}
+
+	if err := checkAllowList(ctx, allowList, acct.AccountName, true, s.config.EnableWhitelist); err != nil {
+		return err
+	}
+
+	if acct.KeyID == "" {
+		return ErrNoAccountKey
+	}
+
+	key, err := keyStore.FindByID(ctx, acct.KeyID)
+	switch err {
+	case nil:
+	case pgx.ErrNoRows:
+		return ErrNoAccountKey
+	default:
+		err = errors.Wrap(err, "failed to check database for key")
+		requestlog.FromContext(ctx).Error().Err(err)
+		return err
+	}
+
+	s.ParsedRequest = &ParsedRequest{
+		AccountName: acct.AccountName,
+	}
+	s.AccountID = acct.ID
+	s.Fingerprint = key.Fingerprint
+	s.TokenID = token.ID
+	s.GroupIDs = token.GroupIDs
+	s.Permission = token.Permission
+	s.tokenName = token.Name
+
+	requestlog.FromContext(ctx).Debug().
+		Str("account_name", acct.AccountName).
+		Str("token_name", token.Name).
+		Str("permission", string(token.Permission)).
+		Msg("auth: session token has been authenticated")
+
+	return nil
+}
+
+// EnsureStatic authenticates a request presenting a static token as the seeded
+// account the token is mapped to. Unlike the other Ensure methods, CloudAPI is
+// never consulted, so the account and its key must already exist within the
+// TSG database.
+func (s *Session) EnsureStatic(ctx context.Context, acctStore *accounts.Store, keyStore *keys.Store) error {
+	static, ok := s.config.FindStaticAccount(s.token)
+	if !ok {
+		return ErrBadToken
+	}
+
+	acct, err := acctStore.FindByName(ctx, static.AccountName)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to find static account %q", static.AccountName)
+		requestlog.FromContext(ctx).Error().Err(err)
+		return err
+	}
+
+	if acct.KeyID == "" {
+		return ErrNoAccountKey
+	}
+
+	key, err := keyStore.FindByID(ctx, acct.KeyID)
+	switch err {
+	case nil:
+	case pgx.ErrNoRows:
+		return ErrNoAccountKey
+	default:
+		err = errors.Wrap(err, "failed to check database for key")
+		requestlog.FromContext(ctx).Error().Err(err)
+		return err
+	}
+
+	permission := static.Permission
+	if permission == "" {
+		permission = users.PermissionOwner
+		if s.config.IsOperator(acct.AccountName) {
+			permission = users.PermissionOperator
+		}
+	}
+
+	s.ParsedRequest = &ParsedRequest{
+		AccountName: acct.AccountName,
+	}
+	s.AccountID = acct.ID
+	s.Fingerprint = key.Fingerprint
+	s.Permission = permission
+	s.token = ""
+
+	requestlog.FromContext(ctx).Debug().
+		Str("account_name", acct.AccountName).
+		Str("permission", string(permission)).
+		Msg("auth: session authenticated by static provider")
+
+	return nil
+}
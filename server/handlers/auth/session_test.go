@@ -0,0 +1,76 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-service-groups/server/handlers/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionActor(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    string
+		subUser bool
+		actor   string
+	}{
+		{"account owner", authHeader, false, "testaccount"},
+		{"sub-user", authUserHeader, true, "testaccount/demouser"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			session, err := auth.NewSession(newAuthRequest(test.auth, dateHeader), auth.Config{})
+			require.NoError(t, err)
+
+			assert.Equal(t, test.subUser, session.IsSubUser())
+			assert.Equal(t, test.actor, session.Actor())
+		})
+	}
+
+	assert.Equal(t, "", (&auth.Session{}).Actor())
+}
+
+func TestSessionBearerToken(t *testing.T) {
+	session, err := auth.NewSession(newAuthRequest("Bearer tsg_secret", dateHeader), auth.Config{})
+	require.NoError(t, err)
+
+	assert.True(t, session.IsToken())
+	assert.False(t, session.IsSubUser())
+	assert.False(t, session.IsAuthenticated())
+	assert.True(t, session.AllowsGroup("6f873d02-172c-418f-8416-4da2b50d5c53"))
+
+	session.GroupIDs = []string{"6f873d02-172c-418f-8416-4da2b50d5c53"}
+	assert.True(t, session.AllowsGroup("6F873D02-172C-418F-8416-4DA2B50D5C53"))
+	assert.False(t, session.AllowsGroup("d255305d-aa60-49bc-acc2-3713cf0beb1c"))
+
+	_, err = auth.NewSession(newAuthRequest("Bearer ", dateHeader), auth.Config{})
+	assert.Equal(t, auth.ErrBadToken, err)
+}
+
+func TestSessionIsManagementKey(t *testing.T) {
+	const fingerprint = "12:23:34:45:56:67:78:89:90:0a:ab:bc:cd:de:ad:01"
+
+	tests := []struct {
+		name        string
+		auth        string
+		fingerprint string
+		expected    bool
+	}{
+		{"management key", authHeader, fingerprint, true},
+		{"other key", authHeader, "a1:b2:c3:d4:e5:f6:a7:b8:c9:d0:e1:f2:a3:b4:c5:d6", false},
+		{"sub-user", authUserHeader, fingerprint, false},
+		{"token", "Bearer tsg_secret", fingerprint, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			session, err := auth.NewSession(newAuthRequest(test.auth, dateHeader), auth.Config{})
+			require.NoError(t, err)
+
+			session.Fingerprint = test.fingerprint
+			assert.Equal(t, test.expected, session.IsManagementKey())
+		})
+	}
+
+	assert.False(t, (&auth.Session{}).IsManagementKey())
+}
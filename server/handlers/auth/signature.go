@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha1" // register hash for rsa-sha1 signatures
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/asn1"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultClockSkew is the maximum difference allowed between the Date header of
+// a request and the local clock when none has been configured.
+const DefaultClockSkew = 5 * time.Minute
+
+var matchSigParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// Signature holds the parameters of an HTTP Signature Authorization header.
+type Signature struct {
+	KeyID     string
+	Algorithm string
+	Headers   []string
+	Value     []byte
+}
+
+// ParseSignature parses the value of an Authorization header using the HTTP
+// Signature scheme. The signature is either the "signature" parameter or, for
+// older clients, the base64 value trailing the parameters. Signatures must
+// cover the Date header.
+func ParseSignature(header string) (*Signature, error) {
+	if !strings.HasPrefix(header, "Signature ") {
+		return nil, ErrMissingSig
+	}
+	header = strings.TrimPrefix(header, "Signature ")
+
+	sig := &Signature{
+		Headers: []string{"date"},
+	}
+
+	var value string
+	for _, match := range matchSigParam.FindAllStringSubmatch(header, -1) {
+		switch strings.ToLower(match[1]) {
+		case "keyid":
+			sig.KeyID = match[2]
+		case "algorithm":
+			sig.Algorithm = strings.ToLower(match[2])
+		case "headers":
+			sig.Headers = strings.Fields(strings.ToLower(match[2]))
+		case "signature":
+			value = match[2]
+		}
+	}
+
+	if value == "" {
+		trailing := strings.TrimSpace(header[strings.LastIndex(header, `"`)+1:])
+		value = strings.TrimSpace(strings.TrimPrefix(trailing, ","))
+	}
+
+	if sig.KeyID == "" || sig.Algorithm == "" || value == "" {
+		return nil, ErrMissingSig
+	}
+
+	// The Date header is only checked against the clock when it's signed,
+	// otherwise a captured request could be replayed with a fresh date.
+	if !sig.covers("date") {
+		return nil, ErrUnsignedDate
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrMissingSig
+	}
+	sig.Value = decoded
+
+	return sig, nil
+}
+
+// covers returns true if name is one of the signed headers.
+func (sig *Signature) covers(name string) bool {
+	for _, header := range sig.Headers {
+		if header == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SigningStrings returns every string the client may have signed. Clients
+// following the HTTP Signatures draft sign "date: <value>", while older Triton
+// clients only sign the raw value of the Date header.
+func (sig *Signature) SigningStrings(req *http.Request) ([]string, error) {
+	lines := make([]string, 0, len(sig.Headers))
+	for _, name := range sig.Headers {
+		if name == "(request-target)" {
+			lines = append(lines, name+": "+strings.ToLower(req.Method)+" "+req.URL.RequestURI())
+			continue
+		}
+
+		value := req.Header.Get(name)
+		if value == "" {
+			return nil, ErrBadSignature
+		}
+		lines = append(lines, name+": "+value)
+	}
+
+	signing := []string{strings.Join(lines, "\n")}
+	if len(sig.Headers) == 1 && sig.Headers[0] == "date" {
+		signing = append(signing, req.Header.Get("Date"))
+	}
+
+	return signing, nil
+}
+
+// Verify checks the signature of req against the public key of the signer.
+func (sig *Signature) Verify(req *http.Request, pub ssh.PublicKey) error {
+	signing, err := sig.SigningStrings(req)
+	if err != nil {
+		return err
+	}
+
+	for _, data := range signing {
+		if err := verifySignature(pub, sig.Algorithm, []byte(data), sig.Value); err == nil {
+			return nil
+		}
+	}
+
+	return ErrBadSignature
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+func verifySignature(pub ssh.PublicKey, algorithm string, data, sig []byte) error {
+	cryptoPub, ok := pub.(ssh.CryptoPublicKey)
+	if !ok {
+		return ErrBadAlgorithm
+	}
+
+	parts := strings.SplitN(algorithm, "-", 2)
+	if len(parts) != 2 {
+		return ErrBadAlgorithm
+	}
+	keyType, hashName := parts[0], parts[1]
+
+	var hash crypto.Hash
+	switch hashName {
+	case "sha1":
+		hash = crypto.SHA1
+	case "sha256":
+		hash = crypto.SHA256
+	case "sha384":
+		hash = crypto.SHA384
+	case "sha512":
+		hash = crypto.SHA512
+	default:
+		return ErrBadAlgorithm
+	}
+
+	switch key := cryptoPub.CryptoPublicKey().(type) {
+	case *rsa.PublicKey:
+		if keyType != "rsa" {
+			return ErrBadAlgorithm
+		}
+		h := hash.New()
+		h.Write(data)
+		return rsa.VerifyPKCS1v15(key, hash, h.Sum(nil), sig)
+	case *ecdsa.PublicKey:
+		if keyType != "ecdsa" {
+			return ErrBadAlgorithm
+		}
+		var es ecdsaSignature
+		if _, err := asn1.Unmarshal(sig, &es); err != nil {
+			return ErrBadSignature
+		}
+		h := hash.New()
+		h.Write(data)
+		if !ecdsa.Verify(key, h.Sum(nil), es.R, es.S) {
+			return ErrBadSignature
+		}
+		return nil
+	case ed25519.PublicKey:
+		if keyType != "ed25519" {
+			return ErrBadAlgorithm
+		}
+		if !ed25519.Verify(key, data, sig) {
+			return ErrBadSignature
+		}
+		return nil
+	default:
+		return ErrBadAlgorithm
+	}
+}
+
+// CheckDate ensures the Date header of req is within skew of now.
+func CheckDate(req *http.Request, now time.Time, skew time.Duration) error {
+	value := req.Header.Get("Date")
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		date, err = time.Parse(time.RFC1123, value)
+		if err != nil {
+			return ErrBadDate
+		}
+	}
+
+	diff := now.Sub(date)
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > skew {
+		return ErrClockSkew
+	}
+
+	return nil
+}
+
+// matchesFingerprint returns true if fingerprint identifies pub, either as a
+// legacy MD5 or SHA256 fingerprint.
+func matchesFingerprint(pub ssh.PublicKey, fingerprint string) bool {
+	if strings.HasPrefix(fingerprint, "SHA256:") {
+		return ssh.FingerprintSHA256(pub) == fingerprint
+	}
+	return strings.EqualFold(ssh.FingerprintLegacyMD5(pub), strings.TrimPrefix(fingerprint, "MD5:"))
+}
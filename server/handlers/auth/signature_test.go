@@ -0,0 +1,212 @@
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/joyent/triton-go/account"
+	"github.com/joyent/triton-service-groups/server/handlers/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+)
+
+const testDate = "Sat, 14 Apr 2018 21:45:59 GMT"
+
+func signedRequest(t *testing.T, signer crypto.Signer, algorithm, headers, data string, legacy bool) *http.Request {
+	var digest []byte
+	opts := crypto.Hash(0)
+	if algorithm != "ed25519-sha512" {
+		sum := sha256.Sum256([]byte(data))
+		digest, opts = sum[:], crypto.SHA256
+	} else {
+		digest = []byte(data)
+	}
+
+	sig, err := signer.Sign(rand.Reader, digest, opts)
+	require.NoError(t, err)
+	encoded := base64.StdEncoding.EncodeToString(sig)
+
+	keyID := "/testaccount/keys/12:23:34:45:56:67:78:89:90:0a:ab:bc:cd:de:ad:01"
+
+	var header string
+	if legacy {
+		header = fmt.Sprintf(`Signature keyId="%s",algorithm="%s",headers="%s" %s`, keyID, algorithm, headers, encoded)
+	} else {
+		header = fmt.Sprintf(`Signature keyId="%s",algorithm="%s",headers="%s",signature="%s"`, keyID, algorithm, headers, encoded)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/tsg/groups", nil)
+	req.Header.Set("Date", testDate)
+	req.Header.Set("Authorization", header)
+	return req
+}
+
+func TestSignatureVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	rsaPub, err := ssh.NewPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecPub, err := ssh.NewPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edPub, err := ssh.NewPublicKey(edPublic)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		signer    crypto.Signer
+		pub       ssh.PublicKey
+		algorithm string
+		headers   string
+		data      string
+		legacy    bool
+		err       error
+	}{
+		{"rsa date line", rsaKey, rsaPub, "rsa-sha256", "date", "date: " + testDate, false, nil},
+		{"rsa legacy date", rsaKey, rsaPub, "rsa-sha256", "date", testDate, true, nil},
+		{"rsa request target", rsaKey, rsaPub, "rsa-sha256", "(request-target) date", "(request-target): get /v1/tsg/groups\ndate: " + testDate, false, nil},
+		{"ecdsa", ecKey, ecPub, "ecdsa-sha256", "date", "date: " + testDate, false, nil},
+		{"ed25519", edPrivate, edPub, "ed25519-sha512", "date", "date: " + testDate, false, nil},
+		{"wrong data", rsaKey, rsaPub, "rsa-sha256", "date", "date: yesterday", false, auth.ErrBadSignature},
+		{"wrong key", rsaKey, ecPub, "rsa-sha256", "date", "date: " + testDate, false, auth.ErrBadSignature},
+		{"missing header", rsaKey, rsaPub, "rsa-sha256", "date host-name", "date: " + testDate, false, auth.ErrBadSignature},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := signedRequest(t, test.signer, test.algorithm, test.headers, test.data, test.legacy)
+
+			sig, err := auth.ParseSignature(req.Header.Get("Authorization"))
+			require.NoError(t, err)
+
+			err = sig.Verify(req, test.pub)
+			if test.err != nil {
+				assert.Equal(t, test.err, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestParseSignature(t *testing.T) {
+	sig, err := auth.ParseSignature(`Signature keyId="/testaccount/keys/aa:bb",algorithm="RSA-SHA256",signature="c2lnbmF0dXJl"`)
+	require.NoError(t, err)
+	assert.Equal(t, "/testaccount/keys/aa:bb", sig.KeyID)
+	assert.Equal(t, "rsa-sha256", sig.Algorithm)
+	assert.Equal(t, []string{"date"}, sig.Headers)
+	assert.Equal(t, []byte("signature"), sig.Value)
+
+	for _, header := range []string{
+		"",
+		"Basic dXNlcjpwYXNz",
+		`Signature keyId="/testaccount/keys/aa:bb",algorithm="rsa-sha256"`,
+		`Signature keyId="/testaccount/keys/aa:bb",algorithm="rsa-sha256",signature="!!!"`,
+	} {
+		_, err := auth.ParseSignature(header)
+		assert.Equal(t, auth.ErrMissingSig, err, header)
+	}
+}
+
+func TestParseSignatureWithoutDate(t *testing.T) {
+	for _, headers := range []string{"(request-target)", "host", ""} {
+		_, err := auth.ParseSignature(`Signature keyId="/testaccount/keys/aa:bb",algorithm="rsa-sha256",headers="` +
+			headers + `",signature="c2lnbmF0dXJl"`)
+		assert.Equal(t, auth.ErrUnsignedDate, err, headers)
+	}
+}
+
+func TestSignatureReplayWithNewDate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	rsaPub, err := ssh.NewPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name    string
+		headers string
+		data    string
+		legacy  bool
+	}{
+		{"date line", "date", "date: " + testDate, false},
+		{"legacy date", "date", testDate, true},
+		{"request target", "(request-target) date", "(request-target): get /v1/tsg/groups\ndate: " + testDate, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req := signedRequest(t, rsaKey, "rsa-sha256", test.headers, test.data, test.legacy)
+
+			sig, err := auth.ParseSignature(req.Header.Get("Authorization"))
+			require.NoError(t, err)
+			require.NoError(t, sig.Verify(req, rsaPub))
+
+			// Replaying the captured request with a fresh Date passes the
+			// clock check, but no longer matches the signature.
+			req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+			assert.NoError(t, auth.CheckDate(req, time.Now(), auth.DefaultClockSkew))
+			assert.Equal(t, auth.ErrBadSignature, sig.Verify(req, rsaPub))
+		})
+	}
+}
+
+func TestCheckDate(t *testing.T) {
+	now, err := http.ParseTime(testDate)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/tsg/groups", nil)
+
+	req.Header.Set("Date", testDate)
+	assert.NoError(t, auth.CheckDate(req, now.Add(4*time.Minute), 5*time.Minute))
+	assert.NoError(t, auth.CheckDate(req, now.Add(-4*time.Minute), 5*time.Minute))
+	assert.Equal(t, auth.ErrClockSkew, auth.CheckDate(req, now.Add(6*time.Minute), 5*time.Minute))
+	assert.Equal(t, auth.ErrClockSkew, auth.CheckDate(req, now.Add(-6*time.Minute), 5*time.Minute))
+
+	req.Header.Set("Date", "Sat, 14 Apr 2018 21:45:59 UTC")
+	assert.NoError(t, auth.CheckDate(req, now, 5*time.Minute))
+
+	req.Header.Set("Date", "yesterday")
+	assert.Equal(t, auth.ErrBadDate, auth.CheckDate(req, now, 5*time.Minute))
+}
+
+func TestFetchSignerKeyFromCache(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	pub, err := ssh.NewPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+
+	fingerprint := ssh.FingerprintLegacyMD5(pub)
+	key := &account.Key{
+		Name:        "laptop",
+		Fingerprint: fingerprint,
+		Key:         string(ssh.MarshalAuthorizedKey(pub)),
+	}
+
+	cache := auth.NewKeyCache(time.Minute)
+	cache.Add("testaccount", "", fingerprint, key)
+	cache.Add("testaccount", "", "aa:bb", key)
+
+	req := &auth.ParsedRequest{AccountName: "testaccount", Fingerprint: fingerprint}
+	found, err := auth.FetchSignerKey(context.Background(), req, cache, auth.Config{})
+	require.NoError(t, err)
+	assert.Equal(t, pub.Marshal(), found.Marshal())
+
+	req = &auth.ParsedRequest{AccountName: "testaccount", Fingerprint: "aa:bb"}
+	_, err = auth.FetchSignerKey(context.Background(), req, cache, auth.Config{})
+	assert.Equal(t, auth.ErrSignerKey, err)
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/accounts"
+	"github.com/joyent/triton-service-groups/allowlist"
+	"github.com/joyent/triton-service-groups/keys"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/handlers/auth"
+	"github.com/joyent/triton-service-groups/server/requestlog"
+	"github.com/joyent/triton-service-groups/sessions"
+	"github.com/joyent/triton-service-groups/tokens"
+	"github.com/joyent/triton-service-groups/users"
+)
+
+// authProvider authenticates requests for the authHandler. When a request
+// can't be authenticated the provider responds to it and returns false.
+type authProvider interface {
+	Authenticate(w http.ResponseWriter, req *http.Request) (*auth.Session, bool)
+}
+
+// signatureProvider authenticates requests signed with the key of a Triton
+// account or sub-user, checked against CloudAPI, as well as API tokens and
+// browser sessions.
+type signatureProvider struct {
+	pool   *pgx.ConnPool
+	config auth.Config
+	keys   *auth.KeyCache
+}
+
+func (p signatureProvider) Authenticate(w http.ResponseWriter, req *http.Request) (*auth.Session, bool) {
+	ctx := req.Context()
+
+	session, ok := newSession(w, req, p.config)
+	if !ok {
+		return nil, false
+	}
+
+	if session.IsCookie() {
+		return session, ensureCookie(w, req, p.pool, session)
+	}
+
+	if session.IsToken() {
+		return session, ensureToken(w, req, p.pool, session)
+	}
+
+	if err := session.VerifySignature(ctx, req, p.keys); err != nil {
+		requestlog.FromContext(ctx).Debug().
+			Str("module", "auth").
+			Err(err).
+			Msg("auth: failed to authenticate request")
+		apierror.Write(w, req, ErrFailedAuth)
+		return nil, false
+	}
+
+	accountStore := accounts.NewStore(p.pool)
+
+	acct, err := session.EnsureAccount(ctx, accountStore, allowlist.NewStore(p.pool))
+	if err != nil {
+		requestlog.FromContext(ctx).Debug().
+			Str("module", "auth").
+			Err(err).
+			Msg("auth: failed to authenticate account")
+		apierror.Write(w, req, ErrFailedAccount)
+		return nil, false
+	}
+
+	keyring, _ := GetKeyring(ctx)
+	keyStore := keys.NewStore(p.pool, keyring)
+
+	if session.IsSubUser() {
+		userStore := users.NewStore(p.pool)
+
+		if err := session.EnsureUser(ctx, acct, userStore, keyStore); err != nil {
+			requestlog.FromContext(ctx).Debug().
+				Str("module", "auth").
+				Err(err).
+				Msg("auth: failed to authorize sub-user")
+			apierror.Write(w, req, ErrFailedUser)
+			return nil, false
+		}
+
+		return session, true
+	}
+
+	if err := session.EnsureKeys(ctx, acct, keyStore, p.keys); err != nil {
+		requestlog.FromContext(ctx).Debug().
+			Str("module", "auth").
+			Err(err).
+			Msg("auth: failed to ensure account key")
+		apierror.Write(w, req, ErrFailedKey)
+		return nil, false
+	}
+
+	session.Permission = users.PermissionOwner
+	if p.config.IsOperator(session.AccountName) {
+		session.Permission = users.PermissionOperator
+	}
+
+	return session, true
+}
+
+// staticProvider authenticates requests presenting one of the configured
+// static tokens as the seeded account the token is mapped to, as well as API
+// tokens and browser sessions. Signed requests are rejected since CloudAPI is never consulted.
+type staticProvider struct {
+	pool   *pgx.ConnPool
+	config auth.Config
+}
+
+func (p staticProvider) Authenticate(w http.ResponseWriter, req *http.Request) (*auth.Session, bool) {
+	ctx := req.Context()
+
+	session, ok := newSession(w, req, p.config)
+	if !ok {
+		return nil, false
+	}
+
+	if session.IsCookie() {
+		return session, ensureCookie(w, req, p.pool, session)
+	}
+
+	if !session.IsToken() {
+		apierror.Write(w, req, ErrFailedAuth)
+		return nil, false
+	}
+
+	token, _ := auth.BearerToken(req.Header.Get("Authorization"))
+	if _, ok := p.config.FindStaticAccount(token); !ok {
+		return session, ensureToken(w, req, p.pool, session)
+	}
+
+	keyring, _ := GetKeyring(ctx)
+
+	err := session.EnsureStatic(ctx,
+		accounts.NewStore(p.pool),
+		keys.NewStore(p.pool, keyring),
+	)
+	if err != nil {
+		requestlog.FromContext(ctx).Debug().
+			Str("module", "auth").
+			Err(err).
+			Msg("auth: failed to authenticate request")
+		apierror.Write(w, req, ErrFailedAuth)
+		return nil, false
+	}
+
+	return session, true
+}
+
+func newSession(w http.ResponseWriter, req *http.Request, config auth.Config) (*auth.Session, bool) {
+	ctx := req.Context()
+
+	session, err := auth.NewSession(req, config)
+	if err != nil {
+		requestlog.FromContext(ctx).Debug().
+			Str("module", "auth").
+			Err(err).
+			Msg("auth: failed to parse session")
+		apierror.Write(w, req, ErrFailedSession)
+		return nil, false
+	}
+	return session, true
+}
+
+// ensureToken authenticates a session presenting an API token.
+func ensureToken(w http.ResponseWriter, req *http.Request, pool *pgx.ConnPool, session *auth.Session) bool {
+	ctx := req.Context()
+	keyring, _ := GetKeyring(ctx)
+
+	err := session.EnsureToken(ctx,
+		tokens.NewStore(pool),
+		accounts.NewStore(pool),
+		keys.NewStore(pool, keyring),
+		allowlist.NewStore(pool),
+	)
+	if err != nil {
+		requestlog.FromContext(ctx).Debug().
+			Str("module", "auth").
+			Err(err).
+			Msg("auth: failed to authenticate token")
+		apierror.Write(w, req, ErrFailedToken)
+		return false
+	}
+	return true
+}
+
+// ensureCookie authenticates a session presenting the cookie of a browser
+// session. Requests changing a resource must also send the session's CSRF
+// token.
+func ensureCookie(w http.ResponseWriter, req *http.Request, pool *pgx.ConnPool, session *auth.Session) bool {
+	ctx := req.Context()
+	keyring, _ := GetKeyring(ctx)
+
+	err := session.EnsureCookie(ctx,
+		sessions.NewStore(pool),
+		accounts.NewStore(pool),
+		users.NewStore(pool),
+		tokens.NewStore(pool),
+		keys.NewStore(pool, keyring),
+		allowlist.NewStore(pool),
+	)
+	if err != nil {
+		requestlog.FromContext(ctx).Debug().
+			Str("module", "auth").
+			Err(err).
+			Msg("auth: failed to authenticate browser session")
+		apierror.Write(w, req, ErrFailedCookie)
+		return false
+	}
+
+	if err := session.VerifyCSRF(req); err != nil {
+		requestlog.FromContext(ctx).Debug().
+			Str("module", "auth").
+			Str("session_id", session.SessionID).
+			Err(err).
+			Msg("auth: rejected browser session request")
+		apierror.Write(w, req, ErrFailedCSRF)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/requestlog"
+	"github.com/joyent/triton-service-groups/users"
+)
+
+// GroupFunc returns the identifier of the group a request acts on.
+type GroupFunc func(req *http.Request) (string, error)
+
+// groupVar returns the {identifier} path variable of the request.
+func groupVar(req *http.Request) (string, error) {
+	return mux.Vars(req)["identifier"], nil
+}
+
+// Authorize wraps an HTTP handler, only calling it when the authenticated
+// session has been granted at least the required permission. Sessions limited
+// to specific groups can only call group scoped handlers for those groups,
+// given by groupOf or otherwise the {identifier} path variable.
+func Authorize(required users.Permission, groupScoped bool, groupOf GroupFunc, handler http.Handler) http.Handler {
+	if groupOf == nil {
+		groupOf = groupVar
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		session := GetAuthSession(req.Context())
+
+		if !session.Permission.Allows(required) {
+			requestlog.FromContext(req.Context()).Debug().
+				Str("module", "auth").
+				Str("actor", session.Actor()).
+				Str("permission", string(session.Permission)).
+				Str("required", string(required)).
+				Msg("auth: denied request with insufficient permissions")
+			apierror.Write(w, req, ErrForbidden)
+			return
+		}
+
+		if len(session.GroupIDs) > 0 {
+			var groupID string
+			if groupScoped {
+				var err error
+				if groupID, err = groupOf(req); err != nil {
+					apierror.Write(w, req, err)
+					return
+				}
+			}
+
+			if !groupScoped || !session.AllowsGroup(groupID) {
+				requestlog.FromContext(req.Context()).Debug().
+					Str("module", "auth").
+					Str("actor", session.Actor()).
+					Str("group_id", groupID).
+					Msg("auth: denied request outside of token group scope")
+				apierror.Write(w, req, ErrForbidden)
+				return
+			}
+		}
+
+		handler.ServeHTTP(w, req)
+	})
+}
@@ -6,6 +6,7 @@ import (
 
 	nomad "github.com/hashicorp/nomad/api"
 	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/envelope"
 )
 
 type contextKey int
@@ -14,6 +15,8 @@ const (
 	dbKeyName contextKey = iota
 	authKey
 	nomadKeyName
+	keyringKeyName
+	concurrencyKey
 )
 
 type dbValue struct {
@@ -24,6 +27,10 @@ type nomadValue struct {
 	client *nomad.Client
 }
 
+type keyringValue struct {
+	keyring *envelope.Keyring
+}
+
 // GetDBPool pulls a configured database client out of the current request
 // context.
 func GetDBPool(ctx context.Context) (*pgx.ConnPool, bool) {
@@ -42,22 +49,43 @@ func GetNomadClient(ctx context.Context) (*nomad.Client, bool) {
 	return nil, false
 }
 
+// GetKeyring pulls the configured encryption keyring out of the current
+// request context. The keyring is only available when the agent has been
+// configured with a key-encryption key.
+func GetKeyring(ctx context.Context) (*envelope.Keyring, bool) {
+	if keyring, ok := ctx.Value(keyringKeyName).(keyringValue); ok && keyring.keyring != nil {
+		return keyring.keyring, true
+	}
+	return nil, false
+}
+
 type contextHandler struct {
 	pool    *pgx.ConnPool
 	nomad   *nomad.Client
+	keyring *envelope.Keyring
 	handler http.Handler
 }
 
-func ContextHandler(pool *pgx.ConnPool, nomad *nomad.Client, h http.Handler) *contextHandler {
+func ContextHandler(pool *pgx.ConnPool, nomad *nomad.Client, keyring *envelope.Keyring, h http.Handler) *contextHandler {
 	return &contextHandler{
 		pool:    pool,
 		nomad:   nomad,
+		keyring: keyring,
 		handler: h,
 	}
 }
 
 func (h *contextHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	ctx := context.WithValue(req.Context(), dbKeyName, dbValue{h.pool})
-	ctx = context.WithValue(ctx, nomadKeyName, nomadValue{h.nomad})
+	ctx := NewContext(req.Context(), h.pool, h.nomad, h.keyring)
 	h.handler.ServeHTTP(w, req.WithContext(ctx))
 }
+
+// NewContext returns a copy of ctx carrying the database pool, nomad client and
+// keyring used by handlers. It's used when calling into handler code outside of
+// an HTTP request, such as from the command line.
+func NewContext(ctx context.Context, pool *pgx.ConnPool, nomad *nomad.Client, keyring *envelope.Keyring) context.Context {
+	ctx = context.WithValue(ctx, dbKeyName, dbValue{pool})
+	ctx = context.WithValue(ctx, nomadKeyName, nomadValue{nomad})
+	ctx = context.WithValue(ctx, keyringKeyName, keyringValue{keyring})
+	return ctx
+}
@@ -0,0 +1,121 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AnyOrigin allows browsers on every origin to call the API. Browsers never
+// send cookies along with these requests, so only API tokens can be used.
+const AnyOrigin = "*"
+
+// CORSConfig configures which origins, besides the API's own, browsers allow
+// to call the API. CORS is disabled when no origins are allowed.
+type CORSConfig struct {
+	// AllowedOrigins lists origins such as "https://tsg.example.com", or
+	// AnyOrigin.
+	AllowedOrigins []string
+
+	// MaxAge is how long browsers can cache the response to a preflight
+	// request.
+	MaxAge time.Duration
+}
+
+var (
+	corsAllowedMethods = strings.Join([]string{
+		http.MethodGet,
+		http.MethodHead,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodDelete,
+	}, ", ")
+
+	corsAllowedHeaders = strings.Join([]string{
+		"Authorization",
+		"Content-Type",
+		"Date",
+		"Last-Event-ID",
+		"Traceparent",
+		"X-CSRF-Token",
+		"X-Request-ID",
+	}, ", ")
+
+	corsExposedHeaders = strings.Join([]string{
+		"Location",
+		"Operation-Location",
+		"Retry-After",
+		"X-RateLimit-Limit",
+		"X-RateLimit-Remaining",
+		"X-Request-ID",
+	}, ", ")
+)
+
+// CORSHandler answers the preflight requests browsers send before calling the
+// API from another origin, and lets them read the responses to requests from
+// the allowed origins. Preflight requests are answered before authentication,
+// since browsers never send credentials along with them.
+func CORSHandler(config CORSConfig, handler http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(config.AllowedOrigins))
+	for _, origin := range config.AllowedOrigins {
+		allowed[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+	anyOrigin := allowed[AnyOrigin]
+
+	maxAge := strconv.Itoa(int(config.MaxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		origin := req.Header.Get("Origin")
+		if origin == "" {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		header := w.Header()
+		header.Add("Vary", "Origin")
+
+		preflight := req.Method == http.MethodOptions &&
+			req.Header.Get("Access-Control-Request-Method") != ""
+
+		if !anyOrigin && !allowed[strings.ToLower(origin)] {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			// Browsers won't let the origin read the response, though the
+			// request is still served as it could have been sent by any
+			// other client.
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		if anyOrigin {
+			header.Set("Access-Control-Allow-Origin", AnyOrigin)
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			header.Set("Access-Control-Allow-Methods", corsAllowedMethods)
+			header.Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			if config.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		header.Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		handler.ServeHTTP(w, req)
+	})
+}
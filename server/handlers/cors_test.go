@@ -0,0 +1,100 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSHandler(t *testing.T) {
+	var calls int
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	})
+
+	handler := handlers.CORSHandler(handlers.CORSConfig{
+		AllowedOrigins: []string{"https://console.example.com/"},
+		MaxAge:         10 * time.Minute,
+	}, next)
+
+	serve := func(method, origin string, preflight bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/v1/tsg/groups", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if preflight {
+			req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("preflight", func(t *testing.T) {
+		calls = 0
+		rec := serve(http.MethodOptions, "https://console.example.com", true)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "https://console.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Contains(t, rec.Header().Get("Access-Control-Allow-Methods"), http.MethodPut)
+		assert.Contains(t, rec.Header().Get("Access-Control-Allow-Headers"), "X-CSRF-Token")
+		assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+		assert.Equal(t, 0, calls, "preflight requests aren't passed on")
+	})
+
+	t.Run("allowed origin", func(t *testing.T) {
+		calls = 0
+		rec := serve(http.MethodGet, "https://console.example.com", false)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "https://console.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Contains(t, rec.Header().Get("Access-Control-Expose-Headers"), "Operation-Location")
+		assert.Equal(t, []string{"Origin"}, rec.Header()["Vary"])
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("disallowed origin", func(t *testing.T) {
+		calls = 0
+		rec := serve(http.MethodOptions, "https://evil.example.com", true)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+
+		rec = serve(http.MethodGet, "https://evil.example.com", false)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("same origin", func(t *testing.T) {
+		calls = 0
+		rec := serve(http.MethodGet, "", false)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, rec.Header().Get("Vary"))
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestCORSHandlerAnyOrigin(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	handler := handlers.CORSHandler(handlers.CORSConfig{
+		AllowedOrigins: []string{handlers.AnyOrigin},
+	}, next)
+
+	req := httptest.NewRequest(http.MethodOptions, "/v1/tsg/groups", nil)
+	req.Header.Set("Origin", "https://anywhere.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, handlers.AnyOrigin, rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"), "cookies are never sent to every origin")
+	assert.Empty(t, rec.Header().Get("Access-Control-Max-Age"))
+}
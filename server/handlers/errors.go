@@ -1,13 +1,28 @@
 package handlers
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+
+	"github.com/joyent/triton-service-groups/server/apierror"
+)
 
 var (
 	ErrNoConnPool    = errors.New("handlers can't access database pool")
 	ErrNoNomadClient = errors.New("handlers can't access nomad client")
-	ErrFailedAuth    = errors.New("failed request authentication")
-	ErrFailedSession = errors.New("failed session authentication")
-	ErrFailedAccount = errors.New("failed account authentication")
-	ErrFailedKey     = errors.New("failed key authentication")
+	ErrNoKeyring     = errors.New("handlers can't access encryption keyring")
 	ErrNoSession     = errors.New("failed to get authenticated session")
+
+	ErrFailedAuth    = apierror.New(http.StatusUnauthorized, apierror.CodeAuthenticationFailed, "failed request authentication")
+	ErrFailedSession = apierror.New(http.StatusUnauthorized, apierror.CodeInvalidAuthorization, "failed to parse authorization header")
+	ErrFailedAccount = apierror.New(http.StatusUnauthorized, apierror.CodeAccountRejected, "failed account authentication")
+	ErrFailedKey     = apierror.New(http.StatusUnauthorized, apierror.CodeKeyFailed, "failed key authentication")
+	ErrFailedUser    = apierror.New(http.StatusForbidden, apierror.CodeUserForbidden, "failed user authorization")
+	ErrFailedToken   = apierror.New(http.StatusUnauthorized, apierror.CodeTokenInvalid, "failed token authentication")
+	ErrFailedCookie  = apierror.New(http.StatusUnauthorized, apierror.CodeSessionInvalid, "failed browser session authentication")
+	ErrFailedCSRF    = apierror.New(http.StatusForbidden, apierror.CodeCSRFFailed, "missing or invalid CSRF token")
+	ErrForbidden     = apierror.New(http.StatusForbidden, apierror.CodeForbidden, "insufficient permissions for request")
+
+	ErrRateLimited        = apierror.New(http.StatusTooManyRequests, apierror.CodeRateLimited, "rate limit exceeded for account")
+	ErrConcurrencyLimited = apierror.New(http.StatusTooManyRequests, apierror.CodeTooManyConcurrent, "too many concurrent requests for account")
 )
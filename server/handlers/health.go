@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jackc/pgx"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// PingPath is the path of the liveness endpoint.
+	PingPath = "/ping"
+
+	// ReadyPath is the path of the readiness endpoint.
+	ReadyPath = "/ready"
+
+	// checkTimeout bounds how long a single dependency check can take.
+	checkTimeout = 2 * time.Second
+)
+
+// Status values reported by the health endpoints.
+const (
+	StatusOK           = "ok"
+	StatusDown         = "down"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Health serves the liveness and readiness endpoints used by load balancers.
+// Neither endpoint requires authentication.
+type Health struct {
+	pool  *pgx.ConnPool
+	nomad *nomad.Client
+
+	shuttingDown int32
+}
+
+// Readiness reports whether the API can serve requests, along with the status
+// of each of its dependencies.
+type Readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]*Check `json:"checks,omitempty"`
+}
+
+// Check reports the status of a single dependency.
+type Check struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+}
+
+// NewHealth constructs the health endpoints, checking pool and nomadClient
+// when asked whether the API is ready.
+func NewHealth(pool *pgx.ConnPool, nomadClient *nomad.Client) *Health {
+	return &Health{
+		pool:  pool,
+		nomad: nomadClient,
+	}
+}
+
+// SetShuttingDown marks the API as shutting down. From then on the readiness
+// endpoint responds with a 503 so load balancers stop sending requests.
+func (h *Health) SetShuttingDown() {
+	atomic.StoreInt32(&h.shuttingDown, 1)
+}
+
+// IsShuttingDown returns true once SetShuttingDown has been called.
+func (h *Health) IsShuttingDown() bool {
+	return atomic.LoadInt32(&h.shuttingDown) == 1
+}
+
+// Ping responds with a 200 as long as the process is serving requests.
+func (h *Health) Ping(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, r, &Readiness{Status: StatusOK}, http.StatusOK)
+}
+
+// Ready checks the database and Nomad, responding with a 200 when both are
+// reachable and a 503 otherwise, or while shutting down.
+func (h *Health) Ready(w http.ResponseWriter, r *http.Request) {
+	if h.IsShuttingDown() {
+		writeHealth(w, r, &Readiness{Status: StatusShuttingDown}, http.StatusServiceUnavailable)
+		return
+	}
+
+	readiness := &Readiness{
+		Status: StatusOK,
+		Checks: map[string]*Check{
+			"database": check(r.Context(), "database", h.checkDatabase),
+			"nomad":    check(r.Context(), "nomad", h.checkNomad),
+		},
+	}
+
+	status := http.StatusOK
+	for _, c := range readiness.Checks {
+		if c.Status != StatusOK {
+			readiness.Status = StatusDown
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	writeHealth(w, r, readiness, status)
+}
+
+func (h *Health) checkDatabase(ctx context.Context) error {
+	if h.pool == nil {
+		return ErrNoConnPool
+	}
+
+	var one int
+	return h.pool.QueryRowEx(ctx, "SELECT 1", nil).Scan(&one)
+}
+
+func (h *Health) checkNomad(ctx context.Context) error {
+	if h.nomad == nil {
+		return ErrNoNomadClient
+	}
+
+	// The Nomad client doesn't accept a context, therefore give up waiting on
+	// it once ctx is done.
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := h.nomad.Status().Leader()
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// check runs fn within checkTimeout. Failures are only logged, since the
+// health endpoints are served to unauthenticated clients.
+func check(ctx context.Context, name string, fn func(context.Context) error) *Check {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := fn(ctx)
+	c := &Check{
+		Status:  StatusOK,
+		Latency: time.Since(start).String(),
+	}
+
+	if err != nil {
+		log.Warn().
+			Str("check", name).
+			Err(err).
+			Msg("health: dependency is unavailable")
+		c.Status = StatusDown
+	}
+
+	return c
+}
+
+// HealthHandler serves the health endpoints without authentication, passing
+// every other request on to handler.
+func HealthHandler(health *Health, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet || req.Method == http.MethodHead {
+			switch req.URL.Path {
+			case PingPath:
+				health.Ping(w, req)
+				return
+			case ReadyPath:
+				health.Ready(w, req)
+				return
+			}
+		}
+
+		handler.ServeHTTP(w, req)
+	})
+}
+
+func writeHealth(w http.ResponseWriter, r *http.Request, readiness *Readiness, statusCode int) {
+	bytes, err := json.Marshal(readiness)
+	if err != nil {
+		log.Printf("%v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if n, err := w.Write(bytes); err != nil {
+		log.Printf("%v", err)
+	} else if n != len(bytes) {
+		log.Printf("short write: %d/%d", n, len(bytes))
+	}
+}
@@ -0,0 +1,78 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveHealth(t *testing.T, handler http.Handler, method, path string) (*httptest.ResponseRecorder, *handlers.Readiness) {
+	req := httptest.NewRequest(method, path, nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var readiness handlers.Readiness
+	if method != http.MethodHead && rec.Header().Get("Content-Type") != "" {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &readiness))
+	}
+	return rec, &readiness
+}
+
+func TestHealthHandler(t *testing.T) {
+	var authenticated bool
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authenticated = true
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	health := handlers.NewHealth(nil, nil)
+	handler := handlers.HealthHandler(health, next)
+
+	rec, readiness := serveHealth(t, handler, http.MethodGet, "/ping")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, handlers.StatusOK, readiness.Status)
+	assert.False(t, authenticated, "health checks bypass authentication")
+
+	rec, _ = serveHealth(t, handler, http.MethodHead, "/ping")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	rec, readiness = serveHealth(t, handler, http.MethodGet, "/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, handlers.StatusDown, readiness.Status)
+	require.Contains(t, readiness.Checks, "database")
+	require.Contains(t, readiness.Checks, "nomad")
+	assert.Equal(t, handlers.StatusDown, readiness.Checks["database"].Status)
+	assert.Equal(t, handlers.StatusDown, readiness.Checks["nomad"].Status)
+	assert.False(t, authenticated)
+
+	rec, _ = serveHealth(t, handler, http.MethodPost, "/ping")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.True(t, authenticated, "other methods are authenticated")
+
+	authenticated = false
+	rec, _ = serveHealth(t, handler, http.MethodGet, "/v1/tsg/groups")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.True(t, authenticated, "other paths are authenticated")
+}
+
+func TestHealthShuttingDown(t *testing.T) {
+	health := handlers.NewHealth(nil, nil)
+	handler := handlers.HealthHandler(health, http.NotFoundHandler())
+
+	health.SetShuttingDown()
+	assert.True(t, health.IsShuttingDown())
+
+	rec, readiness := serveHealth(t, handler, http.MethodGet, "/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, handlers.StatusShuttingDown, readiness.Status)
+	assert.Empty(t, readiness.Checks)
+
+	rec, _ = serveHealth(t, handler, http.MethodGet, "/ping")
+	assert.Equal(t, http.StatusOK, rec.Code, "the process is still alive while shutting down")
+}
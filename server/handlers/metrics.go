@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/joyent/triton-service-groups/metrics"
+)
+
+const (
+	// unmatchedRoute labels the metrics of requests which don't match any route.
+	unmatchedRoute = "unmatched"
+
+	// otherMethod labels the metrics of requests with a method the API doesn't
+	// serve, so clients can't create a series per made up method.
+	otherMethod = "OTHER"
+)
+
+// knownMethods are the methods recorded by name.
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// RouteMatcher matches a request to a named route without serving it, as
+// implemented by *mux.Router.
+type RouteMatcher interface {
+	Match(req *http.Request, match *mux.RouteMatch) bool
+}
+
+// statusWriter captures the status code of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush passes flushes through to the wrapped writer, for streamed responses.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// RouteName returns the name of the route within routes matching req, or
+// "unmatched" when none does.
+func RouteName(routes RouteMatcher, req *http.Request) string {
+	var match mux.RouteMatch
+	if routes.Match(req, &match) && match.Route != nil && match.Route.GetName() != "" {
+		return match.Route.GetName()
+	}
+	return unmatchedRoute
+}
+
+// methodLabel returns the method of req, or "OTHER" for any method the API
+// doesn't serve.
+func methodLabel(req *http.Request) string {
+	if knownMethods[req.Method] {
+		return req.Method
+	}
+	return otherMethod
+}
+
+// MetricsHandler wraps an HTTP handler, recording the count and latency of
+// every request by the name of the route it matches within routes. Requests
+// are matched before being served, so requests which fail authentication are
+// recorded against their route too.
+func MetricsHandler(routes RouteMatcher, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		route := RouteName(routes, req)
+		method := methodLabel(req)
+
+		sw := &statusWriter{ResponseWriter: w}
+		handler.ServeHTTP(sw, req)
+
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		status := strconv.Itoa(sw.status)
+
+		metrics.HTTPRequests.WithLabelValues(route, method, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(route, method, status).Observe(time.Since(start).Seconds())
+	})
+}
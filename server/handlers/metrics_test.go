@@ -0,0 +1,60 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/joyent/triton-service-groups/metrics"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	routes := mux.NewRouter()
+	routes.Path("/v1/tsg/things/{identifier}").
+		Methods(http.MethodGet).
+		Name("GetThing").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	handler := handlers.MetricsHandler(routes, next)
+
+	requests := func(route, method string) float64 {
+		var m dto.Metric
+		require.NoError(t, metrics.HTTPRequests.WithLabelValues(route, method, "401").Write(&m))
+		return m.GetCounter().GetValue()
+	}
+	observations := func(route, method string) uint64 {
+		var m dto.Metric
+		observer := metrics.HTTPRequestDuration.WithLabelValues(route, method, "401")
+		require.NoError(t, observer.(prometheus.Metric).Write(&m))
+		return m.GetHistogram().GetSampleCount()
+	}
+
+	before := requests("GetThing", http.MethodGet)
+	unmatched := requests("unmatched", http.MethodGet)
+	observed := observations("GetThing", http.MethodGet)
+	other := requests("unmatched", "OTHER")
+
+	serve := func(method, path string) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
+	}
+	serve(http.MethodGet, "/v1/tsg/things/abc")
+	serve(http.MethodGet, "/v1/tsg/nothing")
+	serve("MADEUP", "/v1/tsg/things/abc")
+
+	assert.Equal(t, before+1, requests("GetThing", http.MethodGet),
+		"requests are recorded against their route, even when rejected before routing")
+	assert.Equal(t, unmatched+1, requests("unmatched", http.MethodGet))
+	assert.Equal(t, observed+1, observations("GetThing", http.MethodGet))
+	assert.Equal(t, other+1, requests("unmatched", "OTHER"),
+		"unknown methods are recorded under a single label")
+	assert.Equal(t, 0.0, requests("unmatched", "MADEUP"))
+}
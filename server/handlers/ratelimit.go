@@ -0,0 +1,143 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package handlers
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/joyent/triton-service-groups/ratelimit"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/requestlog"
+)
+
+// RateLimitConfig configures the budgets of every account. Reads are GET, HEAD
+// and OPTIONS requests, every other request is a mutation. Concurrency limits
+// the mutations of each account in flight within a single agent.
+type RateLimitConfig struct {
+	Reads       ratelimit.Limit
+	Mutations   ratelimit.Limit
+	Concurrency int
+}
+
+// rateLimitHandler limits the requests of each authenticated account. It must
+// be wrapped by the authHandler.
+type rateLimitHandler struct {
+	handler     http.Handler
+	limiter     ratelimit.Limiter
+	config      RateLimitConfig
+	concurrency *ratelimit.Concurrency
+}
+
+// RateLimitHandler constructs and returns the HTTP handler object responsible
+// for rate limiting the requests of each account, counted by limiter. Requests
+// over budget are rejected with a 429 and a Retry-After header.
+func RateLimitHandler(limiter ratelimit.Limiter, config RateLimitConfig, handler http.Handler) rateLimitHandler {
+	return rateLimitHandler{
+		handler:     handler,
+		limiter:     limiter,
+		config:      config,
+		concurrency: ratelimit.NewConcurrency(config.Concurrency),
+	}
+}
+
+func (h rateLimitHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	session := GetAuthSession(ctx)
+
+	if session.AccountID == "" {
+		h.handler.ServeHTTP(w, req)
+		return
+	}
+
+	budget, limit := "mutations", h.config.Mutations
+	if isRead(req) {
+		budget, limit = "reads", h.config.Reads
+	}
+
+	key := session.AccountID + "/" + budget
+
+	result, err := h.limiter.Allow(ctx, key, limit, time.Now())
+	if err != nil {
+		// Rather than rejecting every request while the backend is
+		// unavailable, allow them through.
+		requestlog.FromContext(ctx).Warn().
+			Str("module", "ratelimit").
+			Str("account_id", session.AccountID).
+			Err(err).
+			Msg("ratelimit: failed to count request")
+		h.handler.ServeHTTP(w, req)
+		return
+	}
+
+	if !limit.IsZero() {
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	}
+
+	if !result.Allowed {
+		requestlog.FromContext(ctx).Debug().
+			Str("module", "ratelimit").
+			Str("actor", session.Actor()).
+			Str("budget", budget).
+			Msg("ratelimit: rejected request over budget")
+		writeTooManyRequests(w, req, ErrRateLimited, result.RetryAfter)
+		return
+	}
+
+	if budget == "mutations" {
+		if !h.concurrency.Acquire(session.AccountID) {
+			requestlog.FromContext(ctx).Debug().
+				Str("module", "ratelimit").
+				Str("actor", session.Actor()).
+				Msg("ratelimit: rejected request over concurrency limit")
+			writeTooManyRequests(w, req, ErrConcurrencyLimited, time.Second)
+			return
+		}
+
+		var once sync.Once
+		release := func() {
+			once.Do(func() { h.concurrency.Release(session.AccountID) })
+		}
+		defer release()
+		req = req.WithContext(context.WithValue(ctx, concurrencyKey, release))
+	}
+
+	h.handler.ServeHTTP(w, req)
+}
+
+// ReleaseConcurrency releases the concurrency slot held by the mutation served
+// with ctx before it has been served, for handlers which go on to wait without
+// mutating anything, such as waiting for a group to converge. Requests which
+// don't hold a slot are left alone.
+func ReleaseConcurrency(ctx context.Context) {
+	if release, ok := ctx.Value(concurrencyKey).(func()); ok {
+		release()
+	}
+}
+
+func isRead(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+func writeTooManyRequests(w http.ResponseWriter, req *http.Request, err error, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	apierror.Write(w, req, err)
+}
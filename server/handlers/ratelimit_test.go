@@ -0,0 +1,136 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/joyent/triton-service-groups/ratelimit"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/handlers/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitHandler(t *testing.T) {
+	var calls int
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	})
+
+	limit := ratelimit.Limit{Requests: 1, Period: time.Hour}
+	handler := handlers.RateLimitHandler(ratelimit.NewMemoryLimiter(), handlers.RateLimitConfig{
+		Reads:     limit,
+		Mutations: limit,
+	}, next)
+
+	serve := func(method, accountID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/v1/tsg/groups", nil)
+		req = req.WithContext(handlers.WithAuthSession(req.Context(), &auth.Session{
+			AccountID: accountID,
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodPut, "account")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+
+	rec = serve(http.MethodPut, "account")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), `"code":"rate_limited"`)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "account").Code, "reads have a separate budget")
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "other").Code, "accounts have separate budgets")
+	assert.Equal(t, 3, calls)
+}
+
+func TestRateLimitHandlerConcurrency(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			close(started)
+			<-release
+		}
+	})
+
+	handler := handlers.RateLimitHandler(ratelimit.NewMemoryLimiter(), handlers.RateLimitConfig{
+		Concurrency: 1,
+	}, next)
+
+	serve := func(method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/v1/tsg/groups", nil)
+		req = req.WithContext(handlers.WithAuthSession(req.Context(), &auth.Session{
+			AccountID: "account",
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	done := make(chan struct{})
+	go func() {
+		serve(http.MethodPost)
+		close(done)
+	}()
+	<-started
+
+	rec := serve(http.MethodPut)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet).Code, "reads aren't limited")
+
+	close(release)
+	<-done
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut).Code)
+}
+
+func TestReleaseConcurrency(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.ReleaseConcurrency(r.Context())
+			handlers.ReleaseConcurrency(r.Context())
+			close(started)
+			<-release
+		}
+	})
+
+	handler := handlers.RateLimitHandler(ratelimit.NewMemoryLimiter(), handlers.RateLimitConfig{
+		Concurrency: 1,
+	}, next)
+
+	serve := func(method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/v1/tsg/groups", nil)
+		req = req.WithContext(handlers.WithAuthSession(req.Context(), &auth.Session{
+			AccountID: "account",
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	done := make(chan struct{})
+	go func() {
+		serve(http.MethodPost)
+		close(done)
+	}()
+	<-started
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut).Code, "released slots are reused while waiting")
+
+	close(release)
+	<-done
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut).Code)
+}
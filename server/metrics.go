@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/config"
+	"github.com/joyent/triton-service-groups/groups"
+	"github.com/joyent/triton-service-groups/metrics"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/rs/zerolog/log"
+)
+
+// MetricsPath is the path metrics are served at.
+const MetricsPath = "/metrics"
+
+// MetricsServer serves Prometheus metrics on a listener separate from the
+// API, so it can be kept private and scraped without credentials.
+type MetricsServer struct {
+	http.Server
+}
+
+// NewMetricsServer constructs the metrics server, exposing the statistics of
+// pool and the desired capacity of every group alongside the API metrics.
+func NewMetricsServer(cfg config.Metrics, pool *pgx.ConnPool) *MetricsServer {
+	registry := metrics.NewRegistry(
+		metrics.NewPoolStats(pool),
+		newGroupCapacity(pool),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, metrics.Handler(registry))
+
+	return &MetricsServer{
+		Server: http.Server{
+			Addr:    fmt.Sprintf("%s:%d", cfg.Bind, cfg.Port),
+			Handler: mux,
+		},
+	}
+}
+
+// Start serves metrics in the background.
+func (srv *MetricsServer) Start() {
+	go func() {
+		log.Info().Msgf("metrics: started serving at %q", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("metrics: failed to serve metrics")
+		}
+	}()
+}
+
+// Stop shuts down the metrics server.
+func (srv *MetricsServer) Stop(ctx context.Context) error {
+	return srv.Shutdown(ctx)
+}
+
+// newGroupCapacity constructs a gauge of the desired capacity of every group,
+// read from the database whenever metrics are scraped so every agent reports
+// the same values.
+func newGroupCapacity(pool *pgx.ConnPool) *metrics.GaugeFunc {
+	return metrics.NewGaugeFunc("tsg_group_desired_capacity",
+		"Desired number of compute instances of each group.",
+		func(ctx context.Context) ([]metrics.Sample, error) {
+			ctx = handlers.NewContext(ctx, pool, nil, nil)
+
+			capacities, err := groups_v1.FindCapacities(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			samples := make([]metrics.Sample, 0, len(capacities))
+			for _, c := range capacities {
+				samples = append(samples, metrics.Sample{
+					LabelValues: []string{c.AccountName, c.GroupID, c.GroupName},
+					Value:       float64(c.Capacity),
+				})
+			}
+			return samples, nil
+		}, "account", "group_id", "group_name")
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/joyent/triton-service-groups/buildtime"
+	"github.com/joyent/triton-service-groups/server/openapi"
+	"github.com/rs/zerolog/log"
+)
+
+// openAPIDocument describes every route within RoutingTable. It's generated
+// once every route has been declared, since the route serving it is part of
+// the table.
+var openAPIDocument []byte
+
+func init() {
+	doc, err := openapi.New(RoutingTable, buildtime.Version)
+	if err != nil {
+		panic(err)
+	}
+
+	openAPIDocument, err = json.Marshal(doc)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func getOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if n, err := w.Write(openAPIDocument); err != nil {
+		log.Printf("%v", err)
+	} else if n != len(openAPIDocument) {
+		log.Printf("short write: %d/%d", n, len(openAPIDocument))
+	}
+}
@@ -0,0 +1,374 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package openapi generates an OpenAPI 3 document describing the API from its
+// routing table and the schemas attached to each route.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/joyent/triton-service-groups/server/router"
+	"github.com/joyent/triton-service-groups/server/schema"
+)
+
+// Version is the version of the OpenAPI specification documents conform to.
+const Version = "3.0.3"
+
+const (
+	jsonContentType = "application/json"
+	componentPrefix = "#/components/schemas/"
+)
+
+// Document is the root object of an OpenAPI document.
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security"`
+}
+
+// Info describes the API.
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem holds the operations of a single path, keyed by lower case HTTP
+// method.
+type PathItem map[string]*Operation
+
+// Operation describes a single route.
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter describes a path parameter of an operation.
+type Parameter struct {
+	Name     string                 `json:"name"`
+	In       string                 `json:"in"`
+	Required bool                   `json:"required"`
+	Schema   map[string]interface{} `json:"schema"`
+}
+
+// RequestBody describes the request body accepted by an operation.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType describes a request or response body of a given content type.
+type MediaType struct {
+	Schema map[string]interface{} `json:"schema"`
+}
+
+// Components holds the schemas referenced throughout the document.
+type Components struct {
+	Schemas         map[string]map[string]interface{} `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme        `json:"securitySchemes"`
+}
+
+// SecurityScheme describes a way of authenticating with the API.
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Scheme      string `json:"scheme,omitempty"`
+	Name        string `json:"name,omitempty"`
+	In          string `json:"in,omitempty"`
+}
+
+// errorSchema describes the body of every failed request.
+var errorSchema = &schema.Schema{
+	Title: "Error",
+	Type:  "object",
+	Properties: map[string]*schema.Schema{
+		"code": {
+			Type:        "string",
+			Description: "A stable, machine-readable identifier of the kind of error.",
+		},
+		"message": {
+			Type:        "string",
+			Description: "A human-readable description of the error.",
+		},
+		"request_id": {
+			Type:        "string",
+			Description: "The identifier of the request.",
+		},
+		"details": schema.ArrayOf(&schema.Schema{
+			Type: "object",
+			Properties: map[string]*schema.Schema{
+				"pointer": {Type: "string"},
+				"message": {Type: "string"},
+			},
+		}),
+	},
+	Required:             []string{"code", "message", "request_id"},
+	AdditionalProperties: schema.Any,
+}
+
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// generator converts the schemas of every route, collecting the schemas with a
+// title as components so they're only described once.
+type generator struct {
+	doc    *Document
+	titled map[string]*schema.Schema
+}
+
+// New generates the OpenAPI document describing every route within routes.
+// version is the version of the API itself.
+func New(routes router.RouteTable, version string) (*Document, error) {
+	if version == "" {
+		version = "dev"
+	}
+
+	g := &generator{
+		doc: &Document{
+			OpenAPI: Version,
+			Info: Info{
+				Title:       "Triton Service Groups",
+				Description: "Manages groups of Triton compute instances launched from templates.",
+				Version:     version,
+			},
+			Paths: make(map[string]PathItem),
+			Components: Components{
+				Schemas: make(map[string]map[string]interface{}),
+				SecuritySchemes: map[string]*SecurityScheme{
+					"signature": {
+						Type:        "apiKey",
+						Description: "An HTTP Signature of the Date header, signed with a Triton key.",
+						Name:        "Authorization",
+						In:          "header",
+					},
+					"token": {
+						Type:        "http",
+						Description: "An API token issued through /v1/tsg/tokens.",
+						Scheme:      "bearer",
+					},
+				},
+			},
+			Security: []map[string][]string{
+				{"signature": {}},
+				{"token": {}},
+			},
+		},
+		titled: make(map[string]*schema.Schema),
+	}
+
+	errorRef, err := g.schema(errorSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rs := range routes {
+		for _, r := range rs {
+			op, err := g.operation(r, errorRef)
+			if err != nil {
+				return nil, err
+			}
+
+			path := pathParam.ReplaceAllString(r.Pattern, "{$1}")
+			item, ok := g.doc.Paths[path]
+			if !ok {
+				item = make(PathItem)
+				g.doc.Paths[path] = item
+			}
+
+			method := strings.ToLower(r.Method)
+			if _, ok := item[method]; ok {
+				return nil, fmt.Errorf("openapi: route %q duplicates %s %s", r.Name, r.Method, path)
+			}
+			item[method] = op
+		}
+	}
+
+	return g.doc, nil
+}
+
+func (g *generator) operation(r router.Route, errorRef map[string]interface{}) (*Operation, error) {
+	op := &Operation{
+		OperationID: r.Name,
+		Summary:     r.Summary,
+		Tags:        tags(r.Pattern),
+		Responses:   make(map[string]*Response, 2),
+	}
+
+	for _, match := range pathParam.FindAllStringSubmatch(r.Pattern, -1) {
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   map[string]interface{}{"type": "string"},
+		})
+	}
+
+	if r.Request != nil {
+		s, err := g.schema(r.Request)
+		if err != nil {
+			return nil, routeError(r, err)
+		}
+		op.RequestBody = &RequestBody{
+			Required: len(r.Request.Required) > 0,
+			Content:  map[string]MediaType{jsonContentType: {Schema: s}},
+		}
+	}
+
+	status := r.SuccessStatus()
+	success := &Response{Description: http.StatusText(status)}
+	if r.Response != nil {
+		s, err := g.schema(r.Response)
+		if err != nil {
+			return nil, routeError(r, err)
+		}
+		contentType := r.ContentType
+		if contentType == "" {
+			contentType = jsonContentType
+		}
+		success.Content = map[string]MediaType{contentType: {Schema: s}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	op.Responses["default"] = &Response{
+		Description: "The request failed, see the error code.",
+		Content:     map[string]MediaType{jsonContentType: {Schema: errorRef}},
+	}
+
+	return op, nil
+}
+
+func routeError(r router.Route, err error) error {
+	return fmt.Errorf("openapi: route %q: %v", r.Name, err)
+}
+
+// schema converts s to an OpenAPI schema object. Schemas with a title are
+// described once within the components of the document and referenced.
+func (g *generator) schema(s *schema.Schema) (map[string]interface{}, error) {
+	if s.Title == "" {
+		return g.inline(s)
+	}
+
+	ref := map[string]interface{}{"$ref": componentPrefix + s.Title}
+
+	if existing, ok := g.titled[s.Title]; ok {
+		if existing != s {
+			return nil, fmt.Errorf("two different schemas are titled %q", s.Title)
+		}
+		return ref, nil
+	}
+	g.titled[s.Title] = s
+
+	component, err := g.inline(s)
+	if err != nil {
+		return nil, err
+	}
+	g.doc.Components.Schemas[s.Title] = component
+
+	return ref, nil
+}
+
+func (g *generator) inline(s *schema.Schema) (map[string]interface{}, error) {
+	doc := make(map[string]interface{})
+
+	if s.Title != "" {
+		doc["title"] = s.Title
+	}
+	if s.Description != "" {
+		doc["description"] = s.Description
+	}
+	if s.Type != "" {
+		doc["type"] = s.Type
+	}
+	if s.Nullable {
+		doc["nullable"] = true
+	}
+	if s.ReadOnly {
+		doc["readOnly"] = true
+	}
+
+	if s.Type == "object" {
+		if len(s.Properties) > 0 {
+			props := make(map[string]interface{}, len(s.Properties))
+			for name, prop := range s.Properties {
+				p, err := g.schema(prop)
+				if err != nil {
+					return nil, err
+				}
+				props[name] = p
+			}
+			doc["properties"] = props
+		}
+		if len(s.Required) > 0 {
+			doc["required"] = s.Required
+		}
+		if s.AdditionalProperties != nil {
+			ap, err := g.schema(s.AdditionalProperties)
+			if err != nil {
+				return nil, err
+			}
+			doc["additionalProperties"] = ap
+		} else {
+			doc["additionalProperties"] = false
+		}
+	}
+
+	if s.Items != nil {
+		items, err := g.schema(s.Items)
+		if err != nil {
+			return nil, err
+		}
+		doc["items"] = items
+	}
+	if len(s.Enum) > 0 {
+		doc["enum"] = s.Enum
+	}
+	if s.Format != "" {
+		doc["format"] = s.Format
+	}
+	if s.Pattern != "" {
+		doc["pattern"] = s.Pattern
+	}
+	if s.MinLength != nil {
+		doc["minLength"] = *s.MinLength
+	}
+	if s.MaxLength != nil {
+		doc["maxLength"] = *s.MaxLength
+	}
+	if s.Minimum != nil {
+		doc["minimum"] = *s.Minimum
+	}
+	if s.Maximum != nil {
+		doc["maximum"] = *s.Maximum
+	}
+
+	return doc, nil
+}
+
+// tags groups a route by the resource it acts on, the first path segment
+// after /v1/tsg without any extension.
+func tags(pattern string) []string {
+	segments := strings.Split(strings.TrimPrefix(pattern, "/v1/tsg/"), "/")
+	resource := strings.TrimSuffix(segments[0], ".json")
+	if resource == "" {
+		return nil
+	}
+	return []string{resource}
+}
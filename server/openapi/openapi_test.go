@@ -0,0 +1,127 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/server/openapi"
+	"github.com/joyent/triton-service-groups/server/router"
+	"github.com/joyent/triton-service-groups/server/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var thing = &schema.Schema{
+	Title: "Thing",
+	Type:  "object",
+	Properties: map[string]*schema.Schema{
+		"name": {Type: "string", MinLength: schema.Int(1)},
+		"note": {Type: "string", Nullable: true},
+	},
+	Required: []string{"name"},
+}
+
+func generate(t *testing.T, routes ...router.Route) map[string]interface{} {
+	doc, err := openapi.New(router.RouteTable{routes}, "1.0.0")
+	require.NoError(t, err)
+
+	bytes, err := json.Marshal(doc)
+	require.NoError(t, err)
+
+	var out map[string]interface{}
+	require.NoError(t, json.Unmarshal(bytes, &out))
+	return out
+}
+
+func TestNew(t *testing.T) {
+	doc := generate(t,
+		router.Route{
+			Name:     "CreateThing",
+			Method:   http.MethodPost,
+			Pattern:  "/v1/tsg/things",
+			Request:  thing,
+			Response: thing,
+			Status:   http.StatusCreated,
+		},
+		router.Route{
+			Name:     "ListThings",
+			Method:   http.MethodGet,
+			Pattern:  "/v1/tsg/things",
+			Response: schema.ArrayOf(thing),
+		},
+		router.Route{
+			Name:    "DeleteThing",
+			Method:  http.MethodDelete,
+			Pattern: "/v1/tsg/things/{identifier}",
+		},
+	)
+
+	assert.Equal(t, openapi.Version, doc["openapi"])
+
+	paths := doc["paths"].(map[string]interface{})
+	things := paths["/v1/tsg/things"].(map[string]interface{})
+
+	create := things["post"].(map[string]interface{})
+	assert.Equal(t, "CreateThing", create["operationId"])
+	assert.Equal(t, []interface{}{"things"}, create["tags"])
+	assert.Contains(t, create["responses"], "201")
+	assert.Contains(t, create["responses"], "default")
+
+	body := create["requestBody"].(map[string]interface{})
+	assert.Equal(t, true, body["required"])
+	assert.Equal(t, map[string]interface{}{
+		"application/json": map[string]interface{}{
+			"schema": map[string]interface{}{"$ref": "#/components/schemas/Thing"},
+		},
+	}, body["content"])
+
+	remove := paths["/v1/tsg/things/{identifier}"].(map[string]interface{})["delete"].(map[string]interface{})
+	assert.Contains(t, remove["responses"], "204")
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"name":     "identifier",
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		},
+	}, remove["parameters"])
+
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	assert.Contains(t, schemas, "Error")
+	assert.Equal(t, map[string]interface{}{
+		"title": "Thing",
+		"type":  "object",
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{"type": "string", "minLength": float64(1)},
+			"note": map[string]interface{}{"type": "string", "nullable": true},
+		},
+		"required":             []interface{}{"name"},
+		"additionalProperties": false,
+	}, schemas["Thing"])
+}
+
+func TestNewContentType(t *testing.T) {
+	doc := generate(t, router.Route{
+		Name:        "WatchThings",
+		Method:      http.MethodGet,
+		Pattern:     "/v1/tsg/things/watch",
+		Response:    thing,
+		ContentType: "text/event-stream",
+	})
+
+	watch := doc["paths"].(map[string]interface{})["/v1/tsg/things/watch"].(map[string]interface{})["get"].(map[string]interface{})
+	ok := watch["responses"].(map[string]interface{})["200"].(map[string]interface{})
+	assert.Contains(t, ok["content"], "text/event-stream")
+	assert.NotContains(t, ok["content"], "application/json")
+}
+
+func TestNewRejectsDuplicateTitles(t *testing.T) {
+	other := &schema.Schema{Title: "Thing", Type: "string"}
+
+	_, err := openapi.New(router.RouteTable{{
+		router.Route{Name: "GetThing", Method: http.MethodGet, Pattern: "/v1/tsg/things", Response: thing},
+		router.Route{Name: "GetOther", Method: http.MethodGet, Pattern: "/v1/tsg/other", Response: other},
+	}}, "1.0.0")
+	assert.Error(t, err)
+}
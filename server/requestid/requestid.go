@@ -0,0 +1,74 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Header is the HTTP header carrying the identifier of a request, both on the
+// request and its response.
+const Header = "X-Request-ID"
+
+// maxLength is the longest client supplied identifier which is accepted.
+const maxLength = 128
+
+type contextKey struct{}
+
+// New returns a new random request identifier.
+func New() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// NewContext returns a copy of ctx carrying the request identifier id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request identifier carried by ctx, if any.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Handler wraps an HTTP handler, identifying every request by the identifier
+// supplied by the client within the X-Request-ID header, or a new identifier
+// when none was supplied. The identifier is returned within the response
+// headers and carried by the request context.
+func Handler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(Header)
+		if !isValid(id) {
+			id = New()
+		}
+
+		w.Header().Set(Header, id)
+
+		ctx := NewContext(req.Context(), id)
+		handler.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// isValid returns true if a client supplied identifier is short and only
+// contains printable ASCII characters, so it's safe to log and return.
+func isValid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
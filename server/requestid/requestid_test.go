@@ -0,0 +1,36 @@
+package requestid_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/server/requestid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	var seen string
+	handler := requestid.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestid.FromContext(r.Context())
+	}))
+
+	serve := func(id string) string {
+		req := httptest.NewRequest(http.MethodGet, "/v1/tsg/groups", nil)
+		if id != "" {
+			req.Header.Set(requestid.Header, id)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, seen, rec.Header().Get(requestid.Header))
+		return seen
+	}
+
+	assert.Equal(t, "client-id-1", serve("client-id-1"))
+	assert.Len(t, serve(""), 32)
+	assert.Len(t, serve("has spaces"), 32, "invalid identifiers are replaced")
+	assert.Len(t, serve(strings.Repeat("a", 129)), 32, "long identifiers are replaced")
+	assert.NotEqual(t, serve(""), serve(""))
+}
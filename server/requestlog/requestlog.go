@@ -0,0 +1,108 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package requestlog attaches a logger identifying the current request to the
+// request context, so every message logged while serving it can be correlated,
+// and logs a line once each request has been served.
+package requestlog
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/joyent/triton-service-groups/server/requestid"
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+type contextKey struct{}
+
+// FromContext returns the logger attached to ctx by Handler, or the global
+// logger outside of a request.
+func FromContext(ctx context.Context) *zerolog.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*zerolog.Logger); ok {
+		return l
+	}
+	return &log.Logger
+}
+
+// NewContext returns a copy of ctx carrying a logger derived from the global
+// logger, tagged with the request identifier and trace carried by ctx. The
+// logger is also available through zerolog.Ctx.
+func NewContext(ctx context.Context) context.Context {
+	l := with(log.Logger.With(), ctx).Logger()
+	ctx = l.WithContext(ctx)
+	return context.WithValue(ctx, contextKey{}, &l)
+}
+
+func with(c zerolog.Context, ctx context.Context) zerolog.Context {
+	if id := requestid.FromContext(ctx); id != "" {
+		c = c.Str("request_id", id)
+	}
+	if id := tracing.TraceID(ctx); id != "" {
+		c = c.Str("trace_id", id)
+	}
+	return c
+}
+
+// responseWriter captures the status code and size of a response.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+// Flush passes flushes through to the wrapped writer, for streamed responses.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Handler wraps an HTTP handler, attaching a request scoped logger to the
+// context of every request and logging each request to logger once served.
+// It expects requests to have been identified by requestid.Handler.
+func Handler(logger zerolog.Logger, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		ctx := NewContext(req.Context())
+
+		rw := &responseWriter{ResponseWriter: w}
+		handler.ServeHTTP(rw, req.WithContext(ctx))
+
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+
+		l := with(logger.With(), ctx).Logger()
+		l.Info().
+			Str("method", req.Method).
+			Str("path", req.URL.RequestURI()).
+			Str("remote_addr", req.RemoteAddr).
+			Str("user_agent", req.UserAgent()).
+			Int("status", rw.status).
+			Int("size", rw.size).
+			Dur("duration", time.Since(start)).
+			Msg("http: served request")
+	})
+}
@@ -0,0 +1,56 @@
+package requestlog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/server/requestid"
+	"github.com/joyent/triton-service-groups/server/requestlog"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	global := log.Logger
+	log.Logger = zerolog.New(&buf)
+	defer func() { log.Logger = global }()
+
+	var access bytes.Buffer
+	handler := requestid.Handler(requestlog.Handler(zerolog.New(&access), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestlog.FromContext(r.Context()).Info().Msg("auth: authenticated")
+		zerolog.Ctx(r.Context()).Info().Msg("orchestrator: registered job")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("{}"))
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/tsg/groups", nil)
+	req.Header.Set(requestid.Header, "request-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	dec := json.NewDecoder(&buf)
+	for i := 0; i < 2; i++ {
+		var line map[string]interface{}
+		require.NoError(t, dec.Decode(&line))
+		assert.Equal(t, "request-1", line["request_id"])
+	}
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(access.Bytes(), &line))
+	assert.Equal(t, "request-1", line["request_id"])
+	assert.Equal(t, "POST", line["method"])
+	assert.Equal(t, "/v1/tsg/groups", line["path"])
+	assert.Equal(t, float64(http.StatusCreated), line["status"])
+	assert.Equal(t, float64(2), line["size"])
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, &log.Logger, requestlog.FromContext(context.Background()),
+		"the global logger is used outside of a request")
+}
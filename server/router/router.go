@@ -4,6 +4,9 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/schema"
+	"github.com/joyent/triton-service-groups/users"
 )
 
 type RouteTable []Routes
@@ -15,6 +18,78 @@ type Route struct {
 	Method  string
 	Pattern string
 	Handler http.HandlerFunc
+
+	// Permission is the minimum permission a session requires to call the
+	// route. Defaults to read-only for GET requests and full otherwise.
+	Permission users.Permission
+
+	// GroupScoped marks routes acting on a single group, identified by the
+	// {identifier} path variable. API tokens limited to specific groups can
+	// only call these routes.
+	GroupScoped bool
+
+	// GroupOf returns the group acted on by a GroupScoped route whose
+	// {identifier} path variable doesn't identify a group, such as one
+	// identifying an operation.
+	GroupOf handlers.GroupFunc
+
+	// Summary briefly describes the route within the OpenAPI document.
+	Summary string
+
+	// Request describes the request body accepted by the route, nil when the
+	// route doesn't read a body.
+	Request *schema.Schema
+
+	// Response describes the response body of a successful call, nil when
+	// the route responds without a body.
+	Response *schema.Schema
+
+	// ContentType is the media type of the response body. Defaults to
+	// application/json.
+	ContentType string
+
+	// Status is the HTTP status code of a successful call. Defaults to 200
+	// OK, or 204 No Content for routes without a Response.
+	Status int
+}
+
+// SuccessStatus returns the HTTP status code of a successful call to the
+// route.
+func (r Route) SuccessStatus() int {
+	switch {
+	case r.Status != 0:
+		return r.Status
+	case r.Response == nil:
+		return http.StatusNoContent
+	default:
+		return http.StatusOK
+	}
+}
+
+// RequiredPermission returns the minimum permission required to call the
+// route.
+func (r Route) RequiredPermission() users.Permission {
+	if r.Permission != "" {
+		return r.Permission
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return users.PermissionReadOnly
+	default:
+		return users.PermissionFull
+	}
+}
+
+// IsMutation returns true if the route changes any resource. Every call to
+// these routes is audited.
+func (r Route) IsMutation() bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
 }
 
 func WithRoutes(routes RouteTable) *mux.Router {
@@ -22,10 +97,15 @@ func WithRoutes(routes RouteTable) *mux.Router {
 
 	for _, rs := range routes {
 		for _, r := range rs {
+			handler := handlers.Authorize(r.RequiredPermission(), r.GroupScoped, r.GroupOf, r.Handler)
+			if r.IsMutation() {
+				handler = handlers.Audit(r.Name, handler)
+			}
+
 			router.Path(r.Pattern).
 				Methods(r.Method).
 				Name(r.Name).
-				HandlerFunc(r.Handler)
+				Handler(handler)
 		}
 	}
 
@@ -0,0 +1,171 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/handlers/auth"
+	"github.com/joyent/triton-service-groups/server/router"
+	"github.com/joyent/triton-service-groups/server/schema"
+	"github.com/joyent/triton-service-groups/users"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequiredPermission(t *testing.T) {
+	tests := []struct {
+		name     string
+		route    router.Route
+		required users.Permission
+	}{
+		{"get", router.Route{Method: http.MethodGet}, users.PermissionReadOnly},
+		{"post", router.Route{Method: http.MethodPost}, users.PermissionFull},
+		{"put", router.Route{Method: http.MethodPut}, users.PermissionFull},
+		{"delete", router.Route{Method: http.MethodDelete}, users.PermissionFull},
+		{"explicit", router.Route{Method: http.MethodPut, Permission: users.PermissionScaleOnly}, users.PermissionScaleOnly},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.required, test.route.RequiredPermission())
+		})
+	}
+}
+
+func TestSuccessStatus(t *testing.T) {
+	assert.Equal(t, http.StatusOK, router.Route{Response: schema.Group}.SuccessStatus())
+	assert.Equal(t, http.StatusNoContent, router.Route{}.SuccessStatus())
+	assert.Equal(t, http.StatusCreated, router.Route{Response: schema.Group, Status: http.StatusCreated}.SuccessStatus())
+}
+
+func TestWithRoutesRequiresPermission(t *testing.T) {
+	var called bool
+
+	routes := router.RouteTable{
+		router.Routes{
+			router.Route{
+				Name:    "GetThing",
+				Method:  http.MethodGet,
+				Pattern: "/v1/tsg/things",
+				Handler: func(w http.ResponseWriter, r *http.Request) {
+					called = true
+				},
+			},
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/tsg/things", nil)
+	rec := httptest.NewRecorder()
+
+	router.WithRoutes(routes).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.False(t, called)
+}
+
+func TestWithRoutesLimitsGroupScope(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+
+	routes := router.RouteTable{
+		router.Routes{
+			router.Route{
+				Name:        "GetThing",
+				Method:      http.MethodGet,
+				Pattern:     "/v1/tsg/things/{identifier}",
+				Handler:     handler,
+				GroupScoped: true,
+			},
+			router.Route{
+				Name:    "ListThings",
+				Method:  http.MethodGet,
+				Pattern: "/v1/tsg/things",
+				Handler: handler,
+			},
+		},
+	}
+
+	session := &auth.Session{
+		Permission: users.PermissionReadOnly,
+		GroupIDs:   []string{"6f873d02-172c-418f-8416-4da2b50d5c53"},
+	}
+
+	tests := []struct {
+		name string
+		path string
+		code int
+	}{
+		{"in scope", "/v1/tsg/things/6f873d02-172c-418f-8416-4da2b50d5c53", http.StatusOK},
+		{"out of scope", "/v1/tsg/things/d255305d-aa60-49bc-acc2-3713cf0beb1c", http.StatusForbidden},
+		{"not group scoped", "/v1/tsg/things", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			req = req.WithContext(handlers.WithAuthSession(req.Context(), session))
+			rec := httptest.NewRecorder()
+
+			router.WithRoutes(routes).ServeHTTP(rec, req)
+
+			assert.Equal(t, test.code, rec.Code)
+		})
+	}
+}
+
+func TestWithRoutesResolvesGroup(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	groupOf := func(r *http.Request) (string, error) {
+		if r.URL.Query().Get("fail") != "" {
+			return "", apierror.NotFound("thing not found")
+		}
+		return r.URL.Query().Get("group"), nil
+	}
+
+	routes := router.RouteTable{
+		router.Routes{
+			router.Route{
+				Name:        "GetThing",
+				Method:      http.MethodGet,
+				Pattern:     "/v1/tsg/things/{identifier}",
+				Handler:     handler,
+				GroupScoped: true,
+				GroupOf:     groupOf,
+			},
+		},
+	}
+
+	session := &auth.Session{
+		Permission: users.PermissionReadOnly,
+		GroupIDs:   []string{"6f873d02-172c-418f-8416-4da2b50d5c53"},
+	}
+
+	tests := []struct {
+		name string
+		path string
+		code int
+	}{
+		{"in scope", "/v1/tsg/things/1?group=6f873d02-172c-418f-8416-4da2b50d5c53", http.StatusOK},
+		{"out of scope", "/v1/tsg/things/1?group=d255305d-aa60-49bc-acc2-3713cf0beb1c", http.StatusForbidden},
+		{"without group", "/v1/tsg/things/6f873d02-172c-418f-8416-4da2b50d5c53", http.StatusForbidden},
+		{"failed", "/v1/tsg/things/1?fail=1", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			req = req.WithContext(handlers.WithAuthSession(req.Context(), session))
+			rec := httptest.NewRecorder()
+
+			router.WithRoutes(routes).ServeHTTP(rec, req)
+
+			assert.Equal(t, test.code, rec.Code)
+		})
+	}
+}
+
+func TestRouteIsMutation(t *testing.T) {
+	assert.False(t, router.Route{Method: http.MethodGet}.IsMutation())
+	assert.False(t, router.Route{Method: http.MethodHead}.IsMutation())
+	assert.True(t, router.Route{Method: http.MethodPost}.IsMutation())
+	assert.True(t, router.Route{Method: http.MethodPut}.IsMutation())
+	assert.True(t, router.Route{Method: http.MethodDelete}.IsMutation())
+}
@@ -3,90 +3,500 @@ package server
 import (
 	"net/http"
 
+	"github.com/joyent/triton-service-groups/accounts/v1"
+	"github.com/joyent/triton-service-groups/allowlist/v1"
+	"github.com/joyent/triton-service-groups/audit/v1"
 	"github.com/joyent/triton-service-groups/groups"
+	"github.com/joyent/triton-service-groups/keys/v1"
+	"github.com/joyent/triton-service-groups/operations/v1"
+	"github.com/joyent/triton-service-groups/secrets"
 	"github.com/joyent/triton-service-groups/server/router"
+	"github.com/joyent/triton-service-groups/server/schema"
+	"github.com/joyent/triton-service-groups/sessions/v1"
 	"github.com/joyent/triton-service-groups/templates"
+	"github.com/joyent/triton-service-groups/tokens/v1"
+	"github.com/joyent/triton-service-groups/users"
+	"github.com/joyent/triton-service-groups/users/v1"
 )
 
 var templateRoutes = router.Routes{
 	router.Route{
-		Name:    "ListTemplates",
-		Method:  http.MethodGet,
-		Pattern: "/v1/tsg/templates",
-		Handler: templates_v1.List,
+		Name:     "ListTemplates",
+		Method:   http.MethodGet,
+		Pattern:  "/v1/tsg/templates",
+		Handler:  templates_v1.List,
+		Summary:  "List the templates of the account",
+		Response: schema.ArrayOf(schema.Template),
 	},
 	router.Route{
-		Name:    "GetTemplate",
-		Method:  http.MethodGet,
-		Pattern: "/v1/tsg/templates/{identifier}",
-		Handler: templates_v1.Get,
+		Name:     "GetTemplate",
+		Method:   http.MethodGet,
+		Pattern:  "/v1/tsg/templates/{identifier}",
+		Handler:  templates_v1.Get,
+		Summary:  "Get a template",
+		Response: schema.Template,
+	},
+	router.Route{
+		Name:     "ListTemplateGroups",
+		Method:   http.MethodGet,
+		Pattern:  "/v1/tsg/templates/{identifier}/groups",
+		Handler:  templates_v1.ListGroups,
+		Summary:  "List the groups referencing a template",
+		Response: schema.ArrayOf(schema.TemplateGroup),
 	},
 	router.Route{
-		Name:    "CreateTemplate",
-		Method:  http.MethodPost,
-		Pattern: "/v1/tsg/templates",
-		Handler: templates_v1.Create,
+		Name:     "DiffTemplates",
+		Method:   http.MethodGet,
+		Pattern:  "/v1/tsg/templates/{identifier}/diff/{other}",
+		Handler:  templates_v1.Diff,
+		Summary:  "Compare two templates",
+		Response: schema.TemplateDiff,
+	},
+	router.Route{
+		Name:     "CreateTemplate",
+		Method:   http.MethodPost,
+		Pattern:  "/v1/tsg/templates",
+		Handler:  templates_v1.Create,
+		Summary:  "Create a template",
+		Request:  schema.Template,
+		Response: schema.Template,
+		Status:   http.StatusCreated,
 	},
 	router.Route{
 		Name:    "DeleteTemplate",
 		Method:  http.MethodDelete,
 		Pattern: "/v1/tsg/templates/{identifier}",
 		Handler: templates_v1.Delete,
+		Summary: "Delete a template",
 	},
 }
 
 var groupRoutes = router.Routes{
+	// The watch stream of every group is routed before the group identifier
+	// pattern, which would otherwise match it.
+	router.Route{
+		Name:        "WatchGroups",
+		Method:      http.MethodGet,
+		Pattern:     "/v1/tsg/groups/watch",
+		Handler:     groups_v1.Watch,
+		Summary:     "Stream changes to the groups of the account",
+		Response:    schema.GroupEvent,
+		ContentType: "text/event-stream",
+	},
+	router.Route{
+		Name:        "GetGroup",
+		Method:      http.MethodGet,
+		Pattern:     "/v1/tsg/groups/{identifier}",
+		Handler:     groups_v1.Get,
+		GroupScoped: true,
+		Summary:     "Get a group",
+		Response:    schema.Group,
+	},
+	router.Route{
+		Name:     "CreateGroup",
+		Method:   http.MethodPost,
+		Pattern:  "/v1/tsg/groups",
+		Handler:  groups_v1.Create,
+		Summary:  "Create a group",
+		Request:  schema.Group,
+		Response: schema.Group,
+		Status:   http.StatusCreated,
+	},
+	router.Route{
+		Name:        "UpdateGroup",
+		Method:      http.MethodPut,
+		Pattern:     "/v1/tsg/groups/{identifier}",
+		Handler:     groups_v1.Update,
+		GroupScoped: true,
+		Summary:     "Update a group",
+		Request:     schema.Group,
+		Response:    schema.Group,
+	},
+	router.Route{
+		Name:        "DeleteGroup",
+		Method:      http.MethodDelete,
+		Pattern:     "/v1/tsg/groups/{identifier}",
+		Handler:     groups_v1.Delete,
+		GroupScoped: true,
+		Summary:     "Delete a group",
+		Response:    schema.Operation,
+		Status:      http.StatusAccepted,
+	},
+	router.Route{
+		Name:     "ListGroups",
+		Method:   http.MethodGet,
+		Pattern:  "/v1/tsg/groups",
+		Handler:  groups_v1.List,
+		Summary:  "List the groups of the account",
+		Response: schema.ArrayOf(schema.Group),
+	},
+	router.Route{
+		Name:        "IncrementGroupCapacity",
+		Method:      http.MethodPut,
+		Pattern:     "/v1/tsg/groups/{identifier}/increment",
+		Handler:     groups_v1.Increment,
+		Permission:  users.PermissionScaleOnly,
+		GroupScoped: true,
+		Summary:     "Increment the capacity of a group",
+		Request:     schema.Increment,
+		Response:    schema.Operation,
+		Status:      http.StatusAccepted,
+	},
+	router.Route{
+		Name:        "DecrementGroupCapacity",
+		Method:      http.MethodPut,
+		Pattern:     "/v1/tsg/groups/{identifier}/decrement",
+		Handler:     groups_v1.Decrement,
+		Permission:  users.PermissionScaleOnly,
+		GroupScoped: true,
+		Summary:     "Decrement the capacity of a group",
+		Request:     schema.Decrement,
+		Response:    schema.Operation,
+		Status:      http.StatusAccepted,
+	},
+	router.Route{
+		Name:        "ListInstancesInGroup",
+		Method:      http.MethodGet,
+		Pattern:     "/v1/tsg/groups/{identifier}/instances",
+		Handler:     groups_v1.ListInstances,
+		GroupScoped: true,
+		Summary:     "List the instances of a group",
+		Response:    schema.ArrayOf(schema.Instance),
+	},
 	router.Route{
-		Name:    "GetGroup",
-		Method:  http.MethodGet,
-		Pattern: "/v1/tsg/groups/{identifier}",
-		Handler: groups_v1.Get,
+		Name:     "ResolveGroupSecrets",
+		Method:   http.MethodGet,
+		Pattern:  "/v1/tsg/groups/{identifier}/secrets",
+		Handler:  groups_v1.ResolveSecrets,
+		Summary:  "Get the values of a group's secrets, for its scaling job",
+		Response: schema.SecretValues,
 	},
 	router.Route{
-		Name:    "CreateGroup",
-		Method:  http.MethodPost,
-		Pattern: "/v1/tsg/groups",
-		Handler: groups_v1.Create,
+		Name:        "WatchGroup",
+		Method:      http.MethodGet,
+		Pattern:     "/v1/tsg/groups/{identifier}/watch",
+		Handler:     groups_v1.Watch,
+		GroupScoped: true,
+		Summary:     "Stream changes to a group",
+		Response:    schema.GroupEvent,
+		ContentType: "text/event-stream",
+	},
+}
+
+var secretRoutes = router.Routes{
+	router.Route{
+		Name:     "ListSecrets",
+		Method:   http.MethodGet,
+		Pattern:  "/v1/tsg/secrets",
+		Handler:  secrets_v1.List,
+		Summary:  "List the secrets of the account",
+		Response: schema.ArrayOf(schema.SecretMetadata),
+	},
+	router.Route{
+		Name:     "GetSecret",
+		Method:   http.MethodGet,
+		Pattern:  "/v1/tsg/secrets/{identifier}",
+		Handler:  secrets_v1.Get,
+		Summary:  "Get a secret, without its value",
+		Response: schema.SecretMetadata,
 	},
 	router.Route{
-		Name:    "UpdateGroup",
-		Method:  http.MethodPut,
-		Pattern: "/v1/tsg/groups/{identifier}",
-		Handler: groups_v1.Update,
+		Name:     "CreateSecret",
+		Method:   http.MethodPost,
+		Pattern:  "/v1/tsg/secrets",
+		Handler:  secrets_v1.Create,
+		Summary:  "Create a secret",
+		Request:  schema.Secret,
+		Response: schema.SecretMetadata,
+		Status:   http.StatusCreated,
 	},
 	router.Route{
-		Name:    "DeleteGroup",
+		Name:     "UpdateSecret",
+		Method:   http.MethodPut,
+		Pattern:  "/v1/tsg/secrets/{identifier}",
+		Handler:  secrets_v1.Update,
+		Summary:  "Replace the value of a secret",
+		Request:  schema.Secret,
+		Response: schema.SecretMetadata,
+	},
+	router.Route{
+		Name:    "DeleteSecret",
 		Method:  http.MethodDelete,
-		Pattern: "/v1/tsg/groups/{identifier}",
-		Handler: groups_v1.Delete,
+		Pattern: "/v1/tsg/secrets/{identifier}",
+		Handler: secrets_v1.Delete,
+		Summary: "Delete a secret",
+	},
+}
+
+var userRoutes = router.Routes{
+	router.Route{
+		Name:       "ListUsers",
+		Method:     http.MethodGet,
+		Pattern:    "/v1/tsg/users",
+		Handler:    users_v1.List,
+		Permission: users.PermissionOwner,
+		Summary:    "List the sub-users granted access",
+		Response:   schema.ArrayOf(schema.User),
 	},
 	router.Route{
-		Name:    "ListGroups",
-		Method:  http.MethodGet,
-		Pattern: "/v1/tsg/groups",
-		Handler: groups_v1.List,
+		Name:       "GetUser",
+		Method:     http.MethodGet,
+		Pattern:    "/v1/tsg/users/{identifier}",
+		Handler:    users_v1.Get,
+		Permission: users.PermissionOwner,
+		Summary:    "Get a sub-user",
+		Response:   schema.User,
 	},
 	router.Route{
-		Name:    "IncrementGroupCapacity",
-		Method:  http.MethodPut,
-		Pattern: "/v1/tsg/groups/{identifier}/increment",
-		Handler: groups_v1.Increment,
+		Name:       "CreateUser",
+		Method:     http.MethodPost,
+		Pattern:    "/v1/tsg/users",
+		Handler:    users_v1.Create,
+		Permission: users.PermissionOwner,
+		Summary:    "Grant a sub-user access",
+		Request:    schema.User,
+		Response:   schema.User,
+		Status:     http.StatusCreated,
+	},
+	router.Route{
+		Name:       "UpdateUser",
+		Method:     http.MethodPut,
+		Pattern:    "/v1/tsg/users/{identifier}",
+		Handler:    users_v1.Update,
+		Permission: users.PermissionOwner,
+		Summary:    "Update the permission of a sub-user",
+		Request:    schema.User,
+		Response:   schema.User,
+	},
+	router.Route{
+		Name:       "DeleteUser",
+		Method:     http.MethodDelete,
+		Pattern:    "/v1/tsg/users/{identifier}",
+		Handler:    users_v1.Delete,
+		Permission: users.PermissionOwner,
+		Summary:    "Revoke the access of a sub-user",
+	},
+}
+
+var tokenRoutes = router.Routes{
+	router.Route{
+		Name:       "ListTokens",
+		Method:     http.MethodGet,
+		Pattern:    "/v1/tsg/tokens",
+		Handler:    tokens_v1.List,
+		Permission: users.PermissionOwner,
+		Summary:    "List the API tokens of the account",
+		Response:   schema.ArrayOf(schema.Token),
+	},
+	router.Route{
+		Name:       "GetToken",
+		Method:     http.MethodGet,
+		Pattern:    "/v1/tsg/tokens/{identifier}",
+		Handler:    tokens_v1.Get,
+		Permission: users.PermissionOwner,
+		Summary:    "Get an API token",
+		Response:   schema.Token,
+	},
+	router.Route{
+		Name:       "CreateToken",
+		Method:     http.MethodPost,
+		Pattern:    "/v1/tsg/tokens",
+		Handler:    tokens_v1.Create,
+		Permission: users.PermissionOwner,
+		Summary:    "Issue an API token",
+		Request:    schema.Token,
+		Response:   schema.Token,
+		Status:     http.StatusCreated,
+	},
+	router.Route{
+		Name:       "DeleteToken",
+		Method:     http.MethodDelete,
+		Pattern:    "/v1/tsg/tokens/{identifier}",
+		Handler:    tokens_v1.Delete,
+		Permission: users.PermissionOwner,
+		Summary:    "Revoke an API token",
+	},
+}
+
+// sessionRoutes sign the web console in and out. Any permission can sign in,
+// the session then acts with the permission of whoever created it.
+var sessionRoutes = router.Routes{
+	router.Route{
+		Name:       "CreateSession",
+		Method:     http.MethodPost,
+		Pattern:    "/v1/tsg/sessions",
+		Handler:    sessions_v1.Create,
+		Permission: users.PermissionReadOnly,
+		Summary:    "Sign in to the web console",
+		Response:   schema.Session,
+		Status:     http.StatusCreated,
+	},
+	router.Route{
+		Name:     "GetSession",
+		Method:   http.MethodGet,
+		Pattern:  "/v1/tsg/sessions/current",
+		Handler:  sessions_v1.Get,
+		Summary:  "Get the browser session of the web console",
+		Response: schema.Session,
+	},
+	router.Route{
+		Name:       "DeleteSession",
+		Method:     http.MethodDelete,
+		Pattern:    "/v1/tsg/sessions/current",
+		Handler:    sessions_v1.Delete,
+		Permission: users.PermissionReadOnly,
+		Summary:    "Sign out of the web console",
+	},
+}
+
+var allowListRoutes = router.Routes{
+	router.Route{
+		Name:       "ListAllowList",
+		Method:     http.MethodGet,
+		Pattern:    "/v1/tsg/allowlist",
+		Handler:    allowlist_v1.List,
+		Permission: users.PermissionOperator,
+		Summary:    "List the allow-list",
+		Response:   schema.ArrayOf(schema.AllowListEntry),
+	},
+	router.Route{
+		Name:       "GetAllowListEntry",
+		Method:     http.MethodGet,
+		Pattern:    "/v1/tsg/allowlist/{account}",
+		Handler:    allowlist_v1.Get,
+		Permission: users.PermissionOperator,
+		Summary:    "Get the allow-list entry of an account",
+		Response:   schema.AllowListEntry,
+	},
+	router.Route{
+		Name:       "UpdateAllowListEntry",
+		Method:     http.MethodPut,
+		Pattern:    "/v1/tsg/allowlist/{account}",
+		Handler:    allowlist_v1.Update,
+		Permission: users.PermissionOperator,
+		Summary:    "Allow or deny an account",
+		Request:    schema.AllowListEntry,
+		Response:   schema.AllowListEntry,
+	},
+	router.Route{
+		Name:       "DeleteAllowListEntry",
+		Method:     http.MethodDelete,
+		Pattern:    "/v1/tsg/allowlist/{account}",
+		Handler:    allowlist_v1.Delete,
+		Permission: users.PermissionOperator,
+		Summary:    "Remove the allow-list entry of an account",
+	},
+}
+
+var accountRoutes = router.Routes{
+	router.Route{
+		Name:       "OffboardAccount",
+		Method:     http.MethodPost,
+		Pattern:    "/v1/tsg/accounts/{account}/offboard",
+		Handler:    accounts_v1.Offboard,
+		Permission: users.PermissionOperator,
+		Summary:    "Offboard an account",
+		Response:   schema.Offboarding,
+	},
+}
+
+var auditRoutes = router.Routes{
+	router.Route{
+		Name:       "ListAuditRecords",
+		Method:     http.MethodGet,
+		Pattern:    "/v1/tsg/audit",
+		Handler:    audit_v1.List,
+		Permission: users.PermissionOwner,
+		Summary:    "List the audit records of the account",
+		Response:   schema.ArrayOf(schema.AuditRecord),
+	},
+}
+
+var schemaRoutes = router.Routes{
+	router.Route{
+		Name:     "ListSchemas",
+		Method:   http.MethodGet,
+		Pattern:  "/v1/tsg/schemas",
+		Handler:  schema.List,
+		Summary:  "List the published schemas",
+		Response: schema.ArrayOf(schema.SchemaLink),
+	},
+	router.Route{
+		Name:     "GetSchema",
+		Method:   http.MethodGet,
+		Pattern:  "/v1/tsg/schemas/{name}",
+		Handler:  schema.Get,
+		Summary:  "Get a published schema",
+		Response: schema.Document,
+	},
+	router.Route{
+		Name:     "GetOpenAPI",
+		Method:   http.MethodGet,
+		Pattern:  "/v1/tsg/openapi.json",
+		Handler:  getOpenAPI,
+		Summary:  "Get the OpenAPI document describing the API",
+		Response: schema.Document,
+	},
+}
+
+var keyRoutes = router.Routes{
+	router.Route{
+		Name:       "RotateKey",
+		Method:     http.MethodPost,
+		Pattern:    "/v1/tsg/keys/rotate",
+		Handler:    keys_v1.Rotate,
+		Permission: users.PermissionOwner,
+		Summary:    "Rotate the management key of the account",
+		Request:    schema.KeyRotation,
+		Response:   schema.KeyRotationResult,
+	},
+}
+
+var operationRoutes = router.Routes{
+	router.Route{
+		Name:        "ListOperations",
+		Method:      http.MethodGet,
+		Pattern:     "/v1/tsg/operations",
+		Handler:     operations_v1.List,
+		GroupScoped: true,
+		GroupOf:     operations_v1.GroupOf,
+		Summary:     "List the recent operations of the account",
+		Response:    schema.ArrayOf(schema.Operation),
 	},
 	router.Route{
-		Name:    "DecrementGroupCapacity",
-		Method:  http.MethodPut,
-		Pattern: "/v1/tsg/groups/{identifier}/decrement",
-		Handler: groups_v1.Decrement,
+		Name:        "GetOperation",
+		Method:      http.MethodGet,
+		Pattern:     "/v1/tsg/operations/{identifier}",
+		Handler:     operations_v1.Get,
+		GroupScoped: true,
+		GroupOf:     operations_v1.GroupOf,
+		Summary:     "Get an operation",
+		Response:    schema.Operation,
 	},
 	router.Route{
-		Name:    "ListInstancesInGroup",
-		Method:  http.MethodGet,
-		Pattern: "/v1/tsg/groups/{identifier}/instances",
-		Handler: groups_v1.ListInstances,
+		Name:        "CancelOperation",
+		Method:      http.MethodPost,
+		Pattern:     "/v1/tsg/operations/{identifier}/cancel",
+		Handler:     operations_v1.Cancel,
+		Permission:  users.PermissionScaleOnly,
+		GroupScoped: true,
+		GroupOf:     operations_v1.GroupOf,
+		Summary:     "Cancel a running operation",
+		Response:    schema.Operation,
 	},
 }
 
 var RoutingTable = router.RouteTable{
 	templateRoutes,
 	groupRoutes,
+	operationRoutes,
+	secretRoutes,
+	userRoutes,
+	keyRoutes,
+	tokenRoutes,
+	sessionRoutes,
+	allowListRoutes,
+	accountRoutes,
+	auditRoutes,
+	schemaRoutes,
 }
@@ -0,0 +1,61 @@
+package server_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/server"
+	"github.com/joyent/triton-service-groups/server/openapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withoutBody lists the POST and PUT routes which don't read a request body.
+var withoutBody = map[string]bool{
+	"OffboardAccount": true,
+	"CancelOperation": true,
+	"CreateSession":   true,
+}
+
+func TestRoutesHaveSchemas(t *testing.T) {
+	for _, routes := range server.RoutingTable {
+		for _, route := range routes {
+			t.Run(route.Name, func(t *testing.T) {
+				assert.NotEmpty(t, route.Summary, "route is missing a summary")
+
+				switch route.SuccessStatus() {
+				case http.StatusAccepted, http.StatusNoContent:
+				default:
+					assert.NotNil(t, route.Response, "route is missing a response schema")
+				}
+
+				switch route.Method {
+				case http.MethodPost, http.MethodPut, http.MethodPatch:
+					if !withoutBody[route.Name] {
+						assert.NotNil(t, route.Request, "route is missing a request schema")
+					}
+				default:
+					assert.Nil(t, route.Request, "%s routes don't read a request body", route.Method)
+				}
+			})
+		}
+	}
+}
+
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	doc, err := openapi.New(server.RoutingTable, "test")
+	require.NoError(t, err)
+
+	operations := make(map[string]bool)
+	for _, item := range doc.Paths {
+		for _, op := range item {
+			operations[op.OperationID] = true
+		}
+	}
+
+	for _, routes := range server.RoutingTable {
+		for _, route := range routes {
+			assert.True(t, operations[route.Name], "route %q is missing from the document", route.Name)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package schema
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrInvalidBody is written when a request body fails validation.
+var ErrInvalidBody = apierror.New(http.StatusUnprocessableEntity, apierror.CodeInvalidBody, "invalid request body")
+
+type schemaLink struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+// List serves the names and locations of every published schema.
+func List(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(Published))
+	for name := range Published {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	links := make([]schemaLink, 0, len(names))
+	for _, name := range names {
+		links = append(links, schemaLink{Name: name, ID: Published[name].ID})
+	}
+
+	bytes, err := json.Marshal(links)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, "application/json", http.StatusOK)
+}
+
+// Get serves a single published schema as a JSON Schema document.
+func Get(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	s, ok := Published[vars["name"]]
+	if !ok {
+		apierror.Write(w, r, apierror.NotFound("schema %q not found", vars["name"]))
+		return
+	}
+
+	bytes, err := json.Marshal(s)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, "application/schema+json", http.StatusOK)
+}
+
+// WriteError writes a request body error as a 422 response. Validation errors
+// list every failure with its JSON Pointer within the error details.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		apierror.Write(w, r, apierror.New(http.StatusUnprocessableEntity, apierror.CodeInvalidBody, err.Error()))
+		return
+	}
+
+	details := make([]*apierror.Detail, 0, len(verr.Errors))
+	for _, ferr := range verr.Errors {
+		details = append(details, &apierror.Detail{
+			Pointer: ferr.Pointer,
+			Message: ferr.Message,
+		})
+	}
+
+	apierror.Write(w, r, ErrInvalidBody.WithDetails(details...))
+}
+
+func writeJSONResponse(w http.ResponseWriter, bytes []byte, contentType string, statusCode int) {
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if n, err := w.Write(bytes); err != nil {
+		log.Printf("%v", err)
+	} else if n != len(bytes) {
+		log.Printf("short write: %d/%d", n, len(bytes))
+	}
+}
@@ -0,0 +1,377 @@
+package schema
+
+import (
+	"github.com/joyent/triton-service-groups/server/handlers/auth"
+	"github.com/joyent/triton-service-groups/users"
+)
+
+// Group describes the request body used to create and update a group.
+var Group = &Schema{
+	ID:    "/v1/tsg/schemas/group",
+	Title: "Group",
+	Type:  "object",
+	Required: []string{
+		"group_name",
+		"template_id",
+		"capacity",
+	},
+	Properties: map[string]*Schema{
+		"id": {
+			Type:     "string",
+			ReadOnly: true,
+		},
+		"group_name": {
+			Type:        "string",
+			Description: "The name of the group.",
+			MinLength:   Int(1),
+			MaxLength:   Int(182),
+		},
+		"template_id": {
+			Type:        "string",
+			Description: "The identifier of the template the group launches instances from.",
+			Format:      "uuid",
+		},
+		"capacity": {
+			Type:        "integer",
+			Description: "The desired number of compute instances.",
+			Minimum:     Float(0),
+			Maximum:     Float(100),
+		},
+		"created_at": {
+			Type:     "string",
+			Format:   "date-time",
+			ReadOnly: true,
+		},
+		"updated_at": {
+			Type:     "string",
+			Format:   "date-time",
+			ReadOnly: true,
+		},
+		"created_by": {
+			Type:     "string",
+			ReadOnly: true,
+		},
+		"updated_by": {
+			Type:     "string",
+			ReadOnly: true,
+		},
+	},
+}
+
+// Template describes the request body used to create a template.
+var Template = &Schema{
+	ID:    "/v1/tsg/schemas/template",
+	Title: "Template",
+	Type:  "object",
+	Required: []string{
+		"template_name",
+		"package",
+		"image_id",
+	},
+	Properties: map[string]*Schema{
+		"id": {
+			Type:     "string",
+			ReadOnly: true,
+		},
+		"template_name": {
+			Type:        "string",
+			Description: "The name of the template.",
+			MinLength:   Int(1),
+		},
+		"package": {
+			Type:        "string",
+			Description: "The identifier of the package used to launch compute instances.",
+			Format:      "uuid",
+		},
+		"image_id": {
+			Type:        "string",
+			Description: "The identifier of the image used to launch compute instances.",
+			Format:      "uuid",
+		},
+		"firewall_enabled": {
+			Type:        "boolean",
+			Description: "Whether to enable the firewall on launched compute instances.",
+		},
+		"networks": {
+			Type:        "array",
+			Description: "The identifiers of the networks attached to launched compute instances.",
+			Nullable:    true,
+			Items: &Schema{
+				Type:   "string",
+				Format: "uuid",
+			},
+		},
+		"userdata": {
+			Type:        "string",
+			Description: "Arbitrary data copied to compute instances on boot.",
+			Nullable:    true,
+		},
+		"metadata": {
+			Type:                 "object",
+			Description:          "Metadata applied to launched compute instances.",
+			Nullable:             true,
+			AdditionalProperties: &Schema{Type: "string"},
+		},
+		"tags": {
+			Type:                 "object",
+			Description:          "Tags applied to launched compute instances.",
+			Nullable:             true,
+			AdditionalProperties: &Schema{Type: "string"},
+		},
+		"created_at": {
+			Type:     "string",
+			Format:   "date-time",
+			ReadOnly: true,
+		},
+		"created_by": {
+			Type:     "string",
+			ReadOnly: true,
+		},
+	},
+}
+
+func scaleSchema(id, title string, required ...string) *Schema {
+	return &Schema{
+		ID:       id,
+		Title:    title,
+		Type:     "object",
+		Required: required,
+		Properties: map[string]*Schema{
+			"instance_count": {
+				Type:        "integer",
+				Description: "The number of compute instances to scale the group by.",
+				Minimum:     Float(0),
+			},
+			"max_instance": {
+				Type:        "integer",
+				Description: "The maximum number of compute instances allowed in the group.",
+				Minimum:     Float(0),
+			},
+			"min_instance": {
+				Type:        "integer",
+				Description: "The minimum number of compute instances allowed in the group.",
+				Minimum:     Float(0),
+			},
+		},
+	}
+}
+
+// Increment describes the request body used to increment a group's capacity.
+var Increment = scaleSchema("/v1/tsg/schemas/increment", "Increment",
+	"instance_count", "max_instance")
+
+// Decrement describes the request body used to decrement a group's capacity.
+// Without a min_instance the capacity can be decremented to 0.
+var Decrement = scaleSchema("/v1/tsg/schemas/decrement", "Decrement",
+	"instance_count")
+
+// Secret describes the request body used to create and update a secret.
+var Secret = &Schema{
+	ID:    "/v1/tsg/schemas/secret",
+	Title: "Secret",
+	Type:  "object",
+	Required: []string{
+		"name",
+		"value",
+	},
+	Properties: map[string]*Schema{
+		"name": {
+			Type:        "string",
+			Description: "The name of the secret, used to reference it from templates.",
+			Pattern:     `^[a-zA-Z][a-zA-Z0-9_]{0,127}$`,
+		},
+		"value": {
+			Type:        "string",
+			Description: "The write-only value of the secret.",
+			MinLength:   Int(1),
+		},
+	},
+}
+
+// User describes the request body used to grant a sub-user access and update
+// their permission.
+var User = &Schema{
+	ID:    "/v1/tsg/schemas/user",
+	Title: "User",
+	Type:  "object",
+	Required: []string{
+		"username",
+		"permission",
+	},
+	Properties: map[string]*Schema{
+		"id": {
+			Type:     "string",
+			ReadOnly: true,
+		},
+		"username": {
+			Type:        "string",
+			Description: "The login of the Triton sub-user.",
+			Pattern:     `^[a-zA-Z][a-zA-Z0-9_\.@]{2,}$`,
+		},
+		"permission": {
+			Type:        "string",
+			Description: "The access granted to the sub-user.",
+			Enum: []string{
+				string(users.PermissionReadOnly),
+				string(users.PermissionScaleOnly),
+				string(users.PermissionFull),
+			},
+		},
+		"created_at": {
+			Type:     "string",
+			Format:   "date-time",
+			ReadOnly: true,
+		},
+		"updated_at": {
+			Type:     "string",
+			Format:   "date-time",
+			ReadOnly: true,
+		},
+	},
+}
+
+// KeyRotation describes the request body used to rotate the account's TSG
+// management key. The body is optional.
+var KeyRotation = &Schema{
+	ID:    "/v1/tsg/schemas/key-rotation",
+	Title: "KeyRotation",
+	Type:  "object",
+	Properties: map[string]*Schema{
+		"type": {
+			Type:        "string",
+			Description: "The type of the new key, defaults to the configured key type.",
+			Enum: []string{
+				string(auth.KeyTypeRSA),
+				string(auth.KeyTypeECDSA),
+				string(auth.KeyTypeEd25519),
+			},
+		},
+	},
+}
+
+// Token describes the request body used to issue an API token.
+var Token = &Schema{
+	ID:    "/v1/tsg/schemas/token",
+	Title: "Token",
+	Type:  "object",
+	Required: []string{
+		"name",
+		"permission",
+	},
+	Properties: map[string]*Schema{
+		"id": {
+			Type:     "string",
+			ReadOnly: true,
+		},
+		"name": {
+			Type:        "string",
+			Description: "A name describing the automation client using the token.",
+			Pattern:     `^[a-zA-Z][a-zA-Z0-9_\-\.]{0,127}$`,
+		},
+		"permission": {
+			Type:        "string",
+			Description: "The access granted to the token.",
+			Enum: []string{
+				string(users.PermissionReadOnly),
+				string(users.PermissionScaleOnly),
+				string(users.PermissionFull),
+			},
+		},
+		"groups": {
+			Type:        "array",
+			Description: "The groups the token is limited to, every group when empty.",
+			Nullable:    true,
+			Items: &Schema{
+				Type:   "string",
+				Format: "uuid",
+			},
+		},
+		"expires_at": {
+			Type:        "string",
+			Description: "When the token expires, defaults to 30 days from now.",
+			Format:      "date-time",
+		},
+		"token": {
+			Type:        "string",
+			Description: "The token secret, only returned once when the token is issued.",
+			ReadOnly:    true,
+		},
+		"prefix": {
+			Type:     "string",
+			ReadOnly: true,
+		},
+		"revoked_at": {
+			Type:     "string",
+			Format:   "date-time",
+			Nullable: true,
+			ReadOnly: true,
+		},
+		"created_by": {
+			Type:     "string",
+			ReadOnly: true,
+		},
+		"created_at": {
+			Type:     "string",
+			Format:   "date-time",
+			ReadOnly: true,
+		},
+		"updated_at": {
+			Type:     "string",
+			Format:   "date-time",
+			ReadOnly: true,
+		},
+	},
+}
+
+// AllowListEntry describes the request body used to allow or deny an account
+// access to TSG.
+var AllowListEntry = &Schema{
+	ID:    "/v1/tsg/schemas/allowlist-entry",
+	Title: "AllowListEntry",
+	Type:  "object",
+	Required: []string{
+		"allowed",
+	},
+	Properties: map[string]*Schema{
+		"account_name": {
+			Type:     "string",
+			ReadOnly: true,
+		},
+		"allowed": {
+			Type:        "boolean",
+			Description: "Whether the account can access TSG.",
+		},
+		"note": {
+			Type:        "string",
+			Description: "Why the account was allowed or denied.",
+			MaxLength:   Int(1024),
+		},
+		"created_by": {
+			Type:     "string",
+			ReadOnly: true,
+		},
+		"created_at": {
+			Type:     "string",
+			Format:   "date-time",
+			ReadOnly: true,
+		},
+		"updated_at": {
+			Type:     "string",
+			Format:   "date-time",
+			ReadOnly: true,
+		},
+	},
+}
+
+// Published holds every schema served by the API, keyed by name.
+var Published = map[string]*Schema{
+	"group":           Group,
+	"template":        Template,
+	"increment":       Increment,
+	"decrement":       Decrement,
+	"secret":          Secret,
+	"user":            User,
+	"key-rotation":    KeyRotation,
+	"token":           Token,
+	"allowlist-entry": AllowListEntry,
+}
@@ -0,0 +1,244 @@
+package schema
+
+// The schemas below describe response bodies only. They're published within
+// the OpenAPI document of the API rather than used for validation. Responses
+// which match a request body, such as a group, reuse its schema.
+
+// Any describes a value of any type.
+var Any = &Schema{}
+
+// SecretMetadata describes a secret as returned by the API. The value of a
+// secret is never returned.
+var SecretMetadata = &Schema{
+	Title: "SecretMetadata",
+	Type:  "object",
+	Properties: map[string]*Schema{
+		"id":         {Type: "string", Format: "uuid"},
+		"name":       {Type: "string", Description: "The name of the secret."},
+		"created_at": {Type: "string", Format: "date-time"},
+		"updated_at": {Type: "string", Format: "date-time"},
+		"created_by": {Type: "string"},
+		"updated_by": {Type: "string"},
+	},
+}
+
+// SecretValues describes the values of the secrets referenced by a group's
+// template, keyed by name. They're only returned to the group's scaling job.
+var SecretValues = &Schema{
+	Title:                "SecretValues",
+	Type:                 "object",
+	AdditionalProperties: &Schema{Type: "string"},
+}
+
+// TemplateGroup describes a group which references a template.
+var TemplateGroup = &Schema{
+	Title: "TemplateGroup",
+	Type:  "object",
+	Properties: map[string]*Schema{
+		"id":         {Type: "string", Format: "uuid"},
+		"group_name": {Type: "string"},
+		"capacity":   {Type: "integer"},
+	},
+}
+
+var valueChange = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"from": Any,
+		"to":   Any,
+	},
+}
+
+var keyChange = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"key":    {Type: "string"},
+		"change": {Type: "string", Enum: []string{"added", "removed", "changed"}},
+		"from":   {Type: "string"},
+		"to":     {Type: "string"},
+	},
+}
+
+// TemplateDiff describes the differences between two templates. Fields which
+// are the same within both templates are omitted.
+var TemplateDiff = &Schema{
+	Title: "TemplateDiff",
+	Type:  "object",
+	Properties: map[string]*Schema{
+		"from":             {Type: "string", Format: "uuid"},
+		"to":               {Type: "string", Format: "uuid"},
+		"template_name":    valueChange,
+		"package":          valueChange,
+		"image_id":         valueChange,
+		"firewall_enabled": valueChange,
+		"networks": {
+			Type: "object",
+			Properties: map[string]*Schema{
+				"added":   ArrayOf(&Schema{Type: "string"}),
+				"removed": ArrayOf(&Schema{Type: "string"}),
+			},
+		},
+		"tags":     ArrayOf(keyChange),
+		"metadata": ArrayOf(keyChange),
+		"userdata": {Type: "string", Description: "A unified diff of the userdata."},
+	},
+}
+
+// Instance describes a compute instance launched by a group, as returned by
+// CloudAPI. Only the most useful fields are listed.
+var Instance = &Schema{
+	Title: "Instance",
+	Type:  "object",
+	Properties: map[string]*Schema{
+		"id":        {Type: "string", Format: "uuid"},
+		"name":      {Type: "string"},
+		"state":     {Type: "string"},
+		"image":     {Type: "string", Format: "uuid"},
+		"package":   {Type: "string"},
+		"ips":       ArrayOf(&Schema{Type: "string"}),
+		"primaryIp": {Type: "string"},
+		"created":   {Type: "string", Format: "date-time"},
+		"updated":   {Type: "string", Format: "date-time"},
+	},
+	AdditionalProperties: Any,
+}
+
+// KeyRotationResult describes the management key created by a key rotation.
+var KeyRotationResult = &Schema{
+	Title: "KeyRotationResult",
+	Type:  "object",
+	Properties: map[string]*Schema{
+		"name":                 {Type: "string"},
+		"type":                 {Type: "string"},
+		"fingerprint":          {Type: "string"},
+		"previous_fingerprint": {Type: "string"},
+		"groups": {
+			Type:        "integer",
+			Description: "The number of groups whose orchestrator jobs were updated.",
+		},
+		"rotated_at": {Type: "string", Format: "date-time"},
+	},
+}
+
+// Offboarding describes what was removed when offboarding an account.
+var Offboarding = &Schema{
+	Title: "Offboarding",
+	Type:  "object",
+	Properties: map[string]*Schema{
+		"account_name":  {Type: "string"},
+		"groups":        ArrayOf(&Schema{Type: "string"}),
+		"instances":     {Type: "integer"},
+		"templates":     ArrayOf(&Schema{Type: "string"}),
+		"key":           {Type: "string"},
+		"fingerprint":   {Type: "string"},
+		"tokens":        {Type: "integer"},
+		"failed":        ArrayOf(&Schema{Type: "string"}),
+		"dry_run":       {Type: "boolean"},
+		"offboarded_at": {Type: "string", Format: "date-time"},
+	},
+}
+
+// AuditRecord describes a single audited API call.
+var AuditRecord = &Schema{
+	Title: "AuditRecord",
+	Type:  "object",
+	Properties: map[string]*Schema{
+		"id":          {Type: "string", Format: "uuid"},
+		"actor":       {Type: "string"},
+		"user_name":   {Type: "string"},
+		"token_id":    {Type: "string"},
+		"fingerprint": {Type: "string"},
+		"route":       {Type: "string"},
+		"method":      {Type: "string"},
+		"path":        {Type: "string"},
+		"resource_id": {Type: "string"},
+		"request": {
+			Description: "The request body, with sensitive fields redacted.",
+		},
+		"status":     {Type: "integer"},
+		"outcome":    {Type: "string", Enum: []string{"success", "failure"}},
+		"created_at": {Type: "string", Format: "date-time"},
+	},
+}
+
+// SchemaLink describes the name and location of a published schema.
+var SchemaLink = &Schema{
+	Title: "SchemaLink",
+	Type:  "object",
+	Properties: map[string]*Schema{
+		"name": {Type: "string"},
+		"id":   {Type: "string"},
+	},
+}
+
+// Document describes a JSON document, such as a JSON Schema or the OpenAPI
+// document of the API.
+var Document = &Schema{
+	Title:                "Document",
+	Type:                 "object",
+	AdditionalProperties: Any,
+}
+
+// GroupEvent describes a change to a group, as sent within the data field of
+// each message of a watch stream.
+var GroupEvent = &Schema{
+	Title: "GroupEvent",
+	Type:  "object",
+	Properties: map[string]*Schema{
+		"id": {
+			Type:        "string",
+			Description: "The identifier to resume watching after, sent as Last-Event-ID.",
+		},
+		"type": {
+			Type: "string",
+			Enum: []string{"created", "updated", "scaled", "deleted", "status_changed"},
+		},
+		"group_id": {Type: "string", Format: "uuid"},
+		"actor":    {Type: "string"},
+		"data": {
+			Description: "The group after the change, or its status for status_changed events.",
+		},
+		"created_at": {Type: "string", Format: "date-time"},
+	},
+}
+
+// Operation describes a long-running action, such as scaling a group.
+var Operation = &Schema{
+	Title: "Operation",
+	Type:  "object",
+	Properties: map[string]*Schema{
+		"id":       {Type: "string", Format: "uuid"},
+		"group_id": {Type: "string", Format: "uuid"},
+		"type": {
+			Type: "string",
+			Enum: []string{"create_group", "update_group", "scale_group", "delete_group"},
+		},
+		"state": {
+			Type: "string",
+			Enum: []string{"running", "succeeded", "failed", "cancelled"},
+		},
+		"progress":    {Type: "string", Description: "The current step of a running operation."},
+		"error":       {Type: "string", Description: "Why a failed operation failed."},
+		"created_by":  {Type: "string"},
+		"created_at":  {Type: "string", Format: "date-time"},
+		"updated_at":  {Type: "string", Format: "date-time"},
+		"finished_at": {Type: "string", Format: "date-time"},
+	},
+}
+
+// Session describes the browser session of the web console.
+var Session = &Schema{
+	Title: "Session",
+	Type:  "object",
+	Properties: map[string]*Schema{
+		"id":         {Type: "string", Format: "uuid"},
+		"actor":      {Type: "string"},
+		"permission": {Type: "string"},
+		"csrf_token": {
+			Type:        "string",
+			Description: "Sent within the X-CSRF-Token header of every request changing a resource.",
+		},
+		"expires_at": {Type: "string", Format: "date-time"},
+		"created_at": {Type: "string", Format: "date-time"},
+	},
+}
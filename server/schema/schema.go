@@ -0,0 +1,355 @@
+// Package schema publishes the JSON Schemas of API request payloads and
+// validates incoming request bodies against them. Only the subset of JSON
+// Schema used by the TSG API is implemented.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const draft = "http://json-schema.org/draft-07/schema#"
+
+// Schema describes a JSON value.
+type Schema struct {
+	ID          string
+	Title       string
+	Description string
+	Type        string
+
+	// Nullable allows the value to be null in addition to Type.
+	Nullable bool
+
+	// ReadOnly marks properties which are returned by the API and accepted, but
+	// ignored, within requests.
+	ReadOnly bool
+
+	Properties map[string]*Schema
+	Required   []string
+
+	// AdditionalProperties describes the values of properties not listed
+	// within Properties. Objects without AdditionalProperties reject any
+	// unknown property.
+	AdditionalProperties *Schema
+
+	Items *Schema
+
+	Enum      []string
+	Format    string
+	Pattern   string
+	MinLength *int
+	MaxLength *int
+	Minimum   *float64
+	Maximum   *float64
+}
+
+// Int returns a pointer to i, used for setting length constraints.
+func Int(i int) *int {
+	return &i
+}
+
+// Float returns a pointer to f, used for setting numeric constraints.
+func Float(f float64) *float64 {
+	return &f
+}
+
+// ArrayOf returns a schema describing an array of items.
+func ArrayOf(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+// MarshalJSON renders the schema as a JSON Schema document.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	doc := make(map[string]interface{})
+
+	if s.ID != "" {
+		doc["$schema"] = draft
+		doc["$id"] = s.ID
+	}
+	if s.Title != "" {
+		doc["title"] = s.Title
+	}
+	if s.Description != "" {
+		doc["description"] = s.Description
+	}
+
+	if s.Nullable {
+		doc["type"] = []string{s.Type, "null"}
+	} else if s.Type != "" {
+		doc["type"] = s.Type
+	}
+
+	if s.ReadOnly {
+		doc["readOnly"] = true
+	}
+
+	if s.Type == "object" {
+		if len(s.Properties) > 0 {
+			doc["properties"] = s.Properties
+		}
+		if len(s.Required) > 0 {
+			doc["required"] = s.Required
+		}
+		if s.AdditionalProperties != nil {
+			doc["additionalProperties"] = s.AdditionalProperties
+		} else {
+			doc["additionalProperties"] = false
+		}
+	}
+
+	if s.Items != nil {
+		doc["items"] = s.Items
+	}
+	if len(s.Enum) > 0 {
+		doc["enum"] = s.Enum
+	}
+	if s.Format != "" {
+		doc["format"] = s.Format
+	}
+	if s.Pattern != "" {
+		doc["pattern"] = s.Pattern
+	}
+	if s.MinLength != nil {
+		doc["minLength"] = *s.MinLength
+	}
+	if s.MaxLength != nil {
+		doc["maxLength"] = *s.MaxLength
+	}
+	if s.Minimum != nil {
+		doc["minimum"] = *s.Minimum
+	}
+	if s.Maximum != nil {
+		doc["maximum"] = *s.Maximum
+	}
+
+	return json.Marshal(doc)
+}
+
+// FieldError describes a single validation failure. Pointer is a JSON Pointer
+// (RFC 6901) to the offending value within the request body.
+type FieldError struct {
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+// ValidationError holds every validation failure of a request body.
+type ValidationError struct {
+	Errors []*FieldError `json:"errors"`
+}
+
+// NewValidationError constructs a ValidationError with a single failure.
+func NewValidationError(pointer, format string, args ...interface{}) *ValidationError {
+	return &ValidationError{
+		Errors: []*FieldError{{Pointer: pointer, Message: fmt.Sprintf(format, args...)}},
+	}
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", fe.Pointer, fe.Message))
+	}
+	return "invalid request body: " + strings.Join(msgs, "; ")
+}
+
+// Add appends a failure to the ValidationError.
+func (e *ValidationError) Add(pointer, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, &FieldError{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+}
+
+// Decode validates body against s, then unmarshals it into v. A
+// *ValidationError is returned if body doesn't match the schema.
+func Decode(s *Schema, body []byte, v interface{}) error {
+	if err := Validate(s, body); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return NewValidationError("", "%v", err)
+	}
+
+	return nil
+}
+
+// Validate checks body against s, returning a *ValidationError listing every
+// failure or nil when body is valid.
+func Validate(s *Schema, body []byte) error {
+	dec := json.NewDecoder(strings.NewReader(string(body)))
+	dec.UseNumber()
+
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return NewValidationError("", "request body is not valid JSON: %v", err)
+	}
+	if dec.More() {
+		return NewValidationError("", "request body must contain a single JSON value")
+	}
+
+	verr := &ValidationError{}
+	validate(s, value, "", verr)
+
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func validate(s *Schema, value interface{}, pointer string, verr *ValidationError) {
+	if value == nil {
+		if !s.Nullable {
+			verr.Add(pointer, "must be of type %s, not null", s.Type)
+		}
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			verr.Add(pointer, "must be of type object")
+			return
+		}
+		validateObject(s, obj, pointer, verr)
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			verr.Add(pointer, "must be of type array")
+			return
+		}
+		for i, item := range arr {
+			validate(s.Items, item, fmt.Sprintf("%s/%d", pointer, i), verr)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			verr.Add(pointer, "must be of type string")
+			return
+		}
+		validateString(s, str, pointer, verr)
+	case "integer", "number":
+		num, ok := value.(json.Number)
+		if !ok {
+			verr.Add(pointer, "must be of type %s", s.Type)
+			return
+		}
+		validateNumber(s, num, pointer, verr)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			verr.Add(pointer, "must be of type boolean")
+		}
+	}
+}
+
+func validateObject(s *Schema, obj map[string]interface{}, pointer string, verr *ValidationError) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			verr.Add(pointer+"/"+escape(name), "is required")
+		}
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		child := pointer + "/" + escape(name)
+
+		prop, ok := s.Properties[name]
+		if !ok {
+			prop = s.AdditionalProperties
+		}
+		if prop == nil {
+			verr.Add(child, "is not a known field")
+			continue
+		}
+
+		validate(prop, obj[name], child, verr)
+	}
+}
+
+func validateString(s *Schema, str string, pointer string, verr *ValidationError) {
+	length := len([]rune(str))
+
+	if s.MinLength != nil && length < *s.MinLength {
+		if *s.MinLength == 1 {
+			verr.Add(pointer, "cannot be empty")
+		} else {
+			verr.Add(pointer, "must be at least %d characters", *s.MinLength)
+		}
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		verr.Add(pointer, "cannot be more than %d characters", *s.MaxLength)
+	}
+
+	if len(s.Enum) > 0 && !contains(s.Enum, str) {
+		verr.Add(pointer, "must be one of %q", s.Enum)
+	}
+
+	if s.Pattern != "" {
+		if matched, err := regexp.MatchString(s.Pattern, str); err != nil || !matched {
+			verr.Add(pointer, "must match the pattern %q", s.Pattern)
+		}
+	}
+
+	switch s.Format {
+	case "uuid":
+		if _, err := uuid.Parse(str); err != nil {
+			verr.Add(pointer, "must be a valid UUID")
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			verr.Add(pointer, "must be a valid RFC 3339 date-time")
+		}
+	}
+}
+
+func validateNumber(s *Schema, num json.Number, pointer string, verr *ValidationError) {
+	f, err := num.Float64()
+	if err != nil {
+		verr.Add(pointer, "must be of type %s", s.Type)
+		return
+	}
+
+	if s.Type == "integer" && f != float64(int64(f)) {
+		verr.Add(pointer, "must be of type integer")
+		return
+	}
+
+	if s.Minimum != nil && f < *s.Minimum {
+		verr.Add(pointer, "must be greater than or equal to %v", *s.Minimum)
+	}
+	if s.Maximum != nil && f > *s.Maximum {
+		verr.Add(pointer, "must be less than or equal to %v", *s.Maximum)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// escape escapes a property name for use within a JSON Pointer.
+func escape(name string) string {
+	return strings.Replace(strings.Replace(name, "~", "~0", -1), "/", "~1", -1)
+}
+
+// Pointer builds a JSON Pointer from a list of property names.
+func Pointer(names ...string) string {
+	var pointer string
+	for _, name := range names {
+		pointer += "/" + escape(name)
+	}
+	return pointer
+}
@@ -0,0 +1,196 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/server/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fieldErrors(t *testing.T, err error) map[string]string {
+	require.Error(t, err)
+
+	verr, ok := err.(*schema.ValidationError)
+	require.True(t, ok, "expected a *schema.ValidationError, got %T", err)
+
+	errs := make(map[string]string, len(verr.Errors))
+	for _, fe := range verr.Errors {
+		errs[fe.Pointer] = fe.Message
+	}
+	return errs
+}
+
+func TestValidateGroup(t *testing.T) {
+	valid := `{
+		"group_name": "jolly-jelly",
+		"template_id": "29a08459-1a41-4ec9-bbb7-5c737f17a463",
+		"capacity": 2
+	}`
+	assert.NoError(t, schema.Validate(schema.Group, []byte(valid)))
+
+	readOnly := `{
+		"id": "722d25ed-f32a-4944-9861-8990e204850e",
+		"group_name": "jolly-jelly",
+		"template_id": "29a08459-1a41-4ec9-bbb7-5c737f17a463",
+		"capacity": 2,
+		"created_at": "2018-04-12T17:41:54Z"
+	}`
+	assert.NoError(t, schema.Validate(schema.Group, []byte(readOnly)))
+
+	tests := []struct {
+		name    string
+		body    string
+		pointer string
+		message string
+	}{
+		{
+			"unknown field",
+			`{"group_name": "jolly-jelly", "template_id": "29a08459-1a41-4ec9-bbb7-5c737f17a463", "capcity": 2}`,
+			"/capcity", "is not a known field",
+		},
+		{
+			"missing field",
+			`{"group_name": "jolly-jelly", "template_id": "29a08459-1a41-4ec9-bbb7-5c737f17a463"}`,
+			"/capacity", "is required",
+		},
+		{
+			"wrong type",
+			`{"group_name": "jolly-jelly", "template_id": "29a08459-1a41-4ec9-bbb7-5c737f17a463", "capacity": "2"}`,
+			"/capacity", "must be of type integer",
+		},
+		{
+			"fractional integer",
+			`{"group_name": "jolly-jelly", "template_id": "29a08459-1a41-4ec9-bbb7-5c737f17a463", "capacity": 2.5}`,
+			"/capacity", "must be of type integer",
+		},
+		{
+			"above maximum",
+			`{"group_name": "jolly-jelly", "template_id": "29a08459-1a41-4ec9-bbb7-5c737f17a463", "capacity": 101}`,
+			"/capacity", "must be less than or equal to 100",
+		},
+		{
+			"empty name",
+			`{"group_name": "", "template_id": "29a08459-1a41-4ec9-bbb7-5c737f17a463", "capacity": 2}`,
+			"/group_name", "cannot be empty",
+		},
+		{
+			"invalid uuid",
+			`{"group_name": "jolly-jelly", "template_id": "jolly-template", "capacity": 2}`,
+			"/template_id", "must be a valid UUID",
+		},
+		{
+			"null body",
+			`null`,
+			"", "must be of type object, not null",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errs := fieldErrors(t, schema.Validate(schema.Group, []byte(test.body)))
+			assert.Equal(t, test.message, errs[test.pointer])
+		})
+	}
+}
+
+func TestValidateCollectsEveryError(t *testing.T) {
+	body := `{"group_name": "", "template_id": "nope", "capacity": -1, "extra": true}`
+
+	errs := fieldErrors(t, schema.Validate(schema.Group, []byte(body)))
+	assert.Len(t, errs, 4)
+	assert.Contains(t, errs, "/group_name")
+	assert.Contains(t, errs, "/template_id")
+	assert.Contains(t, errs, "/capacity")
+	assert.Contains(t, errs, "/extra")
+}
+
+func TestValidateTemplate(t *testing.T) {
+	body := `{
+		"template_name": "jolly-template",
+		"package": "14aba044-d0f8-11e5-8c88-eb339a5da5d0",
+		"image_id": "342045ce-6af1-4adf-9ef1-e5bfaf9de28c",
+		"networks": null,
+		"userdata": null,
+		"metadata": {"env": "production"},
+		"tags": {"role": "web"}
+	}`
+	assert.NoError(t, schema.Validate(schema.Template, []byte(body)))
+
+	body = `{
+		"template_name": "jolly-template",
+		"package": "14aba044-d0f8-11e5-8c88-eb339a5da5d0",
+		"image_id": "342045ce-6af1-4adf-9ef1-e5bfaf9de28c",
+		"networks": ["27ea1d5f-df02-410e-843a-c60dba9ec5ca", "web"],
+		"metadata": {"a/b": 1}
+	}`
+	errs := fieldErrors(t, schema.Validate(schema.Template, []byte(body)))
+	assert.Equal(t, "must be a valid UUID", errs["/networks/1"])
+	assert.Equal(t, "must be of type string", errs["/metadata/a~1b"])
+}
+
+func TestValidateToken(t *testing.T) {
+	body := `{
+		"name": "deploy-bot",
+		"permission": "scale-only",
+		"groups": ["722d25ed-f32a-4944-9861-8990e204850e"],
+		"expires_at": "2018-11-19T00:00:00Z"
+	}`
+	assert.NoError(t, schema.Validate(schema.Token, []byte(body)))
+
+	body = `{"name": "deploy-bot", "permission": "owner", "expires_at": "next week"}`
+	errs := fieldErrors(t, schema.Validate(schema.Token, []byte(body)))
+	assert.Equal(t, `must be one of ["read-only" "scale-only" "full"]`, errs["/permission"])
+	assert.Equal(t, "must be a valid RFC 3339 date-time", errs["/expires_at"])
+}
+
+func TestValidateScale(t *testing.T) {
+	assert.NoError(t, schema.Validate(schema.Decrement, []byte(`{"instance_count": 1}`)))
+
+	errs := fieldErrors(t, schema.Validate(schema.Increment, []byte(`{"instance_count": 1}`)))
+	assert.Contains(t, errs, "/max_instance")
+}
+
+func TestDecode(t *testing.T) {
+	var input struct {
+		InstanceCount int `json:"instance_count"`
+		MaxInstance   int `json:"max_instance"`
+	}
+
+	body := `{"instance_count": 3, "max_instance": 10}`
+	require.NoError(t, schema.Decode(schema.Increment, []byte(body), &input))
+	assert.Equal(t, 3, input.InstanceCount)
+	assert.Equal(t, 10, input.MaxInstance)
+
+	err := schema.Decode(schema.Increment, []byte(`{"instance_count": `), &input)
+	errs := fieldErrors(t, err)
+	assert.Contains(t, errs[""], "not valid JSON")
+}
+
+func TestPointer(t *testing.T) {
+	assert.Equal(t, "", schema.Pointer())
+	assert.Equal(t, "/metadata/env", schema.Pointer("metadata", "env"))
+	assert.Equal(t, "/metadata/a~1b~0c", schema.Pointer("metadata", "a/b~c"))
+}
+
+func TestMarshalJSON(t *testing.T) {
+	bytes, err := json.Marshal(schema.Group)
+	require.NoError(t, err)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(bytes, &doc))
+
+	assert.Equal(t, "http://json-schema.org/draft-07/schema#", doc["$schema"])
+	assert.Equal(t, "/v1/tsg/schemas/group", doc["$id"])
+	assert.Equal(t, "object", doc["type"])
+	assert.Equal(t, false, doc["additionalProperties"])
+	assert.Equal(t, []interface{}{"group_name", "template_id", "capacity"}, doc["required"])
+
+	props := doc["properties"].(map[string]interface{})
+	capacity := props["capacity"].(map[string]interface{})
+	assert.Equal(t, "integer", capacity["type"])
+	assert.Equal(t, float64(100), capacity["maximum"])
+
+	id := props["id"].(map[string]interface{})
+	assert.Equal(t, true, id["readOnly"])
+}
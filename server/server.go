@@ -9,13 +9,21 @@ import (
 	"net/http"
 	"time"
 
-	ghandlers "github.com/gorilla/handlers"
 	nomad "github.com/hashicorp/nomad/api"
 	"github.com/jackc/pgx"
 	"github.com/joyent/triton-service-groups/config"
+	"github.com/joyent/triton-service-groups/envelope"
+	"github.com/joyent/triton-service-groups/events"
+	"github.com/joyent/triton-service-groups/operations"
+	"github.com/joyent/triton-service-groups/ratelimit"
+	"github.com/joyent/triton-service-groups/server/console"
 	"github.com/joyent/triton-service-groups/server/handlers"
 	"github.com/joyent/triton-service-groups/server/handlers/auth"
+	"github.com/joyent/triton-service-groups/server/requestid"
+	"github.com/joyent/triton-service-groups/server/requestlog"
 	"github.com/joyent/triton-service-groups/server/router"
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/joyent/triton-service-groups/users"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -32,38 +40,113 @@ type HTTPServer struct {
 	logger     zerolog.Logger
 	pool       *pgx.ConnPool
 	nomad      *nomad.Client
+	keyring    *envelope.Keyring
 	authConfig auth.Config
+	rateLimit  config.RateLimit
+	health     *handlers.Health
+	drainDelay time.Duration
+	tls        config.TLS
+	cors       config.CORS
+	console    config.Console
+	certs      *certificates
+	events     *events.Broker
+	operations *operations.Runner
 
 	http.Server
 }
 
-func New(cfg config.HTTPServer, pool *pgx.ConnPool, nomad *nomad.Client) *HTTPServer {
+func New(cfg config.HTTPServer, pool *pgx.ConnPool, nomad *nomad.Client, keyring *envelope.Keyring) *HTTPServer {
 	log.Debug().Msg("http: creating new HTTP server")
 	addr := fmt.Sprintf("%s:%d", cfg.Bind, cfg.Port)
 
-	authConfig := auth.Config{
-		Datacenter:      cfg.DC,
-		TritonURL:       cfg.TritonURL,
-		AuthURL:         cfg.AuthURL,
-		KeyNamePrefix:   cfg.KeyNamePrefix,
-		EnableWhitelist: cfg.EnableWhitelist,
-	}
-
+	// WriteTimeout is left unset, requests waiting for a group to converge
+	// write their response after up to groups_v1.MaxWait.
 	return &HTTPServer{
 		Addr:       addr,
 		Bind:       cfg.Bind,
 		Port:       cfg.Port,
 		logger:     cfg.Logger,
-		authConfig: authConfig,
+		authConfig: NewAuthConfig(cfg),
+		rateLimit:  cfg.RateLimit,
+		health:     handlers.NewHealth(pool, nomad),
+		drainDelay: cfg.DrainDelay,
+		tls:        cfg.TLS,
+		cors:       cfg.CORS,
+		console:    cfg.Console,
 		pool:       pool,
 		nomad:      nomad,
+		keyring:    keyring,
 	}
 }
 
-func (srv *HTTPServer) Start() {
+// NewAuthConfig builds the authentication configuration from the HTTP server
+// configuration.
+func NewAuthConfig(cfg config.HTTPServer) auth.Config {
+	staticAccounts := make([]auth.StaticAccount, 0, len(cfg.StaticAccounts))
+	for _, static := range cfg.StaticAccounts {
+		staticAccounts = append(staticAccounts, auth.StaticAccount{
+			Token:       static.Token,
+			AccountName: static.Account,
+			Permission:  users.Permission(static.Permission),
+		})
+	}
+
+	return auth.Config{
+		Provider:        cfg.AuthProvider,
+		StaticAccounts:  staticAccounts,
+		Insecure:        cfg.AuthInsecure,
+		Datacenter:      cfg.DC,
+		TritonURL:       cfg.TritonURL,
+		AuthURL:         cfg.AuthURL,
+		KeyNamePrefix:   cfg.KeyNamePrefix,
+		KeyType:         auth.KeyType(cfg.KeyType),
+		EnableWhitelist: cfg.EnableWhitelist,
+		Operators:       cfg.Operators,
+		KeyCacheTTL:     cfg.KeyCacheTTL,
+		MaxClockSkew:    cfg.MaxClockSkew,
+		SessionTTL:      cfg.Console.SessionTTL,
+	}
+}
+
+func (srv *HTTPServer) Start() error {
 	log.Debug().Msg("http: starting up HTTP server")
 
+	if srv.tls.Enabled() {
+		certs, err := newCertificates(srv.tls)
+		if err != nil {
+			return err
+		}
+		srv.certs = certs
+		srv.TLSConfig = certs.TLSConfig()
+	}
+
+	// Watchers and operations are only served when backed by a database,
+	// which every agent shares.
+	if srv.pool != nil {
+		srv.events = events.NewBroker(events.NewStore(srv.pool), events.DefaultPollInterval)
+		srv.events.Start()
+
+		srv.operations = operations.NewRunner(operations.NewStore(srv.pool))
+		srv.operations.Start()
+	}
+
 	srv.setup()
+	return nil
+}
+
+// ReloadTLS reads the TLS certificate, key and client CAs from disk again,
+// serving them to new connections. It does nothing when TLS isn't enabled.
+func (srv *HTTPServer) ReloadTLS() error {
+	if srv.certs == nil {
+		return nil
+	}
+
+	if err := srv.certs.Reload(); err != nil {
+		return err
+	}
+
+	log.Info().Msg("http: reloaded TLS certificates")
+	return nil
 }
 
 func (srv *HTTPServer) setup() {
@@ -71,21 +154,82 @@ func (srv *HTTPServer) setup() {
 
 	router := router.WithRoutes(RoutingTable)
 
-	authHandler := handlers.AuthHandler(srv.pool, srv.authConfig, router)
-	contextHandler := handlers.ContextHandler(srv.pool, srv.nomad, authHandler)
-	srv.Handler = ghandlers.LoggingHandler(srv.logger, contextHandler)
+	var handler http.Handler = router
+	if srv.rateLimit.Enable {
+		handler = handlers.RateLimitHandler(srv.newLimiter(), handlers.RateLimitConfig{
+			Reads: ratelimit.Limit{
+				Requests: srv.rateLimit.Reads,
+				Period:   srv.rateLimit.Period,
+			},
+			Mutations: ratelimit.Limit{
+				Requests: srv.rateLimit.Mutations,
+				Period:   srv.rateLimit.Period,
+			},
+			Concurrency: srv.rateLimit.Concurrency,
+		}, handler)
+	}
+
+	if srv.events != nil {
+		handler = events.Handler(srv.events, handler)
+	}
+	if srv.operations != nil {
+		handler = operations.Handler(srv.operations, handler)
+	}
+
+	authHandler := handlers.AuthHandler(srv.pool, srv.authConfig, handler)
+	contextHandler := handlers.ContextHandler(srv.pool, srv.nomad, srv.keyring, authHandler)
+	loggingHandler := requestlog.Handler(srv.logger, contextHandler)
+	tracingHandler := tracing.Handler(func(req *http.Request) string {
+		return handlers.RouteName(router, req)
+	}, loggingHandler)
+
+	// Preflight requests from browsers are answered before authentication,
+	// since they never carry credentials.
+	var corsHandler http.Handler = tracingHandler
+	if len(srv.cors.AllowedOrigins) > 0 {
+		corsHandler = handlers.CORSHandler(handlers.CORSConfig{
+			AllowedOrigins: srv.cors.AllowedOrigins,
+			MaxAge:         srv.cors.MaxAge,
+		}, tracingHandler)
+	}
+	requestHandler := requestid.Handler(corsHandler)
+
+	// Health checks and the console's assets bypass authentication and aren't
+	// logged, since load balancers call the former every few seconds.
+	var publicHandler http.Handler = handlers.MetricsHandler(router, requestHandler)
+	if srv.console.Enable {
+		publicHandler = console.Handler(publicHandler)
+	}
+	srv.Handler = handlers.HealthHandler(srv.health, publicHandler)
 
 	ln := srv.listenWithRetry()
 
 	go func() {
-		log.Info().Msgf("http: started serving at %q", srv.Addr)
-		err := srv.Serve(ln)
+		var err error
+		if srv.certs != nil {
+			log.Info().Msgf("http: started serving TLS at %q", srv.Addr)
+			err = srv.ServeTLS(ln, "", "")
+		} else {
+			log.Info().Msgf("http: started serving at %q", srv.Addr)
+			err = srv.Serve(ln)
+		}
 		if err != nil {
 			log.Warn().Err(err)
 		}
 	}()
 }
 
+// newLimiter returns the limiter counting requests within the configured
+// backend.
+func (srv *HTTPServer) newLimiter() ratelimit.Limiter {
+	switch srv.rateLimit.Backend {
+	case "database":
+		return ratelimit.NewStore(srv.pool)
+	default:
+		return ratelimit.NewMemoryLimiter()
+	}
+}
+
 // listenWithRetry attempts to listen on our socket, failing after 10 seconds.
 func (srv *HTTPServer) listenWithRetry() net.Listener {
 	var (
@@ -109,12 +253,29 @@ func (srv *HTTPServer) listenWithRetry() net.Listener {
 }
 
 // Stop handles gracefully shutting down the server, finally forcing shutdown
-// after 3 seconds.
+// after 3 seconds. The readiness endpoint reports the server as shutting down
+// for the configured drain delay first, so load balancers stop sending
+// requests before the listener is closed.
 func (srv *HTTPServer) Stop(ctx context.Context) error {
+	srv.health.SetShuttingDown()
+	if srv.drainDelay > 0 {
+		log.Info().Msgf("http: draining for %v before shutting down", srv.drainDelay)
+		time.Sleep(srv.drainDelay)
+	}
+
 	log.Debug().Msg("http: gracefully shutting down HTTP server")
 
-	if err := srv.Shutdown(ctx); err != nil {
-		return err
+	// End every watch stream first, since Shutdown waits for them.
+	if srv.events != nil {
+		srv.events.Stop()
 	}
-	return nil
+
+	err := srv.Shutdown(ctx)
+
+	// Operations started by the requests just served are interrupted, so
+	// they're recorded as failed rather than abandoned.
+	if srv.operations != nil {
+		srv.operations.Stop()
+	}
+	return err
 }
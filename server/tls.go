@@ -0,0 +1,84 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/joyent/triton-service-groups/config"
+	"github.com/pkg/errors"
+)
+
+// certificates holds the certificate and client CAs served by the HTTP
+// server, which are reloaded from disk without restarting the server.
+type certificates struct {
+	config config.TLS
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// newCertificates loads the certificate, key and client CAs configured by cfg.
+func newCertificates(cfg config.TLS) (*certificates, error) {
+	c := &certificates{config: cfg}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload reads the certificate, key and client CAs from disk again. The
+// previous files keep being served if any of them are invalid.
+func (c *certificates) Reload() error {
+	cert, err := tls.LoadX509KeyPair(c.config.CertFile, c.config.KeyFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load TLS certificate")
+	}
+
+	var clientCAs *x509.CertPool
+	if c.config.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(c.config.ClientCAFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to read client CAs")
+		}
+
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found within %q", c.config.ClientCAFile)
+		}
+	}
+
+	c.mu.Lock()
+	c.cert = &cert
+	c.clientCAs = clientCAs
+	c.mu.Unlock()
+
+	return nil
+}
+
+// TLSConfig returns the TLS configuration of the server. The certificate and
+// client CAs are looked up on every handshake, so reloading them takes effect
+// for new connections.
+func (c *certificates) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: c.config.MinVersion,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c.mu.RLock()
+			defer c.mu.RUnlock()
+
+			cfg := &tls.Config{
+				MinVersion:   c.config.MinVersion,
+				Certificates: []tls.Certificate{*c.cert},
+				NextProtos:   []string{"h2", "http/1.1"},
+			}
+			if c.clientCAs != nil {
+				cfg.ClientCAs = c.clientCAs
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return cfg, nil
+		},
+	}
+}
@@ -0,0 +1,196 @@
+package server_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/joyent/triton-service-groups/config"
+	"github.com/joyent/triton-service-groups/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pair tls.Certificate
+}
+
+// newTestCert issues a certificate for commonName, signed by parent or
+// self-signed when parent is nil.
+func newTestCert(t *testing.T, commonName string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCert{
+		cert: cert,
+		key:  key,
+		pair: tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key},
+	}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+	require.NoError(t, ioutil.WriteFile(certFile, certPEM, 0600))
+
+	if keyFile == "" {
+		return
+	}
+	der, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	require.NoError(t, ioutil.WriteFile(keyFile, keyPEM, 0600))
+}
+
+func freePort(t *testing.T) uint16 {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	return uint16(ln.Addr().(*net.TCPAddr).Port)
+}
+
+func startTLSServer(t *testing.T, tlsConfig config.TLS) *server.HTTPServer {
+	srv := server.New(config.HTTPServer{
+		Bind: "127.0.0.1",
+		Port: freePort(t),
+		TLS:  tlsConfig,
+	}, nil, nil, nil)
+	require.NoError(t, srv.Start())
+	t.Cleanup(func() { srv.Stop(context.Background()) })
+	return srv
+}
+
+// handshake connects to srv, returning the common name of the certificate it
+// served.
+func handshake(srv *server.HTTPServer, clientConfig *tls.Config) (string, error) {
+	conn, err := tls.Dial("tcp", srv.Addr, clientConfig)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	// TLS 1.3 servers reject client certificates after the handshake
+	// completes, so read to surface the rejection.
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, err := conn.Read(make([]byte, 1)); err != nil {
+		if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+			return "", err
+		}
+	}
+
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName, nil
+}
+
+func TestTLSReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	ca := newTestCert(t, "ca", nil)
+	newTestCert(t, "first", ca).write(t, certFile, keyFile)
+
+	srv := startTLSServer(t, config.TLS{
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		MinVersion: tls.VersionTLS12,
+	})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := &tls.Config{RootCAs: roots}
+
+	name, err := handshake(srv, client)
+	require.NoError(t, err)
+	assert.Equal(t, "first", name)
+
+	_, err = handshake(srv, &tls.Config{RootCAs: roots, MaxVersion: tls.VersionTLS11})
+	assert.Error(t, err, "versions below the minimum are rejected")
+
+	newTestCert(t, "second", ca).write(t, certFile, keyFile)
+	require.NoError(t, srv.ReloadTLS())
+
+	name, err = handshake(srv, client)
+	require.NoError(t, err)
+	assert.Equal(t, "second", name)
+
+	require.NoError(t, ioutil.WriteFile(certFile, []byte("invalid"), 0600))
+	assert.Error(t, srv.ReloadTLS())
+
+	name, err = handshake(srv, client)
+	require.NoError(t, err)
+	assert.Equal(t, "second", name, "the previous certificate is kept when reloading fails")
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	clientCAFile := filepath.Join(dir, "client-ca.pem")
+
+	ca := newTestCert(t, "ca", nil)
+	newTestCert(t, "server", ca).write(t, certFile, keyFile)
+
+	clientCA := newTestCert(t, "client-ca", nil)
+	clientCA.write(t, clientCAFile, "")
+
+	srv := startTLSServer(t, config.TLS{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		MinVersion:   tls.VersionTLS12,
+		ClientCAFile: clientCAFile,
+	})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	_, err := handshake(srv, &tls.Config{RootCAs: roots})
+	assert.Error(t, err, "clients must present a certificate")
+
+	_, err = handshake(srv, &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{newTestCert(t, "stranger", ca).pair},
+	})
+	assert.Error(t, err, "client certificates must be signed by a client CA")
+
+	name, err := handshake(srv, &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{newTestCert(t, "client", clientCA).pair},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "server", name)
+}
@@ -0,0 +1,171 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package sessions
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"time"
+
+	"github.com/jackc/pgx/pgtype"
+	"github.com/joyent/triton-service-groups/convert"
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/joyent/triton-service-groups/users"
+	"github.com/pkg/errors"
+)
+
+// secretSize is the number of random bytes within a session secret or CSRF
+// token.
+const secretSize = 32
+
+var (
+	ErrNoAccountID = errors.New("missing account identifer for insert")
+	ErrMissingID   = errors.New("missing identifer for delete")
+	ErrBadSecret   = errors.New("malformed session secret")
+)
+
+// Session represents the data associated with a tsg_sessions row. A session
+// is created for a browser by a request authenticated with a signature or an
+// API token, and acts on behalf of the same account owner, sub-user or token
+// until it expires or is deleted. Only a hash of the session's secret, which
+// the browser holds within a cookie, is stored.
+type Session struct {
+	ID        string
+	AccountID string
+
+	// UserID is the identifier of the sub-user the session was created by,
+	// empty for the account owner. TokenID is the identifier of the API token
+	// the session was created with, empty unless the request presented one.
+	UserID  string
+	TokenID string
+
+	// Permission is the permission of the session when it was created. It's
+	// only relied upon for sessions of the account owner, since sub-users
+	// and tokens are checked on every request.
+	Permission users.Permission
+
+	// CSRFToken must be sent back within a header by every request changing
+	// a resource, since browsers send cookies along with requests made by
+	// other sites.
+	CSRFToken string
+	CreatedBy string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+
+	store *Store
+}
+
+// New constructs a new Session with the Store for backend persistence.
+func New(store *Store) *Session {
+	return &Session{
+		store: store,
+	}
+}
+
+// GenerateSecret returns a new random session secret.
+func GenerateSecret() (string, error) {
+	buf := make([]byte, secretSize)
+	if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+		return "", errors.Wrap(err, "failed to generate session secret")
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashSecret returns the hash of a session secret as stored in the database.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsActive returns true if the session hasn't expired.
+func (s *Session) IsActive(now time.Time) bool {
+	return now.Before(s.ExpiresAt)
+}
+
+// CheckCSRF returns true if token is the session's CSRF token.
+func (s *Session) CheckCSRF(token string) bool {
+	if s.CSRFToken == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(s.CSRFToken), []byte(token)) == 1
+}
+
+// Insert inserts a new session into the tsg_sessions table, storing only the
+// hash of secret. A new CSRF token is generated for the session.
+func (s *Session) Insert(ctx context.Context, secret string) error {
+	if s.AccountID == "" {
+		return ErrNoAccountID
+	}
+
+	if len(secret) < secretSize {
+		return ErrBadSecret
+	}
+
+	csrfToken, err := GenerateSecret()
+	if err != nil {
+		return errors.Wrap(err, "failed to generate CSRF token")
+	}
+
+	var userID, tokenID interface{}
+	if s.UserID != "" {
+		userID = s.UserID
+	}
+	if s.TokenID != "" {
+		tokenID = s.TokenID
+	}
+
+	query := `
+INSERT INTO tsg_sessions (session_hash, account_id, user_id, token_id, permission, csrf_token, created_by, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+RETURNING id, created_at;
+`
+	var (
+		id        pgtype.UUID
+		createdAt pgtype.Timestamptz
+	)
+
+	err = tracing.QueryRowEx(ctx, s.store.pool, query, nil,
+		HashSecret(secret),
+		s.AccountID,
+		userID,
+		tokenID,
+		string(s.Permission),
+		csrfToken,
+		s.CreatedBy,
+		s.ExpiresAt,
+	).Scan(&id, &createdAt)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert session")
+	}
+
+	s.ID = convert.BytesToUUID(id.Bytes)
+	s.CSRFToken = csrfToken
+	s.CreatedAt = createdAt.Time
+
+	return nil
+}
+
+// Delete deletes the session, which is rejected from then on.
+func (s *Session) Delete(ctx context.Context) error {
+	if s.ID == "" {
+		return ErrMissingID
+	}
+
+	query := `DELETE FROM tsg_sessions WHERE id = $1 AND account_id = $2;`
+
+	_, err := tracing.ExecEx(ctx, s.store.pool, query, nil, s.ID, s.AccountID)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete session")
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package sessions_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/accounts"
+	"github.com/joyent/triton-service-groups/sessions"
+	"github.com/joyent/triton-service-groups/testutils"
+	"github.com/joyent/triton-service-groups/users"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := sessions.GenerateSecret()
+	require.NoError(t, err)
+
+	other, err := sessions.GenerateSecret()
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, other)
+
+	assert.Equal(t, sessions.HashSecret(secret), sessions.HashSecret(secret))
+	assert.NotEqual(t, sessions.HashSecret(secret), sessions.HashSecret(other))
+	assert.NotContains(t, sessions.HashSecret(secret), secret)
+}
+
+func TestSessionIsActive(t *testing.T) {
+	now := time.Now()
+
+	session := &sessions.Session{ExpiresAt: now.Add(time.Hour)}
+	assert.True(t, session.IsActive(now))
+	assert.False(t, session.IsActive(now.Add(2*time.Hour)))
+}
+
+func TestSessionCheckCSRF(t *testing.T) {
+	session := &sessions.Session{}
+	assert.False(t, session.CheckCSRF(""), "sessions without a token reject every request")
+
+	session.CSRFToken = "csrf-token"
+	assert.True(t, session.CheckCSRF("csrf-token"))
+	assert.False(t, session.CheckCSRF("other-token"))
+	assert.False(t, session.CheckCSRF(""))
+}
+
+func TestInsertAndDelete(t *testing.T) {
+	if os.Getenv("TSG_TEST") == "" {
+		t.Skip("Acceptance tests skipped unless env 'TSG_TEST=1' set")
+		return
+	}
+
+	db, err := testutils.NewTestDB()
+	if err != nil {
+		t.Error(err)
+	}
+	db.Clear(t)
+	defer db.Clear(t)
+
+	ctx := context.Background()
+
+	account := accounts.New(accounts.NewStore(db.Conn))
+	account.AccountName = "baconuser"
+	require.NoError(t, account.Insert(ctx))
+
+	store := sessions.NewStore(db.Conn)
+
+	secret, err := sessions.GenerateSecret()
+	require.NoError(t, err)
+
+	session := sessions.New(store)
+	session.AccountID = account.ID
+	session.Permission = users.PermissionOwner
+	session.CreatedBy = account.AccountName
+	session.ExpiresAt = time.Now().Add(time.Hour)
+
+	require.NoError(t, session.Insert(ctx, secret))
+	assert.NotZero(t, session.ID)
+	assert.NotEmpty(t, session.CSRFToken)
+
+	found, err := store.FindBySecret(ctx, secret)
+	require.NoError(t, err)
+	assert.Equal(t, session.ID, found.ID)
+	assert.Equal(t, users.PermissionOwner, found.Permission)
+	assert.Equal(t, session.CSRFToken, found.CSRFToken)
+	assert.Empty(t, found.UserID)
+	assert.Empty(t, found.TokenID)
+	assert.True(t, found.IsActive(time.Now()))
+
+	_, err = store.FindBySecret(ctx, secret+"x")
+	assert.Equal(t, pgx.ErrNoRows, err)
+
+	expired := sessions.New(store)
+	expired.AccountID = account.ID
+	expired.Permission = users.PermissionOwner
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+
+	expiredSecret, err := sessions.GenerateSecret()
+	require.NoError(t, err)
+	require.NoError(t, expired.Insert(ctx, expiredSecret))
+
+	n, err := store.DeleteExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	require.NoError(t, found.Delete(ctx))
+
+	_, err = store.FindByID(ctx, session.ID, account.ID)
+	assert.Equal(t, pgx.ErrNoRows, err)
+}
@@ -0,0 +1,128 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package sessions persists the sessions of browsers signed in to the web
+// console. Browsers can't sign requests with a Triton key, so they exchange a
+// signed request or an API token for a session cookie instead.
+package sessions
+
+import (
+	"context"
+
+	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/pgtype"
+	"github.com/joyent/triton-service-groups/convert"
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/joyent/triton-service-groups/users"
+)
+
+type Store struct {
+	pool *pgx.ConnPool
+}
+
+// NewStore returns a new store object.
+func NewStore(pool *pgx.ConnPool) *Store {
+	return &Store{
+		pool: pool,
+	}
+}
+
+const selectSessions = `
+SELECT id, account_id, user_id, token_id, permission, csrf_token, created_by,
+       expires_at, created_at
+FROM tsg_sessions
+`
+
+// scanner is implemented by both *pgx.Row and *pgx.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (s *Store) scanSession(row scanner) (*Session, error) {
+	var (
+		id         pgtype.UUID
+		accountID  pgtype.UUID
+		userID     pgtype.UUID
+		tokenID    pgtype.UUID
+		permission string
+		csrfToken  string
+		createdBy  string
+		expiresAt  pgtype.Timestamptz
+		createdAt  pgtype.Timestamptz
+	)
+
+	err := row.Scan(
+		&id,
+		&accountID,
+		&userID,
+		&tokenID,
+		&permission,
+		&csrfToken,
+		&createdBy,
+		&expiresAt,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	session := New(s)
+	session.ID = convert.BytesToUUID(id.Bytes)
+	session.AccountID = convert.BytesToUUID(accountID.Bytes)
+	if userID.Status == pgtype.Present {
+		session.UserID = convert.BytesToUUID(userID.Bytes)
+	}
+	if tokenID.Status == pgtype.Present {
+		session.TokenID = convert.BytesToUUID(tokenID.Bytes)
+	}
+	session.Permission = users.Permission(permission)
+	session.CSRFToken = csrfToken
+	session.CreatedBy = createdBy
+	session.ExpiresAt = expiresAt.Time
+	session.CreatedAt = createdAt.Time
+
+	return session, nil
+}
+
+// FindByID finds a session of an account by a specific ID.
+func (s *Store) FindByID(ctx context.Context, sessionID string, accountID string) (*Session, error) {
+	query := selectSessions + `WHERE id = $1 AND account_id = $2;`
+
+	return s.scanSession(tracing.QueryRowEx(ctx, s.pool, query, nil, sessionID, accountID))
+}
+
+// FindBySecret finds the session of a secret presented by a browser. The
+// session is returned whether or not it has expired.
+func (s *Store) FindBySecret(ctx context.Context, secret string) (*Session, error) {
+	query := selectSessions + `WHERE session_hash = $1;`
+
+	return s.scanSession(tracing.QueryRowEx(ctx, s.pool, query, nil, HashSecret(secret)))
+}
+
+// DeleteExpired deletes every session which has expired, returning the number
+// of sessions deleted.
+func (s *Store) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM tsg_sessions WHERE expires_at <= NOW();`
+
+	tag, err := tracing.ExecEx(ctx, s.pool, query, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// DeleteByAccount deletes every session of an account, returning the number of
+// sessions deleted.
+func (s *Store) DeleteByAccount(ctx context.Context, accountID string) (int64, error) {
+	query := `DELETE FROM tsg_sessions WHERE account_id = $1;`
+
+	tag, err := tracing.ExecEx(ctx, s.pool, query, nil, accountID)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
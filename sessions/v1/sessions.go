@@ -0,0 +1,211 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package sessions_v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/handlers/auth"
+	"github.com/joyent/triton-service-groups/sessions"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	errSessionNotFound = apierror.NotFound("not signed in with a browser session")
+
+	// errSessionRenewal is returned when a browser session tries to create
+	// another, which would let it outlive its TTL.
+	errSessionRenewal = apierror.New(http.StatusForbidden, apierror.CodeForbidden,
+		"browser sessions can only be created with a signature or an API token")
+)
+
+// Session represents the browser session of the web console. The CSRF token
+// must be sent within the X-CSRF-Token header of every request changing a
+// resource.
+type Session struct {
+	ID         string    `json:"id"`
+	Actor      string    `json:"actor"`
+	Permission string    `json:"permission"`
+	CSRFToken  string    `json:"csrf_token"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func newSession(session *sessions.Session, authSession *auth.Session) *Session {
+	return &Session{
+		ID:         session.ID,
+		Actor:      authSession.Actor(),
+		Permission: string(authSession.Permission),
+		CSRFToken:  session.CSRFToken,
+		ExpiresAt:  session.ExpiresAt,
+		CreatedAt:  session.CreatedAt,
+	}
+}
+
+func getStore(ctx context.Context) (*sessions.Store, error) {
+	pool, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		return nil, handlers.ErrNoConnPool
+	}
+	return sessions.NewStore(pool), nil
+}
+
+// Create exchanges the signature or API token of the request for a browser
+// session, handing its secret to the browser within a cookie.
+func Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	authSession := handlers.GetAuthSession(ctx)
+
+	if authSession.IsCookie() {
+		apierror.Write(w, r, errSessionRenewal)
+		return
+	}
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	secret, err := sessions.GenerateSecret()
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	ttl := authSession.Config().SessionTTL
+	if ttl <= 0 {
+		ttl = auth.DefaultSessionTTL
+	}
+
+	session := sessions.New(store)
+	session.AccountID = authSession.AccountID
+	session.UserID = authSession.UserID
+	session.TokenID = authSession.TokenID
+	session.Permission = authSession.Permission
+	session.CreatedBy = authSession.Actor()
+	session.ExpiresAt = time.Now().Add(ttl)
+
+	if err := session.Insert(ctx, secret); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	log.Info().
+		Str("actor", authSession.Actor()).
+		Str("session_id", session.ID).
+		Time("expires_at", session.ExpiresAt).
+		Msg("sessions: created browser session")
+
+	// Expired sessions are rejected regardless, so failing to delete them is
+	// only logged.
+	if n, err := store.DeleteExpired(ctx); err != nil {
+		log.Warn().Err(err).Msg("sessions: failed to delete expired sessions")
+	} else if n > 0 {
+		log.Debug().Int64("count", n).Msg("sessions: deleted expired sessions")
+	}
+
+	bytes, err := json.Marshal(newSession(session, authSession))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, auth.NewCookie(r, secret, session.ExpiresAt))
+	writeJSONResponse(w, bytes, http.StatusCreated)
+}
+
+// Get returns the browser session the request was authenticated with, so the
+// console can find its CSRF token again after being reloaded.
+func Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	authSession := handlers.GetAuthSession(ctx)
+
+	session, ok := findSession(w, r, authSession)
+	if !ok {
+		return
+	}
+
+	bytes, err := json.Marshal(newSession(session, authSession))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+// Delete signs the browser out, deleting the session the request was
+// authenticated with and its cookie.
+func Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	authSession := handlers.GetAuthSession(ctx)
+
+	session, ok := findSession(w, r, authSession)
+	if !ok {
+		return
+	}
+
+	if err := session.Delete(ctx); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	log.Info().
+		Str("actor", authSession.Actor()).
+		Str("session_id", session.ID).
+		Msg("sessions: deleted browser session")
+
+	http.SetCookie(w, auth.ExpiredCookie(r))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findSession finds the browser session the request was authenticated with,
+// responding with a 404 when it wasn't authenticated by cookie.
+func findSession(w http.ResponseWriter, r *http.Request, authSession *auth.Session) (*sessions.Session, bool) {
+	ctx := r.Context()
+
+	if authSession.SessionID == "" {
+		apierror.Write(w, r, errSessionNotFound)
+		return nil, false
+	}
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return nil, false
+	}
+
+	session, err := store.FindByID(ctx, authSession.SessionID, authSession.AccountID)
+	if err != nil {
+		if err != pgx.ErrNoRows {
+			log.Debug().Err(err).Msg("sessions: failed to find session")
+		}
+		apierror.Write(w, r, errSessionNotFound)
+		return nil, false
+	}
+
+	return session, true
+}
+
+// writeJSONResponse responds with bytes, which are never cached since they
+// carry the CSRF token of the session.
+func writeJSONResponse(w http.ResponseWriter, bytes []byte, statusCode int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	if n, err := w.Write(bytes); err != nil {
+		log.Printf("%v", err)
+	} else if n != len(bytes) {
+		log.Printf("short write: %d/%d", n, len(bytes))
+	}
+}
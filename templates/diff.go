@@ -0,0 +1,253 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package templates_v1
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeUpdated = "changed"
+)
+
+// TemplateDiff describes every change required to move from one template to
+// another.
+type TemplateDiff struct {
+	From            string       `json:"from"`
+	To              string       `json:"to"`
+	TemplateName    *ValueChange `json:"template_name,omitempty"`
+	Package         *ValueChange `json:"package,omitempty"`
+	ImageID         *ValueChange `json:"image_id,omitempty"`
+	FirewallEnabled *ValueChange `json:"firewall_enabled,omitempty"`
+	Networks        *ListChange  `json:"networks,omitempty"`
+	Tags            []*KeyChange `json:"tags,omitempty"`
+	MetaData        []*KeyChange `json:"metadata,omitempty"`
+	UserData        string       `json:"userdata,omitempty"`
+}
+
+// ValueChange holds the previous and next value of a single field.
+type ValueChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// ListChange holds the values added to and removed from a list field. Order
+// holds both lists when the values kept by both are in a different order,
+// since the first network of a template is the primary NIC of its instances.
+type ListChange struct {
+	Added   []string     `json:"added,omitempty"`
+	Removed []string     `json:"removed,omitempty"`
+	Order   *ValueChange `json:"order,omitempty"`
+}
+
+// KeyChange holds the change to a single key of a map field. From is empty
+// when the key was added and To is empty when the key was removed.
+type KeyChange struct {
+	Key    string `json:"key"`
+	Change string `json:"change"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+}
+
+// DiffTemplates compares two templates field by field. The userdata of both
+// templates is compared as a unified diff.
+func DiffTemplates(from, to *InstanceTemplate) (*TemplateDiff, error) {
+	diff := &TemplateDiff{
+		From: from.ID,
+		To:   to.ID,
+	}
+
+	if from.TemplateName != to.TemplateName {
+		diff.TemplateName = &ValueChange{from.TemplateName, to.TemplateName}
+	}
+
+	if from.Package != to.Package {
+		diff.Package = &ValueChange{from.Package, to.Package}
+	}
+
+	if from.ImageID != to.ImageID {
+		diff.ImageID = &ValueChange{from.ImageID, to.ImageID}
+	}
+
+	if from.FirewallEnabled != to.FirewallEnabled {
+		diff.FirewallEnabled = &ValueChange{from.FirewallEnabled, to.FirewallEnabled}
+	}
+
+	diff.Networks = diffList(from.Networks, to.Networks)
+	diff.Tags = diffMap(from.Tags, to.Tags)
+	diff.MetaData = diffMap(from.MetaData, to.MetaData)
+
+	if from.UserData != to.UserData {
+		userdata, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(from.UserData),
+			B:        difflib.SplitLines(to.UserData),
+			FromFile: from.TemplateName,
+			ToFile:   to.TemplateName,
+			Context:  3,
+		})
+		if err != nil {
+			return nil, err
+		}
+		diff.UserData = userdata
+	}
+
+	return diff, nil
+}
+
+// IsEmpty returns true when both templates are configured identically.
+func (d *TemplateDiff) IsEmpty() bool {
+	return d.TemplateName == nil &&
+		d.Package == nil &&
+		d.ImageID == nil &&
+		d.FirewallEnabled == nil &&
+		d.Networks == nil &&
+		len(d.Tags) == 0 &&
+		len(d.MetaData) == 0 &&
+		d.UserData == ""
+}
+
+// String formats the diff for human consumption.
+func (d *TemplateDiff) String() string {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "--- %s\n+++ %s\n", d.From, d.To)
+
+	if d.IsEmpty() {
+		fmt.Fprintln(buf, "no changes")
+		return buf.String()
+	}
+
+	writeValue := func(name string, change *ValueChange) {
+		if change != nil {
+			fmt.Fprintf(buf, "%s: %v => %v\n", name, change.From, change.To)
+		}
+	}
+	writeValue("template_name", d.TemplateName)
+	writeValue("package", d.Package)
+	writeValue("image_id", d.ImageID)
+	writeValue("firewall_enabled", d.FirewallEnabled)
+
+	if d.Networks != nil {
+		fmt.Fprintln(buf, "networks:")
+		for _, network := range d.Networks.Added {
+			fmt.Fprintf(buf, "  + %s\n", network)
+		}
+		for _, network := range d.Networks.Removed {
+			fmt.Fprintf(buf, "  - %s\n", network)
+		}
+		if order := d.Networks.Order; order != nil {
+			fmt.Fprintf(buf, "  order: %s => %s\n",
+				strings.Join(order.From.([]string), ", "),
+				strings.Join(order.To.([]string), ", "))
+		}
+	}
+
+	writeKeys := func(name string, changes []*KeyChange) {
+		if len(changes) == 0 {
+			return
+		}
+
+		fmt.Fprintf(buf, "%s:\n", name)
+		for _, change := range changes {
+			switch change.Change {
+			case ChangeAdded:
+				fmt.Fprintf(buf, "  + %s=%s\n", change.Key, change.To)
+			case ChangeRemoved:
+				fmt.Fprintf(buf, "  - %s=%s\n", change.Key, change.From)
+			default:
+				fmt.Fprintf(buf, "  ~ %s: %s => %s\n", change.Key, change.From, change.To)
+			}
+		}
+	}
+	writeKeys("tags", d.Tags)
+	writeKeys("metadata", d.MetaData)
+
+	if d.UserData != "" {
+		fmt.Fprintf(buf, "userdata:\n%s", d.UserData)
+	}
+
+	return buf.String()
+}
+
+func diffList(from, to []string) *ListChange {
+	fromSet := make(map[string]bool, len(from))
+	for _, v := range from {
+		fromSet[v] = true
+	}
+
+	toSet := make(map[string]bool, len(to))
+	for _, v := range to {
+		toSet[v] = true
+	}
+
+	change := &ListChange{}
+	var keptTo []string
+	for _, v := range to {
+		if fromSet[v] {
+			keptTo = append(keptTo, v)
+		} else {
+			change.Added = append(change.Added, v)
+		}
+	}
+	var keptFrom []string
+	for _, v := range from {
+		if toSet[v] {
+			keptFrom = append(keptFrom, v)
+		} else {
+			change.Removed = append(change.Removed, v)
+		}
+	}
+
+	for i := range keptFrom {
+		if i >= len(keptTo) || keptFrom[i] != keptTo[i] {
+			change.Order = &ValueChange{from, to}
+			break
+		}
+	}
+
+	if len(change.Added) == 0 && len(change.Removed) == 0 && change.Order == nil {
+		return nil
+	}
+
+	return change
+}
+
+func diffMap(from, to map[string]string) []*KeyChange {
+	keys := make([]string, 0, len(from)+len(to))
+	for key := range from {
+		keys = append(keys, key)
+	}
+	for key := range to {
+		if _, ok := from[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []*KeyChange
+	for _, key := range keys {
+		fromValue, inFrom := from[key]
+		toValue, inTo := to[key]
+
+		switch {
+		case !inFrom:
+			changes = append(changes, &KeyChange{Key: key, Change: ChangeAdded, To: toValue})
+		case !inTo:
+			changes = append(changes, &KeyChange{Key: key, Change: ChangeRemoved, From: fromValue})
+		case fromValue != toValue:
+			changes = append(changes, &KeyChange{Key: key, Change: ChangeUpdated, From: fromValue, To: toValue})
+		}
+	}
+
+	return changes
+}
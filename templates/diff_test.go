@@ -0,0 +1,110 @@
+package templates_v1_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/templates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDiffTemplates() (*templates_v1.InstanceTemplate, *templates_v1.InstanceTemplate) {
+	from := &templates_v1.InstanceTemplate{
+		ID:           "29a08459-1a41-4ec9-bbb7-5c737f17a463",
+		TemplateName: "jolly-jelly",
+		Package:      "14aba044-d0f8-11e5-8c88-eb339a5da5d0",
+		ImageID:      "342045ce-6af1-4adf-9ef1-e5bfaf9de28c",
+		Networks:     []string{"27ea1d5f-df02-410e-843a-c60dba9ec5ca", "f7ed95d3-faaf-43ef-9346-15644403b963"},
+		UserData:     "#!/bin/bash\necho hello\ndate\n",
+		MetaData:     map[string]string{"env": "staging", "legacy": "true"},
+		Tags:         map[string]string{"owner": "user"},
+	}
+
+	to := &templates_v1.InstanceTemplate{
+		ID:           "f5435e8b-70b8-4e4d-8c59-1dbe5d100b5b",
+		TemplateName: "jolly-jelly-v2",
+		Package:      "14aba044-d0f8-11e5-8c88-eb339a5da5d0",
+		ImageID:      "49b22aec-0c8a-11e6-8807-a3eb4db576ba",
+		Networks:     []string{"f7ed95d3-faaf-43ef-9346-15644403b963", "0106d35d-90fa-48dc-b0dd-2ebcbaa64ca0"},
+		UserData:     "#!/bin/bash\necho goodbye\ndate\n",
+		MetaData:     map[string]string{"env": "production", "role": "web"},
+		Tags:         map[string]string{"owner": "user"},
+	}
+
+	return from, to
+}
+
+func TestDiffTemplates(t *testing.T) {
+	from, to := newDiffTemplates()
+
+	diff, err := templates_v1.DiffTemplates(from, to)
+	require.NoError(t, err)
+	require.False(t, diff.IsEmpty())
+
+	assert.Equal(t, from.ID, diff.From)
+	assert.Equal(t, to.ID, diff.To)
+	assert.Equal(t, &templates_v1.ValueChange{From: "jolly-jelly", To: "jolly-jelly-v2"}, diff.TemplateName)
+	assert.Nil(t, diff.Package)
+	assert.Equal(t, &templates_v1.ValueChange{From: from.ImageID, To: to.ImageID}, diff.ImageID)
+	assert.Nil(t, diff.FirewallEnabled)
+
+	require.NotNil(t, diff.Networks)
+	assert.Equal(t, []string{"0106d35d-90fa-48dc-b0dd-2ebcbaa64ca0"}, diff.Networks.Added)
+	assert.Equal(t, []string{"27ea1d5f-df02-410e-843a-c60dba9ec5ca"}, diff.Networks.Removed)
+	assert.Nil(t, diff.Networks.Order)
+
+	assert.Empty(t, diff.Tags)
+	assert.Equal(t, []*templates_v1.KeyChange{
+		{Key: "env", Change: templates_v1.ChangeUpdated, From: "staging", To: "production"},
+		{Key: "legacy", Change: templates_v1.ChangeRemoved, From: "true"},
+		{Key: "role", Change: templates_v1.ChangeAdded, To: "web"},
+	}, diff.MetaData)
+
+	assert.Contains(t, diff.UserData, "--- jolly-jelly\n")
+	assert.Contains(t, diff.UserData, "+++ jolly-jelly-v2\n")
+	assert.Contains(t, diff.UserData, "-echo hello\n")
+	assert.Contains(t, diff.UserData, "+echo goodbye\n")
+
+	text := diff.String()
+	assert.Contains(t, text, "image_id: 342045ce-6af1-4adf-9ef1-e5bfaf9de28c => 49b22aec-0c8a-11e6-8807-a3eb4db576ba\n")
+	assert.Contains(t, text, "  + 0106d35d-90fa-48dc-b0dd-2ebcbaa64ca0\n")
+	assert.Contains(t, text, "  ~ env: staging => production\n")
+	assert.Contains(t, text, "  - legacy=true\n")
+	assert.Contains(t, text, "  + role=web\n")
+
+	_, err = json.Marshal(diff)
+	assert.NoError(t, err)
+}
+
+func TestDiffTemplatesIdentical(t *testing.T) {
+	from, _ := newDiffTemplates()
+
+	diff, err := templates_v1.DiffTemplates(from, from)
+	require.NoError(t, err)
+
+	assert.True(t, diff.IsEmpty())
+	assert.Contains(t, diff.String(), "no changes")
+
+	bytes, err := json.Marshal(diff)
+	require.NoError(t, err)
+	assert.Equal(t, `{"from":"29a08459-1a41-4ec9-bbb7-5c737f17a463","to":"29a08459-1a41-4ec9-bbb7-5c737f17a463"}`, string(bytes))
+}
+
+func TestDiffTemplatesNetworkOrder(t *testing.T) {
+	from, _ := newDiffTemplates()
+	to := *from
+	to.Networks = []string{"f7ed95d3-faaf-43ef-9346-15644403b963", "27ea1d5f-df02-410e-843a-c60dba9ec5ca"}
+
+	diff, err := templates_v1.DiffTemplates(from, &to)
+	require.NoError(t, err)
+	require.False(t, diff.IsEmpty())
+
+	require.NotNil(t, diff.Networks)
+	assert.Empty(t, diff.Networks.Added)
+	assert.Empty(t, diff.Networks.Removed)
+	assert.Equal(t, &templates_v1.ValueChange{From: from.Networks, To: to.Networks}, diff.Networks.Order)
+
+	assert.Contains(t, diff.String(), "  order: 27ea1d5f-df02-410e-843a-c60dba9ec5ca, f7ed95d3-faaf-43ef-9346-15644403b963 => "+
+		"f7ed95d3-faaf-43ef-9346-15644403b963, 27ea1d5f-df02-410e-843a-c60dba9ec5ca\n")
+}
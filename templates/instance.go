@@ -8,19 +8,25 @@ package templates_v1
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"path"
+	"sort"
 	"time"
 
-	"errors"
-
-	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/joyent/triton-service-groups/secrets"
+	"github.com/joyent/triton-service-groups/server/apierror"
 	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/schema"
+	"github.com/joyent/triton-service-groups/templates/render"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 )
 
+var errTemplateNotFound = apierror.NotFound("template not found")
+
 type InstanceTemplate struct {
 	ID              string            `json:"id"`
 	TemplateName    string            `json:"template_name"`
@@ -32,6 +38,7 @@ type InstanceTemplate struct {
 	MetaData        map[string]string `json:"metadata"`
 	Tags            map[string]string `json:"tags"`
 	CreatedAt       time.Time         `json:"created_at"`
+	CreatedBy       string            `json:"created_by"`
 }
 
 func (t *InstanceTemplate) ShortID() string {
@@ -41,6 +48,16 @@ func (t *InstanceTemplate) ShortID() string {
 	return t.ID[:8]
 }
 
+// SecretNames returns the names of every secret referenced within the
+// template's userdata and metadata.
+func (t *InstanceTemplate) SecretNames() []string {
+	values := []string{t.UserData}
+	for _, value := range t.MetaData {
+		values = append(values, value)
+	}
+	return render.SecretNames(values...)
+}
+
 func Get(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	session := handlers.GetAuthSession(ctx)
@@ -52,13 +69,13 @@ func Get(w http.ResponseWriter, r *http.Request) {
 
 	template, ok := FindTemplateByID(ctx, uuid, session.AccountID)
 	if !ok {
-		http.NotFound(w, r)
+		apierror.Write(w, r, errTemplateNotFound)
 		return
 	}
 
 	bytes, err := json.Marshal(template)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -71,43 +88,54 @@ func Create(w http.ResponseWriter, r *http.Request) {
 
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
 	template, err := decodeResponseBodyAndValidate(body)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		schema.WriteError(w, r, err)
+		return
+	}
+
+	missingSecrets, err := secrets_v1.FindMissingSecrets(ctx, session.AccountID, template.SecretNames())
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if len(missingSecrets) > 0 {
+		schema.WriteError(w, r, missingSecretsError(template, missingSecrets))
 		return
 	}
 
 	templateExists, err := CheckTemplateExistsByName(ctx, template.TemplateName, session.AccountID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 	if templateExists {
-		http.Error(w, fmt.Sprintf("Cannot create template %q, "+
-			"conflicts with another template.", template.TemplateName),
-			http.StatusConflict)
+		apierror.Write(w, r, apierror.Conflict("Cannot create template %q, "+
+			"conflicts with another template.", template.TemplateName))
 		return
 	}
 
+	template.CreatedBy = session.Actor()
+
 	err = SaveTemplate(ctx, session.AccountID, template)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
 	com, ok := FindTemplateByName(ctx, template.TemplateName, session.AccountID)
 	if !ok {
-		http.NotFound(w, r)
+		apierror.Write(w, r, errors.Errorf("failed to find template %q after save", template.TemplateName))
 		return
 	}
 
 	bytes, err := json.Marshal(com)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -122,33 +150,111 @@ func Delete(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	uuid := vars["identifier"]
 
-	var template *InstanceTemplate
+	template, ok := FindTemplateByID(ctx, uuid, session.AccountID)
+	if !ok {
+		apierror.Write(w, r, errTemplateNotFound)
+		return
+	}
 
-	templateAllocated, err := CheckTemplateAllocationByID(ctx, uuid, session.AccountID)
+	groups, err := FindTemplateGroups(ctx, template.ID, session.AccountID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
-	if templateAllocated {
-		http.Error(w, fmt.Sprintf("Cannot delete template %q while in use, "+
-			"must be removed from all groups first.", uuid),
-			http.StatusConflict)
+	if len(groups) > 0 {
+		conflict := apierror.New(http.StatusConflict, apierror.CodeTemplateInUse,
+			fmt.Sprintf("Cannot delete template %q while in use, "+
+				"must be removed from all groups first.", template.ID))
+
+		apierror.Write(w, r, conflict.With("groups", groups))
 		return
 	}
 
+	err = RemoveTemplate(ctx, template.ID, session.AccountID, session.Actor())
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func Diff(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	vars := mux.Vars(r)
+
+	from, ok := FindTemplateByID(ctx, vars["identifier"], session.AccountID)
+	if !ok {
+		apierror.Write(w, r, errTemplateNotFound)
+		return
+	}
+
+	to, ok := FindTemplateByID(ctx, vars["other"], session.AccountID)
+	if !ok {
+		apierror.Write(w, r, errTemplateNotFound)
+		return
+	}
+
+	diff, err := DiffTemplates(from, to)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		bytes, err := json.Marshal(diff)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		writeJSONResponse(w, bytes, http.StatusOK)
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, diff.String()); err != nil {
+			log.Printf("%v", err)
+		}
+	default:
+		apierror.Write(w, r, apierror.BadRequest("Unsupported diff format %q, "+
+			"must be one of \"json\" or \"text\".", format))
+	}
+}
+
+func ListGroups(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	vars := mux.Vars(r)
+	uuid := vars["identifier"]
+
 	template, ok := FindTemplateByID(ctx, uuid, session.AccountID)
 	if !ok {
-		http.NotFound(w, r)
+		apierror.Write(w, r, errTemplateNotFound)
 		return
 	}
 
-	err = RemoveTemplate(ctx, template.ID, session.AccountID)
+	groups, err := FindTemplateGroups(ctx, template.ID, session.AccountID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	if len(groups) == 0 {
+		writeJSONResponse(w, []byte("[]"), http.StatusOK)
+		return
+	}
+
+	bytes, err := json.Marshal(groups)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
 }
 
 func List(w http.ResponseWriter, r *http.Request) {
@@ -157,7 +263,7 @@ func List(w http.ResponseWriter, r *http.Request) {
 
 	rows, err := FindTemplates(ctx, session.AccountID)
 	if err != nil {
-		http.NotFound(w, r)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -168,7 +274,7 @@ func List(w http.ResponseWriter, r *http.Request) {
 
 	bytes, err := json.Marshal(rows)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -187,23 +293,62 @@ func writeJSONResponse(w http.ResponseWriter, bytes []byte, statusCode int) {
 
 func decodeResponseBodyAndValidate(body []byte) (*InstanceTemplate, error) {
 	var template *InstanceTemplate
-	err := json.Unmarshal(body, &template)
-	if err != nil {
-		return nil, errors.New("error in unmarshal request body")
+	if err := schema.Decode(schema.Template, body, &template); err != nil {
+		return nil, err
+	}
+
+	verr := &schema.ValidationError{}
+
+	if err := render.Validate(template.UserData); err != nil {
+		verr.Add(schema.Pointer("userdata"), "%v", err)
 	}
 
-	if !isValidUUID(template.Package) {
-		return nil, errors.New("package must be a valid UUID")
+	for _, key := range sortedKeys(template.MetaData) {
+		if err := render.Validate(template.MetaData[key]); err != nil {
+			verr.Add(schema.Pointer("metadata", key), "%v", err)
+		}
 	}
 
-	if !isValidUUID(template.ImageID) {
-		return nil, errors.New("imageID must be a valid UUID")
+	if len(verr.Errors) > 0 {
+		return nil, verr
 	}
 
 	return template, nil
 }
 
-func isValidUUID(u string) bool {
-	_, err := uuid.Parse(u)
-	return err == nil
+// missingSecretsError reports every field of the template which references one
+// of the missing secrets.
+func missingSecretsError(template *InstanceTemplate, missing []string) error {
+	isMissing := make(map[string]bool, len(missing))
+	for _, name := range missing {
+		isMissing[name] = true
+	}
+
+	verr := &schema.ValidationError{}
+
+	check := func(pointer, value string) {
+		for _, name := range render.SecretNames(value) {
+			if isMissing[name] {
+				verr.Add(pointer, "references unknown secret %q", name)
+			}
+		}
+	}
+
+	check(schema.Pointer("userdata"), template.UserData)
+
+	for _, key := range sortedKeys(template.MetaData) {
+		check(schema.Pointer("metadata", key), template.MetaData[key])
+	}
+
+	return verr
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
 }
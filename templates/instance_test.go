@@ -1,6 +1,8 @@
 package templates_v1_test
 
 import (
+	"context"
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +11,7 @@ import (
 
 	"bytes"
 
+	"github.com/gorilla/mux"
 	"github.com/jackc/pgx"
 	"github.com/joyent/triton-service-groups/server"
 	"github.com/joyent/triton-service-groups/server/handlers"
@@ -18,6 +21,7 @@ import (
 	"github.com/joyent/triton-service-groups/testutils"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -81,7 +85,7 @@ func TestAcc_Get(t *testing.T) {
 
 	router := router.WithRoutes(server.RoutingTable)
 	authHandler := handlers.AuthHandler(pool, authConfig, router)
-	contextHandler := handlers.ContextHandler(pool, nomad, authHandler)
+	contextHandler := handlers.ContextHandler(pool, nomad, nil, authHandler)
 
 	req := httptest.NewRequest("GET", "http://example.com/v1/tsg/templates/319209784155176962", nil)
 	recorder := httptest.NewRecorder()
@@ -121,7 +125,7 @@ func TestAcc_GetIncorrectTemplateName(t *testing.T) {
 
 	router := router.WithRoutes(server.RoutingTable)
 	authHandler := handlers.AuthHandler(pool, authConfig, router)
-	contextHandler := handlers.ContextHandler(pool, nomad, authHandler)
+	contextHandler := handlers.ContextHandler(pool, nomad, nil, authHandler)
 
 	req := httptest.NewRequest("GET", "http://example.com/v1/tsg/templates/12345", nil)
 	recorder := httptest.NewRecorder()
@@ -157,7 +161,7 @@ func TestAcc_List(t *testing.T) {
 
 	router := router.WithRoutes(server.RoutingTable)
 	authHandler := handlers.AuthHandler(pool, authConfig, router)
-	contextHandler := handlers.ContextHandler(pool, nomad, authHandler)
+	contextHandler := handlers.ContextHandler(pool, nomad, nil, authHandler)
 
 	req := httptest.NewRequest("GET", "http://example.com/v1/tsg/templates", nil)
 	recorder := httptest.NewRecorder()
@@ -198,7 +202,7 @@ func TestAcc_Delete(t *testing.T) {
 
 	router := router.WithRoutes(server.RoutingTable)
 	authHandler := handlers.AuthHandler(pool, authConfig, router)
-	contextHandler := handlers.ContextHandler(pool, nomad, authHandler)
+	contextHandler := handlers.ContextHandler(pool, nomad, nil, authHandler)
 
 	req := httptest.NewRequest("DELETE", "http://example.com/v1/tsg/templates/328937419456806913", nil)
 	recorder := httptest.NewRecorder()
@@ -235,7 +239,7 @@ func TestAcc_DeleteNonExistantTemplate(t *testing.T) {
 
 	router := router.WithRoutes(server.RoutingTable)
 	authHandler := handlers.AuthHandler(pool, authConfig, router)
-	contextHandler := handlers.ContextHandler(pool, nomad, authHandler)
+	contextHandler := handlers.ContextHandler(pool, nomad, nil, authHandler)
 
 	req := httptest.NewRequest("DELETE", "http://example.com/v1/tsg/templates/1234", nil)
 	recorder := httptest.NewRecorder()
@@ -271,12 +275,11 @@ func TestAcc_CreateTemplate(t *testing.T) {
 
 	router := router.WithRoutes(server.RoutingTable)
 	authHandler := handlers.AuthHandler(pool, authConfig, router)
-	contextHandler := handlers.ContextHandler(pool, nomad, authHandler)
+	contextHandler := handlers.ContextHandler(pool, nomad, nil, authHandler)
 
 	testBody := `{
 	"template_name": "test-template-7",
-		"account_id": "joyent",
-		"package": "test-package",
+		"package": "14aba044-d0f8-11e5-8c88-eb339a5da5d0",
 		"image_id": "49b22aec-0c8a-11e6-8807-a3eb4db576ba",
 		"firewall_enabled": false,
 		"networks": [
@@ -300,3 +303,86 @@ func TestAcc_CreateTemplate(t *testing.T) {
 
 	assert.Equal(t, http.StatusCreated, resp.StatusCode)
 }
+
+const (
+	usageAccountID  = "6f873d02-172c-418f-8416-4da2b50d5c53"
+	usageTemplateID = "ad74301e-ad62-404a-be44-3b2f24d082ac"
+	usageGroupID    = "9e075e5d-60d5-4cff-968e-b70db0badc12"
+)
+
+// newUsageDB seeds a template used by a single group, returning the pool of
+// the test database.
+func newUsageDB(t *testing.T) *pgx.ConnPool {
+	if os.Getenv("TSG_TEST") == "" {
+		t.Skip("Acceptance tests skipped unless env 'TSG_TEST=1' set")
+	}
+
+	db, err := testutils.NewTestDB()
+	require.NoError(t, err)
+	db.Clear(t)
+	t.Cleanup(func() { db.Clear(t) })
+
+	for _, query := range []string{
+		`INSERT INTO tsg_accounts (id, account_name, created_at, updated_at)
+VALUES ('` + usageAccountID + `', 'joyent', NOW(), NOW());`,
+		`INSERT INTO tsg_templates (id, template_name, package, image_id, account_id, firewall_enabled, networks, userdata, created_at, archived)
+VALUES ('` + usageTemplateID + `', 'test-template-1', 'test-package', '49b22aec-0c8a-11e6-8807-a3eb4db576ba', '` + usageAccountID + `', false, 'f7ed95d3-faaf-43ef-9346-15644403b963', 'bash script here', NOW(), false);`,
+		`INSERT INTO tsg_groups (id, "name", template_id, account_id, capacity, health_check_interval, created_at, updated_at, archived)
+VALUES ('` + usageGroupID + `', 'test-group-1', '` + usageTemplateID + `', '` + usageAccountID + `', 3, 300, NOW(), NOW(), false);`,
+	} {
+		_, err := db.Conn.Exec(query)
+		require.NoError(t, err)
+	}
+
+	return db.Conn
+}
+
+// serveTemplate calls handler for the template identified by id, as the
+// account the template belongs to.
+func serveTemplate(pool *pgx.ConnPool, handler http.HandlerFunc, method, id string) *httptest.ResponseRecorder {
+	ctx := handlers.NewContext(context.Background(), pool, nil, nil)
+	ctx = handlers.WithAuthSession(ctx, &auth.Session{AccountID: usageAccountID})
+
+	req := httptest.NewRequest(method, "/v1/tsg/templates/"+id, nil).WithContext(ctx)
+	req = mux.SetURLVars(req, map[string]string{"identifier": id})
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, req)
+	return recorder
+}
+
+func TestListGroups(t *testing.T) {
+	pool := newUsageDB(t)
+
+	recorder := serveTemplate(pool, templates_v1.ListGroups, http.MethodGet, usageTemplateID)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var groups []*templates_v1.TemplateGroup
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &groups))
+	assert.Equal(t, []*templates_v1.TemplateGroup{
+		{ID: usageGroupID, GroupName: "test-group-1", Capacity: 3},
+	}, groups)
+
+	recorder = serveTemplate(pool, templates_v1.ListGroups, http.MethodGet, "8b5a6001-8a59-4d85-bc72-1af83015b2c2")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestDeleteTemplateInUse(t *testing.T) {
+	pool := newUsageDB(t)
+
+	recorder := serveTemplate(pool, templates_v1.Delete, http.MethodDelete, usageTemplateID)
+	require.Equal(t, http.StatusConflict, recorder.Code)
+
+	var body struct {
+		Code   string                        `json:"code"`
+		Groups []*templates_v1.TemplateGroup `json:"groups"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, "template_in_use", body.Code)
+	assert.Equal(t, []*templates_v1.TemplateGroup{
+		{ID: usageGroupID, GroupName: "test-group-1", Capacity: 3},
+	}, body.Groups)
+
+	recorder = serveTemplate(pool, templates_v1.Delete, http.MethodDelete, "8b5a6001-8a59-4d85-bc72-1af83015b2c2")
+	assert.Equal(t, http.StatusNotFound, recorder.Code, "unknown templates aren't checked for groups")
+}
@@ -0,0 +1,194 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package render substitutes per-instance values into the userdata and
+// metadata of an instance template. It has no dependencies on the API server
+// so the scaling worker can render values for each instance it creates.
+//
+// Variables are written as `{{tsg.<name>}}`, whitespace inside the braces is
+// ignored. Account secrets are referenced as `{{secret.<name>}}` and are only
+// resolved by the scaling worker. Any other text is left untouched, which keeps
+// shell scripts and other templating languages within userdata intact.
+package render
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Names of the variables which can be referenced within userdata and metadata.
+const (
+	VarGroupName       = "group_name"
+	VarGroupID         = "group_id"
+	VarInstanceIndex   = "instance_index"
+	VarDatacenter      = "datacenter"
+	VarTemplateVersion = "template_version"
+)
+
+// IndexTag is the instance tag the scaling worker uses to persist the ordinal
+// index of each instance, keeping the index stable across scaling actions.
+const IndexTag = "tsg.index"
+
+const (
+	namespaceTSG    = "tsg"
+	namespaceSecret = "secret"
+)
+
+var matchVar = regexp.MustCompile(`{{\s*(tsg|secret)\.([a-zA-Z0-9_]*)\s*}}`)
+
+// Instance holds the values which are rendered for a single instance.
+type Instance struct {
+	GroupName       string
+	GroupID         string
+	Index           int
+	Datacenter      string
+	TemplateVersion string
+
+	// Secrets maps the name of every secret referenced by the template to its
+	// decrypted value.
+	Secrets map[string]string
+}
+
+func (i Instance) lookup(name string) (string, bool) {
+	switch name {
+	case VarGroupName:
+		return i.GroupName, true
+	case VarGroupID:
+		return i.GroupID, true
+	case VarInstanceIndex:
+		return strconv.Itoa(i.Index), true
+	case VarDatacenter:
+		return i.Datacenter, true
+	case VarTemplateVersion:
+		return i.TemplateVersion, true
+	default:
+		return "", false
+	}
+}
+
+// Render substitutes every variable within s with the values of inst. An error
+// is returned if s references an unknown variable or secret.
+func Render(s string, inst Instance) (string, error) {
+	return render(s, inst, true)
+}
+
+func render(s string, inst Instance, resolveSecrets bool) (string, error) {
+	var err error
+
+	out := matchVar.ReplaceAllStringFunc(s, func(match string) string {
+		submatches := matchVar.FindStringSubmatch(match)
+		namespace, name := submatches[1], submatches[2]
+
+		var (
+			value string
+			ok    bool
+		)
+		switch {
+		case namespace == namespaceTSG:
+			value, ok = inst.lookup(name)
+		case !resolveSecrets:
+			value, ok = match, name != ""
+		default:
+			value, ok = inst.Secrets[name]
+		}
+
+		if !ok && err == nil {
+			err = fmt.Errorf("render: unknown %s %q", variableKind(namespace), namespace+"."+name)
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return out, nil
+}
+
+func variableKind(namespace string) string {
+	if namespace == namespaceSecret {
+		return "secret"
+	}
+	return "variable"
+}
+
+// RenderMap renders every value of m, returning a new map. Keys are never
+// rendered.
+func RenderMap(m map[string]string, inst Instance) (map[string]string, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	out := make(map[string]string, len(m))
+	for key, value := range m {
+		rendered, err := Render(value, inst)
+		if err != nil {
+			return nil, fmt.Errorf("%v in %q", err, key)
+		}
+		out[key] = rendered
+	}
+
+	return out, nil
+}
+
+// Validate checks that s only references known variables. Secrets are not
+// resolved, use SecretNames to find the secrets referenced by s.
+func Validate(s string) error {
+	_, err := render(s, Instance{}, false)
+	return err
+}
+
+// ValidateMap checks that every value of m only references known variables.
+func ValidateMap(m map[string]string) error {
+	for key, value := range m {
+		if err := Validate(value); err != nil {
+			return fmt.Errorf("%v in %q", err, key)
+		}
+	}
+	return nil
+}
+
+// SecretNames returns the sorted and de-duplicated names of every secret
+// referenced within values.
+func SecretNames(values ...string) []string {
+	seen := make(map[string]bool)
+
+	var names []string
+	for _, value := range values {
+		for _, submatches := range matchVar.FindAllStringSubmatch(value, -1) {
+			namespace, name := submatches[1], submatches[2]
+			if namespace != namespaceSecret || name == "" || seen[name] {
+				continue
+			}
+
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// NextIndex returns the lowest ordinal index which isn't within used. The
+// scaling worker calls this with the indexes of a group's running instances
+// when creating a new instance, so indexes are reused after scaling down.
+func NextIndex(used []int) int {
+	sorted := make([]int, len(used))
+	copy(sorted, used)
+	sort.Ints(sorted)
+
+	next := 0
+	for _, idx := range sorted {
+		if idx == next {
+			next++
+		} else if idx > next {
+			break
+		}
+	}
+
+	return next
+}
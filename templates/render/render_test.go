@@ -0,0 +1,117 @@
+package render_test
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-service-groups/templates/render"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testInstance = render.Instance{
+	GroupName:       "jolly-jelly",
+	GroupID:         "722d25ed-f32a-4944-9861-8990e204850e",
+	Index:           3,
+	Datacenter:      "us-east-1",
+	TemplateVersion: "29a08459",
+	Secrets: map[string]string{
+		"db_password": "database123",
+	},
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		output string
+		err    bool
+	}{
+		{"empty", "", "", false},
+		{"no variables", "#!/bin/bash\necho ${HOME}\n", "#!/bin/bash\necho ${HOME}\n", false},
+		{"group name", "{{tsg.group_name}}", "jolly-jelly", false},
+		{"group id", "{{tsg.group_id}}", "722d25ed-f32a-4944-9861-8990e204850e", false},
+		{"instance index", "web-{{tsg.instance_index}}", "web-3", false},
+		{"datacenter", "{{ tsg.datacenter }}", "us-east-1", false},
+		{"template version", "v={{tsg.template_version}}", "v=29a08459", false},
+		{"multiple", "{{tsg.group_name}}-{{tsg.instance_index}}.{{tsg.datacenter}}", "jolly-jelly-3.us-east-1", false},
+		{"other templating", "{{ .Values.name }}", "{{ .Values.name }}", false},
+		{"unknown variable", "{{tsg.bogus}}", "", true},
+		{"empty variable", "{{tsg.}}", "", true},
+		{"secret", "PASSWORD={{ secret.db_password }}", "PASSWORD=database123", false},
+		{"unknown secret", "{{secret.api_token}}", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, err := render.Render(test.input, testInstance)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.output, output)
+		})
+	}
+}
+
+func TestRenderMap(t *testing.T) {
+	output, err := render.RenderMap(nil, testInstance)
+	require.NoError(t, err)
+	assert.Nil(t, output)
+
+	input := map[string]string{
+		"hostname":         "{{tsg.group_name}}-{{tsg.instance_index}}",
+		"{{tsg.group_id}}": "static",
+	}
+	output, err = render.RenderMap(input, testInstance)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"hostname":         "jolly-jelly-3",
+		"{{tsg.group_id}}": "static",
+	}, output)
+
+	_, err = render.RenderMap(map[string]string{"foo": "{{tsg.nope}}"}, testInstance)
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, render.Validate("echo {{tsg.group_name}}"))
+	assert.Error(t, render.Validate("echo {{tsg.group}}"))
+
+	assert.NoError(t, render.ValidateMap(map[string]string{"name": "{{tsg.group_id}}"}))
+	assert.Error(t, render.ValidateMap(map[string]string{"name": "{{tsg.id}}"}))
+
+	assert.NoError(t, render.Validate("{{secret.anything}}"))
+	assert.Error(t, render.Validate("{{secret.}}"))
+}
+
+func TestSecretNames(t *testing.T) {
+	assert.Nil(t, render.SecretNames("echo {{tsg.group_name}}"))
+
+	names := render.SecretNames(
+		"DB={{secret.db_password}} TOKEN={{ secret.api_token }}",
+		"{{secret.db_password}}",
+		"{{secret.}}",
+	)
+	assert.Equal(t, []string{"api_token", "db_password"}, names)
+}
+
+func TestNextIndex(t *testing.T) {
+	tests := []struct {
+		name string
+		used []int
+		next int
+	}{
+		{"no instances", nil, 0},
+		{"contiguous", []int{0, 1, 2}, 3},
+		{"unordered", []int{2, 0, 1}, 3},
+		{"gap", []int{0, 2, 3}, 1},
+		{"missing first", []int{1, 2}, 0},
+		{"duplicates", []int{0, 0, 1}, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.next, render.NextIndex(test.used))
+		})
+	}
+}
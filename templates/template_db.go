@@ -14,6 +14,7 @@ import (
 	"github.com/jackc/pgx/pgtype"
 	"github.com/joyent/triton-service-groups/convert"
 	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/tracing"
 	"github.com/rs/zerolog/log"
 )
 
@@ -33,7 +34,7 @@ SELECT EXISTS
           AND account_id = $2)
      AND archived IS FALSE);`
 
-	err := db.QueryRowEx(ctx, sql, nil, templateName, accountID).Scan(&exists)
+	err := tracing.QueryRowEx(ctx, db, sql, nil, templateName, accountID).Scan(&exists)
 	if err != nil {
 		return false, err
 	}
@@ -41,31 +42,56 @@ SELECT EXISTS
 	return exists, nil
 }
 
-func CheckTemplateAllocationByID(ctx context.Context, templateID, accountID string) (bool, error) {
+// TemplateGroup represents a group which references a template.
+type TemplateGroup struct {
+	ID        string `json:"id"`
+	GroupName string `json:"group_name"`
+	Capacity  int    `json:"capacity"`
+}
+
+func FindTemplateGroups(ctx context.Context, templateID, accountID string) ([]*TemplateGroup, error) {
 	db, ok := handlers.GetDBPool(ctx)
 	if !ok {
-		return false, handlers.ErrNoConnPool
+		return nil, handlers.ErrNoConnPool
 	}
 
-	var allocated bool
+	sqlStatement := `
+SELECT g.id, g.name, g.capacity
+FROM tsg_groups AS g
+WHERE g.template_id = $1
+  AND g.account_id = $2
+  AND g.archived = false
+ORDER BY g.name;`
 
-	sql := `
-SELECT EXISTS
-  (SELECT 1
-   FROM tsg_templates AS t,
-        tsg_groups AS g
-   WHERE (t.id = g.template_id
-          AND t.account_id = g.account_id)
-     AND (t.id = $1
-          AND t.account_id = $2)
-     AND g.archived IS FALSE);`
-
-	err := db.QueryRowEx(ctx, sql, nil, templateID, accountID).Scan(&allocated)
+	var groups []*TemplateGroup
+
+	rows, err := tracing.QueryEx(ctx, db, sqlStatement, nil, templateID, accountID)
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			group   TemplateGroup
+			groupID pgtype.UUID
+		)
+
+		err := rows.Scan(
+			&groupID,
+			&group.GroupName,
+			&group.Capacity,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		group.ID = convert.BytesToUUID(groupID.Bytes)
+
+		groups = append(groups, &group)
 	}
 
-	return allocated, nil
+	return groups, rows.Err()
 }
 
 func FindTemplateByName(ctx context.Context, key string, accountID string) (*InstanceTemplate, bool) {
@@ -75,7 +101,7 @@ func FindTemplateByName(ctx context.Context, key string, accountID string) (*Ins
 	}
 
 	sqlStatement := `
-SELECT id, template_name, package, image_id, firewall_enabled, networks, COALESCE(metadata,''), userdata, COALESCE(tags,''), created_at
+SELECT id, template_name, package, image_id, firewall_enabled, networks, COALESCE(metadata,''), userdata, COALESCE(tags,''), created_at, COALESCE(created_by, '')
 FROM tsg_templates
 WHERE template_name = $1 and account_id = $2
 AND archived = false
@@ -90,7 +116,7 @@ AND archived = false
 		createdAt    pgtype.Timestamp
 	)
 
-	err := db.QueryRowEx(ctx, sqlStatement, nil, key, accountID).Scan(
+	err := tracing.QueryRowEx(ctx, db, sqlStatement, nil, key, accountID).Scan(
 		&templateID,
 		&template.TemplateName,
 		&template.Package,
@@ -101,6 +127,7 @@ AND archived = false
 		&template.UserData,
 		&tagsJson,
 		&createdAt,
+		&template.CreatedBy,
 	)
 	switch err {
 	case nil:
@@ -138,7 +165,7 @@ func FindTemplateByID(ctx context.Context, key string, accountID string) (*Insta
 	}
 
 	sqlStatement := `
-SELECT id, template_name, package, image_id, firewall_enabled, networks, COALESCE(metadata,''), userdata, COALESCE(tags,''), created_at
+SELECT id, template_name, package, image_id, firewall_enabled, networks, COALESCE(metadata,''), userdata, COALESCE(tags,''), created_at, COALESCE(created_by, '')
 FROM tsg_templates
 WHERE id = $1 and account_id = $2
 AND archived = false
@@ -153,7 +180,7 @@ AND archived = false
 		createdAt    pgtype.Timestamp
 	)
 
-	err := db.QueryRowEx(ctx, sqlStatement, nil, key, accountID).Scan(
+	err := tracing.QueryRowEx(ctx, db, sqlStatement, nil, key, accountID).Scan(
 		&templateID,
 		&template.TemplateName,
 		&template.Package,
@@ -164,6 +191,7 @@ AND archived = false
 		&template.UserData,
 		&tagsJson,
 		&createdAt,
+		&template.CreatedBy,
 	)
 	switch err {
 	case nil:
@@ -199,7 +227,7 @@ func FindTemplates(ctx context.Context, accountID string) ([]*InstanceTemplate,
 		return nil, handlers.ErrNoConnPool
 	}
 
-	sqlStatement := `SELECT id, template_name, package, image_id, firewall_enabled, networks, COALESCE(metadata,''), userdata, COALESCE(tags, ''), created_at
+	sqlStatement := `SELECT id, template_name, package, image_id, firewall_enabled, networks, COALESCE(metadata,''), userdata, COALESCE(tags, ''), created_at, COALESCE(created_by, '')
 FROM tsg_templates
 WHERE account_id = $1
 AND archived = false;`
@@ -213,7 +241,7 @@ AND archived = false;`
 		createdAt    pgtype.Timestamp
 	)
 
-	rows, err := db.QueryEx(ctx, sqlStatement, nil, accountID)
+	rows, err := tracing.QueryEx(ctx, db, sqlStatement, nil, accountID)
 	if err != nil {
 		return nil, err
 	}
@@ -231,6 +259,7 @@ AND archived = false;`
 			&template.UserData,
 			&tagsJson,
 			&createdAt,
+			&template.CreatedBy,
 		)
 		if err != nil {
 			return nil, err
@@ -267,8 +296,8 @@ func SaveTemplate(ctx context.Context, accountID string, template *InstanceTempl
 	}
 
 	sqlStatement := `
-INSERT INTO tsg_templates (template_name, package, image_id, account_id, firewall_enabled, networks, metadata, userdata, tags, created_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+INSERT INTO tsg_templates (template_name, package, image_id, account_id, firewall_enabled, networks, metadata, userdata, tags, created_by, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW())
 `
 
 	metaDataJson, err := convertToJson(template.MetaData)
@@ -283,7 +312,7 @@ VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
 
 	networksList := strings.Join(template.Networks, ",")
 
-	_, err = db.ExecEx(ctx, sqlStatement, nil,
+	_, err = tracing.ExecEx(ctx, db, sqlStatement, nil,
 		template.TemplateName,
 		template.Package,
 		template.ImageID,
@@ -293,6 +322,7 @@ VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
 		metaDataJson,
 		template.UserData,
 		tagsJson,
+		template.CreatedBy,
 	)
 	if err != nil {
 		return err
@@ -301,17 +331,17 @@ VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
 	return nil
 }
 
-func RemoveTemplate(ctx context.Context, identifier string, accountID string) error {
+func RemoveTemplate(ctx context.Context, identifier string, accountID string, actor string) error {
 	db, ok := handlers.GetDBPool(ctx)
 	if !ok {
 		return handlers.ErrNoConnPool
 	}
 
 	sqlStatement := `UPDATE triton.tsg_templates
-SET archived = true
+SET archived = true, updated_by = $3
 WHERE id = $1 and account_id = $2`
 
-	_, err := db.ExecEx(ctx, sqlStatement, nil, identifier, accountID)
+	_, err := tracing.ExecEx(ctx, db, sqlStatement, nil, identifier, accountID, actor)
 	if err != nil {
 		return err
 	}
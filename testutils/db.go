@@ -45,11 +45,51 @@ func (db *TestDB) Clear(t *testing.T) {
 		t.Fatalf("conn.Exec failed: %v", err)
 	}
 
+	_, err6 := db.Conn.Exec(`DELETE FROM tsg_secrets`)
+	if err6 != nil {
+		t.Fatalf("conn.Exec failed: %v", err6)
+	}
+
+	_, err7 := db.Conn.Exec(`DELETE FROM tsg_tokens`)
+	if err7 != nil {
+		t.Fatalf("conn.Exec failed: %v", err7)
+	}
+
 	_, err3 := db.Conn.Exec(`DELETE FROM tsg_users`)
 	if err3 != nil {
 		t.Fatalf("conn.Exec failed: %v", err2)
 	}
 
+	_, err8 := db.Conn.Exec(`DELETE FROM tsg_allowlist`)
+	if err8 != nil {
+		t.Fatalf("conn.Exec failed: %v", err8)
+	}
+
+	_, err9 := db.Conn.Exec(`DELETE FROM tsg_rate_limits`)
+	if err9 != nil {
+		t.Fatalf("conn.Exec failed: %v", err9)
+	}
+
+	_, err10 := db.Conn.Exec(`DELETE FROM tsg_audit`)
+	if err10 != nil {
+		t.Fatalf("conn.Exec failed: %v", err10)
+	}
+
+	_, err11 := db.Conn.Exec(`DELETE FROM tsg_group_events`)
+	if err11 != nil {
+		t.Fatalf("conn.Exec failed: %v", err11)
+	}
+
+	_, err12 := db.Conn.Exec(`DELETE FROM tsg_operations`)
+	if err12 != nil {
+		t.Fatalf("conn.Exec failed: %v", err12)
+	}
+
+	_, err13 := db.Conn.Exec(`DELETE FROM tsg_sessions`)
+	if err13 != nil {
+		t.Fatalf("conn.Exec failed: %v", err13)
+	}
+
 	_, err4 := db.Conn.Exec(`DELETE FROM tsg_accounts`)
 	if err4 != nil {
 		t.Fatalf("conn.Exec failed: %v", err2)
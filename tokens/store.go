@@ -0,0 +1,149 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package tokens
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/pgtype"
+	"github.com/joyent/triton-service-groups/convert"
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/joyent/triton-service-groups/users"
+	"github.com/pkg/errors"
+)
+
+type Store struct {
+	pool *pgx.ConnPool
+}
+
+// NewStore returns a new store object.
+func NewStore(pool *pgx.ConnPool) *Store {
+	return &Store{
+		pool: pool,
+	}
+}
+
+const selectTokens = `
+SELECT id, name, prefix, token_hash, account_id, permission, group_ids,
+       COALESCE(created_by, ''), expires_at, revoked_at, created_at, updated_at
+FROM tsg_tokens
+`
+
+// scanner is implemented by both *pgx.Row and *pgx.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (s *Store) scanToken(row scanner) (*Token, error) {
+	var (
+		id         pgtype.UUID
+		accountID  pgtype.UUID
+		name       string
+		prefix     string
+		hash       string
+		permission string
+		groupIDs   string
+		createdBy  string
+		expiresAt  pgtype.Timestamptz
+		revokedAt  pgtype.Timestamptz
+		createdAt  pgtype.Timestamptz
+		updatedAt  pgtype.Timestamptz
+	)
+
+	err := row.Scan(
+		&id,
+		&name,
+		&prefix,
+		&hash,
+		&accountID,
+		&permission,
+		&groupIDs,
+		&createdBy,
+		&expiresAt,
+		&revokedAt,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	token := New(s)
+	token.ID = convert.BytesToUUID(id.Bytes)
+	token.Name = name
+	token.Prefix = prefix
+	token.AccountID = convert.BytesToUUID(accountID.Bytes)
+	token.Permission = users.Permission(permission)
+	token.CreatedBy = createdBy
+	token.ExpiresAt = expiresAt.Time
+	token.CreatedAt = createdAt.Time
+	token.UpdatedAt = updatedAt.Time
+	token.hash = hash
+
+	if revokedAt.Status == pgtype.Present {
+		token.RevokedAt = revokedAt.Time
+	}
+
+	if err := json.Unmarshal([]byte(groupIDs), &token.GroupIDs); err != nil {
+		return nil, errors.Wrap(err, "failed to decode token groups")
+	}
+
+	return token, nil
+}
+
+// FindByID finds a token of an account by a specific ID.
+func (s *Store) FindByID(ctx context.Context, tokenID string, accountID string) (*Token, error) {
+	query := selectTokens + `WHERE id = $1 AND account_id = $2;`
+
+	return s.scanToken(tracing.QueryRowEx(ctx, s.pool, query, nil, tokenID, accountID))
+}
+
+// FindBySecret finds the token of a secret presented by a client. The token is
+// returned whether or not it's still active.
+func (s *Store) FindBySecret(ctx context.Context, secret string) (*Token, error) {
+	query := selectTokens + `WHERE token_hash = $1;`
+
+	return s.scanToken(tracing.QueryRowEx(ctx, s.pool, query, nil, HashSecret(secret)))
+}
+
+// FindByAccount finds every token of an account, ordered by name.
+func (s *Store) FindByAccount(ctx context.Context, accountID string) ([]*Token, error) {
+	query := selectTokens + `WHERE account_id = $1 ORDER BY name, created_at;`
+
+	rows, err := tracing.QueryEx(ctx, s.pool, query, nil, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*Token
+	for rows.Next() {
+		token, err := s.scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// RevokeByAccount revokes every active token of an account, returning the
+// number of tokens revoked.
+func (s *Store) RevokeByAccount(ctx context.Context, accountID string) (int64, error) {
+	query := `
+UPDATE tsg_tokens SET (revoked_at, updated_at) = (NOW(), NOW())
+WHERE account_id = $1 AND revoked_at IS NULL;
+`
+	tag, err := tracing.ExecEx(ctx, s.pool, query, nil, accountID)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
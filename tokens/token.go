@@ -0,0 +1,216 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/joyent/triton-service-groups/users"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SecretPrefix is prepended to every token secret so tokens are easy to
+	// recognize, for example by secret scanners.
+	SecretPrefix = "tsg_"
+
+	// secretSize is the number of random bytes within a token secret.
+	secretSize = 32
+
+	// displayLength is the number of leading characters of a secret which are
+	// stored in plain text to help identify a token.
+	displayLength = len(SecretPrefix) + 8
+)
+
+var (
+	ErrNoAccountID = errors.New("missing account identifer for save")
+	ErrMissingID   = errors.New("missing identifer for save")
+	ErrBadSecret   = errors.New("malformed token secret")
+)
+
+// Token represents the data associated with an tsg_tokens row. A token is a
+// bearer credential issued by the account owner for automation clients which
+// can't sign requests with a Triton key. Only a hash of the token's secret is
+// stored.
+type Token struct {
+	ID         string
+	Name       string
+	Prefix     string
+	AccountID  string
+	Permission users.Permission
+	GroupIDs   []string
+	CreatedBy  string
+	ExpiresAt  time.Time
+	RevokedAt  time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+
+	hash  string
+	store *Store
+}
+
+// New constructs a new Token with the Store for backend persistence.
+func New(store *Store) *Token {
+	return &Token{
+		store: store,
+	}
+}
+
+// GenerateSecret returns a new random token secret.
+func GenerateSecret() (string, error) {
+	buf := make([]byte, secretSize)
+	if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+		return "", errors.Wrap(err, "failed to generate token secret")
+	}
+
+	return SecretPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashSecret returns the hash of a token secret as stored in the database.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsActive returns true if the token has neither expired nor been revoked.
+func (t *Token) IsActive(now time.Time) bool {
+	return t.RevokedAt.IsZero() && now.Before(t.ExpiresAt)
+}
+
+// IsRevoked returns true if the token has been revoked.
+func (t *Token) IsRevoked() bool {
+	return !t.RevokedAt.IsZero()
+}
+
+// AllowsGroup returns true if the token can act on the group. Tokens which
+// aren't scoped to any group can act on every group of the account.
+func (t *Token) AllowsGroup(groupID string) bool {
+	if len(t.GroupIDs) == 0 {
+		return true
+	}
+
+	for _, id := range t.GroupIDs {
+		if strings.EqualFold(id, groupID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Insert inserts a new token into the tsg_tokens table, storing only the hash
+// of secret.
+func (t *Token) Insert(ctx context.Context, secret string) error {
+	if t.AccountID == "" {
+		return ErrNoAccountID
+	}
+
+	if !strings.HasPrefix(secret, SecretPrefix) || len(secret) <= displayLength {
+		return ErrBadSecret
+	}
+
+	groupIDs := t.GroupIDs
+	if groupIDs == nil {
+		groupIDs = []string{}
+	}
+
+	encodedGroups, err := json.Marshal(groupIDs)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode token groups")
+	}
+
+	t.hash = HashSecret(secret)
+	t.Prefix = secret[:displayLength]
+
+	query := `
+INSERT INTO tsg_tokens (name, token_hash, prefix, account_id, permission, group_ids, created_by, expires_at, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW());
+`
+	pool := t.store.pool
+
+	tx, err := pool.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback() // nolint: errcheck
+
+	_, err = tracing.ExecEx(ctx, tx, query, nil,
+		t.Name,
+		t.hash,
+		t.Prefix,
+		t.AccountID,
+		string(t.Permission),
+		string(encodedGroups),
+		t.CreatedBy,
+		t.ExpiresAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert token")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	token, err := t.store.FindBySecret(ctx, secret)
+	if err != nil {
+		return errors.Wrap(err, "failed to find token after insert")
+	}
+
+	t.ID = token.ID
+	t.CreatedAt = token.CreatedAt
+	t.UpdatedAt = token.UpdatedAt
+
+	return nil
+}
+
+// Revoke revokes the token, which is rejected from then on.
+func (t *Token) Revoke(ctx context.Context) error {
+	if t.ID == "" {
+		return ErrMissingID
+	}
+
+	query := `
+UPDATE tsg_tokens SET (revoked_at, updated_at) = ($3, $3)
+WHERE id = $1 AND account_id = $2;
+`
+	revokedAt := time.Now()
+
+	pool := t.store.pool
+
+	tx, err := pool.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback() // nolint: errcheck
+
+	_, err = tracing.ExecEx(ctx, tx, query, nil,
+		t.ID,
+		t.AccountID,
+		revokedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to revoke token")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	t.RevokedAt = revokedAt
+	t.UpdatedAt = revokedAt
+
+	return nil
+}
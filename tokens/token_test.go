@@ -0,0 +1,109 @@
+package tokens_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/accounts"
+	"github.com/joyent/triton-service-groups/testutils"
+	"github.com/joyent/triton-service-groups/tokens"
+	"github.com/joyent/triton-service-groups/users"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := tokens.GenerateSecret()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, tokens.SecretPrefix))
+
+	other, err := tokens.GenerateSecret()
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, other)
+
+	assert.Equal(t, tokens.HashSecret(secret), tokens.HashSecret(secret))
+	assert.NotEqual(t, tokens.HashSecret(secret), tokens.HashSecret(other))
+	assert.NotContains(t, tokens.HashSecret(secret), secret)
+}
+
+func TestTokenIsActive(t *testing.T) {
+	now := time.Now()
+
+	token := &tokens.Token{ExpiresAt: now.Add(time.Hour)}
+	assert.True(t, token.IsActive(now))
+	assert.False(t, token.IsActive(now.Add(2*time.Hour)))
+
+	token.RevokedAt = now
+	assert.True(t, token.IsRevoked())
+	assert.False(t, token.IsActive(now))
+}
+
+func TestTokenAllowsGroup(t *testing.T) {
+	token := &tokens.Token{}
+	assert.True(t, token.AllowsGroup("6f873d02-172c-418f-8416-4da2b50d5c53"))
+
+	token.GroupIDs = []string{"6f873d02-172c-418f-8416-4da2b50d5c53"}
+	assert.True(t, token.AllowsGroup("6f873d02-172c-418f-8416-4da2b50d5c53"))
+	assert.False(t, token.AllowsGroup("d255305d-aa60-49bc-acc2-3713cf0beb1c"))
+}
+
+func TestInsertAndRevoke(t *testing.T) {
+	if os.Getenv("TSG_TEST") == "" {
+		t.Skip("Acceptance tests skipped unless env 'TSG_TEST=1' set")
+		return
+	}
+
+	db, err := testutils.NewTestDB()
+	if err != nil {
+		t.Error(err)
+	}
+	db.Clear(t)
+	defer db.Clear(t)
+
+	ctx := context.Background()
+
+	account := accounts.New(accounts.NewStore(db.Conn))
+	account.AccountName = "baconuser"
+	require.NoError(t, account.Insert(ctx))
+
+	store := tokens.NewStore(db.Conn)
+
+	secret, err := tokens.GenerateSecret()
+	require.NoError(t, err)
+
+	token := tokens.New(store)
+	token.Name = "deploy-bot"
+	token.AccountID = account.ID
+	token.Permission = users.PermissionScaleOnly
+	token.GroupIDs = []string{"6f873d02-172c-418f-8416-4da2b50d5c53"}
+	token.CreatedBy = account.AccountName
+	token.ExpiresAt = time.Now().Add(time.Hour)
+
+	require.NoError(t, token.Insert(ctx, secret))
+	assert.NotZero(t, token.ID)
+	assert.True(t, strings.HasPrefix(secret, token.Prefix))
+
+	found, err := store.FindBySecret(ctx, secret)
+	require.NoError(t, err)
+	assert.Equal(t, token.ID, found.ID)
+	assert.Equal(t, users.PermissionScaleOnly, found.Permission)
+	assert.Equal(t, token.GroupIDs, found.GroupIDs)
+	assert.True(t, found.IsActive(time.Now()))
+
+	_, err = store.FindBySecret(ctx, secret+"x")
+	assert.Equal(t, pgx.ErrNoRows, err)
+
+	require.NoError(t, found.Revoke(ctx))
+
+	found, err = store.FindByID(ctx, token.ID, account.ID)
+	require.NoError(t, err)
+	assert.True(t, found.IsRevoked())
+
+	all, err := store.FindByAccount(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+}
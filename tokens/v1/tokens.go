@@ -0,0 +1,287 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package tokens_v1
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/groups"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/schema"
+	"github.com/joyent/triton-service-groups/tokens"
+	"github.com/joyent/triton-service-groups/users"
+	"github.com/rs/zerolog/log"
+)
+
+var errTokenNotFound = apierror.NotFound("token not found")
+
+const (
+	// DefaultExpiry is how long a token is valid for when no expiry was
+	// requested.
+	DefaultExpiry = 30 * 24 * time.Hour
+
+	// MaxExpiry is the longest a token can be valid for.
+	MaxExpiry = 365 * 24 * time.Hour
+)
+
+// Token represents an API token issued to an automation client. The secret is
+// only ever returned when the token is issued.
+type Token struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Permission string     `json:"permission"`
+	GroupIDs   []string   `json:"groups"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Secret     string     `json:"token,omitempty"`
+	Prefix     string     `json:"prefix"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func newToken(token *tokens.Token) *Token {
+	t := &Token{
+		ID:         token.ID,
+		Name:       token.Name,
+		Permission: string(token.Permission),
+		GroupIDs:   token.GroupIDs,
+		ExpiresAt:  &token.ExpiresAt,
+		Prefix:     token.Prefix,
+		CreatedBy:  token.CreatedBy,
+		CreatedAt:  token.CreatedAt,
+		UpdatedAt:  token.UpdatedAt,
+	}
+	if t.GroupIDs == nil {
+		t.GroupIDs = []string{}
+	}
+	if token.IsRevoked() {
+		t.RevokedAt = &token.RevokedAt
+	}
+	return t
+}
+
+func getStore(ctx context.Context) (*tokens.Store, error) {
+	pool, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		return nil, handlers.ErrNoConnPool
+	}
+	return tokens.NewStore(pool), nil
+}
+
+func Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	vars := mux.Vars(r)
+	identifier := vars["identifier"]
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	token, err := store.FindByID(ctx, identifier, session.AccountID)
+	if err != nil {
+		writeFindError(w, r, err)
+		return
+	}
+
+	bytes, err := json.Marshal(newToken(token))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+func Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	input, err := decodeTokenBodyAndValidate(body, time.Now())
+	if err != nil {
+		schema.WriteError(w, r, err)
+		return
+	}
+
+	for _, groupID := range input.GroupIDs {
+		if _, ok := groups_v1.FindGroupByID(ctx, groupID, session.AccountID); !ok {
+			apierror.Write(w, r, apierror.BadRequest("Cannot issue token, group %q "+
+				"does not exist.", groupID))
+			return
+		}
+	}
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	secret, err := tokens.GenerateSecret()
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	token := tokens.New(store)
+	token.Name = input.Name
+	token.AccountID = session.AccountID
+	token.Permission = users.Permission(input.Permission)
+	token.GroupIDs = input.GroupIDs
+	token.CreatedBy = session.Actor()
+	token.ExpiresAt = *input.ExpiresAt
+
+	if err := token.Insert(ctx, secret); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	log.Info().
+		Str("actor", session.Actor()).
+		Str("token_name", token.Name).
+		Str("permission", string(token.Permission)).
+		Time("expires_at", token.ExpiresAt).
+		Msg("tokens: issued API token")
+
+	output := newToken(token)
+	output.Secret = secret
+
+	bytes, err := json.Marshal(output)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", path.Join(r.URL.Path, token.ID))
+	writeJSONResponse(w, bytes, http.StatusCreated)
+}
+
+func Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	vars := mux.Vars(r)
+	identifier := vars["identifier"]
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	token, err := store.FindByID(ctx, identifier, session.AccountID)
+	if err != nil {
+		writeFindError(w, r, err)
+		return
+	}
+
+	if !token.IsRevoked() {
+		if err := token.Revoke(ctx); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		log.Info().
+			Str("actor", session.Actor()).
+			Str("token_name", token.Name).
+			Msg("tokens: revoked API token")
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	rows, err := store.FindByAccount(ctx, session.AccountID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	list := make([]*Token, 0, len(rows))
+	for _, token := range rows {
+		list = append(list, newToken(token))
+	}
+
+	bytes, err := json.Marshal(list)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+// decodeTokenBodyAndValidate decodes the request body, defaulting the expiry
+// to DefaultExpiry from now. Expiries must be in the future and no more than
+// MaxExpiry away.
+func decodeTokenBodyAndValidate(body []byte, now time.Time) (*Token, error) {
+	var token *Token
+	if err := schema.Decode(schema.Token, body, &token); err != nil {
+		return nil, err
+	}
+
+	if token.ExpiresAt == nil {
+		expiresAt := now.Add(DefaultExpiry)
+		token.ExpiresAt = &expiresAt
+	}
+
+	pointer := schema.Pointer("expires_at")
+	switch {
+	case !token.ExpiresAt.After(now):
+		return nil, schema.NewValidationError(pointer, "must be in the future")
+	case token.ExpiresAt.After(now.Add(MaxExpiry)):
+		return nil, schema.NewValidationError(pointer, "cannot be more than %d days away", int(MaxExpiry.Hours()/24))
+	}
+
+	return token, nil
+}
+
+// writeFindError responds with a 404 when a token couldn't be found. Any other
+// error, including a malformed identifier, is logged and treated the same way
+// to match the other resources.
+func writeFindError(w http.ResponseWriter, r *http.Request, err error) {
+	if err != pgx.ErrNoRows {
+		log.Debug().Err(err).Msg("tokens: failed to find token")
+	}
+	apierror.Write(w, r, errTokenNotFound)
+}
+
+func writeJSONResponse(w http.ResponseWriter, bytes []byte, statusCode int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if n, err := w.Write(bytes); err != nil {
+		log.Printf("%v", err)
+	} else if n != len(bytes) {
+		log.Printf("short write: %d/%d", n, len(bytes))
+	}
+}
@@ -0,0 +1,73 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Querier runs queries, as implemented by *pgx.ConnPool, *pgx.Conn and
+// *pgx.Tx.
+type Querier interface {
+	QueryEx(ctx context.Context, sql string, options *pgx.QueryExOptions, args ...interface{}) (*pgx.Rows, error)
+	ExecEx(ctx context.Context, sql string, options *pgx.QueryExOptions, args ...interface{}) (pgx.CommandTag, error)
+}
+
+// QueryEx runs a query with q as with (*pgx.ConnPool).QueryEx, recording a
+// client span until the query has been sent and its first result received.
+func QueryEx(ctx context.Context, q Querier, sql string, options *pgx.QueryExOptions, args ...interface{}) (*pgx.Rows, error) {
+	ctx, span := startQuery(ctx, sql)
+	rows, err := q.QueryEx(ctx, sql, options, args...)
+	Finish(span, err)
+	return rows, err
+}
+
+// QueryRowEx runs a query expected to return at most one row with q, as with
+// (*pgx.ConnPool).QueryRowEx, recording a client span around it.
+func QueryRowEx(ctx context.Context, q Querier, sql string, options *pgx.QueryExOptions, args ...interface{}) *pgx.Row {
+	ctx, span := startQuery(ctx, sql)
+	rows, err := q.QueryEx(ctx, sql, options, args...)
+	Finish(span, err)
+	return (*pgx.Row)(rows)
+}
+
+// ExecEx runs a statement with q as with (*pgx.ConnPool).ExecEx, recording a
+// client span around it.
+func ExecEx(ctx context.Context, q Querier, sql string, options *pgx.QueryExOptions, args ...interface{}) (pgx.CommandTag, error) {
+	ctx, span := startQuery(ctx, sql)
+	tag, err := q.ExecEx(ctx, sql, options, args...)
+	if err == nil {
+		span.SetAttributes(attribute.Int64("db.rows_affected", tag.RowsAffected()))
+	}
+	Finish(span, err)
+	return tag, err
+}
+
+// startQuery starts a span named after the operation of sql, such as SELECT.
+// Arguments are never recorded since they may contain secrets.
+func startQuery(ctx context.Context, sql string) (context.Context, trace.Span) {
+	statement := strings.Join(strings.Fields(sql), " ")
+	operation := statement
+	if i := strings.IndexByte(statement, ' '); i > 0 {
+		operation = statement[:i]
+	}
+	operation = strings.ToUpper(operation)
+
+	return tracer().Start(ctx, "db "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(statement),
+		),
+	)
+}
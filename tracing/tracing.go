@@ -0,0 +1,98 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package tracing records OpenTelemetry spans around requests, database
+// queries and calls to CloudAPI and Nomad. Until a tracer provider has been
+// installed with Install every span is a no-op.
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName names the tracer of every span recorded by TSG.
+const instrumentationName = "github.com/joyent/triton-service-groups"
+
+// TracesPath is the path the collector receives spans on, relative to its
+// endpoint.
+const TracesPath = "/v1/traces"
+
+// NewExporter constructs an exporter sending spans with OTLP over HTTP to the
+// collector at endpoint, such as http://127.0.0.1:4318.
+func NewExporter(ctx context.Context, endpoint string) (sdktrace.SpanExporter, error) {
+	return otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(strings.TrimSuffix(endpoint, "/")+TracesPath),
+	)
+}
+
+// NewProvider constructs a tracer provider exporting the spans of the service
+// named serviceName in batches to exporter.
+func NewProvider(exporter sdktrace.SpanExporter, serviceName string) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+}
+
+// Install installs provider as the source of every span, along with the W3C
+// Trace Context propagator. The returned function exports the remaining spans
+// and uninstalls provider.
+func Install(provider *sdktrace.TracerProvider) func(ctx context.Context) error {
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return func(ctx context.Context) error {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		return provider.Shutdown(ctx)
+	}
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// StartClient starts a client span around a call to operation of an external
+// service, such as CloudAPI or Nomad.
+func StartClient(ctx context.Context, service, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append([]attribute.KeyValue{
+		semconv.PeerService(service),
+		semconv.RPCMethod(operation),
+	}, attrs...)
+	return tracer().Start(ctx, service+" "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// Finish ends span, marking it as failed with err unless err is nil.
+func Finish(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID returns the identifier of the trace of the span carried by ctx, or
+// an empty string if ctx carries no span.
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
@@ -0,0 +1,94 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func install(t *testing.T) *tracetest.SpanRecorder {
+	rec := tracetest.NewSpanRecorder()
+	shutdown := tracing.Install(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { assert.NoError(t, shutdown(context.Background())) })
+	return rec
+}
+
+func TestStartClient(t *testing.T) {
+	rec := install(t)
+
+	assert.Empty(t, tracing.TraceID(context.Background()))
+
+	ctx, span := tracing.StartClient(context.Background(), "nomad", "register",
+		attribute.String("nomad.job_id", "job"))
+	assert.NotEmpty(t, tracing.TraceID(ctx))
+	tracing.Finish(span, errors.New("unavailable"))
+
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "nomad register", spans[0].Name())
+	assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "unavailable", spans[0].Status().Description)
+	assert.Contains(t, spans[0].Attributes(), attribute.String("peer.service", "nomad"))
+	assert.Contains(t, spans[0].Attributes(), attribute.String("nomad.job_id", "job"))
+}
+
+func TestHandler(t *testing.T) {
+	rec := install(t)
+
+	var traceID string
+	handler := tracing.Handler(func(*http.Request) string {
+		return "GetGroup"
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID = tracing.TraceID(r.Context())
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/tsg/groups/1", nil)
+	req.Header.Set("Traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GetGroup", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", traceID)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", span.SpanContext().TraceID().String())
+	assert.Equal(t, "b7ad6b7169203331", span.Parent().SpanID().String())
+	assert.True(t, span.Parent().IsRemote())
+	assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", http.StatusBadGateway))
+	assert.Equal(t, codes.Error, span.Status().Code)
+}
+
+func TestNewExporter(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+	}))
+	defer collector.Close()
+
+	exporter, err := tracing.NewExporter(context.Background(), collector.URL+"/")
+	require.NoError(t, err)
+
+	provider := tracing.NewProvider(exporter, "tsg-test")
+	_, span := provider.Tracer("test").Start(context.Background(), "span")
+	span.End()
+	require.NoError(t, provider.Shutdown(context.Background()))
+
+	req := <-requests
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, tracing.TracesPath, req.URL.Path)
+	assert.Equal(t, "application/x-protobuf", req.Header.Get("Content-Type"))
+}
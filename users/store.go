@@ -0,0 +1,105 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package users
+
+import (
+	"context"
+
+	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/pgtype"
+	"github.com/joyent/triton-service-groups/convert"
+	"github.com/joyent/triton-service-groups/tracing"
+)
+
+type Store struct {
+	pool *pgx.ConnPool
+}
+
+// NewStore returns a new store object.
+func NewStore(pool *pgx.ConnPool) *Store {
+	return &Store{
+		pool: pool,
+	}
+}
+
+const selectUsers = `
+SELECT id, username, account_id, permission, created_at, updated_at
+FROM tsg_users
+`
+
+// scanner is implemented by both *pgx.Row and *pgx.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (s *Store) scanUser(row scanner) (*User, error) {
+	var (
+		id         pgtype.UUID
+		accountID  pgtype.UUID
+		name       string
+		permission string
+		createdAt  pgtype.Timestamp
+		updatedAt  pgtype.Timestamp
+	)
+
+	err := row.Scan(
+		&id,
+		&name,
+		&accountID,
+		&permission,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	user := New(s)
+	user.ID = convert.BytesToUUID(id.Bytes)
+	user.UserName = name
+	user.AccountID = convert.BytesToUUID(accountID.Bytes)
+	user.Permission = Permission(permission)
+	user.CreatedAt = createdAt.Time
+	user.UpdatedAt = updatedAt.Time
+
+	return user, nil
+}
+
+// FindByID finds a user of an account by a specific ID.
+func (s *Store) FindByID(ctx context.Context, userID string, accountID string) (*User, error) {
+	query := selectUsers + `WHERE id = $1 AND account_id = $2 AND archived = false;`
+
+	return s.scanUser(tracing.QueryRowEx(ctx, s.pool, query, nil, userID, accountID))
+}
+
+// FindByName finds a user of an account by a specific username.
+func (s *Store) FindByName(ctx context.Context, userName string, accountID string) (*User, error) {
+	query := selectUsers + `WHERE username = $1 AND account_id = $2 AND archived = false;`
+
+	return s.scanUser(tracing.QueryRowEx(ctx, s.pool, query, nil, userName, accountID))
+}
+
+// FindByAccount finds every user of an account, ordered by username.
+func (s *Store) FindByAccount(ctx context.Context, accountID string) ([]*User, error) {
+	query := selectUsers + `WHERE account_id = $1 AND archived = false ORDER BY username;`
+
+	rows, err := tracing.QueryEx(ctx, s.pool, query, nil, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		user, err := s.scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
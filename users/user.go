@@ -0,0 +1,178 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package users
+
+import (
+	"context"
+	"time"
+
+	"github.com/joyent/triton-service-groups/tracing"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNoAccountID = errors.New("missing account identifer for save")
+	ErrMissingID   = errors.New("missing identifer for save")
+)
+
+// Permission describes the level of access granted to an authenticated
+// session. Each permission grants everything granted by the permissions before
+// it.
+type Permission string
+
+const (
+	// PermissionReadOnly can only read groups, templates and secrets.
+	PermissionReadOnly Permission = "read-only"
+
+	// PermissionScaleOnly can additionally increment and decrement the
+	// capacity of a group.
+	PermissionScaleOnly Permission = "scale-only"
+
+	// PermissionFull can additionally create, update and delete resources.
+	PermissionFull Permission = "full"
+
+	// PermissionOwner is held by the account owner only and can additionally
+	// manage the permissions of sub-users. It can't be granted to a sub-user.
+	PermissionOwner Permission = "owner"
+
+	// PermissionOperator is held by the owners of the accounts configured as
+	// operators of the TSG service itself and can additionally manage which
+	// accounts can access TSG. It can't be granted to a sub-user.
+	PermissionOperator Permission = "operator"
+)
+
+var permissionLevels = map[Permission]int{
+	PermissionReadOnly:  1,
+	PermissionScaleOnly: 2,
+	PermissionFull:      3,
+	PermissionOwner:     4,
+	PermissionOperator:  5,
+}
+
+// Allows returns true if p grants at least the access granted by required.
+func (p Permission) Allows(required Permission) bool {
+	have, ok := permissionLevels[p]
+	if !ok {
+		return false
+	}
+	return have >= permissionLevels[required]
+}
+
+// Assignable returns true if p can be granted to a sub-user.
+func (p Permission) Assignable() bool {
+	switch p {
+	case PermissionReadOnly, PermissionScaleOnly, PermissionFull:
+		return true
+	default:
+		return false
+	}
+}
+
+// User represents the data associated with an tsg_users row. A user is a
+// Triton sub-user of an account which has been granted access to TSG by the
+// account owner.
+type User struct {
+	ID         string
+	UserName   string
+	AccountID  string
+	Permission Permission
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Archived   bool
+
+	store *Store
+}
+
+// New constructs a new User with the Store for backend persistence.
+func New(store *Store) *User {
+	return &User{
+		store: store,
+	}
+}
+
+// Insert inserts a new user into the tsg_users table.
+func (u *User) Insert(ctx context.Context) error {
+	if u.AccountID == "" {
+		return ErrNoAccountID
+	}
+
+	query := `
+INSERT INTO tsg_users (username, account_id, permission, created_at, updated_at)
+VALUES ($1, $2, $3, NOW(), NOW());
+`
+	pool := u.store.pool
+
+	tx, err := pool.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback() // nolint: errcheck
+
+	_, err = tracing.ExecEx(ctx, pool, query, nil,
+		u.UserName,
+		u.AccountID,
+		string(u.Permission),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert user")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	user, err := u.store.FindByName(ctx, u.UserName, u.AccountID)
+	if err != nil {
+		return errors.Wrap(err, "failed to find user after insert")
+	}
+
+	u.ID = user.ID
+	u.CreatedAt = user.CreatedAt
+	u.UpdatedAt = user.UpdatedAt
+
+	return nil
+}
+
+// Save saves an users.User object and it's field values.
+func (u *User) Save(ctx context.Context) error {
+	if u.ID == "" {
+		return ErrMissingID
+	}
+
+	query := `
+UPDATE tsg_users SET (username, permission, archived, updated_at) = ($3, $4, $5, $6)
+WHERE id = $1 AND account_id = $2;
+`
+	updatedAt := time.Now()
+
+	pool := u.store.pool
+
+	tx, err := pool.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback() // nolint: errcheck
+
+	_, err = tracing.ExecEx(ctx, pool, query, nil,
+		u.ID,
+		u.AccountID,
+		u.UserName,
+		string(u.Permission),
+		u.Archived,
+		updatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update user")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	u.UpdatedAt = updatedAt
+
+	return nil
+}
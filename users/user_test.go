@@ -0,0 +1,103 @@
+package users_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/accounts"
+	"github.com/joyent/triton-service-groups/testutils"
+	"github.com/joyent/triton-service-groups/users"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissionAllows(t *testing.T) {
+	tests := []struct {
+		name     string
+		have     users.Permission
+		required users.Permission
+		allowed  bool
+	}{
+		{"read-only reads", users.PermissionReadOnly, users.PermissionReadOnly, true},
+		{"read-only scales", users.PermissionReadOnly, users.PermissionScaleOnly, false},
+		{"scale-only reads", users.PermissionScaleOnly, users.PermissionReadOnly, true},
+		{"scale-only scales", users.PermissionScaleOnly, users.PermissionScaleOnly, true},
+		{"scale-only writes", users.PermissionScaleOnly, users.PermissionFull, false},
+		{"full writes", users.PermissionFull, users.PermissionFull, true},
+		{"full manages users", users.PermissionFull, users.PermissionOwner, false},
+		{"owner manages users", users.PermissionOwner, users.PermissionOwner, true},
+		{"owner manages allow-list", users.PermissionOwner, users.PermissionOperator, false},
+		{"operator manages users", users.PermissionOperator, users.PermissionOwner, true},
+		{"empty", users.Permission(""), users.PermissionReadOnly, false},
+		{"unknown", users.Permission("admin"), users.PermissionReadOnly, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.allowed, test.have.Allows(test.required))
+		})
+	}
+}
+
+func TestPermissionAssignable(t *testing.T) {
+	assert.True(t, users.PermissionReadOnly.Assignable())
+	assert.True(t, users.PermissionScaleOnly.Assignable())
+	assert.True(t, users.PermissionFull.Assignable())
+	assert.False(t, users.PermissionOwner.Assignable())
+	assert.False(t, users.PermissionOperator.Assignable())
+	assert.False(t, users.Permission("admin").Assignable())
+}
+
+func TestInsertAndSave(t *testing.T) {
+	if os.Getenv("TSG_TEST") == "" {
+		t.Skip("Acceptance tests skipped unless env 'TSG_TEST=1' set")
+		return
+	}
+
+	db, err := testutils.NewTestDB()
+	if err != nil {
+		t.Error(err)
+	}
+	db.Clear(t)
+	defer db.Clear(t)
+
+	ctx := context.Background()
+
+	account := accounts.New(accounts.NewStore(db.Conn))
+	account.AccountName = "baconuser"
+	require.NoError(t, account.Insert(ctx))
+
+	store := users.NewStore(db.Conn)
+
+	user := users.New(store)
+	user.UserName = "demouser"
+	user.AccountID = account.ID
+	user.Permission = users.PermissionScaleOnly
+
+	require.NoError(t, user.Insert(ctx))
+	assert.NotZero(t, user.ID)
+	assert.NotZero(t, user.CreatedAt)
+
+	found, err := store.FindByName(ctx, "demouser", account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+	assert.Equal(t, users.PermissionScaleOnly, found.Permission)
+
+	found.Permission = users.PermissionFull
+	require.NoError(t, found.Save(ctx))
+
+	found, err = store.FindByID(ctx, user.ID, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, users.PermissionFull, found.Permission)
+
+	all, err := store.FindByAccount(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+
+	found.Archived = true
+	require.NoError(t, found.Save(ctx))
+
+	_, err = store.FindByID(ctx, user.ID, account.ID)
+	assert.Equal(t, pgx.ErrNoRows, err)
+}
@@ -0,0 +1,293 @@
+//  Copyright (c) 2018, Joyent, Inc. All rights reserved.
+//  This Source Code Form is subject to the terms of the Mozilla Public
+//  License, v. 2.0. If a copy of the MPL was not distributed with this
+//  file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package users_v1
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/server/apierror"
+	"github.com/joyent/triton-service-groups/server/handlers"
+	"github.com/joyent/triton-service-groups/server/schema"
+	"github.com/joyent/triton-service-groups/users"
+	"github.com/rs/zerolog/log"
+)
+
+var errUserNotFound = apierror.NotFound("user not found")
+
+// User represents a Triton sub-user which has been granted access to the
+// account's service groups.
+type User struct {
+	ID         string    `json:"id"`
+	UserName   string    `json:"username"`
+	Permission string    `json:"permission"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func newUser(user *users.User) *User {
+	return &User{
+		ID:         user.ID,
+		UserName:   user.UserName,
+		Permission: string(user.Permission),
+		CreatedAt:  user.CreatedAt,
+		UpdatedAt:  user.UpdatedAt,
+	}
+}
+
+func getStore(ctx context.Context) (*users.Store, error) {
+	pool, ok := handlers.GetDBPool(ctx)
+	if !ok {
+		return nil, handlers.ErrNoConnPool
+	}
+	return users.NewStore(pool), nil
+}
+
+func Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	vars := mux.Vars(r)
+	identifier := vars["identifier"]
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	user, err := store.FindByID(ctx, identifier, session.AccountID)
+	if err != nil {
+		writeFindError(w, r, err)
+		return
+	}
+
+	bytes, err := json.Marshal(newUser(user))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+func Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	input, err := decodeUserBodyAndValidate(body)
+	if err != nil {
+		schema.WriteError(w, r, err)
+		return
+	}
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	_, err = store.FindByName(ctx, input.UserName, session.AccountID)
+	switch err {
+	case pgx.ErrNoRows:
+	case nil:
+		apierror.Write(w, r, apierror.Conflict("Cannot create user %q, "+
+			"conflicts with another user.", input.UserName))
+		return
+	default:
+		apierror.Write(w, r, err)
+		return
+	}
+
+	user := users.New(store)
+	user.UserName = input.UserName
+	user.AccountID = session.AccountID
+	user.Permission = users.Permission(input.Permission)
+
+	if err := user.Insert(ctx); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	log.Info().
+		Str("actor", session.Actor()).
+		Str("user_name", user.UserName).
+		Str("permission", string(user.Permission)).
+		Msg("users: granted sub-user access")
+
+	bytes, err := json.Marshal(newUser(user))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", path.Join(r.URL.Path, user.ID))
+	writeJSONResponse(w, bytes, http.StatusCreated)
+}
+
+func Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	vars := mux.Vars(r)
+	identifier := vars["identifier"]
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	input, err := decodeUserBodyAndValidate(body)
+	if err != nil {
+		schema.WriteError(w, r, err)
+		return
+	}
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	user, err := store.FindByID(ctx, identifier, session.AccountID)
+	if err != nil {
+		writeFindError(w, r, err)
+		return
+	}
+
+	if input.UserName != user.UserName {
+		apierror.Write(w, r, apierror.BadRequest("The username %q does not match "+
+			"the username on the record.", input.UserName))
+		return
+	}
+
+	user.Permission = users.Permission(input.Permission)
+
+	if err := user.Save(ctx); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	log.Info().
+		Str("actor", session.Actor()).
+		Str("user_name", user.UserName).
+		Str("permission", string(user.Permission)).
+		Msg("users: updated sub-user permission")
+
+	bytes, err := json.Marshal(newUser(user))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+func Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	vars := mux.Vars(r)
+	identifier := vars["identifier"]
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	user, err := store.FindByID(ctx, identifier, session.AccountID)
+	if err != nil {
+		writeFindError(w, r, err)
+		return
+	}
+
+	user.Archived = true
+
+	if err := user.Save(ctx); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	log.Info().
+		Str("actor", session.Actor()).
+		Str("user_name", user.UserName).
+		Msg("users: revoked sub-user access")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := handlers.GetAuthSession(ctx)
+
+	store, err := getStore(ctx)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	rows, err := store.FindByAccount(ctx, session.AccountID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	list := make([]*User, 0, len(rows))
+	for _, user := range rows {
+		list = append(list, newUser(user))
+	}
+
+	bytes, err := json.Marshal(list)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, bytes, http.StatusOK)
+}
+
+func decodeUserBodyAndValidate(body []byte) (*User, error) {
+	var user *User
+	if err := schema.Decode(schema.User, body, &user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// writeFindError responds with a 404 when a user couldn't be found. Any other
+// error, including a malformed identifier, is logged and treated the same way
+// to match the other resources.
+func writeFindError(w http.ResponseWriter, r *http.Request, err error) {
+	if err != pgx.ErrNoRows {
+		log.Debug().Err(err).Msg("users: failed to find user")
+	}
+	apierror.Write(w, r, errUserNotFound)
+}
+
+func writeJSONResponse(w http.ResponseWriter, bytes []byte, statusCode int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if n, err := w.Write(bytes); err != nil {
+		log.Printf("%v", err)
+	} else if n != len(bytes) {
+		log.Printf("short write: %d/%d", n, len(bytes))
+	}
+}